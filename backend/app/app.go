@@ -1,13 +1,21 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"slices"
 	"strings"
 
+	"digital-library/backend/blob"
 	"digital-library/backend/config"
 	"digital-library/backend/database"
+	"digital-library/backend/handlers"
+	"digital-library/backend/marc"
 	"digital-library/backend/routes"
+	"digital-library/backend/store"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -21,9 +29,11 @@ func QueryParamsMiddleware(c *fiber.Ctx) error {
 	// Get the original request
 	req := c.Request()
 
-	// Log the original request details
-	log.Printf("QueryParamsMiddleware - Original URI: %s", string(req.RequestURI()))
-	log.Printf("QueryParamsMiddleware - Raw Query: %s", string(req.URI().QueryString()))
+	// Log the original request details, without the feed tokens reading
+	// apps pass in the query string
+	path, query, _ := strings.Cut(string(req.RequestURI()), "?")
+	log.Printf("QueryParamsMiddleware - Original URI: %s", path+redactQuery("?"+query))
+	log.Printf("QueryParamsMiddleware - Raw Query: %s", redactQuery(string(req.URI().QueryString())))
 
 	// If there's no query string in the URI, try to get it from the context
 	if len(req.URI().QueryString()) == 0 {
@@ -31,22 +41,60 @@ func QueryParamsMiddleware(c *fiber.Ctx) error {
 		if queryStr, ok := c.Context().Value("query_string").(string); ok && queryStr != "" {
 			// Set the query string in the request
 			req.URI().SetQueryString(queryStr)
-			log.Printf("QueryParamsMiddleware - Set query from context: %s", queryStr)
+			log.Printf("QueryParamsMiddleware - Set query from context: %s", redactQuery(queryStr))
 		}
 	}
 
 	return c.Next()
 }
 
-// SetupApp creates and configures a new Fiber app
+// redactedParams are the query parameters whose values are not logged
+var redactedParams = []string{"token"}
+
+// redactQuery hides the values of redactedParams in a raw query string,
+// which may start with "?"
+func redactQuery(query string) string {
+	if query == "?" {
+		return ""
+	}
+	params := strings.Split(query, "&")
+	for i, param := range params {
+		prefix, param := "", param
+		if i == 0 && strings.HasPrefix(param, "?") {
+			prefix, param = "?", param[1:]
+		}
+		name, _, hasValue := strings.Cut(param, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		if hasValue && slices.Contains(redactedParams, name) {
+			params[i] = prefix + name + "=REDACTED"
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// SetupApp creates and configures a new Fiber app using the environment config
 func SetupApp() *fiber.App {
-	// Load Config
-	cfg := config.LoadConfig()
+	return New(config.LoadConfig())
+}
 
+// New creates and configures a new Fiber app for the given config
+func New(cfg *config.Config) *fiber.App {
 	// Connect Database
 	database.Connect(cfg)
 
+	// Apply pending schema migrations if enabled
+	if cfg.AutoMigrate {
+		if _, err := database.MigrateUp(context.Background(), database.DB); err != nil {
+			log.Fatalf("Unable to apply database migrations: %v\n", err)
+		}
+	}
+
 	app := fiber.New(fiber.Config{
+		// Hand request bodies beyond the body limit, such as large catalog
+		// imports, to the handler as a stream instead of rejecting them
+		StreamRequestBody: true,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			// Log the error
 			log.Printf("Error: %v", err)
@@ -97,8 +145,43 @@ func SetupApp() *fiber.App {
 	// Setup Swagger
 	app.Get("/swagger/*", swagger.HandlerDefault)
 
-	// Setup Routes
-	routes.SetupRoutes(app, cfg)
+	// Setup Routes backed by the Postgres stores
+	db := store.NewPostgres(database.DB)
+	h := handlers.New(db)
+	h.FineBlockThreshold = cfg.FineBlockThreshold
+	if cfg.MARCMappingFile != "" {
+		mapping, err := marc.LoadMappingFile(cfg.MARCMappingFile)
+		if err != nil {
+			log.Fatalf("Unable to load the MARC mapping: %v\n", err)
+		}
+		h.MARCMapping = mapping
+	}
+	// Without storage, such as a local directory on a read-only filesystem,
+	// the rest of the API still serves and the file endpoints answer 503
+	if blobs, err := newBlobStore(cfg); err != nil {
+		log.Printf("File storage is unavailable, book files are disabled: %v", err)
+	} else {
+		h.Blobs = blobs
+	}
+	h.MaxFileSize = cfg.MaxFileSize
+	routes.SetupRoutes(app, cfg, h)
 
 	return app
 }
+
+// newBlobStore creates the store of the e-book files of books
+func newBlobStore(cfg *config.Config) (blob.Store, error) {
+	switch cfg.FileStorage {
+	case "local":
+		return blob.NewLocal(cfg.FileStorageDir)
+	case "s3":
+		return blob.NewS3(blob.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Bucket:          cfg.S3Bucket,
+			Region:          cfg.S3Region,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretKey,
+		})
+	}
+	return nil, fmt.Errorf("unknown FILE_STORAGE %q, expected local or s3", cfg.FileStorage)
+}
@@ -0,0 +1,24 @@
+package app
+
+import "testing"
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", ""},
+		{"?", ""},
+		{"search=dune&limit=2", "search=dune&limit=2"},
+		{"token=eyJhbGciOi.x.y", "token=REDACTED"},
+		{"?token=eyJhbGciOi.x.y&limit=2", "?token=REDACTED&limit=2"},
+		{"?limit=2&token=abc&search=token%3Dabc", "?limit=2&token=REDACTED&search=token%3Dabc"},
+		{"tok%65n=abc", "token=REDACTED"},
+		{"token", "token"},
+	}
+	for _, tt := range tests {
+		if got := redactQuery(tt.query); got != tt.want {
+			t.Errorf("redactQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Package blob stores the content of uploaded files, such as the e-book
+// files of books, on the local filesystem or in an S3-compatible object
+// store.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned for content missing from a store
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps content under keys such as books/42/3f9a. Keys are made of
+// slash-separated segments of letters, digits, dots, dashes and underscores.
+type Store interface {
+	// Put stores the size bytes read from r under key, replacing any
+	// content stored there. Readers that give another number of bytes are
+	// an error, and nothing is stored.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get reads length bytes of the content under key starting at offset,
+	// or up to the end when length is negative
+	Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete removes the content under key. Missing content is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey reports whether a key is made of safe, non-empty segments, so it
+// cannot name a path outside a store
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	segment := 0
+	for i := 0; i <= len(key); i++ {
+		if i == len(key) || key[i] == '/' {
+			if segment == 0 || key[i-segment:i] == "." || key[i-segment:i] == ".." {
+				return false
+			}
+			segment = 0
+			continue
+		}
+		switch c := key[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '.', c == '-', c == '_':
+			segment++
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// errInvalidKey is returned for keys validKey rejects
+var errInvalidKey = errors.New("invalid blob key")
+
+// errSize is returned when a reader does not give the size it was put with
+var errSize = errors.New("content does not match its size")
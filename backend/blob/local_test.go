@@ -0,0 +1,85 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	if err := store.Put(ctx, "books/1/abc", strings.NewReader("0123456789"), 10, "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	reads := []struct {
+		offset, length int64
+		want           string
+	}{
+		{0, -1, "0123456789"},
+		{3, 4, "3456"},
+		{8, -1, "89"},
+	}
+	for _, tt := range reads {
+		r, err := store.Get(ctx, "books/1/abc", tt.offset, tt.length)
+		if err != nil {
+			t.Fatalf("Get(%d, %d): %v", tt.offset, tt.length, err)
+		}
+		got, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || string(got) != tt.want {
+			t.Errorf("Get(%d, %d) = %q, %v, want %q", tt.offset, tt.length, got, err, tt.want)
+		}
+	}
+
+	// Content of another size leaves the stored content alone
+	for _, content := range []string{"012", "0123456789ab"} {
+		if err := store.Put(ctx, "books/1/abc", strings.NewReader(content), 10, "text/plain"); !errors.Is(err, errSize) {
+			t.Errorf("Put(%q) = %v, want %v", content, err, errSize)
+		}
+	}
+	if r, err := store.Get(ctx, "books/1/abc", 0, -1); err != nil {
+		t.Fatalf("Get: %v", err)
+	} else if got, _ := io.ReadAll(r); string(got) != "0123456789" {
+		t.Errorf("content after failed puts = %q", got)
+	}
+
+	if err := store.Delete(ctx, "books/1/abc"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete(ctx, "books/1/abc"); err != nil {
+		t.Errorf("Delete missing content = %v, want nil", err)
+	}
+	if _, err := store.Get(ctx, "books/1/abc", 0, -1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get deleted content = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"books/42/3f9a", true},
+		{"books/42/Dune_v1.2-final", true},
+		{"", false},
+		{"books//3f9a", false},
+		{"/books/42", false},
+		{"books/42/", false},
+		{"books/../etc", false},
+		{"books/./42", false},
+		{"books/42/a b", false},
+		{`books\42`, false},
+	}
+	for _, tt := range tests {
+		if got := validKey(tt.key); got != tt.want {
+			t.Errorf("validKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config configures a store in a bucket of Amazon S3 or a compatible
+// service, such as MinIO or Cloudflare R2
+type S3Config struct {
+	Endpoint        string // Such as https://s3.eu-west-1.amazonaws.com
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// S3 stores content as objects in a bucket, addressed by path
+// (endpoint/bucket/key) as every S3-compatible service supports. Requests
+// are signed with AWS Signature Version 4.
+type S3 struct {
+	endpoint *url.URL
+	bucket   string
+	signer   signer
+	client   *http.Client
+}
+
+var _ Store = (*S3)(nil)
+
+// NewS3 creates a store in the bucket of cfg
+func NewS3(cfg S3Config) (*S3, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" || cfg.Region == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("S3 bucket, region and credentials are required")
+	}
+	return &S3{
+		endpoint: endpoint,
+		bucket:   cfg.Bucket,
+		signer:   signer{region: cfg.Region, service: "s3", accessKeyID: cfg.AccessKeyID, secretAccessKey: cfg.SecretAccessKey},
+		client:   &http.Client{},
+	}, nil
+}
+
+// request builds a signed request for the object of key. The body is not
+// signed, so content streams through without being read twice.
+func (s *S3) request(ctx context.Context, method, key string, body io.Reader, header http.Header) (*http.Request, error) {
+	if !validKey(key) {
+		return nil, errInvalidKey
+	}
+	u := *s.endpoint
+	u.Path += "/" + s.bucket + "/" + key
+	u.RawPath = ""
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	s.signer.sign(req, time.Now())
+	return req, nil
+}
+
+// do sends a request and checks its status, draining and closing the body
+// of failed responses
+func (s *S3) do(req *http.Request, ok ...int) (*http.Response, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range ok {
+		if resp.StatusCode == status {
+			return resp, nil
+		}
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	return nil, fmt.Errorf("S3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+// Put uploads the content as the object of key
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	req, err := s.request(ctx, http.MethodPut, key, &sizedReader{r: r, left: size}, header)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	resp, err := s.do(req, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get downloads the object of key, or the range of it asked for
+func (s *S3) Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	header := http.Header{}
+	switch {
+	case length == 0:
+		// A range cannot be empty, and no request is needed
+		return io.NopCloser(strings.NewReader("")), nil
+	case length > 0:
+		header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-"+strconv.FormatInt(offset+length-1, 10))
+	case offset > 0:
+		header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+	req, err := s.request(ctx, http.MethodGet, key, nil, header)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req, http.StatusOK, http.StatusPartialContent)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete deletes the object of key. Deleting a missing object succeeds.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, http.StatusOK, http.StatusNoContent)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// sizedReader fails a reader that gives fewer or more bytes than expected,
+// so the upload is aborted rather than stored short or cut off
+type sizedReader struct {
+	r    io.Reader
+	left int64
+}
+
+func (s *sizedReader) Read(p []byte) (int, error) {
+	if s.left == 0 {
+		// Check that the reader is done too
+		var extra [1]byte
+		if n, err := s.r.Read(extra[:]); n > 0 || err != nil && err != io.EOF {
+			return 0, errSize
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > s.left {
+		p = p[:s.left]
+	}
+	n, err := s.r.Read(p)
+	s.left -= int64(n)
+	if err == io.EOF && s.left > 0 {
+		return n, errSize
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
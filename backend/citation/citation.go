@@ -0,0 +1,98 @@
+// Package citation formats books as citations in the APA, MLA and Chicago
+// styles, and writes them as BibTeX, RIS and CSL-JSON references for
+// reference managers.
+package citation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Work is a book to cite
+type Work struct {
+	ID        string
+	Title     string
+	Authors   []Name
+	Publisher string
+	Year      int // Zero when unknown
+	ISBN      string
+	Subject   string
+}
+
+// Name is the name of an author. Given is empty for organizations and
+// people known by a single name.
+type Name struct {
+	Family string
+	Given  string
+}
+
+// ParseNames splits the author of a book into names. Several authors are
+// separated by semicolons, "and" or "&". Each name is either inverted, as in
+// "Herbert, Frank", or in direct order, as in "Frank Herbert", in which case
+// the last word is taken as the family name.
+func ParseNames(author string) []Name {
+	var names []Name
+	for _, part := range splitAuthors(author) {
+		if family, given, inverted := strings.Cut(part, ","); inverted {
+			// Further parts, such as the dates in "Herbert, Frank, 1920-1986",
+			// are not part of the name
+			given, _, _ = strings.Cut(given, ",")
+			names = append(names, Name{Family: strings.TrimSpace(family), Given: strings.TrimSpace(given)})
+			continue
+		}
+		words := strings.Fields(part)
+		last := len(words) - 1
+		names = append(names, Name{Family: words[last], Given: strings.Join(words[:last], " ")})
+	}
+	return names
+}
+
+func splitAuthors(author string) []string {
+	for _, separator := range []string{" and ", " & "} {
+		author = strings.ReplaceAll(author, separator, ";")
+	}
+	var parts []string
+	for _, part := range strings.Split(author, ";") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// Inverted returns the name family name first, as in "Herbert, Frank"
+func (n Name) Inverted() string {
+	if n.Given == "" {
+		return n.Family
+	}
+	return n.Family + ", " + n.Given
+}
+
+// Direct returns the name in direct order, as in "Frank Herbert"
+func (n Name) Direct() string {
+	if n.Given == "" {
+		return n.Family
+	}
+	return n.Given + " " + n.Family
+}
+
+// Initials returns the given names as initials, as in "F. S." for
+// "F. Scott" or "J.-P." for "Jean-Paul"
+func (n Name) Initials() string {
+	var initials []string
+	for _, word := range strings.FieldsFunc(n.Given, func(r rune) bool { return r == ' ' || r == '.' }) {
+		var parts []string
+		for _, part := range strings.Split(word, "-") {
+			for _, r := range part {
+				if unicode.IsLetter(r) {
+					parts = append(parts, string(r)+".")
+					break
+				}
+			}
+		}
+		if len(parts) > 0 {
+			initials = append(initials, strings.Join(parts, "-"))
+		}
+	}
+	return strings.Join(initials, " ")
+}
@@ -0,0 +1,78 @@
+package citation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNames(t *testing.T) {
+	tests := []struct {
+		author string
+		want   []Name
+	}{
+		{"Frank Herbert", []Name{{"Herbert", "Frank"}}},
+		{"Herbert, Frank, 1920-1986", []Name{{"Herbert", "Frank"}}},
+		{"Homer", []Name{{"Homer", ""}}},
+		{"Terry Pratchett and Neil Gaiman", []Name{{"Pratchett", "Terry"}, {"Gaiman", "Neil"}}},
+		{"Strunk, William; White, E. B. & Angell, Roger", []Name{{"Strunk", "William"}, {"White", "E. B."}, {"Angell", "Roger"}}},
+		{" ; ", nil},
+	}
+	for _, tt := range tests {
+		if got := ParseNames(tt.author); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseNames(%q) = %v, want %v", tt.author, got, tt.want)
+		}
+	}
+}
+
+func TestInitials(t *testing.T) {
+	tests := []struct {
+		given string
+		want  string
+	}{
+		{"Frank", "F."},
+		{"F. Scott", "F. S."},
+		{"Jean-Paul", "J.-P."},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := (Name{Family: "X", Given: tt.given}).Initials(); got != tt.want {
+			t.Errorf("Initials(%q) = %q, want %q", tt.given, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	dune := Work{Title: "Dune", Authors: ParseNames("Frank Herbert"), Publisher: "Chilton Books", Year: 1965}
+	omens := Work{Title: "Good Omens", Authors: ParseNames("Terry Pratchett & Neil Gaiman")}
+	elements := Work{Title: "The Elements of Style?", Authors: ParseNames("Strunk, William; White, E. B.; Angell, Roger"), Year: 1999}
+
+	tests := []struct {
+		style string
+		work  Work
+		text  string
+	}{
+		{APA, dune, "Herbert, F. (1965). Dune. Chilton Books."},
+		{APA, omens, "Pratchett, T., & Gaiman, N. (n.d.). Good Omens."},
+		{APA, elements, "Strunk, W., White, E. B., & Angell, R. (1999). The Elements of Style?"},
+		{MLA, dune, "Herbert, Frank. Dune. Chilton Books, 1965."},
+		{MLA, omens, "Pratchett, Terry, and Neil Gaiman. Good Omens."},
+		{MLA, elements, "Strunk, William, et al. The Elements of Style? 1999."},
+		{Chicago, dune, "Herbert, Frank. Dune. Chilton Books, 1965."},
+		{Chicago, omens, "Pratchett, Terry, and Neil Gaiman. Good Omens. n.d."},
+		{Chicago, elements, "Strunk, William, E. B. White, and Roger Angell. The Elements of Style? 1999."},
+	}
+	for _, tt := range tests {
+		got, ok := Format(tt.style, tt.work)
+		if !ok || got.Text != tt.text {
+			t.Errorf("Format(%s, %s) = %q, want %q", tt.style, tt.work.Title, got.Text, tt.text)
+		}
+	}
+
+	got, _ := Format(APA, Work{Title: "Tom & Jerry", Authors: ParseNames("Hanna-Barbera")})
+	if want := "Hanna-Barbera. (n.d.). <i>Tom &amp; Jerry</i>."; got.HTML != want {
+		t.Errorf("HTML = %q, want %q", got.HTML, want)
+	}
+	if _, ok := Format("harvard", dune); ok {
+		t.Error("Format(harvard) succeeded, want an unknown style")
+	}
+}
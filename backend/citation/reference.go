@@ -0,0 +1,210 @@
+package citation
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// BibTeXWriter writes works as BibTeX @book entries
+type BibTeXWriter struct {
+	w    io.Writer
+	keys map[string]int // Entries written per citation key
+}
+
+// NewBibTeXWriter returns a BibTeXWriter writing entries to w
+func NewBibTeXWriter(w io.Writer) *BibTeXWriter {
+	return &BibTeXWriter{w: w, keys: make(map[string]int)}
+}
+
+// Write writes the entry of a work. Its key is the first author's family
+// name and the year, such as fitzgerald2004, with a letter added to tell
+// apart later works with the same key.
+func (b *BibTeXWriter) Write(w Work) error {
+	var entry strings.Builder
+	entry.WriteString("@book{" + b.key(w) + ",\n")
+	field := func(name, value string) {
+		if value != "" {
+			entry.WriteString("  " + name + " = {" + value + "},\n")
+		}
+	}
+	authors := make([]string, len(w.Authors))
+	for i, name := range w.Authors {
+		if name.Given == "" {
+			// Braces keep organizations from being split into names
+			authors[i] = "{" + bibtexEscape(name.Family) + "}"
+		} else {
+			authors[i] = bibtexEscape(name.Family) + ", " + bibtexEscape(name.Given)
+		}
+	}
+	field("author", strings.Join(authors, " and "))
+	field("title", bibtexEscape(w.Title))
+	field("publisher", bibtexEscape(w.Publisher))
+	if w.Year != 0 {
+		field("year", strconv.Itoa(w.Year))
+	}
+	field("isbn", bibtexEscape(w.ISBN))
+	field("keywords", bibtexEscape(w.Subject))
+	entry.WriteString("}\n\n")
+	_, err := io.WriteString(b.w, entry.String())
+	return err
+}
+
+func (b *BibTeXWriter) key(w Work) string {
+	var key strings.Builder
+	if len(w.Authors) > 0 {
+		for _, r := range strings.ToLower(w.Authors[0].Family) {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				key.WriteRune(r)
+			}
+		}
+	}
+	if key.Len() == 0 {
+		key.WriteString("book" + w.ID)
+	}
+	if w.Year != 0 {
+		key.WriteString(strconv.Itoa(w.Year))
+	}
+
+	base := key.String()
+	n := b.keys[base]
+	b.keys[base]++
+	switch {
+	case n == 0:
+		return base
+	case n < 26:
+		return base + string(rune('a'+n))
+	}
+	return base + "-" + strconv.Itoa(n+1)
+}
+
+// bibtexReplacer escapes the characters BibTeX and LaTeX treat specially
+var bibtexReplacer = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`&`, `\&`,
+	`%`, `\%`,
+	`$`, `\$`,
+	`#`, `\#`,
+	`_`, `\_`,
+	`~`, `\textasciitilde{}`,
+	`^`, `\textasciicircum{}`,
+)
+
+func bibtexEscape(s string) string {
+	return bibtexReplacer.Replace(oneLine(s))
+}
+
+// RISWriter writes works as RIS records
+type RISWriter struct {
+	w io.Writer
+}
+
+// NewRISWriter returns a RISWriter writing records to w
+func NewRISWriter(w io.Writer) *RISWriter {
+	return &RISWriter{w: w}
+}
+
+// Write writes the record of a work
+func (r *RISWriter) Write(w Work) error {
+	var record strings.Builder
+	tag := func(name, value string) {
+		if value = oneLine(value); value != "" {
+			record.WriteString(name + "  - " + value + "\r\n")
+		}
+	}
+	tag("TY", "BOOK")
+	tag("ID", w.ID)
+	for _, name := range w.Authors {
+		tag("AU", name.Inverted())
+	}
+	tag("TI", w.Title)
+	tag("PB", w.Publisher)
+	if w.Year != 0 {
+		tag("PY", strconv.Itoa(w.Year))
+	}
+	tag("SN", w.ISBN)
+	tag("KW", w.Subject)
+	record.WriteString("ER  - \r\n\r\n")
+	_, err := io.WriteString(r.w, record.String())
+	return err
+}
+
+// oneLine collapses the line breaks and runs of spaces of a value, which
+// RIS tags and BibTeX fields cannot hold
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// cslItem is a CSL-JSON item
+type cslItem struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Author    []cslName `json:"author,omitempty"`
+	Publisher string    `json:"publisher,omitempty"`
+	Issued    *cslDate  `json:"issued,omitempty"`
+	ISBN      string    `json:"ISBN,omitempty"`
+	Keyword   string    `json:"keyword,omitempty"`
+}
+
+type cslName struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Literal string `json:"literal,omitempty"` // Organizations and single names
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+// CSLWriter writes works as a CSL-JSON array. Close ends the array.
+type CSLWriter struct {
+	w     io.Writer
+	items int
+}
+
+// NewCSLWriter returns a CSLWriter writing an array to w
+func NewCSLWriter(w io.Writer) *CSLWriter {
+	return &CSLWriter{w: w}
+}
+
+// Write writes the item of a work
+func (c *CSLWriter) Write(w Work) error {
+	item := cslItem{ID: w.ID, Type: "book", Title: w.Title, Publisher: w.Publisher, ISBN: w.ISBN, Keyword: w.Subject}
+	for _, name := range w.Authors {
+		if name.Given == "" {
+			item.Author = append(item.Author, cslName{Literal: name.Family})
+		} else {
+			item.Author = append(item.Author, cslName{Family: name.Family, Given: name.Given})
+		}
+	}
+	if w.Year != 0 {
+		item.Issued = &cslDate{DateParts: [][]int{{w.Year}}}
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n  "
+	if c.items == 0 {
+		separator = "[\n  "
+	}
+	c.items++
+	_, err = io.WriteString(c.w, separator+string(data))
+	return err
+}
+
+// Close ends the array
+func (c *CSLWriter) Close() error {
+	end := "\n]\n"
+	if c.items == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(c.w, end)
+	return err
+}
@@ -0,0 +1,86 @@
+package citation
+
+import (
+	"strings"
+	"testing"
+)
+
+var testWorks = []Work{
+	{ID: "1", Title: "Dune", Authors: ParseNames("Frank Herbert"), Publisher: "Chilton Books", Year: 1965, ISBN: "9780441172719", Subject: "scifi"},
+	{ID: "2", Title: "Dune Messiah", Authors: ParseNames("Frank Herbert"), Year: 1965},
+	{ID: "3", Title: "100% R&D\n report", Authors: ParseNames("NASA")},
+}
+
+func TestBibTeXWriter(t *testing.T) {
+	var out strings.Builder
+	w := NewBibTeXWriter(&out)
+	for _, work := range testWorks {
+		if err := w.Write(work); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	want := `@book{herbert1965,
+  author = {Herbert, Frank},
+  title = {Dune},
+  publisher = {Chilton Books},
+  year = {1965},
+  isbn = {9780441172719},
+  keywords = {scifi},
+}
+
+@book{herbert1965b,
+  author = {Herbert, Frank},
+  title = {Dune Messiah},
+  year = {1965},
+}
+
+@book{nasa,
+  author = {{NASA}},
+  title = {100\% R\&D report},
+}
+
+`
+	if out.String() != want {
+		t.Errorf("BibTeX =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestRISWriter(t *testing.T) {
+	var out strings.Builder
+	if err := NewRISWriter(&out).Write(testWorks[0]); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := "TY  - BOOK\r\nID  - 1\r\nAU  - Herbert, Frank\r\nTI  - Dune\r\nPB  - Chilton Books\r\nPY  - 1965\r\n" +
+		"SN  - 9780441172719\r\nKW  - scifi\r\nER  - \r\n\r\n"
+	if out.String() != want {
+		t.Errorf("RIS = %q, want %q", out.String(), want)
+	}
+}
+
+func TestCSLWriter(t *testing.T) {
+	var out strings.Builder
+	w := NewCSLWriter(&out)
+	for _, work := range []Work{testWorks[0], testWorks[2]} {
+		if err := w.Write(work); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	want := `[
+  {"id":"1","type":"book","title":"Dune","author":[{"family":"Herbert","given":"Frank"}],"publisher":"Chilton Books","issued":{"date-parts":[[1965]]},"ISBN":"9780441172719","keyword":"scifi"},
+  {"id":"3","type":"book","title":"100% R\u0026D\n report","author":[{"literal":"NASA"}]}
+]
+`
+	if out.String() != want {
+		t.Errorf("CSL-JSON =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if err := NewCSLWriter(&out).Close(); err != nil || out.String() != "[]\n" {
+		t.Errorf("empty CSL-JSON = %q, %v, want []", out.String(), err)
+	}
+}
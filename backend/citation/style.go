@@ -0,0 +1,161 @@
+package citation
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// Citation styles
+const (
+	APA     = "apa"     // APA, 7th edition
+	MLA     = "mla"     // MLA, 9th edition
+	Chicago = "chicago" // Chicago, 17th edition, bibliography entry
+)
+
+// Styles lists the citation styles Format supports
+var Styles = []string{APA, MLA, Chicago}
+
+// Citation is a formatted citation, as plain text and as HTML with the
+// title in italics
+type Citation struct {
+	Text string
+	HTML string
+}
+
+// span is a run of citation text, which may be in italics
+type span struct {
+	text   string
+	italic bool
+}
+
+var styles = map[string]func(Work) []span{
+	APA:     apa,
+	MLA:     mla,
+	Chicago: chicago,
+}
+
+// Format formats a work in a citation style. It reports false for an
+// unknown style.
+func Format(style string, w Work) (Citation, bool) {
+	format, ok := styles[style]
+	if !ok {
+		return Citation{}, false
+	}
+	w.Title, w.Publisher = oneLine(w.Title), oneLine(w.Publisher)
+	var text, markup strings.Builder
+	for _, s := range format(w) {
+		text.WriteString(s.text)
+		if s.italic {
+			markup.WriteString("<i>" + html.EscapeString(s.text) + "</i>")
+		} else {
+			markup.WriteString(html.EscapeString(s.text))
+		}
+	}
+	return Citation{Text: strings.TrimSpace(text.String()), HTML: strings.TrimSpace(markup.String())}, true
+}
+
+// apa formats "Family, I. (Year). Title. Publisher."
+func apa(w Work) []span {
+	names := make([]string, len(w.Authors))
+	for i, name := range w.Authors {
+		names[i] = name.Family
+		if initials := name.Initials(); initials != "" {
+			names[i] += ", " + initials
+		}
+	}
+	year := "n.d."
+	if w.Year != 0 {
+		year = strconv.Itoa(w.Year)
+	}
+	spans := []span{
+		{text: sentence(list(names, ", ", ", & ", ", & ")) + " (" + year + "). "},
+		{text: w.Title, italic: true},
+		{text: end(w.Title) + " "},
+	}
+	if w.Publisher != "" {
+		spans = append(spans, span{text: sentence(w.Publisher)})
+	}
+	return spans
+}
+
+// mla formats "Family, Given. Title. Publisher, Year."
+func mla(w Work) []span {
+	var authors string
+	switch len(w.Authors) {
+	case 0:
+	case 1:
+		authors = w.Authors[0].Inverted()
+	case 2:
+		authors = w.Authors[0].Inverted() + ", and " + w.Authors[1].Direct()
+	default:
+		authors = w.Authors[0].Inverted() + ", et al"
+	}
+	return append(bibliography(authors, w), publication(w, "")...)
+}
+
+// chicago formats "Family, Given. Title. Publisher, Year."
+func chicago(w Work) []span {
+	names := make([]string, len(w.Authors))
+	for i, name := range w.Authors {
+		if i == 0 {
+			names[i] = name.Inverted()
+		} else {
+			names[i] = name.Direct()
+		}
+	}
+	return append(bibliography(list(names, ", ", ", and ", ", and "), w), publication(w, "n.d.")...)
+}
+
+// bibliography formats the authors and title shared by MLA and Chicago
+func bibliography(authors string, w Work) []span {
+	var spans []span
+	if authors != "" {
+		spans = append(spans, span{text: sentence(authors) + " "})
+	}
+	return append(spans, span{text: w.Title, italic: true}, span{text: end(w.Title) + " "})
+}
+
+// publication formats "Publisher, Year." with unknown as the missing year
+func publication(w Work, unknown string) []span {
+	var parts []string
+	if w.Publisher != "" {
+		parts = append(parts, w.Publisher)
+	}
+	if w.Year != 0 {
+		parts = append(parts, strconv.Itoa(w.Year))
+	} else if unknown != "" {
+		parts = append(parts, unknown)
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return []span{{text: sentence(strings.Join(parts, ", "))}}
+}
+
+// list joins names with sep, using two between exactly two names and last
+// before the last of more
+func list(names []string, sep, two, last string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + two + names[1]
+	}
+	return strings.Join(names[:len(names)-1], sep) + last + names[len(names)-1]
+}
+
+// sentence ends s with a period unless it already ends with punctuation
+func sentence(s string) string {
+	return s + end(s)
+}
+
+// end returns the period that ends a sentence ending with s
+func end(s string) string {
+	if s == "" || strings.ContainsAny(s[len(s)-1:], ".?!") {
+		return ""
+	}
+	return "."
+}
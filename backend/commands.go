@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"digital-library/backend/config"
+	"digital-library/backend/database"
+)
+
+const usage = `Usage:
+  main                    start the API server
+  main migrate up         apply all pending migrations
+  main migrate down [N]   revert the last N applied migrations (default 1)
+  main migrate status     list migrations and whether they are applied
+  main seed               load sample data into an empty database`
+
+// runCommand executes a CLI subcommand and returns the process exit code
+func runCommand(args []string) int {
+	switch args[0] {
+	case "migrate":
+		if len(args) < 2 {
+			break
+		}
+		return runMigrate(args[1:])
+	case "seed":
+		return runSeed()
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, usage)
+	return 2
+}
+
+func runMigrate(args []string) int {
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		connect()
+		defer database.Close()
+
+		applied, err := database.MigrateUp(ctx, database.DB)
+		if err != nil {
+			log.Printf("Migration failed: %v", err)
+			return 1
+		}
+		log.Printf("%d migration(s) applied", len(applied))
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				fmt.Fprintln(os.Stderr, "migrate down: N must be a positive integer")
+				return 2
+			}
+			steps = n
+		}
+		connect()
+		defer database.Close()
+
+		reverted, err := database.MigrateDown(ctx, database.DB, steps)
+		if err != nil {
+			log.Printf("Migration failed: %v", err)
+			return 1
+		}
+		log.Printf("%d migration(s) reverted", len(reverted))
+	case "status":
+		connect()
+		defer database.Close()
+
+		statuses, err := database.MigrationStatuses(ctx, database.DB)
+		if err != nil {
+			log.Printf("Could not read migration status: %v", err)
+			return 1
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	return 0
+}
+
+func runSeed() int {
+	connect()
+	defer database.Close()
+
+	seeded, err := database.Seed(context.Background(), database.DB)
+	if err != nil {
+		log.Printf("Seeding failed: %v", err)
+		return 1
+	}
+	if !seeded {
+		log.Println("Database already contains users, skipping seed data")
+	}
+	return 0
+}
+
+// connect opens the database pool without starting the HTTP server
+func connect() {
+	database.Connect(config.LoadConfig())
+}
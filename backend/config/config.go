@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 
 	"log"
 
@@ -12,6 +14,43 @@ import (
 type Config struct {
 	DatabaseURL string
 	JWTSecret   string
+	AutoMigrate bool // Apply pending migrations when the app starts
+
+	// OverdueScanInterval is how often the server flags overdue loans; zero disables the job
+	OverdueScanInterval time.Duration
+	// HoldExpiryInterval is how often the server expires holds that were
+	// not picked up; zero disables the job
+	HoldExpiryInterval time.Duration
+
+	// FineBlockThreshold is the unpaid fine balance, in cents, above which
+	// borrowers cannot borrow more books
+	FineBlockThreshold int
+
+	// FeedTokenTTL is how long the tokens reading apps pass to the catalog
+	// feeds in the query string stay valid
+	FeedTokenTTL time.Duration
+
+	// MARCMappingFile is a JSON file mapping book fields to MARC fields for
+	// MARC imports and exports; empty uses the default mapping
+	MARCMappingFile string
+
+	// OAI-PMH repository: its name, the address harvesters can contact, and
+	// the namespace of its item identifiers, such as oai:<id>:42
+	OAIRepositoryName string
+	OAIAdminEmail     string
+	OAIRepositoryID   string
+
+	// FileStorage is where the e-book files of books are stored: "local", in
+	// FileStorageDir, or "s3", in an S3-compatible bucket
+	FileStorage    string
+	FileStorageDir string
+	S3Endpoint     string
+	S3Bucket       string
+	S3Region       string
+	S3AccessKeyID  string
+	S3SecretKey    string
+	// MaxFileSize is the largest e-book file that can be uploaded, in bytes
+	MaxFileSize int64
 }
 
 // LoadConfig loads configuration from environment variables or a .env file
@@ -32,8 +71,75 @@ func LoadConfig() *Config {
 		log.Fatal("JWT_SECRET environment variable is required")
 	}
 
+	autoMigrate, _ := strconv.ParseBool(os.Getenv("AUTO_MIGRATE"))
+
+	overdueScanInterval := durationEnv("OVERDUE_SCAN_INTERVAL", time.Hour)
+	holdExpiryInterval := durationEnv("HOLD_EXPIRY_INTERVAL", time.Hour)
+	fineBlockThreshold := intEnv("FINE_BLOCK_THRESHOLD_CENTS", 1000)
+
 	return &Config{
 		DatabaseURL: dbURL,
 		JWTSecret:   jwtSecret,
+		AutoMigrate: autoMigrate,
+
+		OverdueScanInterval: overdueScanInterval,
+		HoldExpiryInterval:  holdExpiryInterval,
+		FineBlockThreshold:  fineBlockThreshold,
+
+		FeedTokenTTL: durationEnv("FEED_TOKEN_TTL", 24*time.Hour),
+
+		MARCMappingFile: os.Getenv("MARC_MAPPING_FILE"),
+
+		OAIRepositoryName: stringEnv("OAI_REPOSITORY_NAME", "Digital Library"),
+		OAIAdminEmail:     stringEnv("OAI_ADMIN_EMAIL", "admin@example.com"),
+		OAIRepositoryID:   stringEnv("OAI_REPOSITORY_ID", "digital-library"),
+
+		FileStorage:    stringEnv("FILE_STORAGE", "local"),
+		FileStorageDir: stringEnv("FILE_STORAGE_DIR", "data/files"),
+		S3Endpoint:     os.Getenv("S3_ENDPOINT"),
+		S3Bucket:       os.Getenv("S3_BUCKET"),
+		S3Region:       stringEnv("S3_REGION", "us-east-1"),
+		S3AccessKeyID:  os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretKey:    os.Getenv("S3_SECRET_ACCESS_KEY"),
+		MaxFileSize:    int64(intEnv("MAX_FILE_SIZE_MB", 500)) << 20,
+	}
+}
+
+// stringEnv reads a string from the environment, falling back to def when
+// the variable is unset
+func stringEnv(name, def string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return def
+}
+
+// durationEnv reads a duration such as "30m" from the environment, falling
+// back to def when the variable is unset or invalid
+func durationEnv(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %v", name, value, def)
+		return def
+	}
+	return d
+}
+
+// intEnv reads an integer from the environment, falling back to def when the
+// variable is unset or invalid
+func intEnv(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %d", name, value, def)
+		return def
 	}
+	return n
 }
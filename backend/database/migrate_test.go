@@ -0,0 +1,45 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("LoadMigrations returned no migrations")
+	}
+	for i, m := range migrations {
+		// Versions are numbered from 1 without gaps, so every database
+		// applies them in the same order
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d, want %d", i, m.Version, i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %04d_%s has an empty up or down file", m.Version, m.Name)
+		}
+	}
+}
+
+func TestMigrationName(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"0001_initial_schema.up.sql", true},
+		{"0012_book_publication.down.sql", true},
+		{"0001_initial_schema.sql", false},
+		{"initial_schema.up.sql", false},
+		{"0001-initial.up.sql", false},
+		{"0001_initial_schema.up.sql.bak", false},
+	}
+	for _, tt := range tests {
+		if ok := migrationName.MatchString(tt.name); ok != tt.ok {
+			t.Errorf("migrationName.MatchString(%q) = %v, want %v", tt.name, ok, tt.ok)
+		}
+	}
+}
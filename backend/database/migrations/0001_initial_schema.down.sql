@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS lending_records;
+DROP TABLE IF EXISTS books;
+DROP TABLE IF EXISTS users;
+DROP FUNCTION IF EXISTS update_updated_at_column();
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS lending_records_open_due_date_idx;
+
+ALTER TABLE lending_records
+    DROP COLUMN IF EXISTS loan_policy_id,
+    DROP COLUMN IF EXISTS due_date;
+
+DROP TABLE IF EXISTS loan_policies;
@@ -0,0 +1,37 @@
+-- Loan policies decide the loan period, renewal limit and grace period of a
+-- loan. A NULL category or role matches any value; the most specific policy
+-- wins when a book is lent.
+CREATE TABLE loan_policies (
+    id SERIAL PRIMARY KEY,
+    category VARCHAR(100) NULL,
+    role VARCHAR(50) NULL,
+    loan_days INTEGER NOT NULL CHECK (loan_days > 0),
+    max_renewals INTEGER NOT NULL DEFAULT 0 CHECK (max_renewals >= 0),
+    grace_days INTEGER NOT NULL DEFAULT 0 CHECK (grace_days >= 0),
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX loan_policies_scope_key
+    ON loan_policies ((COALESCE(LOWER(category), '')), (COALESCE(role, '')));
+
+CREATE TRIGGER update_loan_policies_updated_at
+BEFORE UPDATE ON loan_policies
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- Library-wide default
+INSERT INTO loan_policies (category, role, loan_days, max_renewals, grace_days)
+VALUES (NULL, NULL, 14, 2, 0);
+
+ALTER TABLE lending_records
+    ADD COLUMN due_date DATE,
+    ADD COLUMN loan_policy_id INTEGER REFERENCES loan_policies(id) ON DELETE SET NULL;
+
+-- Existing loans get the default loan period
+UPDATE lending_records SET due_date = borrow_date + 14;
+
+ALTER TABLE lending_records ALTER COLUMN due_date SET NOT NULL;
+
+CREATE INDEX lending_records_open_due_date_idx
+    ON lending_records (due_date) WHERE return_date IS NULL;
@@ -0,0 +1,6 @@
+-- Set by the overdue scheduler the first time it sees a loan past its due date
+ALTER TABLE lending_records ADD COLUMN overdue_at TIMESTAMPTZ NULL;
+
+-- Loans that were already late when the migration ran
+UPDATE lending_records SET overdue_at = CURRENT_TIMESTAMP
+WHERE return_date IS NULL AND due_date < CURRENT_DATE;
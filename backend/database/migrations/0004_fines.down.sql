@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS fine_entries;
+
+ALTER TABLE lending_records DROP COLUMN IF EXISTS return_condition;
+
+ALTER TABLE loan_policies
+    DROP COLUMN IF EXISTS damaged_fee_cents,
+    DROP COLUMN IF EXISTS lost_fee_cents,
+    DROP COLUMN IF EXISTS daily_fine_cents;
@@ -0,0 +1,29 @@
+-- Fine rates live on the loan policy; amounts are in cents
+ALTER TABLE loan_policies
+    ADD COLUMN daily_fine_cents INTEGER NOT NULL DEFAULT 0 CHECK (daily_fine_cents >= 0),
+    ADD COLUMN lost_fee_cents INTEGER NOT NULL DEFAULT 0 CHECK (lost_fee_cents >= 0),
+    ADD COLUMN damaged_fee_cents INTEGER NOT NULL DEFAULT 0 CHECK (damaged_fee_cents >= 0);
+
+-- Condition of the copy when the loan was closed; 'lost' loans are closed
+-- without giving the copy back
+ALTER TABLE lending_records
+    ADD COLUMN return_condition VARCHAR(20) NULL
+        CHECK (return_condition IN ('good', 'damaged', 'lost'));
+
+UPDATE lending_records SET return_condition = 'good' WHERE return_date IS NOT NULL;
+
+-- Ledger of fines per borrower. Charges increase the balance, payments and
+-- waivers decrease it.
+CREATE TABLE fine_entries (
+    id SERIAL PRIMARY KEY,
+    borrower_name VARCHAR(255) NOT NULL,
+    lending_record_id INTEGER NULL REFERENCES lending_records(id) ON DELETE SET NULL,
+    entry_type VARCHAR(20) NOT NULL CHECK (entry_type IN ('charge', 'payment', 'waiver')),
+    reason VARCHAR(20) NULL CHECK (reason IN ('overdue', 'lost', 'damaged')),
+    amount_cents INTEGER NOT NULL CHECK (amount_cents > 0),
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((entry_type = 'charge') = (reason IS NOT NULL))
+);
+
+CREATE INDEX fine_entries_borrower_idx ON fine_entries (LOWER(borrower_name));
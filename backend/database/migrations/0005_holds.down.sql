@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS holds;
+
+ALTER TABLE loan_policies DROP COLUMN IF EXISTS hold_pickup_days;
@@ -0,0 +1,30 @@
+-- Days a borrower has to pick up a copy set aside for their hold
+ALTER TABLE loan_policies
+    ADD COLUMN hold_pickup_days INTEGER NOT NULL DEFAULT 3 CHECK (hold_pickup_days > 0);
+
+-- Holds queue borrowers for a book, first come first served. A hold becomes
+-- 'ready' when a copy is set aside for the borrower; ready holds reserve one
+-- of the book's available copies until they are fulfilled, cancelled or expire.
+CREATE TABLE holds (
+    id SERIAL PRIMARY KEY,
+    book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    borrower_name VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting'
+        CHECK (status IN ('waiting', 'ready', 'fulfilled', 'cancelled', 'expired')),
+    ready_at TIMESTAMPTZ NULL,
+    expires_at DATE NULL, -- Last day a ready hold can be picked up
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A borrower can only hold a book once at a time
+CREATE UNIQUE INDEX holds_open_borrower_key ON holds (book_id, LOWER(borrower_name))
+    WHERE status IN ('waiting', 'ready');
+
+CREATE INDEX holds_queue_idx ON holds (book_id, created_at, id)
+    WHERE status IN ('waiting', 'ready');
+
+CREATE TRIGGER update_holds_updated_at
+    BEFORE UPDATE ON holds
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1 @@
+ALTER TABLE lending_records DROP COLUMN IF EXISTS renewal_count;
@@ -0,0 +1,3 @@
+-- Number of times a loan was renewed; limited by loan_policies.max_renewals
+ALTER TABLE lending_records
+    ADD COLUMN renewal_count INTEGER NOT NULL DEFAULT 0 CHECK (renewal_count >= 0);
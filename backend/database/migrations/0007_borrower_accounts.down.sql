@@ -0,0 +1,12 @@
+CREATE INDEX fine_entries_borrower_idx ON fine_entries (LOWER(borrower_name));
+
+DROP INDEX IF EXISTS holds_open_user_key;
+CREATE UNIQUE INDEX holds_open_borrower_key ON holds (book_id, LOWER(borrower_name))
+    WHERE status IN ('waiting', 'ready');
+
+ALTER TABLE fine_entries DROP COLUMN IF EXISTS user_id;
+ALTER TABLE holds DROP COLUMN IF EXISTS user_id;
+ALTER TABLE lending_records DROP COLUMN IF EXISTS user_id;
+
+-- Dropping the column also drops the sequence it owns
+ALTER TABLE users DROP COLUMN IF EXISTS card_number;
@@ -0,0 +1,61 @@
+-- Patron card numbers identify borrowers at the desk
+CREATE SEQUENCE patron_card_seq;
+
+ALTER TABLE users ADD COLUMN card_number VARCHAR(32);
+
+UPDATE users SET card_number = numbered.card_number
+FROM (
+    SELECT id, 'P' || LPAD(nextval('patron_card_seq')::text, 8, '0') AS card_number
+    FROM (SELECT id FROM users ORDER BY id) ordered
+) numbered
+WHERE users.id = numbered.id;
+
+ALTER TABLE users
+    ALTER COLUMN card_number SET DEFAULT 'P' || LPAD(nextval('patron_card_seq')::text, 8, '0'),
+    ALTER COLUMN card_number SET NOT NULL,
+    ADD CONSTRAINT users_card_number_key UNIQUE (card_number);
+
+ALTER SEQUENCE patron_card_seq OWNED BY users.card_number;
+
+-- Loans, holds and fines reference the borrower's account. borrower_name is
+-- kept as the name at the time and for history that predates accounts.
+ALTER TABLE lending_records ADD COLUMN user_id INTEGER NULL REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE holds ADD COLUMN user_id INTEGER NULL REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE fine_entries ADD COLUMN user_id INTEGER NULL REFERENCES users(id) ON DELETE SET NULL;
+
+-- Map existing names to accounts: exact usernames first, then
+-- case-insensitive matches that are unambiguous
+CREATE TEMPORARY TABLE borrower_accounts ON COMMIT DROP AS
+SELECT names.borrower_name, COALESCE(exact.id, folded.id) AS user_id
+FROM (
+    SELECT borrower_name FROM lending_records
+    UNION SELECT borrower_name FROM holds
+    UNION SELECT borrower_name FROM fine_entries
+) names
+LEFT JOIN users exact ON exact.username = names.borrower_name
+LEFT JOIN LATERAL (
+    SELECT MIN(u.id) AS id
+    FROM users u
+    WHERE LOWER(u.username) = LOWER(names.borrower_name)
+    HAVING COUNT(*) = 1
+) folded ON TRUE;
+
+UPDATE lending_records t SET user_id = a.user_id
+FROM borrower_accounts a WHERE a.borrower_name = t.borrower_name;
+UPDATE holds t SET user_id = a.user_id
+FROM borrower_accounts a WHERE a.borrower_name = t.borrower_name;
+UPDATE fine_entries t SET user_id = a.user_id
+FROM borrower_accounts a WHERE a.borrower_name = t.borrower_name;
+
+-- Lending needs an account now, so open holds without one can never be
+-- picked up
+UPDATE holds SET status = 'cancelled'
+WHERE user_id IS NULL AND status IN ('waiting', 'ready');
+
+DROP INDEX holds_open_borrower_key;
+CREATE UNIQUE INDEX holds_open_user_key ON holds (book_id, user_id)
+    WHERE status IN ('waiting', 'ready');
+
+CREATE INDEX lending_records_user_id_idx ON lending_records (user_id);
+CREATE INDEX fine_entries_user_id_idx ON fine_entries (user_id);
+DROP INDEX fine_entries_borrower_idx;
@@ -0,0 +1,6 @@
+-- books.quantity keeps the count of available copies it was last synced to
+ALTER TABLE lending_records DROP COLUMN IF EXISTS item_id;
+
+-- Dropping the table also drops its triggers and the barcode sequence
+DROP TABLE IF EXISTS book_items;
+DROP FUNCTION IF EXISTS sync_book_quantity();
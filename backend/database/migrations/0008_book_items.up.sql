@@ -0,0 +1,76 @@
+-- Physical copies of a book. Every copy has a barcode; a book's quantity is
+-- the number of its copies that are available and is kept in sync below.
+CREATE SEQUENCE book_item_barcode_seq;
+
+CREATE TABLE book_items (
+    id SERIAL PRIMARY KEY,
+    book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    barcode VARCHAR(64) NOT NULL DEFAULT 'C' || LPAD(nextval('book_item_barcode_seq')::text, 8, '0'),
+    status VARCHAR(20) NOT NULL DEFAULT 'available'
+        CHECK (status IN ('available', 'on_loan', 'lost', 'retired')),
+    condition VARCHAR(20) NOT NULL DEFAULT 'good' CHECK (condition IN ('good', 'damaged')),
+    location VARCHAR(255) NOT NULL DEFAULT '',
+    acquired_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    retired_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT book_items_barcode_key UNIQUE (barcode)
+);
+
+ALTER SEQUENCE book_item_barcode_seq OWNED BY book_items.barcode;
+
+CREATE INDEX book_items_book_status_idx ON book_items (book_id, status);
+
+CREATE TRIGGER update_book_items_updated_at
+    BEFORE UPDATE ON book_items
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Loans are of a specific copy. Loans closed before copies were tracked
+-- have none.
+ALTER TABLE lending_records ADD COLUMN item_id INTEGER NULL REFERENCES book_items(id) ON DELETE SET NULL;
+CREATE INDEX lending_records_item_id_idx ON lending_records (item_id);
+
+-- The copies on the shelf become available items
+INSERT INTO book_items (book_id, acquired_date)
+SELECT b.id, b.created_at::date
+FROM books b, generate_series(1, b.quantity);
+
+-- Copies out on loan or reported lost get an item linked to their loan
+CREATE TEMPORARY TABLE loaned_items ON COMMIT DROP AS
+SELECT lr.id AS lending_record_id,
+       lr.book_id,
+       nextval(pg_get_serial_sequence('book_items', 'id'))::int AS item_id,
+       CASE WHEN lr.return_date IS NULL THEN 'on_loan' ELSE 'lost' END AS status,
+       LEAST(b.created_at::date, lr.borrow_date) AS acquired_date
+FROM lending_records lr
+JOIN books b ON b.id = lr.book_id
+WHERE lr.return_date IS NULL OR lr.return_condition = 'lost';
+
+INSERT INTO book_items (id, book_id, status, acquired_date)
+SELECT item_id, book_id, status, acquired_date FROM loaned_items;
+
+UPDATE lending_records lr SET item_id = li.item_id
+FROM loaned_items li WHERE li.lending_record_id = lr.id;
+
+-- Keep books.quantity equal to the number of available copies
+CREATE OR REPLACE FUNCTION sync_book_quantity()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        UPDATE books SET quantity = (
+            SELECT COUNT(*) FROM book_items WHERE book_id = OLD.book_id AND status = 'available'
+        ) WHERE id = OLD.book_id;
+    ELSE
+        UPDATE books SET quantity = (
+            SELECT COUNT(*) FROM book_items WHERE book_id = NEW.book_id AND status = 'available'
+        ) WHERE id = NEW.book_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER sync_book_items_quantity
+    AFTER INSERT OR DELETE OR UPDATE OF status ON book_items
+    FOR EACH ROW
+    EXECUTE FUNCTION sync_book_quantity();
@@ -0,0 +1,4 @@
+-- Dropping the column also drops its index
+DROP TRIGGER IF EXISTS update_books_search_vector ON books;
+ALTER TABLE books DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS books_search_vector();
@@ -0,0 +1,28 @@
+-- Full-text search over the catalog. Title matches rank above author, then
+-- category, then ISBN. The ISBN uses the simple configuration so it is kept
+-- as a single unstemmed token.
+ALTER TABLE books ADD COLUMN search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION books_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('english', coalesce(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(NEW.author, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(NEW.category, '')), 'C') ||
+        setweight(to_tsvector('simple', coalesce(NEW.isbn, '')), 'D');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER update_books_search_vector
+    BEFORE INSERT OR UPDATE OF title, author, category, isbn ON books
+    FOR EACH ROW
+    EXECUTE FUNCTION books_search_vector();
+
+-- Fill in the existing books through the trigger without touching updated_at
+ALTER TABLE books DISABLE TRIGGER update_books_updated_at;
+UPDATE books SET title = title;
+ALTER TABLE books ENABLE TRIGGER update_books_updated_at;
+
+CREATE INDEX books_search_vector_idx ON books USING GIN (search_vector);
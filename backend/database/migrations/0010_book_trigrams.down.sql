@@ -0,0 +1,3 @@
+-- The pg_trgm extension is left installed; other database objects may use it
+DROP INDEX IF EXISTS books_author_trgm_idx;
+DROP INDEX IF EXISTS books_title_trgm_idx;
@@ -0,0 +1,6 @@
+-- Trigram indexes for finding titles and authors by similar spelling when a
+-- search has no full-text matches, and for autocomplete suggestions
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX books_title_trgm_idx ON books USING GIN (title gin_trgm_ops);
+CREATE INDEX books_author_trgm_idx ON books USING GIN (author gin_trgm_ops);
@@ -0,0 +1,2 @@
+-- Nothing to undo: ISBNs stay in canonical form, as their original
+-- formatting was not kept
@@ -0,0 +1,48 @@
+-- Books store the canonical form of their ISBN: the 13 digits of the ISBN-13
+-- without hyphens, so books_isbn_key also catches the same ISBN written with
+-- hyphens or in its ISBN-10 form.
+CREATE FUNCTION canonical_isbn(raw TEXT)
+RETURNS TEXT AS $$
+DECLARE
+    s TEXT := upper(regexp_replace(raw, '[\s-]', '', 'g'));
+    total INTEGER := 0;
+BEGIN
+    IF s ~ '^[0-9]{9}[0-9X]$' THEN
+        FOR i IN 1..10 LOOP
+            total := total + (11 - i) * CASE WHEN substr(s, i, 1) = 'X' THEN 10 ELSE substr(s, i, 1)::INTEGER END;
+        END LOOP;
+        IF total % 11 <> 0 THEN
+            RETURN NULL;
+        END IF;
+        s := '978' || substr(s, 1, 9);
+        total := 0;
+        FOR i IN 1..12 LOOP
+            total := total + substr(s, i, 1)::INTEGER * CASE WHEN i % 2 = 0 THEN 3 ELSE 1 END;
+        END LOOP;
+        RETURN s || ((10 - total % 10) % 10)::TEXT;
+    ELSIF s ~ '^97[89][0-9]{10}$' THEN
+        FOR i IN 1..13 LOOP
+            total := total + substr(s, i, 1)::INTEGER * CASE WHEN i % 2 = 0 THEN 3 ELSE 1 END;
+        END LOOP;
+        IF total % 10 <> 0 THEN
+            RETURN NULL;
+        END IF;
+        RETURN s;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- Rewrite valid ISBNs in canonical form. Books whose ISBN is invalid, or
+-- that would collide with another book once canonical, are left as they are
+-- for a librarian to fix.
+UPDATE books b
+SET isbn = canonical_isbn(b.isbn)
+WHERE canonical_isbn(b.isbn) IS NOT NULL
+  AND canonical_isbn(b.isbn) <> b.isbn
+  AND NOT EXISTS (
+      SELECT 1 FROM books o
+      WHERE o.id <> b.id AND canonical_isbn(o.isbn) = canonical_isbn(b.isbn)
+  );
+
+DROP FUNCTION canonical_isbn(TEXT);
@@ -0,0 +1,3 @@
+ALTER TABLE books
+    DROP COLUMN IF EXISTS year,
+    DROP COLUMN IF EXISTS publisher;
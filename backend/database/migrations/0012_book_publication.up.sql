@@ -0,0 +1,4 @@
+-- Publisher and year of publication, for citations. Both are optional.
+ALTER TABLE books
+    ADD COLUMN publisher VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN year INTEGER CHECK (year BETWEEN 1 AND 9999);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS record_deleted_books ON books;
+DROP FUNCTION IF EXISTS record_deleted_book();
+DROP INDEX IF EXISTS books_updated_at_idx;
+DROP TABLE IF EXISTS deleted_books;
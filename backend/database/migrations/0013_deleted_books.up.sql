@@ -0,0 +1,29 @@
+-- Books deleted from the catalog, so OAI-PMH harvesters learn of each
+-- deletion. Rows are never removed: the repository keeps deleted records
+-- persistently.
+CREATE TABLE deleted_books (
+    book_id INTEGER PRIMARY KEY,
+    isbn VARCHAR(20) NOT NULL,
+    category VARCHAR(100) NOT NULL DEFAULT '',
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX deleted_books_deleted_at_idx ON deleted_books (deleted_at, book_id);
+
+-- Harvesters list books in order of their last change
+CREATE INDEX books_updated_at_idx ON books (updated_at, id);
+
+CREATE OR REPLACE FUNCTION record_deleted_book()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO deleted_books (book_id, isbn, category)
+    VALUES (OLD.id, OLD.isbn, COALESCE(OLD.category, ''))
+    ON CONFLICT (book_id) DO UPDATE SET deleted_at = CURRENT_TIMESTAMP;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER record_deleted_books
+    AFTER DELETE ON books
+    FOR EACH ROW
+    EXECUTE FUNCTION record_deleted_book();
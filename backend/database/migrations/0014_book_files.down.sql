@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS lending_records_user_book_open_idx;
+DROP TABLE IF EXISTS book_files;
@@ -0,0 +1,20 @@
+-- Digital files of a book, such as EPUB, PDF or audio editions. The content
+-- lives in the blob store under storage_key; the row records what was
+-- uploaded so downloads can be checked and served without reading it.
+CREATE TABLE book_files (
+    id SERIAL PRIMARY KEY,
+    book_id INTEGER NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL CHECK (size >= 0),
+    checksum CHAR(64) NOT NULL, -- SHA-256, in hex
+    storage_key VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT book_files_storage_key_key UNIQUE (storage_key)
+);
+
+CREATE INDEX book_files_book_id_idx ON book_files (book_id);
+
+-- Downloads look up the borrower's open loan of the book
+CREATE INDEX lending_records_user_book_open_idx ON lending_records (user_id, book_id)
+    WHERE return_date IS NULL;
@@ -4,22 +4,45 @@ INSERT INTO users (username, password_hash, email, role) VALUES
 ('user', '$2a$10$B3wviry8gDQIUHwpCQKo7u4OguHWEO3TCk8i11S5UOA/4Y./uOi.a', 'user@example.com', 'user');
 
 -- Insert sample books
-INSERT INTO books (title, author, isbn, quantity, category) VALUES
-('The Great Gatsby', 'F. Scott Fitzgerald', '9780743273565', 5, 'Classic'),
-('To Kill a Mockingbird', 'Harper Lee', '9780446310789', 3, 'Classic'),
-('1984', 'George Orwell', '9780451524935', 4, 'Science Fiction'),
-('Pride and Prejudice', 'Jane Austen', '9780141439518', 2, 'Classic'),
-('The Hobbit', 'J.R.R. Tolkien', '9780547928227', 6, 'Fantasy'),
-('The Catcher in the Rye', 'J.D. Salinger', '9780316769488', 3, 'Classic'),
-('Brave New World', 'Aldous Huxley', '9780060850524', 4, 'Science Fiction'),
-('The Lord of the Rings', 'J.R.R. Tolkien', '9780618640157', 5, 'Fantasy'),
-('Crime and Punishment', 'Fyodor Dostoevsky', '9780143107637', 2, 'Classic'),
-('The Alchemist', 'Paulo Coelho', '9780062315007', 4, 'Fiction');
+INSERT INTO books (title, author, isbn, quantity, category, publisher, year) VALUES
+('The Great Gatsby', 'F. Scott Fitzgerald', '9780743273565', 5, 'Classic', 'Scribner', 2004),
+('To Kill a Mockingbird', 'Harper Lee', '9780446310789', 3, 'Classic', 'Grand Central Publishing', 1988),
+('1984', 'George Orwell', '9780451524935', 4, 'Science Fiction', 'Signet Classic', 1961),
+('Pride and Prejudice', 'Jane Austen', '9780141439518', 2, 'Classic', 'Penguin Classics', 2002),
+('The Hobbit', 'J.R.R. Tolkien', '9780547928227', 6, 'Fantasy', 'Houghton Mifflin Harcourt', 2012),
+('The Catcher in the Rye', 'J.D. Salinger', '9780316769488', 3, 'Classic', 'Little, Brown and Company', 1991),
+('Brave New World', 'Aldous Huxley', '9780060850524', 4, 'Science Fiction', 'Harper Perennial', 2006),
+('The Lord of the Rings', 'J.R.R. Tolkien', '9780618640157', 5, 'Fantasy', 'Houghton Mifflin', 2004),
+('Crime and Punishment', 'Fyodor Dostoevsky', '9780143107637', 2, 'Classic', 'Penguin Classics', 2002),
+('The Alchemist', 'Paulo Coelho', '9780062315007', 4, 'Fiction', 'HarperOne', 2014);
+
+-- Every book starts with its quantity of copies on the shelf
+INSERT INTO book_items (book_id, location, acquired_date)
+SELECT b.id, 'Main shelf', DATE '2023-12-01'
+FROM books b, generate_series(1, b.quantity);
 
 -- Insert sample lending records
-INSERT INTO lending_records (book_id, borrower_name, borrow_date, return_date) VALUES
-(1, 'John Doe', '2024-01-15', '2024-02-15'),
-(2, 'Jane Smith', '2024-02-01', NULL),
-(3, 'Bob Johnson', '2024-01-20', '2024-02-20'),
-(4, 'Alice Brown', '2024-02-10', NULL),
-(5, 'Charlie Wilson', '2024-01-25', '2024-02-25'); 
\ No newline at end of file
+INSERT INTO lending_records (book_id, borrower_name, borrow_date, due_date, return_date) VALUES
+(1, 'John Doe', '2024-01-15', '2024-01-29', '2024-02-15'),
+(2, 'Jane Smith', '2024-02-01', '2024-02-15', NULL),
+(3, 'Bob Johnson', '2024-01-20', '2024-02-03', '2024-02-20'),
+(4, 'Alice Brown', '2024-02-10', '2024-02-24', NULL),
+(5, 'Charlie Wilson', '2024-01-25', '2024-02-08', '2024-02-25');
+
+-- Loans of the sample user account
+INSERT INTO lending_records (book_id, user_id, borrower_name, borrow_date, due_date, return_date)
+SELECT b.id, u.id, u.username, b.borrow_date, b.due_date, b.return_date
+FROM users u, (VALUES
+    (8, DATE '2024-02-05', DATE '2024-02-19', DATE '2024-02-18'),
+    (10, DATE '2024-02-12', DATE '2024-02-26', NULL)
+) AS b (id, borrow_date, due_date, return_date)
+WHERE u.username = 'user';
+
+-- Books still out are on loan with one of their copies
+UPDATE lending_records lr SET item_id = (
+    SELECT MIN(i.id) FROM book_items i WHERE i.book_id = lr.book_id AND i.status = 'available'
+)
+WHERE lr.return_date IS NULL;
+
+UPDATE book_items SET status = 'on_loan'
+WHERE id IN (SELECT item_id FROM lending_records WHERE return_date IS NULL);
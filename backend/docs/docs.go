@@ -17,7 +17,7 @@ const docTemplate = `{
     "paths": {
         "/analytics/category-distribution": {
             "get": {
-                "description": "Get the distribution of books across categories. For admin users, shows all categories. For regular users, shows only categories of books they've borrowed.",
+                "description": "Get the distribution of books across categories. Admins see all books, or a single user's view with user_id. Regular users see only categories of books they've borrowed.",
                 "consumes": [
                     "application/json"
                 ],
@@ -30,15 +30,9 @@ const docTemplate = `{
                 "summary": "Get category distribution",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Username to filter results (required for non-admin users)",
-                        "name": "username",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "User role (admin/user) to determine data scope",
-                        "name": "role",
+                        "type": "integer",
+                        "description": "Admin only: show the view of a single user",
+                        "name": "user_id",
                         "in": "query"
                     }
                 ],
@@ -52,6 +46,33 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -66,7 +87,7 @@ const docTemplate = `{
         },
         "/analytics/monthly-trends": {
             "get": {
-                "description": "Get lending counts grouped by month. For admin users, shows all lending trends. For regular users, shows only their lending history.",
+                "description": "Get lending counts grouped by month. Admins see all lending, or a single user's view with user_id. Regular users see only their own lending history.",
                 "consumes": [
                     "application/json"
                 ],
@@ -79,15 +100,9 @@ const docTemplate = `{
                 "summary": "Get monthly lending trends",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Username to filter results (required for non-admin users)",
-                        "name": "username",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "User role (admin/user) to determine data scope",
-                        "name": "role",
+                        "type": "integer",
+                        "description": "Admin only: show the view of a single user",
+                        "name": "user_id",
                         "in": "query"
                     }
                 ],
@@ -101,6 +116,33 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -115,7 +157,7 @@ const docTemplate = `{
         },
         "/analytics/most-borrowed": {
             "get": {
-                "description": "Get a list of books ordered by number of times borrowed. For admin users, shows all books. For regular users, shows only their borrowed books.",
+                "description": "Get a list of books ordered by number of times borrowed. Admins see all books, or a single user's view with user_id. Regular users see only the books they borrowed.",
                 "consumes": [
                     "application/json"
                 ],
@@ -128,15 +170,9 @@ const docTemplate = `{
                 "summary": "Get most borrowed books",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Username to filter results (required for non-admin users)",
-                        "name": "username",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "User role (admin/user) to determine data scope",
-                        "name": "role",
+                        "type": "integer",
+                        "description": "Admin only: show the view of a single user",
+                        "name": "user_id",
                         "in": "query"
                     }
                 ],
@@ -146,7 +182,34 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/handlers.BorrowCount"
+                                "$ref": "#/definitions/models.BorrowCount"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -162,9 +225,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/books": {
+        "/analytics/overdue": {
             "get": {
-                "description": "Get all books with optional search and filtering",
+                "description": "List unreturned loans past their due date with the number of days overdue, most overdue first. Admins see every borrower, or a single user's view with user_id. Regular users see only their own loans.",
                 "consumes": [
                     "application/json"
                 ],
@@ -172,32 +235,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "books"
+                    "analytics"
                 ],
-                "summary": "Get all books",
+                "summary": "Get overdue loans",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Search term for title or author",
-                        "name": "search",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by category",
-                        "name": "category",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by author",
-                        "name": "author",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by availability (true/false)",
-                        "name": "available",
+                        "type": "integer",
+                        "description": "Admin only: show the view of a single user",
+                        "name": "user_id",
                         "in": "query"
                     }
                 ],
@@ -207,53 +252,30 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Book"
+                                "$ref": "#/definitions/models.OverdueLoan"
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            },
-            "post": {
-                "description": "Create a new book with the provided information",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "books"
-                ],
-                "summary": "Create a new book",
-                "parameters": [
-                    {
-                        "description": "Book object",
-                        "name": "book",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Book"
-                        }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/models.Book"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -273,9 +295,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/books/{id}": {
+        "/books": {
             "get": {
-                "description": "Get a single book by its ID",
+                "description": "Get a page of books with optional search and filtering. Pass next_cursor back as cursor to fetch the following page.",
                 "consumes": [
                     "application/json"
                 ],
@@ -285,25 +307,84 @@ const docTemplate = `{
                 "tags": [
                     "books"
                 ],
-                "summary": "Get a book by ID",
+                "summary": "Get all books",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Full-text search over title, author, category and ISBN. Supports 'quoted phrases', OR and -excluded words. Results carry a rank and highlighted fields. When no book in the catalog matches, returns the books whose title or author is spelled most like the search and sets similar.",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by author",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by availability (true/false)",
+                        "name": "available",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated IDs of the books to list",
+                        "name": "ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Catalog query, e.g. author:\\",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated facets to count over every matching book: category, author, available",
+                        "name": "facets",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "Book ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Page size, 1 to 200 (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field: title (default), author, created_at, quantity, or relevance (default when searching)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order: asc or desc (default desc for relevance, asc otherwise)",
+                        "name": "order",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Book"
+                            "$ref": "#/definitions/models.BookPage"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -322,8 +403,8 @@ const docTemplate = `{
                     }
                 }
             },
-            "put": {
-                "description": "Update an existing book with the provided information",
+            "post": {
+                "description": "Create a new book with the provided information. Quantity is the number of available copies to add with generated barcodes, at most 1000. The ISBN may be an ISBN-10 or ISBN-13, with or without hyphens, and is stored as an ISBN-13 without hyphens.",
                 "consumes": [
                     "application/json"
                 ],
@@ -333,15 +414,8 @@ const docTemplate = `{
                 "tags": [
                     "books"
                 ],
-                "summary": "Update a book",
+                "summary": "Create a new book",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Book ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "description": "Book object",
                         "name": "book",
@@ -353,8 +427,8 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "$ref": "#/definitions/models.Book"
                         }
@@ -368,8 +442,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -387,47 +470,89 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete a book by its ID",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/books/export": {
+            "get": {
+                "description": "Download every book GET /books would list for the same search, filters and sort, across all pages, as CSV, JSON Lines, binary MARC 21, MARCXML, or BibTeX, RIS or CSL-JSON for reference managers. MARC records follow the configured field mapping. Pass ids to export a selection of books. The rows are streamed as they are read.",
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/x-ndjson",
+                    "application/marc",
+                    "application/marcxml+xml",
+                    "application/x-bibtex",
+                    "application/x-research-info-systems",
+                    "application/vnd.citationstyles.csl+json"
                 ],
                 "tags": [
                     "books"
                 ],
-                "summary": "Delete a book",
+                "summary": "Export books",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Book ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "string",
+                        "description": "csv (default), jsonl, marc21, marcxml, bibtex, ris or csljson",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated IDs of the books to export",
+                        "name": "ids",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Full-text search, as for GET /books",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by author",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by availability (true/false)",
+                        "name": "available",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Catalog query, as for GET /books",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field, as for GET /books",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order: asc or desc",
+                        "name": "order",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "file"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -438,36 +563,43 @@ const docTemplate = `{
                 }
             }
         },
-        "/lending": {
-            "get": {
-                "description": "Get all lending records with optional search and filtering",
+        "/books/import": {
+            "post": {
+                "description": "Create or update books from a CSV file, matching existing books by ISBN. The file is the request body or the file field of a multipart form, and is read as it streams in. The first record is the header; columns named title, author, isbn, category, quantity, publisher and year are read, ignoring case, unless ?columns= maps the fields to other headers. Title, author and ISBN are required. New books get quantity copies (default 0, at most 1000); existing books take the title, author and a non-empty category, publisher and year, and keep their copies. Rows are saved in transactions of 500. The report gives the outcome of every row; a dry run validates and reports every row without saving anything.",
                 "consumes": [
-                    "application/json"
+                    "text/csv",
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "lending"
+                    "books"
                 ],
-                "summary": "Get lending records",
+                "summary": "Import books from CSV",
                 "parameters": [
+                    {
+                        "type": "file",
+                        "description": "CSV file, when sent as a multipart form",
+                        "name": "file",
+                        "in": "formData"
+                    },
                     {
                         "type": "string",
-                        "description": "Search term",
-                        "name": "search",
+                        "description": "Headers of the fields, e.g. title:Book Title,isbn:ISBN-13",
+                        "name": "columns",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Filter by status (active/returned)",
-                        "name": "status",
+                        "description": "Field delimiter: a single character or tab (default ,)",
+                        "name": "delimiter",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Filter by book ID",
-                        "name": "book_id",
+                        "type": "boolean",
+                        "description": "Validate and report without saving",
+                        "name": "dry_run",
                         "in": "query"
                     }
                 ],
@@ -475,14 +607,20 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.LendingRecord"
+                            "$ref": "#/definitions/models.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -491,35 +629,48 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/books/import/marc": {
             "post": {
-                "description": "Create a new lending record for a book",
+                "description": "Create or update books from a binary MARC 21 or MARCXML file, matching existing books by ISBN. The file is the request body or the file field of a multipart form, and is read as it streams in. The configured field mapping reads the title (default 245 $a $b), author (100 $a or 110 $a), ISBN (020 $a), category (650 $a), publisher (264 $b or 260 $b) and year (264 $c or 260 $c); title, author and ISBN are required. New books get no copies; existing books take the title, author and a non-empty category, publisher and year. Records are saved in transactions of 500. The report gives the outcome of every record and counts the fields and subfields the mapping does not read; a dry run validates and reports every record without saving anything.",
                 "consumes": [
-                    "application/json"
+                    "application/marc",
+                    "application/marcxml+xml",
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "lending"
+                    "books"
                 ],
-                "summary": "Lend a book",
+                "summary": "Import books from MARC",
                 "parameters": [
                     {
-                        "description": "Lending record object",
-                        "name": "lending",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.LendingRecord"
-                        }
+                        "type": "file",
+                        "description": "MARC file, when sent as a multipart form",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "marc21 or marcxml; detected from the file by default",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate and report without saving",
+                        "name": "dry_run",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.LendingRecord"
+                            "$ref": "#/definitions/models.ImportReport"
                         }
                     },
                     "400": {
@@ -531,17 +682,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -552,9 +694,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/lending/{id}": {
-            "delete": {
-                "description": "Delete a lending record by its ID",
+        "/books/isbn/{isbn}": {
+            "get": {
+                "description": "Get a single book by its ISBN-10 or ISBN-13, with or without hyphens",
                 "consumes": [
                     "application/json"
                 ],
@@ -562,14 +704,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "lending"
+                    "books"
                 ],
-                "summary": "Delete a lending record",
+                "summary": "Get a book by ISBN",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Lending Record ID",
-                        "name": "id",
+                        "type": "string",
+                        "description": "ISBN-10 or ISBN-13",
+                        "name": "isbn",
                         "in": "path",
                         "required": true
                     }
@@ -577,9 +719,17 @@ const docTemplate = `{
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Book"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -603,9 +753,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/lending/{id}/return": {
-            "put": {
-                "description": "Mark a lending record as returned and update book availability",
+        "/books/suggest": {
+            "get": {
+                "description": "Autocomplete a partly typed or misspelled title or author. Returns the books whose title or author is spelled most like the query, with their trigram similarity scores.",
                 "consumes": [
                     "application/json"
                 ],
@@ -613,27 +763,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "lending"
+                    "books"
                 ],
-                "summary": "Return a book",
+                "summary": "Suggest books",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Lending Record ID",
-                        "name": "id",
-                        "in": "path",
+                        "type": "string",
+                        "description": "Partly typed title or author",
+                        "name": "q",
+                        "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of suggestions, 1 to 50 (default 10)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.LendingRecord"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BookSuggestion"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -653,9 +812,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/login": {
-            "post": {
-                "description": "Authenticate user and return JWT token",
+        "/books/{id}": {
+            "get": {
+                "description": "Get a single book by its ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -663,25 +822,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "books"
                 ],
-                "summary": "Login user",
+                "summary": "Get a book by ID",
                 "parameters": [
                     {
-                        "description": "Login credentials",
-                        "name": "credentials",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.LoginRequest"
-                        }
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.LoginResponse"
+                            "$ref": "#/definitions/models.Book"
                         }
                     },
                     "400": {
@@ -693,8 +850,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -712,11 +869,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/register": {
-            "post": {
-                "description": "Create a new user account with the provided information",
+            },
+            "put": {
+                "description": "Update an existing book with the provided information. Quantity follows the book's available copies and is ignored; copies are managed under /books/{id}/items. The ISBN is stored as an ISBN-13 without hyphens.",
                 "consumes": [
                     "application/json"
                 ],
@@ -724,25 +879,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "books"
                 ],
-                "summary": "Register a new user",
+                "summary": "Update a book",
                 "parameters": [
                     {
-                        "description": "User object",
-                        "name": "user",
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Book object",
+                        "name": "book",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Book"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Book"
                         }
                     },
                     "400": {
@@ -754,6 +916,33 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -764,61 +953,3400 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "handlers.BorrowCount": {
-            "type": "object",
-            "properties": {
-                "book_id": {
-                    "type": "integer"
-                },
+            },
+            "delete": {
+                "description": "Delete a book by its ID, with its files",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Delete a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{id}/cite": {
+            "get": {
+                "description": "Format a book as a citation in the APA (7th edition), MLA (9th edition) or Chicago (17th edition, bibliography) style. Several authors are read from an author separated by semicolons, \"and\" or \"\u0026\". The publisher and year are left out, or given as n.d., when the book has none.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Cite a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "apa (default), mla or chicago",
+                        "name": "style",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Citation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{id}/files": {
+            "get": {
+                "description": "List the e-book and audiobook files of a book, with their size, MIME type and SHA-256 checksum",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get the files of a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BookFile"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Upload an EPUB, PDF or audio file (MP3, M4A/M4B, Ogg/Opus or FLAC) of a book as the file field of a multipart form. The format is taken from the extension and checked against the content. The size and SHA-256 checksum are recorded.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Upload a file of a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "E-book or audio file",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.BookFile"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{id}/files/{fileId}": {
+            "get": {
+                "description": "Download an e-book or audio file of a book. Borrowers need an active loan of the book; admins can download any file. Single byte ranges are supported with the Range and If-Range headers, so downloads can resume and audio can seek.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Download a file of a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "File ID",
+                        "name": "fileId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Byte range, such as bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Partial Content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "416": {
+                        "description": "Requested Range Not Satisfiable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete an e-book or audio file of a book and its stored content",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Delete a file of a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "File ID",
+                        "name": "fileId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{id}/items": {
+            "get": {
+                "description": "List every physical copy of a book with its barcode, status, condition and location, retired copies included",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get the copies of a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BookItem"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Add an available physical copy to a book, raising its quantity. The copy goes to the oldest waiting hold first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Add a copy of a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Copy details",
+                        "name": "item",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AddItemPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.BookItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{id}/items/retire/{itemId}": {
+            "post": {
+                "description": "Withdraw an available or lost copy from the collection. Copies on loan or set aside for a ready hold cannot be retired.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Retire a copy of a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Copy ID",
+                        "name": "itemId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BookItem"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/books/{id}/marc": {
+            "get": {
+                "description": "Get a book as a MARC record, built with the configured field mapping",
+                "produces": [
+                    "application/marcxml+xml",
+                    "application/marc"
+                ],
+                "tags": [
+                    "books"
+                ],
+                "summary": "Get the MARC record of a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Book ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "marcxml (default) or marc21",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/export/archive": {
+            "get": {
+                "description": "Download a ZIP archive of the whole library as JSON Lines: books.jsonl, items.jsonl (copies), lending.jsonl, holds.jsonl, fines.jsonl and loan_policies.jsonl. User accounts are not included. The archive is streamed as it is written.",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "export"
+                ],
+                "summary": "Export the library",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/fines": {
+            "get": {
+                "description": "List fine charges, payments and waivers, oldest first. Admins see every borrower, or one with user_id or card_number. Regular users see only their own ledger.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fines"
+                ],
+                "summary": "Get the fines ledger",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Admin only: filter by borrower account",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin only: filter by borrower card number",
+                        "name": "card_number",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.FineEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/fines/balance": {
+            "get": {
+                "description": "Get the charged, paid, waived and outstanding fine amounts of a borrower. Regular users get their own balance.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fines"
+                ],
+                "summary": "Get a fine balance",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Borrower account (admins give this or card_number)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Borrower card number",
+                        "name": "card_number",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FineBalance"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/fines/payments": {
+            "post": {
+                "description": "Record a payment against a borrower's outstanding fines, optionally for one of the borrower's loans",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fines"
+                ],
+                "summary": "Record a fine payment",
+                "parameters": [
+                    {
+                        "description": "Borrower and amount paid",
+                        "name": "payment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FineCreditPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.FineEntry"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/fines/waivers": {
+            "post": {
+                "description": "Waive part or all of a borrower's outstanding fines, optionally for one of the borrower's loans",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fines"
+                ],
+                "summary": "Waive fines",
+                "parameters": [
+                    {
+                        "description": "Borrower and amount waived",
+                        "name": "waiver",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FineCreditPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.FineEntry"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/holds": {
+            "get": {
+                "description": "Get holds oldest first, with the queue position of waiting holds. Admins see every borrower; regular users see only their own holds.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Get holds",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Filter by book ID",
+                        "name": "book_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Admin only: filter by borrower account",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin only: filter by borrower card number",
+                        "name": "card_number",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status (waiting/ready/fulfilled/cancelled/expired), or open for waiting and ready holds",
+                        "name": "status",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.HoldDetail"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Join the queue for a book whose copies are all lent or set aside for other holds. When a copy comes back it is reserved for the oldest hold, which becomes ready for pickup until the pickup window of the loan policy ends.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Place a hold",
+                "parameters": [
+                    {
+                        "description": "Book and borrower",
+                        "name": "hold",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PlaceHoldPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Hold"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/holds/cancel/{id}": {
+            "post": {
+                "description": "Cancel a waiting or ready hold. A copy set aside for the hold goes to the next borrower in the queue.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "holds"
+                ],
+                "summary": "Cancel a hold",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Hold ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Hold"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lending": {
+            "get": {
+                "description": "Get a page of lending records with optional search and filtering. Admins see every borrower; regular users see only their own loans. Pass next_cursor back as cursor to fetch the following page.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lending"
+                ],
+                "summary": "Get lending records",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search term for borrower name or book title",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Admin only: filter by borrower account",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin only: filter by borrower card number",
+                        "name": "card_number",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status (active/overdue/returned/lost); active includes overdue loans",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by book title",
+                        "name": "bookTitle",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only loans due on or before this date (YYYY-MM-DD)",
+                        "name": "due_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only loans due on or after this date (YYYY-MM-DD)",
+                        "name": "due_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 1 to 200 (default 50)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field: borrow_date (default), created_at, title or author",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order: asc or desc (default desc for borrow_date, asc otherwise)",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LendingRecordPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lending/export": {
+            "get": {
+                "description": "Download every lending record GET /lending would list for the same search, filters and sort, across all pages, as CSV or JSON Lines. Regular users get only their own loans. The rows are streamed as they are read.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "lending"
+                ],
+                "summary": "Export lending records",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "csv (default) or jsonl",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Search term for borrower name or book title",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Admin only: filter by borrower account",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Admin only: filter by borrower card number",
+                        "name": "card_number",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status (active/overdue/returned/lost)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by book title",
+                        "name": "bookTitle",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only loans due on or before this date (YYYY-MM-DD)",
+                        "name": "due_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only loans due on or after this date (YYYY-MM-DD)",
+                        "name": "due_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field, as for GET /lending",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order: asc or desc",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lending/lend": {
+            "post": {
+                "description": "Create a new lending record for a copy of a book, lent to the account named by user_id or card_number. The copy with the given barcode is lent, or any available copy without one. Regular users may only borrow for themselves and can omit both. The due date comes from the loan policy matching the book category and the borrower's role. Borrowers whose unpaid fines exceed the configured threshold are refused. Copies set aside for ready holds are only lent to their holders.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lending"
+                ],
+                "summary": "Lend a book",
+                "parameters": [
+                    {
+                        "description": "Book and borrower",
+                        "name": "lending",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LendBookPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.LendingRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lending/lost/{id}": {
+            "post": {
+                "description": "Close a loan whose copy was lost. The copy is not returned to stock; overdue fines and the lost fee under the loan's policy are charged to the borrower.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lending"
+                ],
+                "summary": "Report a book lost",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lending Record ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lending/renew/{id}": {
+            "post": {
+                "description": "Extend the due date of an active loan by the loan period of its policy, counted from the current due date. Loans that are overdue, at the policy's renewal limit, or for a book other borrowers hold cannot be renewed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lending"
+                ],
+                "summary": "Renew a loan",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lending Record ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LendingRecord"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lending/return/{id}": {
+            "post": {
+                "description": "Mark a lending record as returned and update book availability. The copy is set aside for the oldest waiting hold, if any. Overdue and damage fines under the loan's policy are charged to the borrower.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lending"
+                ],
+                "summary": "Return a book",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lending Record ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Condition of the returned copy",
+                        "name": "return",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReturnBookPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/lending/{id}": {
+            "delete": {
+                "description": "Delete a lending record by its ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "lending"
+                ],
+                "summary": "Delete a lending record",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Lending Record ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/loan-policies": {
+            "get": {
+                "description": "Get every loan policy. A null category or role matches any value; the most specific policy applies when a book is lent.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "loan-policies"
+                ],
+                "summary": "Get loan policies",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LoanPolicy"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a loan policy for a category and/or borrower role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "loan-policies"
+                ],
+                "summary": "Create a loan policy",
+                "parameters": [
+                    {
+                        "description": "Loan policy object",
+                        "name": "policy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoanPolicy"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.LoanPolicy"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/loan-policies/{id}": {
+            "put": {
+                "description": "Update an existing loan policy. Existing loans keep their due dates.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "loan-policies"
+                ],
+                "summary": "Update a loan policy",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Loan Policy ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Loan policy object",
+                        "name": "policy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoanPolicy"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LoanPolicy"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a loan policy by its ID. Existing loans keep their due dates.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "loan-policies"
+                ],
+                "summary": "Delete a loan policy",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Loan Policy ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Authenticate user and return JWT token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login user",
+                "parameters": [
+                    {
+                        "description": "Login credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/oai": {
+            "get": {
+                "description": "OAI-PMH 2.0 endpoint for metadata harvesters, open without signing in. It supports the Identify, ListMetadataFormats, ListSets, ListIdentifiers, ListRecords and GetRecord verbs, records in oai_dc and marc21 (MARCXML), one set per category, selective harvesting by the date books were last changed, and persistent deleted records. Lists are paged 100 at a time with resumption tokens. Arguments may also be sent as a form with POST.",
+                "produces": [
+                    "text/xml"
+                ],
+                "tags": [
+                    "oai"
+                ],
+                "summary": "OAI-PMH data provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "OAI-PMH verb",
+                        "name": "verb",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Item identifier, such as oai:digital-library:42",
+                        "name": "identifier",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "oai_dc or marc21",
+                        "name": "metadataPrefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Changed on or after, as YYYY-MM-DD or YYYY-MM-DDThh:mm:ssZ",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Changed on or before, as YYYY-MM-DD or YYYY-MM-DDThh:mm:ssZ",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Set spec of a category",
+                        "name": "set",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token continuing a list",
+                        "name": "resumptionToken",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OAI-PMH response",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/opds": {
+            "get": {
+                "description": "Root navigation feed of the OPDS catalog for reading apps, as an OPDS 1.2 Atom feed under /opds or OPDS 2.0 JSON under /opds/v2. Apps sign in with HTTP Basic or a Bearer JWT, or pass a feed token from POST /opds/token as the token query parameter, which the links of every feed keep.",
+                "produces": [
+                    "text/xml",
+                    "application/json"
+                ],
+                "tags": [
+                    "opds"
+                ],
+                "summary": "OPDS catalog",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Feed token from POST /opds/token, for apps that cannot send headers",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OPDS navigation feed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/opds/authors": {
+            "get": {
+                "description": "Navigation feed of the authors of the OPDS catalog, 100 per page in order, each leading to their books",
+                "produces": [
+                    "text/xml",
+                    "application/json"
+                ],
+                "tags": [
+                    "opds"
+                ],
+                "summary": "OPDS authors",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Last author of the previous page",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Feed token from POST /opds/token, for apps that cannot send headers",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OPDS navigation feed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/opds/books": {
+            "get": {
+                "description": "Books of the OPDS catalog, filtered like GET /books and paged with next and first links. Each book links to its record with the number of available copies and to its MARCXML record.",
+                "produces": [
+                    "text/xml",
+                    "application/json"
+                ],
+                "tags": [
+                    "opds"
+                ],
+                "summary": "OPDS acquisition feed",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Search title, author and ISBN",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by category",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by author",
+                        "name": "author",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by availability",
+                        "name": "available",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page to fetch",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field: title, author, created_at, quantity or relevance",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order: asc or desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Feed token from POST /opds/token, for apps that cannot send headers",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OPDS acquisition feed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/opds/categories": {
+            "get": {
+                "description": "Navigation feed of the categories of the OPDS catalog, 100 per page in order, each leading to its books",
+                "produces": [
+                    "text/xml",
+                    "application/json"
+                ],
+                "tags": [
+                    "opds"
+                ],
+                "summary": "OPDS categories",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Last category of the previous page",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Feed token from POST /opds/token, for apps that cannot send headers",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OPDS navigation feed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/opds/new": {
+            "get": {
+                "description": "Books of the OPDS catalog newest first, filtered and paged like /opds/books",
+                "produces": [
+                    "text/xml",
+                    "application/json"
+                ],
+                "tags": [
+                    "opds"
+                ],
+                "summary": "OPDS new arrivals",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of the page to fetch",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Feed token from POST /opds/token, for apps that cannot send headers",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OPDS acquisition feed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/opds/opensearch.xml": {
+            "get": {
+                "description": "OpenSearch description of the OPDS 1.2 catalog search. OPDS 2.0 feeds link to a templated search instead.",
+                "produces": [
+                    "text/xml"
+                ],
+                "tags": [
+                    "opds"
+                ],
+                "summary": "OPDS search description",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Feed token from POST /opds/token, for apps that cannot send headers",
+                        "name": "token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OpenSearch description",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/opds/token": {
+            "post": {
+                "description": "Issue a token for reading apps that cannot send credentials. Passed as the token query parameter, it opens the OPDS catalog, and only the catalog, as the current user until it expires (FEED_TOKEN_TTL, 24 hours by default). The response includes the catalog URLs with the token, to add to an app.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "opds"
+                ],
+                "summary": "Issue an OPDS feed token",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.FeedTokenResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/register": {
+            "post": {
+                "description": "Create a new user account with the provided information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User object",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/sru": {
+            "get": {
+                "description": "SRU 1.2 endpoint for searching the catalog with CQL, open without signing in. It supports the explain and searchRetrieve operations. CQL queries may use the title (dc.title), author (dc.creator), subject (dc.subject, the category) and isbn (bath.isbn) indexes or none, the =, scr, ==, \u003c\u003e, adj, all and any relations, and, or and not, parentheses and * masking. Records are Dublin Core (dc) or MARCXML (marcxml), packed as XML or as escaped strings. Errors are reported as SRU diagnostics.",
+                "produces": [
+                    "text/xml"
+                ],
+                "tags": [
+                    "sru"
+                ],
+                "summary": "SRU search",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "explain (default) or searchRetrieve",
+                        "name": "operation",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "1.1 or 1.2",
+                        "name": "version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "CQL query, required by searchRetrieve, e.g. dc.title=dune and author=herbert",
+                        "name": "query",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Position of the first record, from 1 to 10000",
+                        "name": "startRecord",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Records to return, at most 100 (default 10)",
+                        "name": "maximumRecords",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "dc (default) or marcxml",
+                        "name": "recordSchema",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "xml (default) or string",
+                        "name": "recordPacking",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "SRU response",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.AddItemPayload": {
+            "type": "object",
+            "properties": {
+                "acquired_date": {
+                    "description": "YYYY-MM-DD, defaults to today",
+                    "type": "string"
+                },
+                "barcode": {
+                    "description": "Generated when empty",
+                    "type": "string"
+                },
+                "condition": {
+                    "description": "good (default) or damaged",
+                    "type": "string"
+                },
+                "location": {
+                    "description": "Shelf or branch",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.FineCreditPayload": {
+            "type": "object",
+            "properties": {
+                "amount_cents": {
+                    "type": "integer"
+                },
+                "card_number": {
+                    "description": "Alternatively, the borrower's patron card number",
+                    "type": "string"
+                },
+                "lending_record_id": {
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.LendBookPayload": {
+            "type": "object",
+            "properties": {
+                "barcode": {
+                    "description": "Copy to lend; any available copy when empty",
+                    "type": "string"
+                },
+                "book_id": {
+                    "type": "integer"
+                },
+                "card_number": {
+                    "description": "Alternatively, the borrower's patron card number",
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "Borrower's account; defaults to the caller",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.PlaceHoldPayload": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "type": "integer"
+                },
+                "card_number": {
+                    "description": "Alternatively, the borrower's patron card number",
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "Borrower's account; defaults to the caller",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ReturnBookPayload": {
+            "type": "object",
+            "properties": {
+                "condition": {
+                    "description": "good (default) or damaged",
+                    "type": "string"
+                }
+            }
+        },
+        "models.Book": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "highlight": {
+                    "description": "Matched terms marked up",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.BookHighlight"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "isbn": {
+                    "type": "string"
+                },
+                "publisher": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "description": "Available copies; sets the initial number of copies on create",
+                    "type": "integer"
+                },
+                "rank": {
+                    "description": "Set on search results only",
+                    "type": "number"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "year": {
+                    "description": "Year of publication; nil when unknown",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.BookFile": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "type": "integer"
+                },
+                "checksum": {
+                    "description": "SHA-256, in hex",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "mime_type": {
+                    "type": "string"
+                },
+                "size": {
+                    "description": "In bytes",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.BookHighlight": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "category": {
+                    "type": "string"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BookItem": {
+            "type": "object",
+            "properties": {
+                "acquired_date": {
+                    "type": "string"
+                },
+                "barcode": {
+                    "type": "string"
+                },
+                "book_id": {
+                    "type": "integer"
+                },
+                "condition": {
+                    "description": "good or damaged",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "location": {
+                    "description": "Shelf or branch",
+                    "type": "string"
+                },
+                "retired_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BookPage": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Book"
+                    }
+                },
+                "facets": {
+                    "description": "Counts of the matching books by facet name, when requested",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "$ref": "#/definitions/models.FacetBucket"
+                        }
+                    }
+                },
+                "next_cursor": {
+                    "description": "Empty on the last page",
+                    "type": "string"
+                },
+                "similar": {
+                    "description": "Set when nothing matched the search itself and the page holds the\nbooks whose title or author is spelled most like it instead",
+                    "type": "boolean"
+                },
+                "total": {
+                    "description": "Books matching the filters on every page",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.BookSuggestion": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "book_id": {
+                    "type": "integer"
+                },
+                "score": {
+                    "description": "Trigram similarity to the query, from 0 to 1",
+                    "type": "number"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BorrowCount": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "type": "integer"
+                },
                 "book_title": {
                     "type": "string"
                 },
-                "borrows": {
+                "borrows": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.CategoryDistribution": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Citation": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "type": "integer"
+                },
+                "html": {
+                    "description": "The text, HTML-escaped, with the title in \u003ci\u003e\u003c/i\u003e",
+                    "type": "string"
+                },
+                "style": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FacetBucket": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FeedTokenResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "opds2_url": {
+                    "type": "string"
+                },
+                "opds_url": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.FineBalance": {
+            "type": "object",
+            "properties": {
+                "balance_cents": {
+                    "description": "Outstanding amount",
+                    "type": "integer"
+                },
+                "charged_cents": {
+                    "type": "integer"
+                },
+                "paid_cents": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "waived_cents": {
                     "type": "integer"
                 }
             }
         },
-        "models.Book": {
+        "models.FineEntry": {
             "type": "object",
             "properties": {
-                "author": {
+                "amount_cents": {
+                    "type": "integer"
+                },
+                "borrower": {
                     "type": "string"
                 },
-                "category": {
+                "created_at": {
+                    "type": "string"
+                },
+                "entry_type": {
+                    "description": "charge, payment or waiver",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lending_record_id": {
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "overdue, lost or damaged for charges",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Hold": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "type": "integer"
+                },
+                "borrower": {
                     "type": "string"
                 },
                 "created_at": {
                     "type": "string"
                 },
+                "expires_at": {
+                    "description": "Last day a ready hold can be picked up",
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
-                "isbn": {
+                "ready_at": {
                     "type": "string"
                 },
-                "quantity": {
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.HoldDetail": {
+            "type": "object",
+            "properties": {
+                "book_id": {
                     "type": "integer"
                 },
-                "title": {
+                "book_title": {
+                    "type": "string"
+                },
+                "borrower": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "Last day a ready hold can be picked up",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "position": {
+                    "description": "1-based place in the queue of waiting holds",
+                    "type": "integer"
+                },
+                "ready_at": {
+                    "type": "string"
+                },
+                "status": {
                     "type": "string"
                 },
                 "updated_at": {
                     "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
                 }
             }
         },
-        "models.CategoryDistribution": {
+        "models.ImportReport": {
             "type": "object",
             "properties": {
-                "category": {
+                "created": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "description": "Nothing was saved",
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ImportRow"
+                    }
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "unmapped": {
+                    "description": "MARC fields and subfields the mapping does not read, such as 260 or\n245$c, with the number of records that have them",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ImportRow": {
+            "type": "object",
+            "properties": {
+                "book_id": {
+                    "description": "Book created, updated or skipped; unset for books a dry run would create",
+                    "type": "integer"
+                },
+                "isbn": {
                     "type": "string"
                 },
-                "count": {
+                "reason": {
+                    "description": "Why the row was skipped or failed",
+                    "type": "string"
+                },
+                "row": {
+                    "description": "Line of a CSV record, where the header is row 1, or number of a MARC record",
                     "type": "integer"
+                },
+                "status": {
+                    "description": "One of the Import* constants",
+                    "type": "string"
                 }
             }
         },
@@ -833,18 +4361,179 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "borrower": {
+                    "description": "Borrower's name at the time of the loan",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "due_date": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "item_id": {
+                    "description": "Copy lent; nil for loans closed before copies were tracked",
+                    "type": "integer"
+                },
+                "loan_policy_id": {
+                    "description": "Policy resolved at lend time",
+                    "type": "integer"
+                },
+                "overdue_at": {
+                    "description": "Set when the overdue job flags the loan",
+                    "type": "string"
+                },
+                "renewal_count": {
+                    "description": "Times the due date was extended",
+                    "type": "integer"
+                },
+                "return_condition": {
+                    "description": "Condition of the copy when the loan was closed: good, damaged or lost",
+                    "type": "string"
+                },
+                "return_date": {
+                    "description": "Pointer to allow null",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "Borrower's account; nil for loans that predate accounts",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LendingRecordDetail": {
+            "type": "object",
+            "properties": {
+                "book_author": {
+                    "type": "string"
+                },
+                "book_id": {
+                    "description": "Foreign key to Book",
+                    "type": "integer"
+                },
+                "book_title": {
+                    "type": "string"
+                },
+                "borrow_date": {
+                    "type": "string"
+                },
+                "borrower": {
+                    "description": "Borrower's name at the time of the loan",
                     "type": "string"
                 },
                 "created_at": {
                     "type": "string"
                 },
+                "due_date": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "item_barcode": {
+                    "type": "string"
+                },
+                "item_id": {
+                    "description": "Copy lent; nil for loans closed before copies were tracked",
+                    "type": "integer"
+                },
+                "loan_policy_id": {
+                    "description": "Policy resolved at lend time",
+                    "type": "integer"
+                },
+                "overdue_at": {
+                    "description": "Set when the overdue job flags the loan",
+                    "type": "string"
+                },
+                "renewal_count": {
+                    "description": "Times the due date was extended",
+                    "type": "integer"
+                },
+                "return_condition": {
+                    "description": "Condition of the copy when the loan was closed: good, damaged or lost",
+                    "type": "string"
+                },
                 "return_date": {
                     "description": "Pointer to allow null",
                     "type": "string"
                 },
+                "status": {
+                    "description": "active, overdue, returned or lost",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "Borrower's account; nil for loans that predate accounts",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LendingRecordPage": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LendingRecordDetail"
+                    }
+                },
+                "next_cursor": {
+                    "description": "Empty on the last page",
+                    "type": "string"
+                },
+                "total": {
+                    "description": "Records matching the filters on every page",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.LoanPolicy": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "daily_fine_cents": {
+                    "description": "Charged per day late beyond the grace period",
+                    "type": "integer"
+                },
+                "damaged_fee_cents": {
+                    "description": "Charged when a copy is returned damaged",
+                    "type": "integer"
+                },
+                "grace_days": {
+                    "type": "integer"
+                },
+                "hold_pickup_days": {
+                    "description": "Days to pick up a copy set aside for a hold",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "loan_days": {
+                    "type": "integer"
+                },
+                "lost_fee_cents": {
+                    "description": "Charged when a copy is reported lost",
+                    "type": "integer"
+                },
+                "max_renewals": {
+                    "type": "integer"
+                },
+                "role": {
+                    "type": "string"
+                },
                 "updated_at": {
                     "type": "string"
                 }
@@ -884,9 +4573,48 @@ const docTemplate = `{
                 }
             }
         },
+        "models.OverdueLoan": {
+            "type": "object",
+            "properties": {
+                "book_author": {
+                    "type": "string"
+                },
+                "book_id": {
+                    "type": "integer"
+                },
+                "book_title": {
+                    "type": "string"
+                },
+                "borrow_date": {
+                    "type": "string"
+                },
+                "borrower": {
+                    "type": "string"
+                },
+                "days_overdue": {
+                    "type": "integer"
+                },
+                "due_date": {
+                    "type": "string"
+                },
+                "lending_record_id": {
+                    "type": "integer"
+                },
+                "overdue_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "models.User": {
             "type": "object",
             "properties": {
+                "card_number": {
+                    "description": "Patron card number",
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
@@ -1,227 +1,164 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"log"
+	"strconv"
+	"time"
 
-	"digital-library/backend/database"
+	"digital-library/backend/middleware"
 	"digital-library/backend/models"
+	"digital-library/backend/store"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// BorrowCount represents the structure for book borrow counts
-type BorrowCount struct {
-	BookID    int    `json:"book_id"`
-	BookTitle string `json:"book_title"`
-	Borrows   int    `json:"borrows"`
+// analyticsScope derives the data scope from the authenticated user. Regular
+// users always get their own lending history; admins see everything unless
+// they ask for a single user's view with ?user_id=.
+func (h *Handler) analyticsScope(c *fiber.Ctx) (store.AnalyticsScope, *requestError) {
+	user, _ := middleware.CurrentUser(c)
+	requested := c.Query("user_id", "")
+
+	if user.Role != models.RoleAdmin {
+		if requested != "" && requested != strconv.Itoa(user.UserID) {
+			return store.AnalyticsScope{}, &requestError{fiber.StatusForbidden, "You can only view your own analytics"}
+		}
+		return store.AnalyticsScope{UserID: user.UserID}, nil
+	}
+
+	if requested == "" {
+		return store.AnalyticsScope{All: true}, nil
+	}
+	userID, err := strconv.Atoi(requested)
+	if err != nil || userID <= 0 {
+		return store.AnalyticsScope{}, &requestError{fiber.StatusBadRequest, "Invalid user ID"}
+	}
+	if _, err := h.Users.GetUser(c.UserContext(), userID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return store.AnalyticsScope{}, &requestError{fiber.StatusNotFound, "User not found"}
+		}
+		log.Printf("Error fetching user %d for analytics scope: %v", userID, err)
+		return store.AnalyticsScope{}, &requestError{fiber.StatusInternalServerError, "Could not determine analytics scope"}
+	}
+	return store.AnalyticsScope{UserID: userID}, nil
 }
 
 // @Summary Get most borrowed books
-// @Description Get a list of books ordered by number of times borrowed. For admin users, shows all books. For regular users, shows only their borrowed books.
+// @Description Get a list of books ordered by number of times borrowed. Admins see all books, or a single user's view with user_id. Regular users see only the books they borrowed.
 // @Tags analytics
 // @Accept json
 // @Produce json
-// @Param username query string false "Username to filter results (required for non-admin users)"
-// @Param role query string false "User role (admin/user) to determine data scope"
-// @Success 200 {array} BorrowCount
+// @Param user_id query int false "Admin only: show the view of a single user"
+// @Success 200 {array} models.BorrowCount
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /analytics/most-borrowed [get]
-func GetMostBorrowedBooks(c *fiber.Ctx) error {
+func (h *Handler) GetMostBorrowedBooks(c *fiber.Ctx) error {
 	// Default limit to top 10, could make this a query param later
 	limit := 10
-	username := c.Query("username", "")
-	role := c.Query("role", "")
-
-	var query string
-	var args []interface{}
-
-	if role == "admin" {
-		query = `SELECT 
-			b.id AS book_id, 
-			b.title AS book_title,
-			COUNT(lr.id) AS borrows
-		FROM books b
-		JOIN lending_records lr ON b.id = lr.book_id
-		GROUP BY b.id, b.title
-		ORDER BY borrows DESC
-		LIMIT $1`
-		args = []interface{}{limit}
-	} else {
-		query = `SELECT 
-			b.id AS book_id, 
-			b.title AS book_title,
-			COUNT(lr.id) AS borrows
-		FROM books b
-		JOIN lending_records lr ON b.id = lr.book_id
-		WHERE lr.borrower_name = $1
-		GROUP BY b.id, b.title
-		ORDER BY borrows DESC
-		LIMIT $2`
-		args = []interface{}{username, limit}
+
+	scope, reqErr := h.analyticsScope(c)
+	if reqErr != nil {
+		return reqErr.send(c)
 	}
 
-	rows, err := database.DB.Query(context.Background(), query, args...)
+	results, err := h.Analytics.MostBorrowedBooks(c.UserContext(), scope, limit)
 	if err != nil {
 		log.Printf("Error fetching most borrowed books: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not retrieve most borrowed books data",
 		})
 	}
-	defer rows.Close()
-
-	results := make([]BorrowCount, 0)
-	for rows.Next() {
-		var bc BorrowCount
-		err := rows.Scan(&bc.BookID, &bc.BookTitle, &bc.Borrows)
-		if err != nil {
-			log.Printf("Error scanning borrow count row: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Error processing borrow count data",
-			})
-		}
-		results = append(results, bc)
-	}
-
-	if rows.Err() != nil {
-		log.Printf("Error iterating borrow count rows: %v", rows.Err())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error retrieving borrow count data",
-		})
-	}
 
 	return c.JSON(results)
 }
 
 // @Summary Get monthly lending trends
-// @Description Get lending counts grouped by month. For admin users, shows all lending trends. For regular users, shows only their lending history.
+// @Description Get lending counts grouped by month. Admins see all lending, or a single user's view with user_id. Regular users see only their own lending history.
 // @Tags analytics
 // @Accept json
 // @Produce json
-// @Param username query string false "Username to filter results (required for non-admin users)"
-// @Param role query string false "User role (admin/user) to determine data scope"
+// @Param user_id query int false "Admin only: show the view of a single user"
 // @Success 200 {array} models.MonthlyTrend
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /analytics/monthly-trends [get]
-func GetMonthlyLendingTrends(c *fiber.Ctx) error {
-	username := c.Query("username", "")
-	role := c.Query("role", "")
-
-	var query string
-	var args []interface{}
-
-	if role == "admin" {
-		query = `SELECT 
-			to_char(borrow_date, 'YYYY-MM') AS month, 
-			COUNT(*) AS count
-		FROM lending_records
-		GROUP BY month
-		ORDER BY month ASC`
-	} else {
-		query = `SELECT 
-			to_char(borrow_date, 'YYYY-MM') AS month, 
-			COUNT(*) AS count
-		FROM lending_records
-		WHERE borrower_name = $1
-		GROUP BY month
-		ORDER BY month ASC`
-		args = []interface{}{username}
+func (h *Handler) GetMonthlyLendingTrends(c *fiber.Ctx) error {
+	scope, reqErr := h.analyticsScope(c)
+	if reqErr != nil {
+		return reqErr.send(c)
 	}
 
-	rows, err := database.DB.Query(context.Background(), query, args...)
+	results, err := h.Analytics.MonthlyLendingTrends(c.UserContext(), scope)
 	if err != nil {
 		log.Printf("Error fetching monthly lending trends: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not retrieve monthly lending trends data",
 		})
 	}
-	defer rows.Close()
-
-	results := make([]models.MonthlyTrend, 0)
-	for rows.Next() {
-		var mt models.MonthlyTrend
-		err := rows.Scan(&mt.Month, &mt.Count)
-		if err != nil {
-			log.Printf("Error scanning monthly trend row: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Error processing monthly trend data",
-			})
-		}
-		results = append(results, mt)
-	}
-
-	if rows.Err() != nil {
-		log.Printf("Error iterating monthly trend rows: %v", rows.Err())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error retrieving monthly trend data",
-		})
-	}
 
 	return c.JSON(results)
 }
 
 // @Summary Get category distribution
-// @Description Get the distribution of books across categories. For admin users, shows all categories. For regular users, shows only categories of books they've borrowed.
+// @Description Get the distribution of books across categories. Admins see all books, or a single user's view with user_id. Regular users see only categories of books they've borrowed.
 // @Tags analytics
 // @Accept json
 // @Produce json
-// @Param username query string false "Username to filter results (required for non-admin users)"
-// @Param role query string false "User role (admin/user) to determine data scope"
+// @Param user_id query int false "Admin only: show the view of a single user"
 // @Success 200 {array} models.CategoryDistribution
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /analytics/category-distribution [get]
-func GetCategoryDistribution(c *fiber.Ctx) error {
-	username := c.Query("username", "")
-	role := c.Query("role", "")
-
-	var query string
-	var args []interface{}
-
-	if role == "admin" {
-		query = `SELECT 
-			COALESCE(b.category, 'Uncategorized') AS category,
-			COUNT(DISTINCT b.id) AS count
-		FROM books b
-		GROUP BY COALESCE(b.category, 'Uncategorized')
-		ORDER BY count DESC`
-	} else {
-		query = `SELECT 
-			COALESCE(b.category, 'Uncategorized') AS category,
-			COUNT(DISTINCT b.id) AS count
-		FROM books b
-		JOIN lending_records lr ON b.id = lr.book_id
-		WHERE lr.borrower_name = $1
-		GROUP BY COALESCE(b.category, 'Uncategorized')
-		ORDER BY count DESC`
-		args = []interface{}{username}
+func (h *Handler) GetCategoryDistribution(c *fiber.Ctx) error {
+	scope, reqErr := h.analyticsScope(c)
+	if reqErr != nil {
+		return reqErr.send(c)
 	}
 
-	rows, err := database.DB.Query(context.Background(), query, args...)
+	results, err := h.Analytics.CategoryDistribution(c.UserContext(), scope)
 	if err != nil {
 		log.Printf("Error fetching category distribution: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not retrieve category distribution data",
 		})
 	}
-	defer rows.Close()
-
-	results := make([]models.CategoryDistribution, 0)
-	for rows.Next() {
-		var cd models.CategoryDistribution
-		err := rows.Scan(&cd.Category, &cd.Count)
-		if err != nil {
-			log.Printf("Error scanning category distribution row: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Error processing category distribution data",
-			})
-		}
-		results = append(results, cd)
+
+	return c.JSON(results)
+}
+
+// @Summary Get overdue loans
+// @Description List unreturned loans past their due date with the number of days overdue, most overdue first. Admins see every borrower, or a single user's view with user_id. Regular users see only their own loans.
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param user_id query int false "Admin only: show the view of a single user"
+// @Success 200 {array} models.OverdueLoan
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /analytics/overdue [get]
+func (h *Handler) GetOverdueLoans(c *fiber.Ctx) error {
+	scope, reqErr := h.analyticsScope(c)
+	if reqErr != nil {
+		return reqErr.send(c)
 	}
 
-	if rows.Err() != nil {
-		log.Printf("Error iterating category distribution rows: %v", rows.Err())
+	today := time.Now().UTC().Truncate(24 * time.Hour) // Use UTC date part only
+
+	results, err := h.Analytics.OverdueLoans(c.UserContext(), scope, today)
+	if err != nil {
+		log.Printf("Error fetching overdue loans: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error retrieving category distribution data",
+			"error": "Could not retrieve overdue loans",
 		})
 	}
 
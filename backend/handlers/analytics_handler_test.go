@@ -0,0 +1,71 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAnalyticsScope(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	alice := s.user(t, "alice", models.RoleUser)
+	bob := s.user(t, "bob", models.RoleUser)
+	dune := s.book(t, "9780441172719", 3)
+	emma := s.book(t, "9780141439587", 3)
+	s.lend(t, admin, dune.ID, alice)
+	s.lend(t, admin, emma.ID, alice)
+	s.lend(t, admin, dune.ID, bob)
+
+	tests := []struct {
+		name   string
+		user   models.User
+		query  string
+		status int
+		want   map[int]int // Borrows by book ID
+	}{
+		{"admin sees every loan", admin, "", fiber.StatusOK, map[int]int{dune.ID: 2, emma.ID: 1}},
+		{"admin views a user", admin, "?user_id=" + strconv.Itoa(bob.ID), fiber.StatusOK, map[int]int{dune.ID: 1}},
+		{"user sees their own loans", alice, "", fiber.StatusOK, map[int]int{dune.ID: 1, emma.ID: 1}},
+		{"user names themselves", bob, "?user_id=" + strconv.Itoa(bob.ID), fiber.StatusOK, map[int]int{dune.ID: 1}},
+		{"user views another user", alice, "?user_id=" + strconv.Itoa(bob.ID), fiber.StatusForbidden, nil},
+		{"admin views an invalid user", admin, "?user_id=abc", fiber.StatusBadRequest, nil},
+		{"admin views an unknown user", admin, "?user_id=9999", fiber.StatusNotFound, nil},
+	}
+	for _, tt := range tests {
+		status, body := s.do(t, tt.user, fiber.MethodGet, "/api/analytics/most-borrowed"+tt.query, nil)
+		if status != tt.status {
+			t.Errorf("%s: status %d %s, want %d", tt.name, status, body, tt.status)
+			continue
+		}
+		if tt.want == nil {
+			continue
+		}
+		var counts []models.BorrowCount
+		if err := json.Unmarshal(body, &counts); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := make(map[int]int)
+		for _, count := range counts {
+			got[count.BookID] = count.Borrows
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: borrows %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Every analytics route takes the same scope
+	for _, path := range []string{"/api/analytics/monthly-trends", "/api/analytics/category-distribution"} {
+		if status, body := s.do(t, alice, fiber.MethodGet, path+"?user_id="+strconv.Itoa(bob.ID), nil); status != fiber.StatusForbidden {
+			t.Errorf("GET %s for another user = %d %s, want 403", path, status, body)
+		}
+		if status, body := s.do(t, alice, fiber.MethodGet, path, nil); status != fiber.StatusOK {
+			t.Errorf("GET %s = %d %s, want 200", path, status, body)
+		}
+	}
+}
@@ -1,14 +1,14 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"log"
-	"strings"
 	"time"
 
 	"digital-library/backend/config"
-	"digital-library/backend/database"
+	"digital-library/backend/middleware"
 	"digital-library/backend/models"
+	"digital-library/backend/store"
 
 	"golang.org/x/crypto/bcrypt"
 
@@ -26,7 +26,7 @@ import (
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /register [post]
-func Register(c *fiber.Ctx) error {
+func (h *Handler) Register(c *fiber.Ctx) error {
 	payload := new(models.RegisterRequest)
 	if err := c.BodyParser(payload); err != nil {
 		log.Printf("Error parsing registration payload: %v", err)
@@ -54,23 +54,15 @@ func Register(c *fiber.Ctx) error {
 	}
 
 	// Insert the new user
-	query := `INSERT INTO users (username, password_hash, email, role) 
-	          VALUES ($1, $2, $3, 'user') 
-	          RETURNING id, username, email, role, created_at, updated_at`
-
-	var user models.User
-	err = database.DB.QueryRow(context.Background(), query,
-		payload.Username, string(hashedPassword), payload.Email).
-		Scan(&user.ID, &user.Username, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt)
-
+	user, err := h.Users.CreateUser(c.UserContext(), payload.Username, string(hashedPassword), payload.Email, models.RoleUser)
 	if err != nil {
 		// Check for unique constraint violation
-		if strings.Contains(err.Error(), "duplicate key value violates unique constraint \"users_username_key\"") {
+		if errors.Is(err, store.ErrDuplicateUsername) {
 			log.Printf("Registration failed: Duplicate username '%s'", payload.Username)
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 				"error": "Username already exists",
 			})
-		} else if strings.Contains(err.Error(), "duplicate key value violates unique constraint \"users_email_key\"") {
+		} else if errors.Is(err, store.ErrDuplicateEmail) {
 			log.Printf("Registration failed: Duplicate email '%s'", payload.Email)
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 				"error": "Email already exists",
@@ -98,7 +90,7 @@ func Register(c *fiber.Ctx) error {
 // @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /login [post]
-func Login(cfg *config.Config) fiber.Handler {
+func (h *Handler) Login(cfg *config.Config) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		payload := new(models.LoginRequest)
 		if err := c.BodyParser(payload); err != nil {
@@ -107,24 +99,8 @@ func Login(cfg *config.Config) fiber.Handler {
 			})
 		}
 
-		// Query the user from the database
-		var user models.User
-		var passwordHash string
-		query := `SELECT id, username, email, role, password_hash, created_at, updated_at 
-		          FROM users WHERE username = $1 OR email = $1`
-
-		err := database.DB.QueryRow(context.Background(), query, payload.Username).
-			Scan(&user.ID, &user.Username, &user.Email, &user.Role, &passwordHash, &user.CreatedAt, &user.UpdatedAt)
-
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid credentials",
-			})
-		}
-
-		// Compare the provided password with the stored hash
-		err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(payload.Password))
-		if err != nil {
+		user, ok := h.checkLogin(c, payload.Username, payload.Password)
+		if !ok {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid credentials",
 			})
@@ -153,3 +129,29 @@ func Login(cfg *config.Config) fiber.Handler {
 		})
 	}
 }
+
+// checkLogin looks a user up by username or email and checks the password
+func (h *Handler) checkLogin(c *fiber.Ctx, login, password string) (models.User, bool) {
+	// Query the user from the database
+	user, passwordHash, err := h.Users.GetUserByLogin(c.UserContext(), login)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("Error looking up user during login: %v", err)
+		}
+		return user, false
+	}
+
+	// Compare the provided password with the stored hash
+	err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password))
+	return user, err == nil
+}
+
+// FeedLogin checks the HTTP Basic credentials of a reading app for
+// middleware.ProtectedFeed
+func (h *Handler) FeedLogin(c *fiber.Ctx, username, password string) (middleware.Claims, bool) {
+	user, ok := h.checkLogin(c, username, password)
+	if !ok {
+		return middleware.Claims{}, false
+	}
+	return middleware.Claims{UserID: user.ID, Username: user.Username, Role: user.Role}, true
+}
@@ -0,0 +1,147 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"digital-library/backend/blob"
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// upload posts content as the file field of a multipart form
+func (s *testServer) upload(t *testing.T, user models.User, path, filename string, content []byte) (int, []byte) {
+	t.Helper()
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+	req := httptest.NewRequest(fiber.MethodPost, path, &form)
+	req.Header.Set(fiber.HeaderContentType, writer.FormDataContentType())
+	return s.send(t, user, req)
+}
+
+func TestBookFiles(t *testing.T) {
+	s := newTestServer(t)
+	blobs, err := blob.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	s.handler.Blobs, s.handler.MaxFileSize = blobs, 1<<10
+	admin := s.user(t, "admin", models.RoleAdmin)
+	alice := s.user(t, "alice", models.RoleUser)
+	bob := s.user(t, "bob", models.RoleUser)
+	book := s.book(t, "9780441172719", 2)
+	files := "/api/books/" + strconv.Itoa(book.ID) + "/files"
+
+	content := []byte("%PDF-1.7 Dune")
+	status, body := s.upload(t, admin, files, "../Dune.pdf", content)
+	var file models.BookFile
+	if err := json.Unmarshal(body, &file); status != fiber.StatusCreated || err != nil {
+		t.Fatalf("upload = %d %s", status, body)
+	}
+	if file.Filename != "Dune.pdf" || file.MimeType != "application/pdf" || file.Size != int64(len(content)) || len(file.Checksum) != 64 {
+		t.Fatalf("uploaded file = %+v", file)
+	}
+	download := files + "/" + strconv.Itoa(file.ID)
+
+	uploads := []struct {
+		name     string
+		user     models.User
+		path     string
+		filename string
+		content  []byte
+		status   int
+	}{
+		{"as user", alice, files, "Dune.pdf", content, fiber.StatusForbidden},
+		{"unknown book", admin, "/api/books/9999/files", "Dune.pdf", content, fiber.StatusNotFound},
+		{"unsupported type", admin, files, "Dune.txt", content, fiber.StatusUnsupportedMediaType},
+		{"content not matching", admin, files, "Dune.epub", content, fiber.StatusUnsupportedMediaType},
+		{"too large", admin, files, "Dune.pdf", append([]byte("%PDF-"), make([]byte, 1<<10)...), fiber.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range uploads {
+		if status, body := s.upload(t, tt.user, tt.path, tt.filename, tt.content); status != tt.status {
+			t.Errorf("upload %s = %d %s, want %d", tt.name, status, body, tt.status)
+		}
+	}
+
+	var listed []models.BookFile
+	if _, body := s.do(t, alice, fiber.MethodGet, files, nil); json.Unmarshal(body, &listed) != nil || len(listed) != 1 {
+		t.Fatalf("files = %s, want 1", body)
+	}
+
+	s.lend(t, alice, book.ID, alice)
+	downloads := []struct {
+		name    string
+		user    models.User
+		rangeHd string
+		ifRange string
+		status  int
+		body    string
+	}{
+		{"without a loan", bob, "", "", fiber.StatusForbidden, ""},
+		{"whole file", alice, "", "", fiber.StatusOK, "%PDF-1.7 Dune"},
+		{"as admin", admin, "", "", fiber.StatusOK, "%PDF-1.7 Dune"},
+		{"range", alice, "bytes=9-", "", fiber.StatusPartialContent, "Dune"},
+		{"suffix range", alice, "bytes=-4", `"` + file.Checksum + `"`, fiber.StatusPartialContent, "Dune"},
+		{"changed content", alice, "bytes=9-", `"other"`, fiber.StatusOK, "%PDF-1.7 Dune"},
+		{"unsatisfiable", alice, "bytes=20-", "", fiber.StatusRequestedRangeNotSatisfiable, ""},
+	}
+	for _, tt := range downloads {
+		req := httptest.NewRequest(fiber.MethodGet, download, nil)
+		if tt.rangeHd != "" {
+			req.Header.Set(fiber.HeaderRange, tt.rangeHd)
+		}
+		if tt.ifRange != "" {
+			req.Header.Set(fiber.HeaderIfRange, tt.ifRange)
+		}
+		status, body := s.send(t, tt.user, req)
+		if status != tt.status || (tt.body != "" && string(body) != tt.body) {
+			t.Errorf("download %s = %d %q, want %d %q", tt.name, status, body, tt.status, tt.body)
+		}
+	}
+
+	if status, body := s.do(t, alice, fiber.MethodDelete, download, nil); status != fiber.StatusForbidden {
+		t.Errorf("delete as user = %d %s, want 403", status, body)
+	}
+	if status, body := s.do(t, admin, fiber.MethodDelete, download, nil); status != fiber.StatusOK {
+		t.Fatalf("delete = %d %s", status, body)
+	}
+	if status, body := s.do(t, admin, fiber.MethodGet, download, nil); status != fiber.StatusNotFound {
+		t.Errorf("download deleted file = %d %s, want 404", status, body)
+	}
+}
+
+func TestBookFilesWithoutStorage(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	book := s.book(t, "9780441172719", 1)
+	files := "/api/books/" + strconv.Itoa(book.ID) + "/files"
+
+	if status, body := s.upload(t, admin, files, "Dune.pdf", []byte("%PDF-1.7 Dune")); status != fiber.StatusServiceUnavailable {
+		t.Errorf("upload = %d %s, want 503", status, body)
+	}
+	for _, req := range []struct{ method, path string }{
+		{fiber.MethodGet, files},
+		{fiber.MethodGet, files + "/1"},
+		{fiber.MethodDelete, files + "/1"},
+	} {
+		if status, body := s.do(t, admin, req.method, req.path, nil); status != fiber.StatusServiceUnavailable {
+			t.Errorf("%s %s = %d %s, want 503", req.method, req.path, status, body)
+		}
+	}
+
+	// The rest of the API is unaffected
+	if status, body := s.do(t, admin, fiber.MethodDelete, "/api/books/"+strconv.Itoa(book.ID), nil); status != fiber.StatusOK {
+		t.Errorf("delete book = %d %s, want 200", status, body)
+	}
+}
@@ -1,110 +1,190 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"log"
+	"slices"
 	"strconv"
 	"strings"
 
-	// Needed for models.Book
-	"digital-library/backend/database"
+	"digital-library/backend/isbn"
 	"digital-library/backend/models"
+	"digital-library/backend/query"
+	"digital-library/backend/store"
 
 	"github.com/gofiber/fiber/v2"
-	// "github.com/golang-jwt/jwt/v5" // Not needed here
-	"github.com/jackc/pgx/v5"
 )
 
 // @Summary Get all books
-// @Description Get all books with optional search and filtering
+// @Description Get a page of books with optional search and filtering. Pass next_cursor back as cursor to fetch the following page.
 // @Tags books
 // @Accept json
 // @Produce json
-// @Param search query string false "Search term for title or author"
+// @Param search query string false "Full-text search over title, author, category and ISBN. Supports 'quoted phrases', OR and -excluded words. Results carry a rank and highlighted fields. When no book in the catalog matches, returns the books whose title or author is spelled most like the search and sets similar."
 // @Param category query string false "Filter by category"
 // @Param author query string false "Filter by author"
 // @Param available query string false "Filter by availability (true/false)"
-// @Success 200 {array} models.Book
+// @Param ids query string false "Comma-separated IDs of the books to list"
+// @Param q query string false "Catalog query, e.g. author:\"Le Guin\" category:scifi available:true isbn:978*, with AND, OR, NOT, parentheses and quoted phrases"
+// @Param facets query string false "Comma-separated facets to count over every matching book: category, author, available"
+// @Param limit query int false "Page size, 1 to 200 (default 50)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "Sort field: title (default), author, created_at, quantity, or relevance (default when searching)"
+// @Param order query string false "Sort order: asc or desc (default desc for relevance, asc otherwise)"
+// @Success 200 {object} models.BookPage
+// @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /books [get]
-func GetBooks(c *fiber.Ctx) error {
-	// Get query parameters
-	search := c.Query("search", "")
-	category := c.Query("category", "")
-	author := c.Query("author", "")
-	available := c.Query("available", "")
-
-	// Build the base query
-	query := `SELECT id, title, author, isbn, quantity, category, created_at, updated_at 
-	          FROM books WHERE 1=1`
-	args := []interface{}{}
-	argCount := 1
-
-	// Add search condition (matches title or author)
-	if search != "" {
-		query += ` AND (LOWER(title) LIKE LOWER($` + strconv.Itoa(argCount) + `) OR LOWER(author) LIKE LOWER($` + strconv.Itoa(argCount) + `))`
-		args = append(args, "%"+search+"%")
-		argCount++
+func (h *Handler) GetBooks(c *fiber.Ctx) error {
+	filter, errBody := bookFilter(c)
+	if errBody != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errBody)
 	}
 
-	// Add category filter
-	if category != "" {
-		query += ` AND LOWER(category) = LOWER($` + strconv.Itoa(argCount) + `)`
-		args = append(args, category)
-		argCount++
+	// Add requested facets
+	if value := c.Query("facets", ""); value != "" {
+		for _, facet := range strings.Split(value, ",") {
+			facet = strings.TrimSpace(facet)
+			if !slices.Contains(store.BookFacets, facet) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid facet, expected one of " + strings.Join(store.BookFacets, ", "),
+				})
+			}
+			if !slices.Contains(filter.Facets, facet) {
+				filter.Facets = append(filter.Facets, facet)
+			}
+		}
 	}
 
-	// Add author filter
-	if author != "" {
-		query += ` AND LOWER(author) = LOWER($` + strconv.Itoa(argCount) + `)`
-		args = append(args, author)
-		argCount++
+	page, reqErr := queryPage(c)
+	if reqErr != nil {
+		return reqErr.send(c)
 	}
-
-	// Add availability filter
-	if available == "true" {
-		query += ` AND quantity > 0`
-	} else if available == "false" {
-		query += ` AND quantity = 0`
+	if page.Sort == "relevance" && filter.Search == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sorting by relevance requires a search"})
 	}
 
-	// Add sorting
-	query += ` ORDER BY title ASC`
-
-	rows, err := database.DB.Query(context.Background(), query, args...)
+	books, err := h.Books.ListBooks(c.UserContext(), filter, page)
 	if err != nil {
+		if reqErr := pageError(err, store.BookSortFields); reqErr != nil {
+			return reqErr.send(c)
+		}
 		log.Printf("Error fetching books: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not retrieve books",
 		})
 	}
-	defer rows.Close()
 
-	books := make([]models.Book, 0)
+	return c.JSON(books)
+}
+
+// bookFilter parses the search, filter and catalog query parameters of the
+// book listing. Invalid filters give the body of a 400 response.
+func bookFilter(c *fiber.Ctx) (store.BookFilter, fiber.Map) {
+	filter := store.BookFilter{
+		Search:   c.Query("search", ""),
+		Category: c.Query("category", ""),
+		Author:   c.Query("author", ""),
+	}
+
+	// Add availability filter
+	switch c.Query("available", "") {
+	case "true":
+		available := true
+		filter.Available = &available
+	case "false":
+		available := false
+		filter.Available = &available
+	}
 
-	for rows.Next() {
-		var book models.Book
-		err := rows.Scan(
-			&book.ID, &book.Title, &book.Author, &book.ISBN,
-			&book.Quantity, &book.Category, &book.CreatedAt, &book.UpdatedAt,
-		)
+	// Add selection
+	if value := c.Query("ids", ""); value != "" {
+		filter.IDs = []int{}
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || id <= 0 {
+				return filter, fiber.Map{"error": "Invalid ids, expected comma-separated book IDs"}
+			}
+			filter.IDs = append(filter.IDs, id)
+		}
+	}
+
+	// Add catalog query
+	if value := strings.TrimSpace(c.Query("q", "")); value != "" {
+		node, err := query.Parse(value)
 		if err != nil {
-			log.Printf("Error scanning book row: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Error processing book data",
-			})
+			var syntaxErr *query.Error
+			if errors.As(err, &syntaxErr) {
+				return filter, fiber.Map{
+					"error":    "Invalid query: " + syntaxErr.Error(),
+					"position": syntaxErr.Pos,
+				}
+			}
+			return filter, fiber.Map{"error": "Invalid query"}
 		}
-		books = append(books, book)
+		filter.Query = canonicalISBNTerms(node)
 	}
+	return filter, nil
+}
 
-	if rows.Err() != nil {
-		log.Printf("Error iterating book rows: %v", rows.Err())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error retrieving book data",
-		})
+// canonicalISBNTerms rewrites the exact ISBNs of a query as stored, so an
+// ISBN-10 or a hyphenated ISBN finds its book. Other terms are kept as given.
+func canonicalISBNTerms(node query.Node) query.Node {
+	switch n := node.(type) {
+	case *query.And:
+		return &query.And{Left: canonicalISBNTerms(n.Left), Right: canonicalISBNTerms(n.Right)}
+	case *query.Or:
+		return &query.Or{Left: canonicalISBNTerms(n.Left), Right: canonicalISBNTerms(n.Right)}
+	case *query.Not:
+		return &query.Not{Node: canonicalISBNTerms(n.Node)}
+	case *query.Term:
+		if n.Field == query.FieldISBN && n.Match == query.MatchEquals {
+			if canonical, err := isbn.Normalize(n.Value); err == nil {
+				return &query.Term{Field: n.Field, Match: n.Match, Value: canonical}
+			}
+		}
 	}
+	return node
+}
 
-	return c.JSON(books)
+// Number of autocomplete suggestions when ?limit= is missing, and the most it may ask for
+const (
+	defaultSuggestions = 10
+	maxSuggestions     = 50
+)
+
+// @Summary Suggest books
+// @Description Autocomplete a partly typed or misspelled title or author. Returns the books whose title or author is spelled most like the query, with their trigram similarity scores.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param q query string true "Partly typed title or author"
+// @Param limit query int false "Number of suggestions, 1 to 50 (default 10)"
+// @Success 200 {array} models.BookSuggestion
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /books/suggest [get]
+func (h *Handler) SuggestBooks(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q", ""))
+	if query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Query is required"})
+	}
+	limit := defaultSuggestions
+	if value := c.Query("limit", ""); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 || n > maxSuggestions {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid limit, expected 1 to " + strconv.Itoa(maxSuggestions)})
+		}
+		limit = n
+	}
+
+	suggestions, err := h.Books.SuggestBooks(c.UserContext(), query, limit)
+	if err != nil {
+		log.Printf("Error suggesting books for %q: %v", query, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve suggestions"})
+	}
+
+	return c.JSON(suggestions)
 }
 
 // @Summary Get a book by ID
@@ -114,30 +194,59 @@ func GetBooks(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path int true "Book ID"
 // @Success 200 {object} models.Book
+// @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /books/{id} [get]
-func GetBook(c *fiber.Ctx) error {
-	id := c.Params("id")
+func (h *Handler) GetBook(c *fiber.Ctx) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid book ID",
+		})
+	}
 
-	query := `SELECT id, title, author, isbn, quantity, category, created_at, updated_at 
-	          FROM books WHERE id = $1`
+	book, err := h.Books.GetBook(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Book not found",
+			})
+		}
+		log.Printf("Error fetching book %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not retrieve book",
+		})
+	}
 
-	var book models.Book
-	row := database.DB.QueryRow(context.Background(), query, id)
+	return c.JSON(book)
+}
 
-	err := row.Scan(
-		&book.ID, &book.Title, &book.Author, &book.ISBN,
-		&book.Quantity, &book.Category, &book.CreatedAt, &book.UpdatedAt,
-	)
+// @Summary Get a book by ISBN
+// @Description Get a single book by its ISBN-10 or ISBN-13, with or without hyphens
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param isbn path string true "ISBN-10 or ISBN-13"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /books/isbn/{isbn} [get]
+func (h *Handler) GetBookByISBN(c *fiber.Ctx) error {
+	canonical, reqErr := canonicalISBN(c.Params("isbn"))
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
 
+	book, err := h.Books.GetBookByISBN(c.UserContext(), canonical)
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Book not found",
 			})
 		}
-		log.Printf("Error fetching book %s: %v", id, err)
+		log.Printf("Error fetching book with ISBN %s: %v", canonical, err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not retrieve book",
 		})
@@ -146,17 +255,41 @@ func GetBook(c *fiber.Ctx) error {
 	return c.JSON(book)
 }
 
+// canonicalISBN validates an ISBN-10 or ISBN-13 and returns the ISBN-13
+// without hyphens that books store
+func canonicalISBN(value string) (string, *requestError) {
+	canonical, err := isbn.Normalize(value)
+	if err != nil {
+		return "", &requestError{fiber.StatusBadRequest, "Invalid ISBN: " + err.Error()}
+	}
+	return canonical, nil
+}
+
+// checkYear validates the optional year of publication of a book
+func checkYear(year *int) *requestError {
+	if year != nil && (*year < 1 || *year > 9999) {
+		return &requestError{fiber.StatusBadRequest, "Year must be between 1 and 9999"}
+	}
+	return nil
+}
+
+// maxNewCopies is the most copies a book can be created with in one request,
+// as each one is a row with its own barcode
+const maxNewCopies = 1000
+
 // @Summary Create a new book
-// @Description Create a new book with the provided information
+// @Description Create a new book with the provided information. Quantity is the number of available copies to add with generated barcodes, at most 1000. The ISBN may be an ISBN-10 or ISBN-13, with or without hyphens, and is stored as an ISBN-13 without hyphens.
 // @Tags books
 // @Accept json
 // @Produce json
 // @Param book body models.Book true "Book object"
 // @Success 201 {object} models.Book
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /books [post]
-func CreateBook(c *fiber.Ctx) error {
+func (h *Handler) CreateBook(c *fiber.Ctx) error {
 	book := new(models.Book)
 
 	if err := c.BodyParser(book); err != nil {
@@ -175,23 +308,26 @@ func CreateBook(c *fiber.Ctx) error {
 			"error": "Quantity cannot be negative",
 		})
 	}
+	if book.Quantity > maxNewCopies {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Quantity cannot be more than " + strconv.Itoa(maxNewCopies),
+		})
+	}
+	var reqErr *requestError
+	if book.ISBN, reqErr = canonicalISBN(book.ISBN); reqErr != nil {
+		return reqErr.send(c)
+	}
+	if reqErr := checkYear(book.Year); reqErr != nil {
+		return reqErr.send(c)
+	}
 
-	query := `INSERT INTO books (title, author, isbn, quantity, category) 
-	          VALUES ($1, $2, $3, $4, $5) 
-	          RETURNING id, created_at, updated_at`
-
-	row := database.DB.QueryRow(context.Background(), query,
-		book.Title, book.Author, book.ISBN, book.Quantity, book.Category)
-
-	err := row.Scan(&book.ID, &book.CreatedAt, &book.UpdatedAt)
-	if err != nil {
-		log.Printf("Error creating book: %v", err)
-		// Check if error is due to duplicate ISBN
-		if strings.Contains(err.Error(), "duplicate key value violates unique constraint \"books_isbn_key\"") {
+	if err := h.Books.CreateBook(c.UserContext(), book); err != nil {
+		if errors.Is(err, store.ErrDuplicateISBN) {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 				"error": "A book with this ISBN already exists",
 			})
 		}
+		log.Printf("Error creating book: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not create book",
 		})
@@ -201,7 +337,7 @@ func CreateBook(c *fiber.Ctx) error {
 }
 
 // @Summary Update a book
-// @Description Update an existing book with the provided information
+// @Description Update an existing book with the provided information. Quantity follows the book's available copies and is ignored; copies are managed under /books/{id}/items. The ISBN is stored as an ISBN-13 without hyphens.
 // @Tags books
 // @Accept json
 // @Produce json
@@ -209,12 +345,18 @@ func CreateBook(c *fiber.Ctx) error {
 // @Param book body models.Book true "Book object"
 // @Success 200 {object} models.Book
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /books/{id} [put]
-func UpdateBook(c *fiber.Ctx) error {
-	id := c.Params("id")
-	// TODO: Add validation for ID
+func (h *Handler) UpdateBook(c *fiber.Ctx) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid book ID",
+		})
+	}
 
 	book := new(models.Book)
 	if err := c.BodyParser(book); err != nil {
@@ -229,42 +371,27 @@ func UpdateBook(c *fiber.Ctx) error {
 			"error": "Title, Author, and ISBN are required fields",
 		})
 	}
-	if book.Quantity < 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Quantity cannot be negative",
-		})
+	var reqErr *requestError
+	if book.ISBN, reqErr = canonicalISBN(book.ISBN); reqErr != nil {
+		return reqErr.send(c)
+	}
+	if reqErr := checkYear(book.Year); reqErr != nil {
+		return reqErr.send(c)
 	}
 
-	query := `UPDATE books 
-	          SET title = $1, author = $2, isbn = $3, quantity = $4, category = $5, updated_at = NOW() 
-	          WHERE id = $6
-	          RETURNING id, title, author, isbn, quantity, category, created_at, updated_at`
-
-	var updatedBook models.Book
-	row := database.DB.QueryRow(context.Background(), query,
-		book.Title, book.Author, book.ISBN, book.Quantity, book.Category, id)
-
-	err := row.Scan(
-		&updatedBook.ID, &updatedBook.Title, &updatedBook.Author, &updatedBook.ISBN,
-		&updatedBook.Quantity, &updatedBook.Category, &updatedBook.CreatedAt, &updatedBook.UpdatedAt,
-	)
-
+	updatedBook, err := h.Books.UpdateBook(c.UserContext(), id, *book)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			// Book not found to update
+		if errors.Is(err, store.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Book not found",
 			})
 		}
-		// Check if error is due to duplicate ISBN
-		if strings.Contains(err.Error(), "duplicate key value violates unique constraint \"books_isbn_key\"") {
+		if errors.Is(err, store.ErrDuplicateISBN) {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 				"error": "A book with this ISBN already exists",
 			})
 		}
-		// Handle other errors like unique constraint violation on ISBN
-		log.Printf("Error updating book %s: %v", id, err)
-		// TODO: Check for specific pgx errors
+		log.Printf("Error updating book %d: %v", id, err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not update book",
 		})
@@ -274,40 +401,49 @@ func UpdateBook(c *fiber.Ctx) error {
 }
 
 // @Summary Delete a book
-// @Description Delete a book by its ID
+// @Description Delete a book by its ID, with its files
 // @Tags books
 // @Accept json
 // @Produce json
 // @Param id path int true "Book ID"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /books/{id} [delete]
-func DeleteBook(c *fiber.Ctx) error {
-	id := c.Params("id")
-	// TODO: Add validation for ID
-
-	query := `DELETE FROM books WHERE id = $1 RETURNING id` // RETURNING id to check if deletion happened
+func (h *Handler) DeleteBook(c *fiber.Ctx) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid book ID",
+		})
+	}
 
-	var deletedID int
-	row := database.DB.QueryRow(context.Background(), query, id)
-	err := row.Scan(&deletedID)
+	// The files of the book are deleted with it, and their content after
+	files, err := h.Files.ListBookFiles(c.UserContext(), id)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		log.Printf("Error fetching files of book %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not delete book",
+		})
+	}
 
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			// Book not found to delete
+	if err := h.Books.DeleteBook(c.UserContext(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "Book not found",
 			})
 		}
-		// Other potential errors (e.g., foreign key constraints if ON DELETE CASCADE wasn't set correctly, though we set it)
-		log.Printf("Error deleting book %s: %v", id, err)
+		log.Printf("Error deleting book %d: %v", id, err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not delete book",
 		})
 	}
 
-	// Return success message or no content
-	// return c.SendStatus(fiber.StatusNoContent) // Option 1: No content
-	return c.JSON(fiber.Map{"message": "Book deleted successfully", "id": deletedID}) // Option 2: Confirmation message
+	for _, file := range files {
+		h.deleteBlob(c, file)
+	}
+
+	return c.JSON(fiber.Map{"message": "Book deleted successfully", "id": id})
 }
@@ -0,0 +1,309 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBookCRUD(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+
+	status, body := s.do(t, admin, fiber.MethodPost, "/api/books", models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Quantity: 2, Category: "scifi"})
+	if status != fiber.StatusCreated {
+		t.Fatalf("create = %d %s, want 201", status, body)
+	}
+	var book models.Book
+	if err := json.Unmarshal(body, &book); err != nil || book.ID == 0 {
+		t.Fatalf("create = %s, %v", body, err)
+	}
+	path := "/api/books/" + strconv.Itoa(book.ID)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{"create without an author", fiber.MethodPost, "/api/books", models.Book{Title: "Emma", ISBN: "9780141439587"}, fiber.StatusBadRequest},
+		{"create with negative copies", fiber.MethodPost, "/api/books", models.Book{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Quantity: -1}, fiber.StatusBadRequest},
+		{"create with too many copies", fiber.MethodPost, "/api/books", models.Book{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Quantity: 1001}, fiber.StatusBadRequest},
+		{"create a duplicate ISBN", fiber.MethodPost, "/api/books", models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719"}, fiber.StatusConflict},
+		{"create a duplicate ISBN-10", fiber.MethodPost, "/api/books", models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "0-441-17271-7"}, fiber.StatusConflict},
+		{"create with a bad checksum", fiber.MethodPost, "/api/books", models.Book{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439588"}, fiber.StatusBadRequest},
+		{"get", fiber.MethodGet, path, nil, fiber.StatusOK},
+		{"get by ISBN-10", fiber.MethodGet, "/api/books/isbn/0-441-17271-7", nil, fiber.StatusOK},
+		{"get by an invalid ISBN", fiber.MethodGet, "/api/books/isbn/12345", nil, fiber.StatusBadRequest},
+		{"get by an unknown ISBN", fiber.MethodGet, "/api/books/isbn/9780141439587", nil, fiber.StatusNotFound},
+		{"get an invalid ID", fiber.MethodGet, "/api/books/abc", nil, fiber.StatusBadRequest},
+		{"get an unknown book", fiber.MethodGet, "/api/books/9999", nil, fiber.StatusNotFound},
+		{"update", fiber.MethodPut, path, models.Book{Title: "Dune Messiah", Author: "Frank Herbert", ISBN: "9780441172719", Quantity: 2}, fiber.StatusOK},
+		{"update without a title", fiber.MethodPut, path, models.Book{Author: "Frank Herbert", ISBN: "9780441172719"}, fiber.StatusBadRequest},
+		{"update with a bad checksum", fiber.MethodPut, path, models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "0441172718"}, fiber.StatusBadRequest},
+		{"update an unknown book", fiber.MethodPut, "/api/books/9999", models.Book{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587"}, fiber.StatusNotFound},
+		{"delete", fiber.MethodDelete, path, nil, fiber.StatusOK},
+		{"get a deleted book", fiber.MethodGet, path, nil, fiber.StatusNotFound},
+		{"delete twice", fiber.MethodDelete, path, nil, fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if status, body := s.do(t, admin, tt.method, tt.path, tt.body); status != tt.status {
+			t.Errorf("%s: %s %s = %d %s, want %d", tt.name, tt.method, tt.path, status, body, tt.status)
+		}
+	}
+}
+
+func TestGetBooks(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	for _, book := range []models.Book{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Quantity: 1, Category: "scifi"},
+		{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Quantity: 0, Category: "classic"},
+		{Title: "Persuasion", Author: "Jane Austen", ISBN: "9780141439686", Quantity: 3, Category: "classic"},
+	} {
+		if status, body := s.do(t, admin, fiber.MethodPost, "/api/books", book); status != fiber.StatusCreated {
+			t.Fatalf("create = %d %s", status, body)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 3},
+		{"?search=dune", 1},
+		{"?search=austen", 2},
+		{"?category=classic", 2},
+		{"?author=Jane%20Austen", 2},
+		{"?available=true", 2},
+		{"?available=false", 1},
+		{"?category=classic&available=true", 1},
+	}
+	for _, tt := range tests {
+		status, body := s.do(t, admin, fiber.MethodGet, "/api/books"+tt.query, nil)
+		var page models.BookPage
+		if err := json.Unmarshal(body, &page); status != fiber.StatusOK || err != nil {
+			t.Errorf("GET /api/books%s = %d %s", tt.query, status, body)
+			continue
+		}
+		if len(page.Data) != tt.want || page.Total != tt.want || page.NextCursor != "" {
+			t.Errorf("GET /api/books%s = %d of %d books, want %d", tt.query, len(page.Data), page.Total, tt.want)
+		}
+	}
+}
+
+func TestGetBooksPages(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	for _, book := range []*models.Book{
+		{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Quantity: 0},
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Quantity: 1},
+		{Title: "Persuasion", Author: "Jane Austen", ISBN: "9780141439686", Quantity: 2},
+	} {
+		if err := s.store.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"?limit=2", []string{"Dune", "Emma", "Persuasion"}},
+		{"?limit=1&order=desc", []string{"Persuasion", "Emma", "Dune"}},
+		{"?limit=2&sort=quantity&order=desc", []string{"Persuasion", "Dune", "Emma"}},
+	}
+	for _, tt := range tests {
+		var titles []string
+		path := "/api/books" + tt.query
+		for i := 0; i < 5; i++ {
+			status, body := s.do(t, admin, fiber.MethodGet, path, nil)
+			var page models.BookPage
+			if err := json.Unmarshal(body, &page); status != fiber.StatusOK || err != nil {
+				t.Fatalf("GET %s = %d %s", path, status, body)
+			}
+			for _, book := range page.Data {
+				titles = append(titles, book.Title)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			path = "/api/books" + tt.query + "&cursor=" + page.NextCursor
+		}
+		if !reflect.DeepEqual(titles, tt.want) {
+			t.Errorf("pages of %s = %q, want %q", tt.query, titles, tt.want)
+		}
+	}
+
+	for _, query := range []string{"?limit=0", "?limit=201", "?sort=isbn", "?order=up", "?cursor=bogus", "?sort=relevance"} {
+		if status, body := s.do(t, admin, fiber.MethodGet, "/api/books"+query, nil); status != fiber.StatusBadRequest {
+			t.Errorf("GET /api/books%s = %d %s, want 400", query, status, body)
+		}
+	}
+}
+
+func TestBookRoutesRequireAdmin(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.user(t, "alice", models.RoleUser)
+	book := s.book(t, "9780441172719", 1)
+	path := "/api/books/" + strconv.Itoa(book.ID)
+	update := models.Book{Title: "Dune Messiah", Author: "Frank Herbert", ISBN: "9780441172719"}
+
+	tests := []struct {
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{fiber.MethodGet, "/api/books", nil, fiber.StatusOK},
+		{fiber.MethodGet, path, nil, fiber.StatusOK},
+		{fiber.MethodPost, "/api/books", models.Book{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587"}, fiber.StatusForbidden},
+		{fiber.MethodPut, path, update, fiber.StatusForbidden},
+		{fiber.MethodDelete, path, nil, fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if status, body := s.do(t, alice, tt.method, tt.path, tt.body); status != tt.status {
+			t.Errorf("%s %s as a regular user = %d %s, want %d", tt.method, tt.path, status, body, tt.status)
+		}
+	}
+}
+
+func TestSearchFallbackAndSuggest(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.user(t, "alice", models.RoleUser)
+	for _, book := range []*models.Book{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Category: "scifi"},
+		{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Category: "classic"},
+		{Title: "Persuasion", Author: "Jane Austen", ISBN: "9780141439686", Category: "classic"},
+	} {
+		if err := s.store.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+	}
+
+	searches := []struct {
+		query   string
+		similar bool
+		want    []string
+	}{
+		{"?search=austen", false, []string{"Emma", "Persuasion"}},
+		{"?search=austin", true, []string{"Emma", "Persuasion"}},
+		{"?search=herbret", true, []string{"Dune"}},
+		{"?search=xyzzy", true, nil},
+	}
+	for _, tt := range searches {
+		status, body := s.do(t, alice, fiber.MethodGet, "/api/books"+tt.query+"&sort=title", nil)
+		var page models.BookPage
+		if err := json.Unmarshal(body, &page); status != fiber.StatusOK || err != nil {
+			t.Fatalf("GET /api/books%s = %d %s", tt.query, status, body)
+		}
+		var titles []string
+		for _, book := range page.Data {
+			titles = append(titles, book.Title)
+		}
+		if page.Similar != tt.similar || !reflect.DeepEqual(titles, tt.want) {
+			t.Errorf("GET /api/books%s = %q similar %v, want %q similar %v", tt.query, titles, page.Similar, tt.want, tt.similar)
+		}
+	}
+
+	status, body := s.do(t, alice, fiber.MethodGet, "/api/books/suggest?q=persu&limit=1", nil)
+	var suggestions []models.BookSuggestion
+	if err := json.Unmarshal(body, &suggestions); status != fiber.StatusOK || err != nil {
+		t.Fatalf("suggest = %d %s", status, body)
+	}
+	if len(suggestions) != 1 || suggestions[0].Title != "Persuasion" || suggestions[0].Score <= 0 {
+		t.Errorf("suggest = %+v, want Persuasion", suggestions)
+	}
+	for _, query := range []string{"", "?q=+", "?q=dune&limit=0", "?q=dune&limit=51"} {
+		if status, body := s.do(t, alice, fiber.MethodGet, "/api/books/suggest"+query, nil); status != fiber.StatusBadRequest {
+			t.Errorf("GET /api/books/suggest%s = %d %s, want 400", query, status, body)
+		}
+	}
+}
+
+func TestGetBooksFacets(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.user(t, "alice", models.RoleUser)
+	for _, book := range []*models.Book{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Category: "scifi", Quantity: 1},
+		{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Category: "classic"},
+		{Title: "Persuasion", Author: "Jane Austen", ISBN: "9780141439686", Category: "classic", Quantity: 2},
+	} {
+		if err := s.store.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+	}
+
+	// Facets count every matching book, not just the page
+	status, body := s.do(t, alice, fiber.MethodGet, "/api/books?author=Jane%20Austen&limit=1&facets=category,%20available,category", nil)
+	var page models.BookPage
+	if err := json.Unmarshal(body, &page); status != fiber.StatusOK || err != nil {
+		t.Fatalf("GET /api/books = %d %s", status, body)
+	}
+	want := map[string][]models.FacetBucket{
+		"category":  {{Value: "classic", Count: 2}},
+		"available": {{Value: "false", Count: 1}, {Value: "true", Count: 1}},
+	}
+	if len(page.Data) != 1 || !reflect.DeepEqual(page.Facets, want) {
+		t.Errorf("page = %d books with facets %+v, want 1 book with %+v", len(page.Data), page.Facets, want)
+	}
+
+	if status, body := s.do(t, alice, fiber.MethodGet, "/api/books?facets=isbn", nil); status != fiber.StatusBadRequest {
+		t.Errorf("GET /api/books?facets=isbn = %d %s, want 400", status, body)
+	}
+}
+
+func TestGetBooksQuery(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.user(t, "alice", models.RoleUser)
+	for _, book := range []*models.Book{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Category: "scifi", Quantity: 1},
+		{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Category: "classic"},
+		{Title: "Persuasion", Author: "Jane Austen", ISBN: "9780141439686", Category: "classic", Quantity: 2},
+	} {
+		if err := s.store.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`author:"jane austen" available:true`, []string{"Persuasion"}},
+		{`category:scifi OR title:emma`, []string{"Dune", "Emma"}},
+		{`NOT author:austen`, []string{"Dune"}},
+		{`isbn:978014*`, []string{"Emma", "Persuasion"}},
+		{`isbn:0-441-17271-7`, []string{"Dune"}},
+		{`isbn:"978-0-14-143958-7" OR isbn:0141439688`, []string{"Emma", "Persuasion"}},
+		{`(dune OR emma) AND NOT available:false`, []string{"Dune"}},
+	}
+	for _, tt := range tests {
+		status, body := s.do(t, alice, fiber.MethodGet, "/api/books?sort=title&q="+url.QueryEscape(tt.query), nil)
+		var page models.BookPage
+		if err := json.Unmarshal(body, &page); status != fiber.StatusOK || err != nil {
+			t.Fatalf("q=%s: %d %s", tt.query, status, body)
+		}
+		var titles []string
+		for _, book := range page.Data {
+			titles = append(titles, book.Title)
+		}
+		if !reflect.DeepEqual(titles, tt.want) {
+			t.Errorf("q=%s: titles = %v, want %v", tt.query, titles, tt.want)
+		}
+	}
+
+	status, body := s.do(t, alice, fiber.MethodGet, "/api/books?q="+url.QueryEscape(`author:(austen`), nil)
+	var resp struct{ Position int }
+	if err := json.Unmarshal(body, &resp); status != fiber.StatusBadRequest || err != nil || resp.Position == 0 {
+		t.Errorf("unbalanced query = %d %s, want 400 with a position", status, body)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"digital-library/backend/middleware"
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// resolveBorrower looks up the account named by a user ID or patron card
+// number. Regular users default to their own account and get forbidden for
+// any other; admins must name the borrower.
+func (h *Handler) resolveBorrower(c *fiber.Ctx, userID int, cardNumber, forbidden string) (models.User, *requestError) {
+	claims, _ := middleware.CurrentUser(c)
+	cardNumber = strings.TrimSpace(cardNumber)
+
+	if userID == 0 && cardNumber == "" {
+		if claims.Role != models.RoleAdmin {
+			userID = claims.UserID
+		} else {
+			return models.User{}, &requestError{fiber.StatusBadRequest, "User ID or card number is required"}
+		}
+	}
+	if claims.Role != models.RoleAdmin && userID != 0 && userID != claims.UserID {
+		return models.User{}, &requestError{fiber.StatusForbidden, forbidden}
+	}
+
+	var user models.User
+	var err error
+	if userID != 0 {
+		user, err = h.Users.GetUser(c.UserContext(), userID)
+	} else {
+		user, err = h.Users.GetUserByCardNumber(c.UserContext(), cardNumber)
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return user, &requestError{fiber.StatusNotFound, "Borrower not found"}
+		}
+		log.Printf("Error resolving borrower (user %d, card %q): %v", userID, cardNumber, err)
+		return user, &requestError{fiber.StatusInternalServerError, "Could not look up borrower"}
+	}
+	if cardNumber != "" && user.CardNumber != cardNumber {
+		return user, &requestError{fiber.StatusBadRequest, "User ID and card number belong to different borrowers"}
+	}
+	if claims.Role != models.RoleAdmin && user.ID != claims.UserID {
+		return user, &requestError{fiber.StatusForbidden, forbidden}
+	}
+	return user, nil
+}
+
+// borrowerFilter returns the account named by the ?user_id= or ?card_number=
+// query parameters of a listing. Regular users always get their own account;
+// zero means an admin did not narrow the listing to one borrower.
+func (h *Handler) borrowerFilter(c *fiber.Ctx, forbidden string) (int, *requestError) {
+	claims, _ := middleware.CurrentUser(c)
+	requested := c.Query("user_id", "")
+	cardNumber := c.Query("card_number", "")
+
+	if requested == "" && cardNumber == "" {
+		if claims.Role != models.RoleAdmin {
+			return claims.UserID, nil
+		}
+		return 0, nil
+	}
+	userID := 0
+	if requested != "" {
+		id, err := strconv.Atoi(requested)
+		if err != nil || id <= 0 {
+			return 0, &requestError{fiber.StatusBadRequest, "Invalid user ID"}
+		}
+		userID = id
+	}
+	user, reqErr := h.resolveBorrower(c, userID, cardNumber, forbidden)
+	if reqErr != nil {
+		return 0, reqErr
+	}
+	return user.ID, nil
+}
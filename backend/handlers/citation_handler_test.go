@@ -0,0 +1,86 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCiteBook(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.user(t, "alice", models.RoleUser)
+	year := 1965
+	book := &models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Publisher: "Chilton Books", Year: &year}
+	if err := s.store.CreateBook(context.Background(), book); err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+	path := "/api/books/" + strconv.Itoa(book.ID) + "/cite"
+
+	tests := []struct {
+		query string
+		want  models.Citation
+	}{
+		{"", models.Citation{BookID: book.ID, Style: "apa", Text: "Herbert, F. (1965). Dune. Chilton Books.", HTML: "Herbert, F. (1965). <i>Dune</i>. Chilton Books."}},
+		{"?style=chicago", models.Citation{BookID: book.ID, Style: "chicago", Text: "Herbert, Frank. Dune. Chilton Books, 1965.", HTML: "Herbert, Frank. <i>Dune</i>. Chilton Books, 1965."}},
+	}
+	for _, tt := range tests {
+		status, body := s.do(t, alice, fiber.MethodGet, path+tt.query, nil)
+		var got models.Citation
+		if err := json.Unmarshal(body, &got); status != fiber.StatusOK || err != nil || got != tt.want {
+			t.Errorf("GET %s%s = %d %s, want %+v", path, tt.query, status, body, tt.want)
+		}
+	}
+
+	failures := []struct {
+		path   string
+		status int
+	}{
+		{path + "?style=harvard", fiber.StatusBadRequest},
+		{"/api/books/9999/cite", fiber.StatusNotFound},
+		{"/api/books/abc/cite", fiber.StatusBadRequest},
+	}
+	for _, tt := range failures {
+		if status, body := s.do(t, alice, fiber.MethodGet, tt.path, nil); status != tt.status {
+			t.Errorf("GET %s = %d %s, want %d", tt.path, status, body, tt.status)
+		}
+	}
+}
+
+func TestExportReferences(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.user(t, "alice", models.RoleUser)
+	var ids []string
+	for _, book := range []*models.Book{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719"},
+		{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587"},
+		{Title: "Persuasion", Author: "Jane Austen", ISBN: "9780141439686"},
+	} {
+		if err := s.store.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+		ids = append(ids, strconv.Itoa(book.ID))
+	}
+	selection := "&ids=" + ids[0] + "," + ids[2]
+
+	status, body := s.do(t, alice, fiber.MethodGet, "/api/books/export?format=bibtex&sort=title"+selection, nil)
+	if status != fiber.StatusOK || strings.Count(string(body), "@book{") != 2 ||
+		!strings.Contains(string(body), "@book{herbert,") || !strings.Contains(string(body), "@book{austen,") {
+		t.Errorf("BibTeX export = %d %s, want Dune and Persuasion", status, body)
+	}
+
+	status, body = s.do(t, alice, fiber.MethodGet, "/api/books/export?format=csljson"+selection, nil)
+	var items []struct{ Title string }
+	if err := json.Unmarshal(body, &items); status != fiber.StatusOK || err != nil || len(items) != 2 {
+		t.Errorf("CSL-JSON export = %d %s, want 2 items", status, body)
+	}
+
+	if status, body := s.do(t, alice, fiber.MethodGet, "/api/books/export?format=ris&ids=1,x", nil); status != fiber.StatusBadRequest {
+		t.Errorf("export with invalid ids = %d %s, want 400", status, body)
+	}
+}
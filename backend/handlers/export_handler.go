@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"digital-library/backend/marc"
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Export formats
+const (
+	exportCSV     = "csv"
+	exportJSONL   = "jsonl"
+	exportMARC21  = "marc21"
+	exportMARCXML = "marcxml"
+)
+
+// Content types and file extensions of the export formats
+var exportTypes = map[string][2]string{
+	exportCSV:     {"text/csv; charset=utf-8", "csv"},
+	exportJSONL:   {"application/x-ndjson", "jsonl"},
+	exportMARC21:  {"application/marc", "mrc"},
+	exportMARCXML: {"application/marcxml+xml", "xml"},
+	exportBibTeX:  {"application/x-bibtex; charset=utf-8", "bib"},
+	exportRIS:     {"application/x-research-info-systems", "ris"},
+	exportCSLJSON: {"application/vnd.citationstyles.csl+json", "json"},
+}
+
+// Columns of the CSV exports. The book columns can be imported again.
+var (
+	bookExportColumns = []string{"id", "title", "author", "isbn", "category", "quantity", "publisher", "year", "created_at", "updated_at"}
+
+	lendingExportColumns = []string{
+		"id", "book_id", "book_title", "book_author", "item_barcode", "user_id", "borrower",
+		"borrow_date", "due_date", "return_date", "return_condition", "status", "renewal_count", "created_at",
+	}
+)
+
+// @Summary Export books
+// @Description Download every book GET /books would list for the same search, filters and sort, across all pages, as CSV, JSON Lines, binary MARC 21, MARCXML, or BibTeX, RIS or CSL-JSON for reference managers. MARC records follow the configured field mapping. Pass ids to export a selection of books. The rows are streamed as they are read.
+// @Tags books
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Produce application/marc
+// @Produce application/marcxml+xml
+// @Produce application/x-bibtex
+// @Produce application/x-research-info-systems
+// @Produce application/vnd.citationstyles.csl+json
+// @Param format query string false "csv (default), jsonl, marc21, marcxml, bibtex, ris or csljson"
+// @Param ids query string false "Comma-separated IDs of the books to export"
+// @Param search query string false "Full-text search, as for GET /books"
+// @Param category query string false "Filter by category"
+// @Param author query string false "Filter by author"
+// @Param available query string false "Filter by availability (true/false)"
+// @Param q query string false "Catalog query, as for GET /books"
+// @Param sort query string false "Sort field, as for GET /books"
+// @Param order query string false "Sort order: asc or desc"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Router /books/export [get]
+func (h *Handler) ExportBooks(c *fiber.Ctx) error {
+	format, reqErr := exportFormat(c, exportCSV, exportJSONL, exportMARC21, exportMARCXML, exportBibTeX, exportRIS, exportCSLJSON)
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+	filter, errBody := bookFilter(c)
+	if errBody != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errBody)
+	}
+	page, reqErr := querySort(c, store.BookSortFields)
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+	if page.Sort == "relevance" && filter.Search == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sorting by relevance requires a search"})
+	}
+
+	ctx, books, mapping := c.UserContext(), h.Books, h.marcMapping()
+	return streamExport(c, format, "books", func(w *bufio.Writer) error {
+		switch format {
+		case exportJSONL:
+			rows := json.NewEncoder(w)
+			return books.ExportBooks(ctx, filter, page, func(book models.Book) error {
+				return rows.Encode(book)
+			})
+		case exportMARC21, exportMARCXML:
+			return writeMARC(w, format, func(write func(*marc.Record) error) error {
+				return books.ExportBooks(ctx, filter, page, func(book models.Book) error {
+					return write(marcRecord(book, mapping))
+				})
+			})
+		case exportBibTeX, exportRIS, exportCSLJSON:
+			return writeReferences(w, format, func(write func(models.Book) error) error {
+				return books.ExportBooks(ctx, filter, page, write)
+			})
+		}
+
+		rows := csv.NewWriter(w)
+		if err := rows.Write(bookExportColumns); err != nil {
+			return err
+		}
+		err := books.ExportBooks(ctx, filter, page, func(book models.Book) error {
+			return rows.Write([]string{
+				strconv.Itoa(book.ID), book.Title, book.Author, book.ISBN, book.Category,
+				strconv.Itoa(book.Quantity), book.Publisher, exportInt(book.Year),
+				exportTime(book.CreatedAt), exportTime(book.UpdatedAt),
+			})
+		})
+		if err != nil {
+			return err
+		}
+		rows.Flush()
+		return rows.Error()
+	})
+}
+
+// @Summary Export lending records
+// @Description Download every lending record GET /lending would list for the same search, filters and sort, across all pages, as CSV or JSON Lines. Regular users get only their own loans. The rows are streamed as they are read.
+// @Tags lending
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "csv (default) or jsonl"
+// @Param search query string false "Search term for borrower name or book title"
+// @Param user_id query int false "Admin only: filter by borrower account"
+// @Param card_number query string false "Admin only: filter by borrower card number"
+// @Param status query string false "Filter by status (active/overdue/returned/lost)"
+// @Param bookTitle query string false "Filter by book title"
+// @Param due_before query string false "Only loans due on or before this date (YYYY-MM-DD)"
+// @Param due_after query string false "Only loans due on or after this date (YYYY-MM-DD)"
+// @Param sort query string false "Sort field, as for GET /lending"
+// @Param order query string false "Sort order: asc or desc"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /lending/export [get]
+func (h *Handler) ExportLendingRecords(c *fiber.Ctx) error {
+	format, reqErr := exportFormat(c, exportCSV, exportJSONL)
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+	filter, reqErr := h.lendingFilter(c)
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+	page, reqErr := querySort(c, store.LendingSortFields)
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+
+	ctx, lending := c.UserContext(), h.Lending
+	return streamExport(c, format, "lending", func(w *bufio.Writer) error {
+		if format == exportJSONL {
+			rows := json.NewEncoder(w)
+			return lending.ExportLendingRecords(ctx, filter, page, func(record models.LendingRecordDetail) error {
+				return rows.Encode(record)
+			})
+		}
+
+		rows := csv.NewWriter(w)
+		if err := rows.Write(lendingExportColumns); err != nil {
+			return err
+		}
+		err := lending.ExportLendingRecords(ctx, filter, page, func(record models.LendingRecordDetail) error {
+			return rows.Write([]string{
+				strconv.Itoa(record.ID), strconv.Itoa(record.BookID), record.BookTitle, record.BookAuthor,
+				exportString(record.ItemBarcode), exportInt(record.UserID), record.Borrower,
+				exportDate(&record.BorrowDate), exportDate(&record.DueDate), exportDate(record.ReturnDate),
+				exportString(record.ReturnCondition), record.Status, strconv.Itoa(record.RenewalCount),
+				exportTime(record.CreatedAt),
+			})
+		})
+		if err != nil {
+			return err
+		}
+		rows.Flush()
+		return rows.Error()
+	})
+}
+
+// @Summary Export the library
+// @Description Download a ZIP archive of the whole library as JSON Lines: books.jsonl, items.jsonl (copies), lending.jsonl, holds.jsonl, fines.jsonl and loan_policies.jsonl. User accounts are not included. The archive is streamed as it is written.
+// @Tags export
+// @Produce application/zip
+// @Success 200 {file} file
+// @Failure 403 {object} map[string]string
+// @Router /export/archive [get]
+func (h *Handler) ExportArchive(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	files := []struct {
+		name  string
+		write func(rows *json.Encoder) error
+	}{
+		{"books.jsonl", func(rows *json.Encoder) error {
+			return h.Books.ExportBooks(ctx, store.BookFilter{}, store.PageRequest{Sort: "created_at"}, func(book models.Book) error {
+				return rows.Encode(book)
+			})
+		}},
+		{"items.jsonl", func(rows *json.Encoder) error {
+			return h.Items.ExportItems(ctx, func(item models.BookItem) error {
+				return rows.Encode(item)
+			})
+		}},
+		{"lending.jsonl", func(rows *json.Encoder) error {
+			return h.Lending.ExportLendingRecords(ctx, store.LendingFilter{}, store.PageRequest{Sort: "created_at"}, func(record models.LendingRecordDetail) error {
+				return rows.Encode(record)
+			})
+		}},
+		{"holds.jsonl", func(rows *json.Encoder) error {
+			return h.Holds.ExportHolds(ctx, func(hold models.HoldDetail) error {
+				return rows.Encode(hold)
+			})
+		}},
+		{"fines.jsonl", func(rows *json.Encoder) error {
+			return h.Fines.ExportFineEntries(ctx, func(entry models.FineEntry) error {
+				return rows.Encode(entry)
+			})
+		}},
+		{"loan_policies.jsonl", func(rows *json.Encoder) error {
+			return h.Policies.ExportLoanPolicies(ctx, func(policy models.LoanPolicy) error {
+				return rows.Encode(policy)
+			})
+		}},
+	}
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="library-`+time.Now().Format("2006-01-02")+`.zip"`)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		archive := zip.NewWriter(w)
+		for _, file := range files {
+			f, err := archive.Create(file.name)
+			if err == nil {
+				err = file.write(json.NewEncoder(f))
+			}
+			if err != nil {
+				// Without the central directory the archive fails to open
+				// rather than looking complete
+				log.Printf("Error exporting %s to the library archive: %v", file.name, err)
+				return
+			}
+		}
+		if err := archive.Close(); err != nil {
+			log.Printf("Error exporting the library archive: %v", err)
+		}
+	})
+	return nil
+}
+
+// exportFormat parses ?format= of an export, which defaults to the first of
+// the formats it offers
+func exportFormat(c *fiber.Ctx, formats ...string) (string, *requestError) {
+	format := c.Query("format", formats[0])
+	if !slices.Contains(formats, format) {
+		return "", &requestError{fiber.StatusBadRequest, "Invalid format, expected " + strings.Join(formats, ", ")}
+	}
+	return format, nil
+}
+
+// streamExport sets the headers of a download named after name and format,
+// and streams the body write produces once the handler has returned. By then
+// errors can no longer change the response, so they are logged and cut the
+// download short.
+func streamExport(c *fiber.Ctx, format, name string, write func(w *bufio.Writer) error) error {
+	c.Set(fiber.HeaderContentType, exportTypes[format][0])
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+name+`.`+exportTypes[format][1]+`"`)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := write(w); err != nil {
+			log.Printf("Error exporting %s: %v", name, err)
+		}
+	})
+	return nil
+}
+
+func exportTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func exportDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func exportString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func exportInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
@@ -0,0 +1,148 @@
+package handlers_test
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestExportBooks(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.user(t, "alice", models.RoleUser)
+	for _, book := range []*models.Book{
+		{Title: "Persuasion", Author: "Jane Austen", ISBN: "9780141439686", Category: "classic", Quantity: 1},
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Category: "scifi", Quantity: 2},
+		{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Category: "classic"},
+	} {
+		if err := s.store.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+	}
+
+	status, body := s.do(t, alice, fiber.MethodGet, "/api/books/export?author=Jane%20Austen&sort=title", nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("CSV export = %d %s", status, body)
+	}
+	rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(rows) != 3 || !reflect.DeepEqual(rows[0][:4], []string{"id", "title", "author", "isbn"}) ||
+		rows[1][1] != "Emma" || rows[2][1] != "Persuasion" {
+		t.Errorf("CSV export = %q, want a header, Emma and Persuasion", rows)
+	}
+
+	status, body = s.do(t, alice, fiber.MethodGet, "/api/books/export?format=jsonl&sort=title&order=desc", nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("JSON Lines export = %d %s", status, body)
+	}
+	var titles []string
+	lines := bufio.NewScanner(bytes.NewReader(body))
+	for lines.Scan() {
+		var book models.Book
+		if err := json.Unmarshal(lines.Bytes(), &book); err != nil {
+			t.Fatalf("line %q: %v", lines.Text(), err)
+		}
+		titles = append(titles, book.Title)
+	}
+	if want := []string{"Persuasion", "Emma", "Dune"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("JSON Lines export = %v, want %v", titles, want)
+	}
+
+	for _, query := range []string{"?format=xml", "?sort=isbn", "?order=up"} {
+		if status, body := s.do(t, alice, fiber.MethodGet, "/api/books/export"+query, nil); status != fiber.StatusBadRequest {
+			t.Errorf("GET /api/books/export%s = %d %s, want 400", query, status, body)
+		}
+	}
+}
+
+func TestExportLendingRecords(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	alice := s.user(t, "alice", models.RoleUser)
+	bob := s.user(t, "bob", models.RoleUser)
+	book := s.book(t, "9780441172719", 3)
+	s.lend(t, alice, book.ID, alice)
+	s.lend(t, bob, book.ID, bob)
+
+	tests := []struct {
+		name      string
+		user      models.User
+		query     string
+		borrowers []string
+	}{
+		{"own loans", alice, "", []string{"alice"}},
+		{"every loan", admin, "?sort=borrow_date", []string{"alice", "bob"}},
+		{"filtered by card", admin, "?card_number=" + bob.CardNumber, []string{"bob"}},
+	}
+	for _, tt := range tests {
+		status, body := s.do(t, tt.user, fiber.MethodGet, "/api/lending/export"+tt.query, nil)
+		rows, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+		if status != fiber.StatusOK || err != nil {
+			t.Fatalf("%s: export = %d %s", tt.name, status, body)
+		}
+		var borrowers []string
+		for _, row := range rows[1:] {
+			borrowers = append(borrowers, row[6])
+		}
+		if !reflect.DeepEqual(borrowers, tt.borrowers) {
+			t.Errorf("%s: borrowers = %v, want %v", tt.name, borrowers, tt.borrowers)
+		}
+	}
+}
+
+func TestExportArchive(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	alice := s.user(t, "alice", models.RoleUser)
+	book := s.book(t, "9780441172719", 2)
+	s.lend(t, alice, book.ID, alice)
+
+	if status, body := s.do(t, alice, fiber.MethodGet, "/api/export/archive", nil); status != fiber.StatusForbidden {
+		t.Errorf("archive as a regular user = %d %s, want 403", status, body)
+	}
+
+	status, body := s.do(t, admin, fiber.MethodGet, "/api/export/archive", nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("archive = %d %s", status, body)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("zip.NewReader: %v", err)
+	}
+	lines := make(map[string]int)
+	for _, file := range archive.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatalf("Open %s: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("ReadAll %s: %v", file.Name, err)
+		}
+		lines[file.Name] = strings.Count(string(data), "\n")
+	}
+	want := map[string]int{
+		"books.jsonl":         1,
+		"items.jsonl":         2,
+		"lending.jsonl":       1,
+		"holds.jsonl":         0,
+		"fines.jsonl":         0,
+		"loan_policies.jsonl": 1,
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("archive lines = %v, want %v", lines, want)
+	}
+}
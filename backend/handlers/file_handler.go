@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"digital-library/backend/blob"
+	"digital-library/backend/middleware"
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// fileFormOverhead is the room left in an upload for the multipart form
+// around the file, when its size is checked before the form is read
+const fileFormOverhead = 1 << 20
+
+// bookFileFormat is a kind of file books can have. It is recognized by its
+// extension and checked by the signature at the start of the content.
+type bookFileFormat struct {
+	mimeType string
+	matches  func(head []byte) bool
+}
+
+// bookFileFormats maps the extensions of the files books can have to their
+// formats
+var bookFileFormats = map[string]bookFileFormat{
+	".epub": {"application/epub+zip", isEPUB},
+	".pdf":  {"application/pdf", hasSignature("%PDF-")},
+	".mp3":  {"audio/mpeg", isMP3},
+	".m4a":  {"audio/mp4", isMP4},
+	".m4b":  {"audio/mp4", isMP4},
+	".ogg":  {"audio/ogg", hasSignature("OggS")},
+	".oga":  {"audio/ogg", hasSignature("OggS")},
+	".opus": {"audio/ogg", hasSignature("OggS")},
+	".flac": {"audio/flac", hasSignature("fLaC")},
+}
+
+// fileHeadSize is how much of a file its signature is looked for in
+const fileHeadSize = 64
+
+// hasSignature checks for content starting with a signature
+func hasSignature(signature string) func([]byte) bool {
+	return func(head []byte) bool {
+		return bytes.HasPrefix(head, []byte(signature))
+	}
+}
+
+// isEPUB checks for a ZIP archive whose first entry is the uncompressed
+// mimetype file, as EPUB requires
+func isEPUB(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("PK\x03\x04")) && len(head) >= 58 &&
+		string(head[30:58]) == "mimetypeapplication/epub+zip"
+}
+
+// isMP3 checks for an ID3 tag or an MPEG audio frame
+func isMP3(head []byte) bool {
+	return bytes.HasPrefix(head, []byte("ID3")) || len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0
+}
+
+// isMP4 checks for the file type box of an MPEG-4 file
+func isMP4(head []byte) bool {
+	return len(head) >= 8 && string(head[4:8]) == "ftyp"
+}
+
+// @Summary Get the files of a book
+// @Description List the e-book and audiobook files of a book, with their size, MIME type and SHA-256 checksum
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param id path int true "Book ID"
+// @Success 200 {array} models.BookFile
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /books/{id}/files [get]
+func (h *Handler) GetBookFiles(c *fiber.Ctx) error {
+	if h.Blobs == nil {
+		return noFileStorage(c)
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid book ID"})
+	}
+
+	files, err := h.Files.ListBookFiles(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Book not found"})
+		}
+		log.Printf("Error fetching files of book %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve files"})
+	}
+
+	return c.JSON(files)
+}
+
+// @Summary Upload a file of a book
+// @Description Upload an EPUB, PDF or audio file (MP3, M4A/M4B, Ogg/Opus or FLAC) of a book as the file field of a multipart form. The format is taken from the extension and checked against the content. The size and SHA-256 checksum are recorded.
+// @Tags books
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param file formData file true "E-book or audio file"
+// @Success 201 {object} models.BookFile
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /books/{id}/files [post]
+func (h *Handler) UploadBookFile(c *fiber.Ctx) error {
+	if h.Blobs == nil {
+		return noFileStorage(c)
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid book ID"})
+	}
+	tooLarge := fiber.Map{"error": "File is larger than " + strconv.FormatInt(h.MaxFileSize>>20, 10) + " MB"}
+	if int64(c.Request().Header.ContentLength()) > h.MaxFileSize+fileFormOverhead {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(tooLarge)
+	}
+	if _, err := h.Books.GetBook(c.UserContext(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Book not found"})
+		}
+		log.Printf("Error fetching book %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not upload file"})
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Missing file in the file field"})
+	}
+	if header.Size > h.MaxFileSize {
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(tooLarge)
+	}
+	filename := uploadFilename(header.Filename)
+	format, ok := bookFileFormats[strings.ToLower(fileExtension(filename))]
+	if !ok {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": "Unsupported file type, expected .epub, .pdf, .mp3, .m4a, .m4b, .ogg, .oga, .opus or .flac",
+		})
+	}
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Could not read the uploaded file"})
+	}
+	defer file.Close()
+
+	head := make([]byte, fileHeadSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Could not read the uploaded file"})
+	}
+	if !format.matches(head[:n]) {
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": "File content does not match its " + fileExtension(filename) + " extension",
+		})
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Could not read the uploaded file"})
+	}
+
+	// The checksum is taken as the content is stored
+	bookFile := &models.BookFile{
+		BookID:     id,
+		Filename:   filename,
+		MimeType:   format.mimeType,
+		Size:       header.Size,
+		StorageKey: "books/" + strconv.Itoa(id) + "/" + randomKey(),
+	}
+	hash := sha256.New()
+	if err := h.Blobs.Put(c.UserContext(), bookFile.StorageKey, io.TeeReader(file, hash), bookFile.Size, bookFile.MimeType); err != nil {
+		log.Printf("Error storing a file of book %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not store file"})
+	}
+	bookFile.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	if err := h.Files.CreateBookFile(c.UserContext(), bookFile); err != nil {
+		h.deleteBlob(c, *bookFile)
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Book not found"})
+		}
+		log.Printf("Error recording a file of book %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not upload file"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(bookFile)
+}
+
+// @Summary Download a file of a book
+// @Description Download an e-book or audio file of a book. Borrowers need an active loan of the book; admins can download any file. Single byte ranges are supported with the Range and If-Range headers, so downloads can resume and audio can seek.
+// @Tags books
+// @Produce octet-stream
+// @Param id path int true "Book ID"
+// @Param fileId path int true "File ID"
+// @Param Range header string false "Byte range, such as bytes=0-1023"
+// @Success 200 {file} file
+// @Success 206 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 416 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /books/{id}/files/{fileId} [get]
+func (h *Handler) DownloadBookFile(c *fiber.Ctx) error {
+	if h.Blobs == nil {
+		return noFileStorage(c)
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid book ID"})
+	}
+	fileID, ok := paramID(c, "fileId")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid file ID"})
+	}
+
+	file, err := h.Files.GetBookFile(c.UserContext(), id, fileID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Book not found"})
+		case errors.Is(err, store.ErrFileNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "File not found"})
+		}
+		log.Printf("Error fetching file %d of book %d: %v", fileID, id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not download file"})
+	}
+
+	claims, _ := middleware.CurrentUser(c)
+	if claims.Role != models.RoleAdmin {
+		borrowed, err := h.Lending.HasActiveLoan(c.UserContext(), claims.UserID, id)
+		if err != nil {
+			log.Printf("Error checking the loans of user %d for book %d: %v", claims.UserID, id, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not download file"})
+		}
+		if !borrowed {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Downloading a file requires an active loan of the book"})
+		}
+	}
+
+	// The checksum identifies the content, which never changes
+	etag := `"` + file.Checksum + `"`
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderLastModified, file.CreatedAt.UTC().Format(http.TimeFormat))
+	c.Set(fiber.HeaderCacheControl, "private")
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": file.Filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	c.Set(fiber.HeaderContentDisposition, disposition)
+
+	// A range is only served if the client still has the same content
+	rangeHeader := c.Get(fiber.HeaderRange)
+	if ifRange := c.Get(fiber.HeaderIfRange); ifRange != "" && ifRange != etag {
+		rangeHeader = ""
+	}
+	start, length, status := byteRange(rangeHeader, file.Size)
+	if status == fiber.StatusRequestedRangeNotSatisfiable {
+		c.Set(fiber.HeaderContentRange, "bytes */"+strconv.FormatInt(file.Size, 10))
+		return c.Status(status).JSON(fiber.Map{"error": "Range not satisfiable"})
+	}
+
+	body, err := h.Blobs.Get(c.UserContext(), file.StorageKey, start, length)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			log.Printf("Content %s of file %d of book %d is missing", file.StorageKey, fileID, id)
+		} else {
+			log.Printf("Error reading file %d of book %d: %v", fileID, id, err)
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not download file"})
+	}
+	if status == fiber.StatusPartialContent {
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, file.Size))
+	}
+	c.Set(fiber.HeaderContentType, file.MimeType)
+	return c.Status(status).SendStream(body, int(length))
+}
+
+// @Summary Delete a file of a book
+// @Description Delete an e-book or audio file of a book and its stored content
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param fileId path int true "File ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /books/{id}/files/{fileId} [delete]
+func (h *Handler) DeleteBookFile(c *fiber.Ctx) error {
+	if h.Blobs == nil {
+		return noFileStorage(c)
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid book ID"})
+	}
+	fileID, ok := paramID(c, "fileId")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid file ID"})
+	}
+
+	file, err := h.Files.DeleteBookFile(c.UserContext(), id, fileID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Book not found"})
+		case errors.Is(err, store.ErrFileNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "File not found"})
+		}
+		log.Printf("Error deleting file %d of book %d: %v", fileID, id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete file"})
+	}
+	h.deleteBlob(c, file)
+
+	return c.JSON(fiber.Map{"message": "File deleted successfully", "id": fileID})
+}
+
+// deleteBlob deletes the content of a file whose record is gone. Content
+// that cannot be deleted is only logged, as the file no longer exists.
+func (h *Handler) deleteBlob(c *fiber.Ctx, file models.BookFile) {
+	if h.Blobs == nil {
+		log.Printf("Content %s of file %d of book %d is left, as no file storage is set up", file.StorageKey, file.ID, file.BookID)
+		return
+	}
+	if err := h.Blobs.Delete(c.UserContext(), file.StorageKey); err != nil {
+		log.Printf("Error deleting content %s of file %d of book %d: %v", file.StorageKey, file.ID, file.BookID, err)
+	}
+}
+
+// noFileStorage answers the file endpoints while no file storage is set up
+func noFileStorage(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"error": "File storage is not configured"})
+}
+
+// byteRange parses the Range header of a download of size bytes into the
+// offset and length to send, and the status to send them with: 206 for a
+// range, 200 for the whole file, or 416 for a range past the end. Malformed
+// headers and multiple ranges are ignored, as HTTP allows, and get the
+// whole file.
+func byteRange(header string, size int64) (int64, int64, int) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, size, fiber.StatusOK
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, size, fiber.StatusOK
+	}
+
+	if first == "" {
+		// The last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, size, fiber.StatusOK
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, fiber.StatusRequestedRangeNotSatisfiable
+		}
+		start := max(size-n, 0)
+		return start, size - start, fiber.StatusPartialContent
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, size, fiber.StatusOK
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, size, fiber.StatusOK
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		return 0, 0, fiber.StatusRequestedRangeNotSatisfiable
+	}
+	return start, end - start + 1, fiber.StatusPartialContent
+}
+
+// uploadFilename keeps the base name of an uploaded file without control
+// characters, cut before its extension to fit its column
+func uploadFilename(name string) string {
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	ext := fileExtension(name)
+	stem := name[:len(name)-len(ext)]
+	for len(stem)+len(ext) > 255 && stem != "" {
+		_, size := utf8.DecodeLastRuneInString(stem)
+		stem = stem[:len(stem)-size]
+	}
+	return stem + ext
+}
+
+// fileExtension returns the extension of a file name, from its last dot
+func fileExtension(name string) string {
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		return name[i:]
+	}
+	return ""
+}
+
+// randomKey returns a random name for stored content
+func randomKey() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err) // crypto/rand does not fail on supported platforms
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestByteRange(t *testing.T) {
+	tests := []struct {
+		header string
+		size   int64
+		start  int64
+		length int64
+		status int
+	}{
+		{"", 100, 0, 100, fiber.StatusOK},
+		{"bytes=0-9", 100, 0, 10, fiber.StatusPartialContent},
+		{"bytes=90-", 100, 90, 10, fiber.StatusPartialContent},
+		{"bytes=90-200", 100, 90, 10, fiber.StatusPartialContent},
+		{"bytes=99-99", 100, 99, 1, fiber.StatusPartialContent},
+		{"bytes=-10", 100, 90, 10, fiber.StatusPartialContent},
+		{"bytes=-200", 100, 0, 100, fiber.StatusPartialContent},
+		{"bytes= 5-9 ", 100, 5, 5, fiber.StatusPartialContent},
+		{"bytes=100-", 100, 0, 0, fiber.StatusRequestedRangeNotSatisfiable},
+		{"bytes=100-200", 100, 0, 0, fiber.StatusRequestedRangeNotSatisfiable},
+		{"bytes=-0", 100, 0, 0, fiber.StatusRequestedRangeNotSatisfiable},
+		{"bytes=-5", 0, 0, 0, fiber.StatusRequestedRangeNotSatisfiable},
+		{"bytes=0-", 0, 0, 0, fiber.StatusRequestedRangeNotSatisfiable},
+		// Malformed headers and multiple ranges get the whole file
+		{"bytes=0-9,20-29", 100, 0, 100, fiber.StatusOK},
+		{"bytes=9-0", 100, 0, 100, fiber.StatusOK},
+		{"bytes=a-9", 100, 0, 100, fiber.StatusOK},
+		{"bytes=0-b", 100, 0, 100, fiber.StatusOK},
+		{"bytes=-1-5", 100, 0, 100, fiber.StatusOK},
+		{"bytes=5", 100, 0, 100, fiber.StatusOK},
+		{"items=0-9", 100, 0, 100, fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		start, length, status := byteRange(tt.header, tt.size)
+		if start != tt.start || length != tt.length || status != tt.status {
+			t.Errorf("byteRange(%q, %d) = %d, %d, %d, want %d, %d, %d", tt.header, tt.size, start, length, status, tt.start, tt.length, tt.status)
+		}
+	}
+}
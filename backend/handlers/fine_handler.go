@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FineCreditPayload defines the expected structure for payment and waiver requests
+type FineCreditPayload struct {
+	UserID          int    `json:"user_id"`
+	CardNumber      string `json:"card_number"` // Alternatively, the borrower's patron card number
+	AmountCents     int    `json:"amount_cents"`
+	Note            string `json:"note"`
+	LendingRecordID *int   `json:"lending_record_id,omitempty"`
+}
+
+// formatCents renders an amount in cents as units with two decimals
+func formatCents(cents int) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// @Summary Get the fines ledger
+// @Description List fine charges, payments and waivers, oldest first. Admins see every borrower, or one with user_id or card_number. Regular users see only their own ledger.
+// @Tags fines
+// @Accept json
+// @Produce json
+// @Param user_id query int false "Admin only: filter by borrower account"
+// @Param card_number query string false "Admin only: filter by borrower card number"
+// @Success 200 {array} models.FineEntry
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /fines [get]
+func (h *Handler) GetFines(c *fiber.Ctx) error {
+	userID, reqErr := h.borrowerFilter(c, "You can only view your own fines")
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+
+	entries, err := h.Fines.ListFineEntries(c.UserContext(), userID)
+	if err != nil {
+		log.Printf("Error fetching fines ledger: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not retrieve fines",
+		})
+	}
+
+	return c.JSON(entries)
+}
+
+// @Summary Get a fine balance
+// @Description Get the charged, paid, waived and outstanding fine amounts of a borrower. Regular users get their own balance.
+// @Tags fines
+// @Accept json
+// @Produce json
+// @Param user_id query int false "Borrower account (admins give this or card_number)"
+// @Param card_number query string false "Borrower card number"
+// @Success 200 {object} models.FineBalance
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /fines/balance [get]
+func (h *Handler) GetFineBalance(c *fiber.Ctx) error {
+	userID, reqErr := h.borrowerFilter(c, "You can only view your own fines")
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+	if userID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "User ID or card number is required"})
+	}
+
+	balance, err := h.Fines.FineBalance(c.UserContext(), userID)
+	if err != nil {
+		log.Printf("Error fetching fine balance for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not retrieve fine balance",
+		})
+	}
+
+	return c.JSON(balance)
+}
+
+// @Summary Record a fine payment
+// @Description Record a payment against a borrower's outstanding fines, optionally for one of the borrower's loans
+// @Tags fines
+// @Accept json
+// @Produce json
+// @Param payment body FineCreditPayload true "Borrower and amount paid"
+// @Success 201 {object} models.FineEntry
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /fines/payments [post]
+func (h *Handler) RecordFinePayment(c *fiber.Ctx) error {
+	return h.creditFines(c, models.FineEntryPayment)
+}
+
+// @Summary Waive fines
+// @Description Waive part or all of a borrower's outstanding fines, optionally for one of the borrower's loans
+// @Tags fines
+// @Accept json
+// @Produce json
+// @Param waiver body FineCreditPayload true "Borrower and amount waived"
+// @Success 201 {object} models.FineEntry
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /fines/waivers [post]
+func (h *Handler) WaiveFines(c *fiber.Ctx) error {
+	return h.creditFines(c, models.FineEntryWaiver)
+}
+
+// creditFines records a payment or waiver of at most the outstanding balance
+func (h *Handler) creditFines(c *fiber.Ctx, entryType string) error {
+	payload := new(FineCreditPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if payload.AmountCents <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A positive amount is required"})
+	}
+
+	borrower, reqErr := h.resolveBorrower(c, payload.UserID, payload.CardNumber, "Only admins can record fines")
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+
+	if payload.LendingRecordID != nil {
+		record, err := h.Lending.GetLendingRecord(c.UserContext(), *payload.LendingRecordID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Lending record not found"})
+			}
+			log.Printf("Error fetching lending record %d: %v", *payload.LendingRecordID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not record " + entryType})
+		}
+		// A credit only settles the borrower's own loans
+		if record.UserID == nil || *record.UserID != borrower.ID {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Lending record belongs to another borrower"})
+		}
+	}
+
+	balance, err := h.Fines.FineBalance(c.UserContext(), borrower.ID)
+	if err != nil {
+		log.Printf("Error fetching fine balance for user %d: %v", borrower.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not record " + entryType})
+	}
+	if payload.AmountCents > balance.BalanceCents {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Amount exceeds the outstanding balance of " + formatCents(balance.BalanceCents),
+		})
+	}
+
+	entry := &models.FineEntry{
+		UserID:          &borrower.ID,
+		Borrower:        borrower.Username,
+		LendingRecordID: payload.LendingRecordID,
+		EntryType:       entryType,
+		AmountCents:     payload.AmountCents,
+		Note:            payload.Note,
+	}
+	if err := h.Fines.AddFineEntry(c.UserContext(), entry); err != nil {
+		log.Printf("Error recording fine %s for user %d: %v", entryType, borrower.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not record " + entryType})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(entry)
+}
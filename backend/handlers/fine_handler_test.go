@@ -0,0 +1,125 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"digital-library/backend/handlers"
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestFines(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	alice := s.user(t, "alice", models.RoleUser)
+
+	book := &models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Category: "SciFi", Quantity: 3}
+	if err := s.store.CreateBook(ctx, book); err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+	scifi := "scifi"
+	policy := &models.LoanPolicy{Category: &scifi, LoanDays: 7, GraceDays: 2, DailyFineCents: 50, LostFeeCents: 2000, DamagedFeeCents: 500}
+	if err := s.store.CreateLoanPolicy(ctx, policy); err != nil {
+		t.Fatalf("CreateLoanPolicy: %v", err)
+	}
+
+	// Both loans are 13 days late, 11 of them past the grace period
+	borrowed := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -20)
+	var loans []string
+	var loanIDs []int
+	for range 2 {
+		loan, err := s.store.LendBook(ctx, book.ID, "", alice, borrowed)
+		if err != nil {
+			t.Fatalf("LendBook: %v", err)
+		}
+		loans = append(loans, strconv.Itoa(loan.ID))
+		loanIDs = append(loanIDs, loan.ID)
+	}
+	adminLoan, err := s.store.LendBook(ctx, book.ID, "", admin, borrowed)
+	if err != nil {
+		t.Fatalf("LendBook: %v", err)
+	}
+
+	closeSteps := []struct {
+		name   string
+		path   string
+		body   interface{}
+		status int
+		fines  int
+	}{
+		{"unknown condition", "/api/lending/return/" + loans[0], handlers.ReturnBookPayload{Condition: "wet"}, fiber.StatusBadRequest, 0},
+		{"return damaged", "/api/lending/return/" + loans[0], handlers.ReturnBookPayload{Condition: models.ConditionDamaged}, fiber.StatusOK, 550 + 500},
+		{"report lost", "/api/lending/lost/" + loans[1], nil, fiber.StatusOK, 550 + 2000},
+		{"report lost twice", "/api/lending/lost/" + loans[1], nil, fiber.StatusConflict, 0},
+	}
+	for _, step := range closeSteps {
+		status, body := s.do(t, alice, fiber.MethodPost, step.path, step.body)
+		if status != step.status {
+			t.Fatalf("%s: POST %s = %d %s, want %d", step.name, step.path, status, body, step.status)
+		}
+		var resp struct{ Fines []models.FineEntry }
+		if err := json.Unmarshal(body, &resp); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		total := 0
+		for _, fine := range resp.Fines {
+			total += fine.AmountCents
+		}
+		if total != step.fines {
+			t.Errorf("%s: fines = %d cents, want %d", step.name, total, step.fines)
+		}
+	}
+
+	credit := func(amount int) handlers.FineCreditPayload {
+		return handlers.FineCreditPayload{CardNumber: alice.CardNumber, AmountCents: amount}
+	}
+	steps := []struct {
+		name   string
+		user   models.User
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{"blocked from borrowing", alice, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: book.ID}, fiber.StatusForbidden},
+		{"another user's balance", alice, fiber.MethodGet, "/api/fines/balance?user_id=" + strconv.Itoa(admin.ID), nil, fiber.StatusForbidden},
+		{"balance without borrower", admin, fiber.MethodGet, "/api/fines/balance", nil, fiber.StatusBadRequest},
+		{"pay as user", alice, fiber.MethodPost, "/api/fines/payments", credit(100), fiber.StatusForbidden},
+		{"pay without borrower", admin, fiber.MethodPost, "/api/fines/payments", handlers.FineCreditPayload{AmountCents: 100}, fiber.StatusBadRequest},
+		{"pay an unknown card", admin, fiber.MethodPost, "/api/fines/payments", handlers.FineCreditPayload{CardNumber: "P99999999", AmountCents: 100}, fiber.StatusNotFound},
+		{"pay nothing", admin, fiber.MethodPost, "/api/fines/payments", credit(0), fiber.StatusBadRequest},
+		{"pay too much", admin, fiber.MethodPost, "/api/fines/payments", credit(3601), fiber.StatusBadRequest},
+		{"pay for unknown loan", admin, fiber.MethodPost, "/api/fines/payments", handlers.FineCreditPayload{UserID: alice.ID, AmountCents: 100, LendingRecordID: new(int)}, fiber.StatusNotFound},
+		{"pay for another borrower's loan", admin, fiber.MethodPost, "/api/fines/payments", handlers.FineCreditPayload{UserID: alice.ID, AmountCents: 100, LendingRecordID: &adminLoan.ID}, fiber.StatusBadRequest},
+		{"pay for a loan", admin, fiber.MethodPost, "/api/fines/payments", handlers.FineCreditPayload{UserID: alice.ID, AmountCents: 1000, LendingRecordID: &loanIDs[0]}, fiber.StatusCreated},
+		{"pay", admin, fiber.MethodPost, "/api/fines/payments", credit(2000), fiber.StatusCreated},
+		{"waive the rest", admin, fiber.MethodPost, "/api/fines/waivers", credit(600), fiber.StatusCreated},
+		{"waive more", admin, fiber.MethodPost, "/api/fines/waivers", credit(1), fiber.StatusBadRequest},
+		{"borrow again", alice, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: book.ID}, fiber.StatusCreated},
+	}
+	for _, step := range steps {
+		if status, body := s.do(t, step.user, step.method, step.path, step.body); status != step.status {
+			t.Errorf("%s: %s %s = %d %s, want %d", step.name, step.method, step.path, status, body, step.status)
+		}
+	}
+
+	var balance models.FineBalance
+	if _, body := s.do(t, alice, fiber.MethodGet, "/api/fines/balance", nil); json.Unmarshal(body, &balance) != nil {
+		t.Fatalf("balance = %s", body)
+	}
+	want := models.FineBalance{UserID: alice.ID, ChargedCents: 3600, PaidCents: 3000, WaivedCents: 600}
+	if balance != want {
+		t.Errorf("balance = %+v, want %+v", balance, want)
+	}
+
+	var entries []models.FineEntry
+	if _, body := s.do(t, admin, fiber.MethodGet, "/api/fines?card_number="+alice.CardNumber, nil); json.Unmarshal(body, &entries) != nil || len(entries) != 7 {
+		t.Errorf("ledger = %s, want 4 charges, 2 payments and a waiver", body)
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"digital-library/backend/blob"
+	"digital-library/backend/marc"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Handler holds the stores used by the HTTP handlers. Each store can be
+// replaced individually, e.g. with an in-memory implementation in tests.
+type Handler struct {
+	Books     store.BookStore
+	Items     store.ItemStore
+	Files     store.FileStore
+	Lending   store.LendingStore
+	Policies  store.LoanPolicyStore
+	Holds     store.HoldStore
+	Fines     store.FineStore
+	Users     store.UserStore
+	Analytics store.AnalyticsStore
+
+	// FineBlockThreshold is the unpaid fine balance, in cents, above which
+	// a borrower cannot borrow more books
+	FineBlockThreshold int
+
+	// MARCMapping maps MARC records to books on import and export; nil
+	// uses marc.DefaultMapping
+	MARCMapping marc.Mapping
+
+	// Blobs holds the content of the e-book files of books, and
+	// MaxFileSize is the largest file it takes, in bytes; nil Blobs
+	// disables the file endpoints
+	Blobs       blob.Store
+	MaxFileSize int64
+}
+
+// New creates a Handler that uses s for every store
+func New(s store.Store) *Handler {
+	return &Handler{
+		Books:     s,
+		Items:     s,
+		Files:     s,
+		Lending:   s,
+		Policies:  s,
+		Holds:     s,
+		Fines:     s,
+		Users:     s,
+		Analytics: s,
+	}
+}
+
+// marcMapping returns the MARC mapping in use
+func (h *Handler) marcMapping() marc.Mapping {
+	if h.MARCMapping == nil {
+		return marc.DefaultMapping
+	}
+	return h.MARCMapping
+}
+
+// paramID parses a positive integer route parameter
+func paramID(c *fiber.Ctx, name string) (int, bool) {
+	id, err := strconv.Atoi(c.Params(name))
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// queryDate parses an optional YYYY-MM-DD query parameter
+func queryDate(c *fiber.Ctx, name string) (*time.Time, *requestError) {
+	value := c.Query(name, "")
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, &requestError{fiber.StatusBadRequest, "Invalid " + name + ", expected YYYY-MM-DD"}
+	}
+	return &date, nil
+}
+
+// Page sizes of listings when ?limit= is missing, and the most it may ask for
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// queryPage parses the ?limit=, ?cursor=, ?sort= and ?order= query parameters
+// of a listing
+func queryPage(c *fiber.Ctx) (store.PageRequest, *requestError) {
+	page := store.PageRequest{
+		Limit:  defaultPageSize,
+		Cursor: c.Query("cursor", ""),
+		Sort:   c.Query("sort", ""),
+		Order:  strings.ToLower(c.Query("order", "")),
+	}
+	if value := c.Query("limit", ""); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxPageSize {
+			return page, &requestError{fiber.StatusBadRequest, "Invalid limit, expected 1 to " + strconv.Itoa(maxPageSize)}
+		}
+		page.Limit = limit
+	}
+	return page, nil
+}
+
+// querySort parses the ?sort= and ?order= query parameters of an export,
+// which lists every page of a listing in its order
+func querySort(c *fiber.Ctx, sortFields []string) (store.PageRequest, *requestError) {
+	page := store.PageRequest{
+		Sort:  c.Query("sort", ""),
+		Order: strings.ToLower(c.Query("order", "")),
+	}
+	if page.Sort != "" && !slices.Contains(sortFields, page.Sort) ||
+		page.Order != "" && page.Order != "asc" && page.Order != "desc" {
+		return page, pageError(store.ErrInvalidSort, sortFields)
+	}
+	return page, nil
+}
+
+// pageError converts the paging errors of a listing into client errors; it
+// returns nil for any other error
+func pageError(err error, sortFields []string) *requestError {
+	switch {
+	case errors.Is(err, store.ErrInvalidSort):
+		return &requestError{fiber.StatusBadRequest, "Invalid sort, expected one of " + strings.Join(sortFields, ", ") + " with order asc or desc"}
+	case errors.Is(err, store.ErrInvalidCursor):
+		return &requestError{fiber.StatusBadRequest, "Invalid cursor"}
+	}
+	return nil
+}
+
+// requestError is a client error found by a helper that the calling handler
+// still has to write to the response
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) send(c *fiber.Ctx) error {
+	return c.Status(e.status).JSON(fiber.Map{"error": e.message})
+}
@@ -0,0 +1,126 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"digital-library/backend/config"
+	"digital-library/backend/handlers"
+	"digital-library/backend/models"
+	"digital-library/backend/routes"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testServer serves the API routes from an in-memory store
+type testServer struct {
+	app     *fiber.App
+	cfg     *config.Config
+	store   *store.Memory
+	handler *handlers.Handler
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	s := &testServer{
+		app:   fiber.New(),
+		cfg:   &config.Config{JWTSecret: "test-secret", FeedTokenTTL: time.Hour},
+		store: store.NewMemory(),
+	}
+	s.handler = handlers.New(s.store)
+	routes.SetupRoutes(s.app, s.cfg, s.handler)
+	return s
+}
+
+// user creates an account with the given role
+func (s *testServer) user(t *testing.T, username, role string) models.User {
+	t.Helper()
+	user, err := s.store.CreateUser(context.Background(), username, "", username+"@example.com", role)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return user
+}
+
+// book creates a book with the given number of copies
+func (s *testServer) book(t *testing.T, isbn string, quantity int) models.Book {
+	t.Helper()
+	book := &models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: isbn, Quantity: quantity}
+	if err := s.store.CreateBook(context.Background(), book); err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+	return *book
+}
+
+// lend lends a copy of the book to the borrower as the user
+func (s *testServer) lend(t *testing.T, user models.User, bookID int, borrower models.User) models.LendingRecord {
+	t.Helper()
+	status, body := s.do(t, user, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: bookID, UserID: borrower.ID})
+	var loan models.LendingRecord
+	if err := json.Unmarshal(body, &loan); status != fiber.StatusCreated || err != nil {
+		t.Fatalf("lend = %d %s", status, body)
+	}
+	return loan
+}
+
+// token signs a login JWT for the user, as handlers.Login does
+func (s *testServer) token(t *testing.T, user models.User) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":  user.ID,
+		"username": user.Username,
+		"role":     user.Role,
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	})
+	signed, err := token.SignedString([]byte(s.cfg.JWTSecret))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+// do sends a request as the user, with body encoded as JSON unless nil, and
+// returns the status and body of the response
+func (s *testServer) do(t *testing.T, user models.User, method, path string, body interface{}) (int, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	return s.send(t, user, req)
+}
+
+// send sends a request as the user, or without credentials for the zero
+// user, and returns the status and body of the response
+func (s *testServer) send(t *testing.T, user models.User, req *http.Request) (int, []byte) {
+	t.Helper()
+	if user.ID != 0 {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+s.token(t, user))
+	}
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", req.Method, req.URL, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: %v", req.Method, req.URL, err)
+	}
+	return resp.StatusCode, data
+}
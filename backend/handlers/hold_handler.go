@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+
+	"digital-library/backend/middleware"
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// PlaceHoldPayload defines the expected structure for the place hold request
+type PlaceHoldPayload struct {
+	BookID     int    `json:"book_id"`
+	UserID     int    `json:"user_id"`     // Borrower's account; defaults to the caller
+	CardNumber string `json:"card_number"` // Alternatively, the borrower's patron card number
+}
+
+// @Summary Place a hold
+// @Description Join the queue for a book whose copies are all lent or set aside for other holds. When a copy comes back it is reserved for the oldest hold, which becomes ready for pickup until the pickup window of the loan policy ends.
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Param hold body PlaceHoldPayload true "Book and borrower"
+// @Success 201 {object} models.Hold
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /holds [post]
+func (h *Handler) PlaceHold(c *fiber.Ctx) error {
+	payload := new(PlaceHoldPayload)
+	if err := c.BodyParser(payload); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+	}
+	if payload.BookID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Book ID is required"})
+	}
+
+	// Regular users may only place holds for themselves
+	borrower, reqErr := h.resolveBorrower(c, payload.UserID, payload.CardNumber, "You can only place holds for yourself")
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+
+	hold, err := h.Holds.PlaceHold(c.UserContext(), payload.BookID, borrower)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Book not found"})
+		case errors.Is(err, store.ErrBookAvailable):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Book has copies available to borrow now"})
+		case errors.Is(err, store.ErrDuplicateHold):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Borrower already has a hold on this book"})
+		}
+		log.Printf("Error placing hold on book %d: %v", payload.BookID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not place hold"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(hold)
+}
+
+// @Summary Get holds
+// @Description Get holds oldest first, with the queue position of waiting holds. Admins see every borrower; regular users see only their own holds.
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Param book_id query int false "Filter by book ID"
+// @Param user_id query int false "Admin only: filter by borrower account"
+// @Param card_number query string false "Admin only: filter by borrower card number"
+// @Param status query string false "Filter by status (waiting/ready/fulfilled/cancelled/expired), or open for waiting and ready holds"
+// @Success 200 {array} models.HoldDetail
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /holds [get]
+func (h *Handler) GetHolds(c *fiber.Ctx) error {
+	filter := store.HoldFilter{
+		Status: c.Query("status", ""),
+	}
+
+	if bookID := c.Query("book_id", ""); bookID != "" {
+		id, err := strconv.Atoi(bookID)
+		if err != nil || id <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid book ID"})
+		}
+		filter.BookID = id
+	}
+
+	switch filter.Status {
+	case "", "open", models.HoldStatusWaiting, models.HoldStatusReady, models.HoldStatusFulfilled,
+		models.HoldStatusCancelled, models.HoldStatusExpired:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid hold status"})
+	}
+
+	// Regular users only see their own holds
+	var reqErr *requestError
+	if filter.UserID, reqErr = h.borrowerFilter(c, "You can only view your own holds"); reqErr != nil {
+		return reqErr.send(c)
+	}
+
+	holds, err := h.Holds.ListHolds(c.UserContext(), filter)
+	if err != nil {
+		log.Printf("Error fetching holds: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not retrieve holds",
+		})
+	}
+
+	return c.JSON(holds)
+}
+
+// @Summary Cancel a hold
+// @Description Cancel a waiting or ready hold. A copy set aside for the hold goes to the next borrower in the queue.
+// @Tags holds
+// @Accept json
+// @Produce json
+// @Param id path int true "Hold ID"
+// @Success 200 {object} models.Hold
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /holds/cancel/{id} [post]
+func (h *Handler) CancelHold(c *fiber.Ctx) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid hold ID"})
+	}
+
+	// Regular users may only cancel their own holds
+	user, _ := middleware.CurrentUser(c)
+	if user.Role != models.RoleAdmin {
+		hold, err := h.Holds.GetHold(c.UserContext(), id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Hold not found"})
+			}
+			log.Printf("Error fetching hold %d: %v", id, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve hold"})
+		}
+		if hold.UserID == nil || *hold.UserID != user.UserID {
+			return middleware.Forbidden(c, "You can only cancel your own holds")
+		}
+	}
+
+	hold, err := h.Holds.CancelHold(c.UserContext(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Hold not found"})
+		case errors.Is(err, store.ErrHoldClosed):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Hold is no longer open"})
+		}
+		log.Printf("Error cancelling hold %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not cancel hold"})
+	}
+
+	return c.JSON(hold)
+}
@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"digital-library/backend/handlers"
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestHolds(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	alice := s.user(t, "alice", models.RoleUser)
+	bob := s.user(t, "bob", models.RoleUser)
+	carol := s.user(t, "carol", models.RoleUser)
+	dune := s.book(t, "9780441172719", 1)
+	emma := s.book(t, "9780141439587", 1)
+
+	loan := s.lend(t, alice, dune.ID, alice)
+	hold := func(user, borrower models.User) models.Hold {
+		t.Helper()
+		status, body := s.do(t, user, fiber.MethodPost, "/api/holds", handlers.PlaceHoldPayload{BookID: dune.ID, UserID: borrower.ID})
+		var hold models.Hold
+		if err := json.Unmarshal(body, &hold); status != fiber.StatusCreated || err != nil {
+			t.Fatalf("hold for %s = %d %s", borrower.Username, status, body)
+		}
+		return hold
+	}
+	bobs := hold(bob, bob)
+	carols := hold(admin, carol)
+
+	steps := []struct {
+		name   string
+		user   models.User
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{"without a book", bob, fiber.MethodPost, "/api/holds", handlers.PlaceHoldPayload{}, fiber.StatusBadRequest},
+		{"unknown book", bob, fiber.MethodPost, "/api/holds", handlers.PlaceHoldPayload{BookID: 9999}, fiber.StatusNotFound},
+		{"available book", bob, fiber.MethodPost, "/api/holds", handlers.PlaceHoldPayload{BookID: emma.ID}, fiber.StatusConflict},
+		{"twice", bob, fiber.MethodPost, "/api/holds", handlers.PlaceHoldPayload{BookID: dune.ID, CardNumber: bob.CardNumber}, fiber.StatusConflict},
+		{"for someone else", bob, fiber.MethodPost, "/api/holds", handlers.PlaceHoldPayload{BookID: dune.ID, UserID: alice.ID}, fiber.StatusForbidden},
+		{"list someone else's", bob, fiber.MethodGet, "/api/holds?user_id=" + strconv.Itoa(carol.ID), nil, fiber.StatusForbidden},
+		{"invalid status", admin, fiber.MethodGet, "/api/holds?status=lost", nil, fiber.StatusBadRequest},
+		{"cancel someone else's", bob, fiber.MethodPost, "/api/holds/cancel/" + strconv.Itoa(carols.ID), nil, fiber.StatusForbidden},
+		{"cancel unknown", admin, fiber.MethodPost, "/api/holds/cancel/9999", nil, fiber.StatusNotFound},
+		{"return to the queue", alice, fiber.MethodPost, "/api/lending/return/" + strconv.Itoa(loan.ID), nil, fiber.StatusOK},
+		{"copy held for bob", carol, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: dune.ID}, fiber.StatusConflict},
+		{"hold while the copy is reserved", alice, fiber.MethodPost, "/api/holds", handlers.PlaceHoldPayload{BookID: dune.ID}, fiber.StatusCreated},
+		{"bob picks up", bob, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: dune.ID}, fiber.StatusCreated},
+		{"cancel fulfilled", bob, fiber.MethodPost, "/api/holds/cancel/" + strconv.Itoa(bobs.ID), nil, fiber.StatusConflict},
+		{"cancel own", carol, fiber.MethodPost, "/api/holds/cancel/" + strconv.Itoa(carols.ID), nil, fiber.StatusOK},
+	}
+	for _, step := range steps {
+		if status, body := s.do(t, step.user, step.method, step.path, step.body); status != step.status {
+			t.Errorf("%s: %s %s = %d %s, want %d", step.name, step.method, step.path, status, body, step.status)
+		}
+	}
+
+	tests := []struct {
+		user  models.User
+		query string
+		want  []string // borrower:status:position
+	}{
+		{admin, "", []string{"bob:fulfilled:0", "carol:cancelled:0", "alice:waiting:1"}},
+		{admin, "?status=open", []string{"alice:waiting:1"}},
+		{admin, "?card_number=" + carol.CardNumber, []string{"carol:cancelled:0"}},
+		{bob, "", []string{"bob:fulfilled:0"}},
+		{admin, "?book_id=" + strconv.Itoa(emma.ID), nil},
+	}
+	for _, tt := range tests {
+		status, body := s.do(t, tt.user, fiber.MethodGet, "/api/holds"+tt.query, nil)
+		var holds []models.HoldDetail
+		if err := json.Unmarshal(body, &holds); status != fiber.StatusOK || err != nil {
+			t.Fatalf("GET /api/holds%s = %d %s", tt.query, status, body)
+		}
+		var got []string
+		for _, hold := range holds {
+			position := 0
+			if hold.Position != nil {
+				position = *hold.Position
+			}
+			got = append(got, hold.Borrower+":"+hold.Status+":"+strconv.Itoa(position))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s holds%s = %v, want %v", tt.user.Username, tt.query, got, tt.want)
+		}
+	}
+}
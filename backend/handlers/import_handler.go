@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Rows of an import saved per transaction
+const importBatchSize = 500
+
+// Book fields an import reads, by their default column header
+const (
+	importTitle     = "title"
+	importAuthor    = "author"
+	importISBN      = "isbn"
+	importCategory  = "category"
+	importQuantity  = "quantity"
+	importPublisher = "publisher"
+	importYear      = "year"
+)
+
+var importFields = []string{importTitle, importAuthor, importISBN, importCategory, importQuantity, importPublisher, importYear}
+
+// ImportBooks godoc
+// @Summary Import books from CSV
+// @Description Create or update books from a CSV file, matching existing books by ISBN. The file is the request body or the file field of a multipart form, and is read as it streams in. The first record is the header; columns named title, author, isbn, category, quantity, publisher and year are read, ignoring case, unless ?columns= maps the fields to other headers. Title, author and ISBN are required. New books get quantity copies (default 0, at most 1000); existing books take the title, author and a non-empty category, publisher and year, and keep their copies. Rows are saved in transactions of 500. The report gives the outcome of every row; a dry run validates and reports every row without saving anything.
+// @Tags books
+// @Accept text/csv
+// @Accept mpfd
+// @Produce json
+// @Param file formData file false "CSV file, when sent as a multipart form"
+// @Param columns query string false "Headers of the fields, e.g. title:Book Title,isbn:ISBN-13"
+// @Param delimiter query string false "Field delimiter: a single character or tab (default ,)"
+// @Param dry_run query bool false "Validate and report without saving"
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /books/import [post]
+func (h *Handler) ImportBooks(c *fiber.Ctx) error {
+	dryRun := c.Query("dry_run", "") == "true"
+	headers, reqErr := importHeaders(c.Query("columns", ""))
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+	delimiter, reqErr := importDelimiter(c.Query("delimiter", ""))
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+
+	body, closeBody, reqErr := importBody(c)
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+	defer closeBody()
+
+	records := csv.NewReader(body)
+	records.Comma = delimiter
+	records.FieldsPerRecord = -1
+	records.ReuseRecord = true
+
+	header, err := records.Read()
+	if err == io.EOF {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "CSV file is empty",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid CSV header: " + err.Error(),
+		})
+	}
+	columns, reqErr := importColumns(header, headers)
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+
+	imp := h.newBookImport(c, dryRun)
+	for {
+		record, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			imp.fail(parseErr.StartLine, "", "Invalid CSV: "+parseErr.Err.Error())
+			continue
+		}
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Could not read CSV: " + err.Error(),
+			})
+		}
+
+		line, _ := records.FieldPos(0)
+		book, reason := importBook(record, columns)
+		if reason != "" {
+			imp.fail(line, book.ISBN, reason)
+			continue
+		}
+		imp.add(line, book)
+	}
+	return c.JSON(imp.finish())
+}
+
+// bookImport saves the valid rows of an import in batches and collects the
+// outcome of every row
+type bookImport struct {
+	h      *Handler
+	c      *fiber.Ctx
+	report models.ImportReport
+
+	// Valid rows waiting to be saved, and their place in report.Rows
+	batch     []models.Book
+	batchRows []int
+
+	// What the batches of a dry run would have saved; nil for an import
+	dryRun *store.ImportDryRun
+}
+
+func (h *Handler) newBookImport(c *fiber.Ctx, dryRun bool) *bookImport {
+	imp := &bookImport{h: h, c: c, report: models.ImportReport{DryRun: dryRun, Rows: []models.ImportRow{}}}
+	if dryRun {
+		imp.dryRun = &store.ImportDryRun{}
+	}
+	return imp
+}
+
+// fail reports an invalid row
+func (imp *bookImport) fail(row int, isbn, reason string) {
+	imp.report.Rows = append(imp.report.Rows, models.ImportRow{Row: row, ISBN: isbn, Status: models.ImportError, Reason: reason})
+}
+
+// add queues a valid row, saving the batch once it is full
+func (imp *bookImport) add(row int, book models.Book) {
+	imp.report.Rows = append(imp.report.Rows, models.ImportRow{Row: row, ISBN: book.ISBN})
+	imp.batch = append(imp.batch, book)
+	imp.batchRows = append(imp.batchRows, len(imp.report.Rows)-1)
+	if len(imp.batch) == importBatchSize {
+		imp.flush()
+	}
+}
+
+// flush saves the queued rows in one transaction
+func (imp *bookImport) flush() {
+	if len(imp.batch) == 0 {
+		return
+	}
+	results, err := imp.h.Books.ImportBooks(imp.c.UserContext(), imp.batch, imp.dryRun)
+	if err != nil {
+		log.Printf("Error importing books: %v", err)
+	}
+	for i, row := range imp.batchRows {
+		if err != nil {
+			imp.report.Rows[row].Status = models.ImportError
+			imp.report.Rows[row].Reason = "Could not save the batch of this row"
+			continue
+		}
+		results[i].Row = imp.report.Rows[row].Row
+		imp.report.Rows[row] = results[i]
+	}
+	imp.batch, imp.batchRows = imp.batch[:0], imp.batchRows[:0]
+}
+
+// finish saves the last batch and counts the outcomes
+func (imp *bookImport) finish() models.ImportReport {
+	imp.flush()
+	for _, row := range imp.report.Rows {
+		switch row.Status {
+		case models.ImportCreated:
+			imp.report.Created++
+		case models.ImportUpdated:
+			imp.report.Updated++
+		case models.ImportSkipped:
+			imp.report.Skipped++
+		default:
+			imp.report.Errors++
+		}
+	}
+	return imp.report
+}
+
+// importHeaders parses ?columns=, a comma separated list of field:header
+// pairs, into the header of each field
+func importHeaders(value string) (map[string]string, *requestError) {
+	headers := make(map[string]string, len(importFields))
+	for _, field := range importFields {
+		headers[field] = field
+	}
+	if value == "" {
+		return headers, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		field, header, ok := strings.Cut(pair, ":")
+		field = strings.ToLower(strings.TrimSpace(field))
+		if _, known := headers[field]; !ok || !known || strings.TrimSpace(header) == "" {
+			return nil, &requestError{fiber.StatusBadRequest, "Invalid columns, expected field:header pairs with fields " + strings.Join(importFields, ", ")}
+		}
+		headers[field] = strings.TrimSpace(header)
+	}
+	return headers, nil
+}
+
+// importDelimiter parses ?delimiter=
+func importDelimiter(value string) (rune, *requestError) {
+	switch value {
+	case "":
+		return ',', nil
+	case "tab":
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(value)
+	if size != len(value) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, &requestError{fiber.StatusBadRequest, "Invalid delimiter, expected a single character or tab"}
+	}
+	return r, nil
+}
+
+// importBody returns the CSV of an import: the file field of a multipart
+// form, or else the request body, streamed when it is too large to buffer
+func importBody(c *fiber.Ctx) (io.Reader, func(), *requestError) {
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, nil, &requestError{fiber.StatusBadRequest, "Missing CSV file in the file field"}
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, nil, &requestError{fiber.StatusBadRequest, "Could not read the uploaded file"}
+		}
+		return file, func() { file.Close() }, nil
+	}
+	if stream := c.Context().RequestBodyStream(); stream != nil {
+		return stream, func() {}, nil
+	}
+	return bytes.NewReader(c.Body()), func() {}, nil
+}
+
+// importColumns finds the column of each field in the header row. Fields
+// without a column are left out; title, author and isbn are required.
+func importColumns(header []string, headers map[string]string) (map[string]int, *requestError) {
+	columns := make(map[string]int, len(importFields))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // Byte order mark
+		}
+		for _, field := range importFields {
+			if _, found := columns[field]; !found && strings.EqualFold(strings.TrimSpace(name), headers[field]) {
+				columns[field] = i
+			}
+		}
+	}
+	for _, field := range []string{importTitle, importAuthor, importISBN} {
+		if _, found := columns[field]; !found {
+			return nil, &requestError{fiber.StatusBadRequest, "Missing column for " + field + ", expected a header named \"" + headers[field] + "\""}
+		}
+	}
+	return columns, nil
+}
+
+// importBook reads a book from a record, or returns why the record is invalid
+func importBook(record []string, columns map[string]int) (models.Book, string) {
+	value := func(field string) string {
+		i, found := columns[field]
+		if !found || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	book := models.Book{
+		Title:     value(importTitle),
+		Author:    value(importAuthor),
+		ISBN:      value(importISBN),
+		Category:  value(importCategory),
+		Publisher: value(importPublisher),
+	}
+	if book.Title == "" || book.Author == "" || book.ISBN == "" {
+		return book, "Title, author and ISBN are required"
+	}
+	canonical, reqErr := canonicalISBN(book.ISBN)
+	if reqErr != nil {
+		return book, reqErr.message
+	}
+	book.ISBN = canonical
+	if quantity := value(importQuantity); quantity != "" {
+		n, err := strconv.Atoi(quantity)
+		if err != nil || n < 0 || n > maxNewCopies {
+			return book, "Invalid quantity, expected a whole number from 0 to " + strconv.Itoa(maxNewCopies)
+		}
+		book.Quantity = n
+	}
+	if year := value(importYear); year != "" {
+		n, err := strconv.Atoi(year)
+		if err != nil || checkYear(&n) != nil {
+			return book, "Invalid year, expected a whole number from 1 to 9999"
+		}
+		book.Year = &n
+	}
+	return book, ""
+}
@@ -0,0 +1,192 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"digital-library/backend/isbn"
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// importRows mixes new, existing, repeated and invalid books, with the
+// fields under other headers and separated by semicolons
+const importRows = `Book Title;Writer;ISBN;Copies
+Dune;Frank Herbert;0-441-17271-7;
+Emma;Jane Austen;9780141439587;2
+Emma, Revised;Jane Austen;9780141439587;1
+Persuasion;;9780141439686;1
+Persuasion;Jane Austen;9780141439686;many
+Persuasion;Jane Austen;9780141439687;1
+Dune Messiah;Frank Herbert;9780441172696;1
+Sense and Sensibility;Jane Austen;9780141439662;5000
+`
+
+func TestImportBooks(t *testing.T) {
+	const query = "?delimiter=;&columns=title:Book%20Title,author:Writer,quantity:Copies"
+	want := []struct {
+		row    int
+		status string
+	}{
+		{2, models.ImportSkipped},
+		{3, models.ImportCreated},
+		{4, models.ImportUpdated},
+		{5, models.ImportError},
+		{6, models.ImportError},
+		{7, models.ImportError},
+		{8, models.ImportCreated},
+		{9, models.ImportError},
+	}
+
+	tests := []struct {
+		name   string
+		dryRun bool
+		books  int // Books in the store after the import
+	}{
+		{"dry run", true, 1},
+		{"import", false, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			admin := s.user(t, "admin", models.RoleAdmin)
+			s.book(t, "9780441172719", 1)
+
+			path := "/api/books/import" + query
+			if tt.dryRun {
+				path += "&dry_run=true"
+			}
+			req := httptest.NewRequest(fiber.MethodPost, path, strings.NewReader(importRows))
+			req.Header.Set(fiber.HeaderContentType, "text/csv")
+			status, body := s.send(t, admin, req)
+			var report models.ImportReport
+			if err := json.Unmarshal(body, &report); status != fiber.StatusOK || err != nil {
+				t.Fatalf("import = %d %s", status, body)
+			}
+			if report.DryRun != tt.dryRun || report.Created != 2 || report.Updated != 1 || report.Skipped != 1 || report.Errors != 4 {
+				t.Errorf("report = %+v, want 2 created, 1 updated, 1 skipped and 4 errors", report)
+			}
+			if len(report.Rows) != len(want) {
+				t.Fatalf("%d rows reported, want %d", len(report.Rows), len(want))
+			}
+			for i, row := range report.Rows {
+				if row.Row != want[i].row || row.Status != want[i].status {
+					t.Errorf("row %d = %+v, want row %d %s", i, row, want[i].row, want[i].status)
+				}
+			}
+
+			books, err := s.store.ListBooks(context.Background(), store.BookFilter{}, store.PageRequest{Sort: "title"})
+			if err != nil {
+				t.Fatalf("ListBooks: %v", err)
+			}
+			if books.Total != tt.books {
+				t.Errorf("%d books after the import, want %d", books.Total, tt.books)
+			}
+			if !tt.dryRun {
+				var titles []string
+				for _, book := range books.Data {
+					titles = append(titles, book.Title+"/"+book.ISBN)
+				}
+				wantTitles := []string{"Dune/9780441172719", "Dune Messiah/9780441172696", "Emma, Revised/9780141439587"}
+				if !reflect.DeepEqual(titles, wantTitles) {
+					t.Errorf("books = %v, want %v", titles, wantTitles)
+				}
+			}
+		})
+	}
+}
+
+func TestImportBooksRequests(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	alice := s.user(t, "alice", models.RoleUser)
+
+	tests := []struct {
+		name   string
+		user   models.User
+		query  string
+		csv    string
+		status int
+	}{
+		{"as a regular user", alice, "", "title,author,isbn\n", fiber.StatusForbidden},
+		{"empty file", admin, "", "", fiber.StatusBadRequest},
+		{"missing column", admin, "", "title,isbn\nDune,9780441172719\n", fiber.StatusBadRequest},
+		{"unknown field", admin, "?columns=pages:Pages", "title,author,isbn\n", fiber.StatusBadRequest},
+		{"long delimiter", admin, "?delimiter=;;", "title,author,isbn\n", fiber.StatusBadRequest},
+		{"tab delimited", admin, "?delimiter=tab", "\ufefftitle\tauthor\tisbn\nDune\tFrank Herbert\t9780441172719\n", fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(fiber.MethodPost, "/api/books/import"+tt.query, strings.NewReader(tt.csv))
+		req.Header.Set(fiber.HeaderContentType, "text/csv")
+		if status, body := s.send(t, tt.user, req); status != tt.status {
+			t.Errorf("%s: import = %d %s, want %d", tt.name, status, body, tt.status)
+		}
+	}
+}
+
+// importCSV builds an import of n rows with distinct ISBNs, except that the
+// last row repeats the ISBN of the first with another title
+func importCSV(n int) string {
+	var b strings.Builder
+	b.WriteString("title,author,isbn,quantity\n")
+	for i := 0; i < n-1; i++ {
+		fmt.Fprintf(&b, "Book %d,Author,%s,1\n", i, importISBN(i))
+	}
+	fmt.Fprintf(&b, "Book 0 revised,Author,%s,1\n", importISBN(0))
+	return b.String()
+}
+
+// importISBN returns a valid ISBN-13 numbered i
+func importISBN(i int) string {
+	return isbn.To13(fmt.Sprintf("%09d0", i))
+}
+
+func TestImportBooksAcrossBatches(t *testing.T) {
+	// More rows than one batch, so the repeated ISBN is in another batch
+	const rows = 600
+	tests := []struct {
+		name    string
+		query   string
+		want    models.ImportReport
+		created int // Books in the store after the import
+	}{
+		{"dry run", "?dry_run=true", models.ImportReport{DryRun: true, Created: rows - 1, Updated: 1}, 0},
+		{"import", "", models.ImportReport{Created: rows - 1, Updated: 1}, rows - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			admin := s.user(t, "admin", models.RoleAdmin)
+
+			req := httptest.NewRequest(fiber.MethodPost, "/api/books/import"+tt.query, strings.NewReader(importCSV(rows)))
+			req.Header.Set(fiber.HeaderContentType, "text/csv")
+			status, body := s.send(t, admin, req)
+			if status != fiber.StatusOK {
+				t.Fatalf("import = %d %s", status, body)
+			}
+			var report models.ImportReport
+			if err := json.Unmarshal(body, &report); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if report.DryRun != tt.want.DryRun || report.Created != tt.want.Created || report.Updated != tt.want.Updated ||
+				report.Skipped != 0 || report.Errors != 0 || len(report.Rows) != rows {
+				t.Errorf("report = %+v rows %d, want %+v", report, len(report.Rows), tt.want)
+			}
+
+			books, err := s.store.ListBooks(context.Background(), store.BookFilter{}, store.PageRequest{})
+			if err != nil {
+				t.Fatalf("ListBooks: %v", err)
+			}
+			if books.Total != tt.created {
+				t.Errorf("%d books after the import, want %d", books.Total, tt.created)
+			}
+		})
+	}
+}
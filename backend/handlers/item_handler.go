@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AddItemPayload defines the expected structure for the add copy request
+type AddItemPayload struct {
+	Barcode      string `json:"barcode"`       // Generated when empty
+	Condition    string `json:"condition"`     // good (default) or damaged
+	Location     string `json:"location"`      // Shelf or branch
+	AcquiredDate string `json:"acquired_date"` // YYYY-MM-DD, defaults to today
+}
+
+// @Summary Get the copies of a book
+// @Description List every physical copy of a book with its barcode, status, condition and location, retired copies included
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param id path int true "Book ID"
+// @Success 200 {array} models.BookItem
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /books/{id}/items [get]
+func (h *Handler) GetBookItems(c *fiber.Ctx) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid book ID"})
+	}
+
+	items, err := h.Items.ListItems(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Book not found"})
+		}
+		log.Printf("Error fetching copies of book %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve copies"})
+	}
+
+	return c.JSON(items)
+}
+
+// @Summary Add a copy of a book
+// @Description Add an available physical copy to a book, raising its quantity. The copy goes to the oldest waiting hold first.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param item body AddItemPayload false "Copy details"
+// @Success 201 {object} models.BookItem
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /books/{id}/items [post]
+func (h *Handler) AddBookItem(c *fiber.Ctx) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid book ID"})
+	}
+
+	payload := new(AddItemPayload)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(payload); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+	}
+
+	item := &models.BookItem{
+		BookID:       id,
+		Barcode:      strings.TrimSpace(payload.Barcode),
+		Condition:    payload.Condition,
+		Location:     strings.TrimSpace(payload.Location),
+		AcquiredDate: time.Now().UTC().Truncate(24 * time.Hour), // Use UTC date part only
+	}
+	switch item.Condition {
+	case "":
+		item.Condition = models.ConditionGood
+	case models.ConditionGood, models.ConditionDamaged:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Condition must be good or damaged"})
+	}
+	if payload.AcquiredDate != "" {
+		date, err := time.Parse("2006-01-02", payload.AcquiredDate)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid acquired_date, expected YYYY-MM-DD"})
+		}
+		item.AcquiredDate = date
+	}
+
+	if err := h.Items.AddItem(c.UserContext(), item); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Book not found"})
+		case errors.Is(err, store.ErrDuplicateBarcode):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "A copy with this barcode already exists"})
+		}
+		log.Printf("Error adding a copy of book %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not add copy"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(item)
+}
+
+// @Summary Retire a copy of a book
+// @Description Withdraw an available or lost copy from the collection. Copies on loan or set aside for a ready hold cannot be retired.
+// @Tags books
+// @Accept json
+// @Produce json
+// @Param id path int true "Book ID"
+// @Param itemId path int true "Copy ID"
+// @Success 200 {object} models.BookItem
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /books/{id}/items/retire/{itemId} [post]
+func (h *Handler) RetireBookItem(c *fiber.Ctx) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid book ID"})
+	}
+	itemID, ok := paramID(c, "itemId")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid copy ID"})
+	}
+
+	item, err := h.Items.RetireItem(c.UserContext(), id, itemID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Book not found"})
+		case errors.Is(err, store.ErrItemNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Copy not found"})
+		case errors.Is(err, store.ErrItemOnLoan):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Copy is on loan and must be returned first"})
+		case errors.Is(err, store.ErrItemReserved):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Copy is set aside for a hold"})
+		case errors.Is(err, store.ErrItemRetired):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Copy is already retired"})
+		}
+		log.Printf("Error retiring copy %d of book %d: %v", itemID, id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retire copy"})
+	}
+
+	return c.JSON(item)
+}
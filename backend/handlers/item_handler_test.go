@@ -0,0 +1,89 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"digital-library/backend/handlers"
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBookItems(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	alice := s.user(t, "alice", models.RoleUser)
+	book := s.book(t, "9780441172719", 1)
+	items := "/api/books/" + strconv.Itoa(book.ID) + "/items"
+
+	status, body := s.do(t, admin, fiber.MethodPost, items, handlers.AddItemPayload{Barcode: " DUNE-2 ", Location: "Shelf A"})
+	var added models.BookItem
+	if err := json.Unmarshal(body, &added); status != fiber.StatusCreated || err != nil {
+		t.Fatalf("add copy = %d %s", status, body)
+	}
+	if added.Barcode != "DUNE-2" || added.Status != models.ItemStatusAvailable || added.Condition != models.ConditionGood {
+		t.Fatalf("added copy = %+v, want an available copy in good condition with barcode DUNE-2", added)
+	}
+	retire := items + "/retire/" + strconv.Itoa(added.ID)
+
+	steps := []struct {
+		name   string
+		user   models.User
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{"add as user", alice, fiber.MethodPost, items, nil, fiber.StatusForbidden},
+		{"add a duplicate barcode", admin, fiber.MethodPost, items, handlers.AddItemPayload{Barcode: "DUNE-2"}, fiber.StatusConflict},
+		{"add in an unknown condition", admin, fiber.MethodPost, items, handlers.AddItemPayload{Condition: "wet"}, fiber.StatusBadRequest},
+		{"add with an invalid date", admin, fiber.MethodPost, items, handlers.AddItemPayload{AcquiredDate: "yesterday"}, fiber.StatusBadRequest},
+		{"add to an unknown book", admin, fiber.MethodPost, "/api/books/9999/items", nil, fiber.StatusNotFound},
+		{"list an unknown book", alice, fiber.MethodGet, "/api/books/9999/items", nil, fiber.StatusNotFound},
+		{"lend an unknown barcode", alice, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: book.ID, Barcode: "EMMA-1"}, fiber.StatusNotFound},
+		{"lend by barcode", alice, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: book.ID, Barcode: "DUNE-2"}, fiber.StatusCreated},
+		{"lend a copy on loan", admin, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: book.ID, Barcode: "DUNE-2", UserID: admin.ID}, fiber.StatusConflict},
+		{"retire as user", alice, fiber.MethodPost, retire, nil, fiber.StatusForbidden},
+		{"retire a copy on loan", admin, fiber.MethodPost, retire, nil, fiber.StatusConflict},
+		{"retire an unknown copy", admin, fiber.MethodPost, items + "/retire/9999", nil, fiber.StatusNotFound},
+	}
+	for _, step := range steps {
+		if status, body := s.do(t, step.user, step.method, step.path, step.body); status != step.status {
+			t.Errorf("%s: %s %s = %d %s, want %d", step.name, step.method, step.path, status, body, step.status)
+		}
+	}
+
+	// The generated copy is still on the shelf
+	var copies []models.BookItem
+	if _, body := s.do(t, alice, fiber.MethodGet, items, nil); json.Unmarshal(body, &copies) != nil || len(copies) != 2 {
+		t.Fatalf("copies = %s, want 2", body)
+	}
+	var shelved models.BookItem
+	for _, item := range copies {
+		if item.ID != added.ID {
+			shelved = item
+		}
+	}
+	if shelved.Barcode == "" || shelved.Status != models.ItemStatusAvailable {
+		t.Fatalf("generated copy = %+v, want an available copy with a barcode", shelved)
+	}
+
+	status, body = s.do(t, admin, fiber.MethodPost, items+"/retire/"+strconv.Itoa(shelved.ID), nil)
+	if status != fiber.StatusOK {
+		t.Fatalf("retire = %d %s", status, body)
+	}
+	if status, body := s.do(t, admin, fiber.MethodPost, items+"/retire/"+strconv.Itoa(shelved.ID), nil); status != fiber.StatusConflict {
+		t.Errorf("retire twice = %d %s, want 409", status, body)
+	}
+	lendRetired := handlers.LendBookPayload{BookID: book.ID, Barcode: shelved.Barcode, UserID: admin.ID}
+	if status, body := s.do(t, admin, fiber.MethodPost, "/api/lending/lend", lendRetired); status != fiber.StatusConflict {
+		t.Errorf("lend a retired copy = %d %s, want 409", status, body)
+	}
+
+	var got models.Book
+	if _, body := s.do(t, alice, fiber.MethodGet, "/api/books/"+strconv.Itoa(book.ID), nil); json.Unmarshal(body, &got) != nil || got.Quantity != 0 {
+		t.Errorf("book = %s, want no available copies", body)
+	}
+}
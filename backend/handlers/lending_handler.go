@@ -1,278 +1,311 @@
 package handlers
 
 import (
-	"context"
+	"errors"
 	"log"
-	"strconv"
+	"strings"
 	"time"
 
-	// For custom errors
-	"digital-library/backend/database"
+	"digital-library/backend/middleware"
 	"digital-library/backend/models"
+	"digital-library/backend/store"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/jackc/pgx/v5"
-	// For checking specific error codes
 )
 
 // LendBookPayload defines the expected structure for the lend book request
 type LendBookPayload struct {
-	BookID   int    `json:"book_id"`
-	Borrower string `json:"borrower"`
+	BookID     int    `json:"book_id"`
+	Barcode    string `json:"barcode"`     // Copy to lend; any available copy when empty
+	UserID     int    `json:"user_id"`     // Borrower's account; defaults to the caller
+	CardNumber string `json:"card_number"` // Alternatively, the borrower's patron card number
 }
 
 // @Summary Lend a book
-// @Description Create a new lending record for a book
+// @Description Create a new lending record for a copy of a book, lent to the account named by user_id or card_number. The copy with the given barcode is lent, or any available copy without one. Regular users may only borrow for themselves and can omit both. The due date comes from the loan policy matching the book category and the borrower's role. Borrowers whose unpaid fines exceed the configured threshold are refused. Copies set aside for ready holds are only lent to their holders.
 // @Tags lending
 // @Accept json
 // @Produce json
-// @Param lending body models.LendingRecord true "Lending record object"
+// @Param lending body LendBookPayload true "Book and borrower"
 // @Success 201 {object} models.LendingRecord
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
-// @Router /lending [post]
-func LendBook(c *fiber.Ctx) error {
+// @Router /lending/lend [post]
+func (h *Handler) LendBook(c *fiber.Ctx) error {
 	payload := new(LendBookPayload)
 	if err := c.BodyParser(payload); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
 	}
 
 	// Basic validation
-	if payload.BookID <= 0 || payload.Borrower == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Book ID and Borrower name are required"})
+	if payload.BookID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Book ID is required"})
 	}
 
-	// Use a transaction to ensure atomicity
-	tx, err := database.DB.Begin(context.Background())
-	if err != nil {
-		log.Printf("Error starting transaction: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start transaction"})
+	// Regular users may only borrow books for themselves
+	borrower, reqErr := h.resolveBorrower(c, payload.UserID, payload.CardNumber, "You can only borrow books for yourself")
+	if reqErr != nil {
+		return reqErr.send(c)
 	}
-	// Defer rollback in case of error, commit will override this if successful
-	defer tx.Rollback(context.Background())
 
-	// 1. Check book quantity and lock the row for update
-	var currentQuantity int
-	checkQuery := `SELECT quantity FROM books WHERE id = $1 FOR UPDATE`
-	err = tx.QueryRow(context.Background(), checkQuery, payload.BookID).Scan(&currentQuantity)
+	// Borrowers owing more than the threshold must pay before borrowing again
+	balance, err := h.Fines.FineBalance(c.UserContext(), borrower.ID)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Book not found"})
-		}
-		log.Printf("Error checking book quantity: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not verify book availability"})
-	}
-
-	if currentQuantity <= 0 {
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Book is currently out of stock"})
+		log.Printf("Error fetching fine balance for user %d: %v", borrower.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not complete lending operation"})
 	}
-
-	// 2. Decrease book quantity
-	updateQuery := `UPDATE books SET quantity = quantity - 1, updated_at = NOW() WHERE id = $1`
-	_, err = tx.Exec(context.Background(), updateQuery, payload.BookID)
-	if err != nil {
-		log.Printf("Error updating book quantity: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update book quantity"})
+	if balance.BalanceCents > h.FineBlockThreshold {
+		return middleware.Forbidden(c, "Borrower has outstanding fines of "+formatCents(balance.BalanceCents)+
+			" and cannot borrow until they are paid")
 	}
 
-	// 3. Create lending record
-	insertQuery := `INSERT INTO lending_records (book_id, borrower_name, borrow_date) 
-	                 VALUES ($1, $2, $3) 
-	                 RETURNING id, borrow_date, created_at, updated_at`
-	var newRecord models.LendingRecord
-	newRecord.BookID = payload.BookID // Populate from payload
-	newRecord.Borrower = payload.Borrower
 	borrowDate := time.Now().UTC().Truncate(24 * time.Hour) // Use UTC date part only
 
-	row := tx.QueryRow(context.Background(), insertQuery,
-		payload.BookID, payload.Borrower, borrowDate)
-	err = row.Scan(&newRecord.ID, &newRecord.BorrowDate, &newRecord.CreatedAt, &newRecord.UpdatedAt)
-	if err != nil {
-		log.Printf("Error creating lending record: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create lending record"})
-	}
-
-	// 4. Commit transaction
-	err = tx.Commit(context.Background())
+	record, err := h.Lending.LendBook(c.UserContext(), payload.BookID, strings.TrimSpace(payload.Barcode), borrower, borrowDate)
 	if err != nil {
-		log.Printf("Error committing transaction: %v", err)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Book not found"})
+		case errors.Is(err, store.ErrOutOfStock):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Book is currently out of stock"})
+		case errors.Is(err, store.ErrOnHold):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "All available copies are held for other borrowers"})
+		case errors.Is(err, store.ErrItemNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "No copy of this book has that barcode"})
+		case errors.Is(err, store.ErrItemUnavailable):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Copy is not available to lend"})
+		}
+		log.Printf("Error lending book %d: %v", payload.BookID, err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not complete lending operation"})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(newRecord)
+	return c.Status(fiber.StatusCreated).JSON(record)
+}
+
+// ReturnBookPayload is the optional body of a return request
+type ReturnBookPayload struct {
+	Condition string `json:"condition"` // good (default) or damaged
 }
 
 // @Summary Return a book
-// @Description Mark a lending record as returned and update book availability
+// @Description Mark a lending record as returned and update book availability. The copy is set aside for the oldest waiting hold, if any. Overdue and damage fines under the loan's policy are charged to the borrower.
 // @Tags lending
 // @Accept json
 // @Produce json
 // @Param id path int true "Lending Record ID"
-// @Success 200 {object} models.LendingRecord
+// @Param return body ReturnBookPayload false "Condition of the returned copy"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
-// @Router /lending/{id}/return [put]
-func ReturnBook(c *fiber.Ctx) error {
-	lendingRecordID := c.Params("id")
-	// TODO: Add validation for lendingRecordID
+// @Router /lending/return/{id} [post]
+func (h *Handler) ReturnBook(c *fiber.Ctx) error {
+	payload := new(ReturnBookPayload)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(payload); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+		}
+	}
+	switch payload.Condition {
+	case "":
+		payload.Condition = models.ConditionGood
+	case models.ConditionGood, models.ConditionDamaged:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Condition must be good or damaged"})
+	}
 
-	returnDate := time.Now().UTC().Truncate(24 * time.Hour) // Use UTC date part only
+	return h.closeLoan(c, payload.Condition, "Book returned successfully")
+}
 
-	// Use a transaction
-	tx, err := database.DB.Begin(context.Background())
-	if err != nil {
-		log.Printf("Error starting transaction: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start transaction"})
+// @Summary Report a book lost
+// @Description Close a loan whose copy was lost. The copy is not returned to stock; overdue fines and the lost fee under the loan's policy are charged to the borrower.
+// @Tags lending
+// @Accept json
+// @Produce json
+// @Param id path int true "Lending Record ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /lending/lost/{id} [post]
+func (h *Handler) ReportLostBook(c *fiber.Ctx) error {
+	return h.closeLoan(c, models.ConditionLost, "Book reported lost")
+}
+
+// authorizeLoan checks that a regular user is the borrower of the lending
+// record; admins may act on every record
+func (h *Handler) authorizeLoan(c *fiber.Ctx, id int, forbidden string) *requestError {
+	user, _ := middleware.CurrentUser(c)
+	if user.Role == models.RoleAdmin {
+		return nil
 	}
-	defer tx.Rollback(context.Background())
-
-	// 1. Update lending record and get the book_id
-	var bookID int
-	updateLendingQuery := `UPDATE lending_records 
-	                       SET return_date = $1, updated_at = NOW() 
-	                       WHERE id = $2 AND return_date IS NULL -- Only update if not already returned
-	                       RETURNING book_id`
-	err = tx.QueryRow(context.Background(), updateLendingQuery, returnDate, lendingRecordID).Scan(&bookID)
+	record, err := h.Lending.GetLendingRecord(c.UserContext(), id)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			// Either record doesn't exist or was already returned
-			// Check if record exists but is already returned
-			var exists bool
-			checkExistsQuery := `SELECT EXISTS(SELECT 1 FROM lending_records WHERE id = $1 AND return_date IS NOT NULL)`
-			errCheck := database.DB.QueryRow(context.Background(), checkExistsQuery, lendingRecordID).Scan(&exists)
-			if errCheck == nil && exists {
-				return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Book already returned"})
-			}
-			// Otherwise, the record wasn't found
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Lending record not found or already returned"})
+		if errors.Is(err, store.ErrNotFound) {
+			return &requestError{fiber.StatusNotFound, "Lending record not found"}
 		}
-		// Other errors
-		log.Printf("Error updating lending record %s: %v", lendingRecordID, err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update lending record"})
+		log.Printf("Error fetching lending record %d: %v", id, err)
+		return &requestError{fiber.StatusInternalServerError, "Could not retrieve lending record"}
+	}
+	if record.UserID == nil || *record.UserID != user.UserID {
+		return &requestError{fiber.StatusForbidden, forbidden}
 	}
+	return nil
+}
 
-	// 2. Increment book quantity
-	updateBookQuery := `UPDATE books SET quantity = quantity + 1, updated_at = NOW() WHERE id = $1`
-	_, err = tx.Exec(context.Background(), updateBookQuery, bookID)
-	if err != nil {
-		// This shouldn't ideally happen if the foreign key constraint is working, but handle it
-		log.Printf("Error incrementing book quantity for book %d: %v", bookID, err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update book quantity after return"})
+// closeLoan closes the lending record named by the :id parameter and responds
+// with the fines charged. Regular users may only close their own loans.
+func (h *Handler) closeLoan(c *fiber.Ctx, condition, message string) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid lending record ID"})
+	}
+
+	if reqErr := h.authorizeLoan(c, id, "You can only return your own books"); reqErr != nil {
+		return reqErr.send(c)
 	}
 
-	// 3. Commit transaction
-	err = tx.Commit(context.Background())
+	returnDate := time.Now().UTC().Truncate(24 * time.Hour) // Use UTC date part only
+
+	fines, err := h.Lending.ReturnBook(c.UserContext(), id, returnDate, condition)
 	if err != nil {
-		log.Printf("Error committing transaction: %v", err)
+		switch {
+		case errors.Is(err, store.ErrAlreadyReturned):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Book already returned"})
+		case errors.Is(err, store.ErrNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Lending record not found or already returned"})
+		}
+		log.Printf("Error closing lending record %d: %v", id, err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not complete return operation"})
 	}
+	if fines == nil {
+		fines = []models.FineEntry{}
+	}
 
-	return c.JSON(fiber.Map{"message": "Book returned successfully"})
-}
-
-// LendingRecordDetail extends LendingRecord to include book details
-type LendingRecordDetail struct {
-	models.LendingRecord        // Embed LendingRecord
-	BookTitle            string `json:"book_title"`
-	BookAuthor           string `json:"book_author"`
+	return c.JSON(fiber.Map{"message": message, "fines": fines})
 }
 
-// @Summary Get lending records
-// @Description Get all lending records with optional search and filtering
+// @Summary Renew a loan
+// @Description Extend the due date of an active loan by the loan period of its policy, counted from the current due date. Loans that are overdue, at the policy's renewal limit, or for a book other borrowers hold cannot be renewed.
 // @Tags lending
 // @Accept json
 // @Produce json
-// @Param search query string false "Search term"
-// @Param status query string false "Filter by status (active/returned)"
-// @Param book_id query int false "Filter by book ID"
-// @Success 200 {array} models.LendingRecord
+// @Param id path int true "Lending Record ID"
+// @Success 200 {object} models.LendingRecord
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
-// @Router /lending [get]
-func GetLendingRecords(c *fiber.Ctx) error {
-	// Get query parameters
-	search := c.Query("search", "")
-	borrower := c.Query("borrower", "")
-	status := c.Query("status", "") // "active" or "returned"
-	bookTitle := c.Query("bookTitle", "")
-
-	// Build the base query
-	query := `SELECT 
-	            lr.id, lr.book_id, lr.borrower_name, lr.borrow_date, lr.return_date, 
-	            lr.created_at, lr.updated_at, 
-	            b.title AS book_title, b.author AS book_author
-	          FROM lending_records lr
-	          JOIN books b ON lr.book_id = b.id
-	          WHERE 1=1`
-	args := []interface{}{}
-	argCount := 1
-
-	// Add search condition (matches borrower name or book title)
-	if search != "" {
-		query += ` AND (LOWER(lr.borrower_name) LIKE LOWER($` + strconv.Itoa(argCount) + `) OR LOWER(b.title) LIKE LOWER($` + strconv.Itoa(argCount) + `))`
-		args = append(args, "%"+search+"%")
-		argCount++
+// @Router /lending/renew/{id} [post]
+func (h *Handler) RenewBook(c *fiber.Ctx) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid lending record ID"})
 	}
 
-	// Add borrower filter
-	if borrower != "" {
-		query += ` AND LOWER(lr.borrower_name) = LOWER($` + strconv.Itoa(argCount) + `)`
-		args = append(args, borrower)
-		argCount++
+	// Regular users may only renew their own loans
+	if reqErr := h.authorizeLoan(c, id, "You can only renew your own loans"); reqErr != nil {
+		return reqErr.send(c)
 	}
 
-	// Add status filter
-	if status == "active" {
-		query += ` AND lr.return_date IS NULL`
-	} else if status == "returned" {
-		query += ` AND lr.return_date IS NOT NULL`
+	today := time.Now().UTC().Truncate(24 * time.Hour) // Use UTC date part only
+
+	record, err := h.Lending.RenewLoan(c.UserContext(), id, today)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Lending record not found"})
+		case errors.Is(err, store.ErrAlreadyReturned):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Book already returned"})
+		case errors.Is(err, store.ErrLoanOverdue):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Overdue loans cannot be renewed"})
+		case errors.Is(err, store.ErrRenewalLimit):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Renewal limit reached for this loan"})
+		case errors.Is(err, store.ErrPendingHolds):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Book has pending holds and cannot be renewed"})
+		}
+		log.Printf("Error renewing lending record %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not complete renewal"})
 	}
 
-	// Add book title filter
-	if bookTitle != "" {
-		query += ` AND LOWER(b.title) = LOWER($` + strconv.Itoa(argCount) + `)`
-		args = append(args, bookTitle)
-		argCount++
+	return c.JSON(record)
+}
+
+// @Summary Get lending records
+// @Description Get a page of lending records with optional search and filtering. Admins see every borrower; regular users see only their own loans. Pass next_cursor back as cursor to fetch the following page.
+// @Tags lending
+// @Accept json
+// @Produce json
+// @Param search query string false "Search term for borrower name or book title"
+// @Param user_id query int false "Admin only: filter by borrower account"
+// @Param card_number query string false "Admin only: filter by borrower card number"
+// @Param status query string false "Filter by status (active/overdue/returned/lost); active includes overdue loans"
+// @Param bookTitle query string false "Filter by book title"
+// @Param due_before query string false "Only loans due on or before this date (YYYY-MM-DD)"
+// @Param due_after query string false "Only loans due on or after this date (YYYY-MM-DD)"
+// @Param limit query int false "Page size, 1 to 200 (default 50)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "Sort field: borrow_date (default), created_at, title or author"
+// @Param order query string false "Sort order: asc or desc (default desc for borrow_date, asc otherwise)"
+// @Success 200 {object} models.LendingRecordPage
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /lending [get]
+func (h *Handler) GetLendingRecords(c *fiber.Ctx) error {
+	filter, reqErr := h.lendingFilter(c)
+	if reqErr != nil {
+		return reqErr.send(c)
 	}
 
-	// Add sorting
-	query += ` ORDER BY lr.borrow_date DESC, lr.created_at DESC`
+	page, reqErr := queryPage(c)
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
 
-	rows, err := database.DB.Query(context.Background(), query, args...)
+	records, err := h.Lending.ListLendingRecords(c.UserContext(), filter, page)
 	if err != nil {
+		if reqErr := pageError(err, store.LendingSortFields); reqErr != nil {
+			return reqErr.send(c)
+		}
 		log.Printf("Error fetching lending records: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Could not retrieve lending records",
 		})
 	}
-	defer rows.Close()
-
-	records := make([]LendingRecordDetail, 0)
-
-	for rows.Next() {
-		var record LendingRecordDetail
-		err := rows.Scan(
-			&record.ID, &record.BookID, &record.Borrower, &record.BorrowDate, &record.ReturnDate,
-			&record.CreatedAt, &record.UpdatedAt,
-			&record.BookTitle, &record.BookAuthor,
-		)
-		if err != nil {
-			log.Printf("Error scanning lending record row: %v", err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Error processing lending record data",
-			})
-		}
-		records = append(records, record)
-	}
 
-	if rows.Err() != nil {
-		log.Printf("Error iterating lending record rows: %v", rows.Err())
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Error retrieving lending record data",
-		})
+	return c.JSON(records)
+}
+
+// lendingFilter parses the search and filter query parameters of the lending
+// listing. Regular users are limited to their own loans.
+func (h *Handler) lendingFilter(c *fiber.Ctx) (store.LendingFilter, *requestError) {
+	filter := store.LendingFilter{
+		Search:    c.Query("search", ""),
+		Status:    c.Query("status", ""), // "active", "overdue", "returned" or "lost"
+		BookTitle: c.Query("bookTitle", ""),
 	}
 
-	return c.JSON(records)
+	var reqErr *requestError
+	if filter.UserID, reqErr = h.borrowerFilter(c, "You can only view your own loans"); reqErr != nil {
+		return filter, reqErr
+	}
+	if filter.DueBefore, reqErr = queryDate(c, "due_before"); reqErr != nil {
+		return filter, reqErr
+	}
+	if filter.DueAfter, reqErr = queryDate(c, "due_after"); reqErr != nil {
+		return filter, reqErr
+	}
+	return filter, nil
 }
 
 // @Summary Delete a lending record
@@ -282,61 +315,22 @@ func GetLendingRecords(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path int true "Lending Record ID"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /lending/{id} [delete]
-func DeleteLendingRecord(c *fiber.Ctx) error {
-	lendingRecordID := c.Params("id")
-	// TODO: Add validation for lendingRecordID
-
-	// Use a transaction
-	tx, err := database.DB.Begin(context.Background())
-	if err != nil {
-		log.Printf("Error starting transaction: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not start transaction"})
+func (h *Handler) DeleteLendingRecord(c *fiber.Ctx) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid lending record ID"})
 	}
-	defer tx.Rollback(context.Background())
 
-	// 1. Get book_id and check if the book was returned before deleting
-	var bookID int
-	var returnDate *time.Time
-	selectQuery := `SELECT book_id, return_date FROM lending_records WHERE id = $1`
-	err = tx.QueryRow(context.Background(), selectQuery, lendingRecordID).Scan(&bookID, &returnDate)
-	if err != nil {
-		if err == pgx.ErrNoRows {
+	if err := h.Lending.DeleteLendingRecord(c.UserContext(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Lending record not found"})
 		}
-		log.Printf("Error checking lending record %s before delete: %v", lendingRecordID, err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve lending record details"})
-	}
-
-	// 2. Delete the lending record
-	deleteQuery := `DELETE FROM lending_records WHERE id = $1`
-	commandTag, err := tx.Exec(context.Background(), deleteQuery, lendingRecordID)
-	if err != nil {
-		log.Printf("Error deleting lending record %s: %v", lendingRecordID, err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not delete lending record"})
-	}
-	if commandTag.RowsAffected() == 0 {
-		// Should have been caught by the select earlier, but as a safeguard
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Lending record not found"})
-	}
-
-	// 3. If the book was *not* returned, increment the book quantity back
-	if returnDate == nil {
-		updateBookQuery := `UPDATE books SET quantity = quantity + 1, updated_at = NOW() WHERE id = $1`
-		_, err = tx.Exec(context.Background(), updateBookQuery, bookID)
-		if err != nil {
-			// Handle potential error updating book quantity
-			log.Printf("Error incrementing book quantity for book %d after deleting unreturned record %s: %v", bookID, lendingRecordID, err)
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update book quantity after deleting lending record"})
-		}
-	}
-
-	// 4. Commit transaction
-	err = tx.Commit(context.Background())
-	if err != nil {
-		log.Printf("Error committing transaction: %v", err)
+		log.Printf("Error deleting lending record %d: %v", id, err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not complete delete operation"})
 	}
 
@@ -0,0 +1,150 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"digital-library/backend/handlers"
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLendReturnRenew(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	alice := s.user(t, "alice", models.RoleUser)
+	bob := s.user(t, "bob", models.RoleUser)
+	book := s.book(t, "9780441172719", 1)
+	other := s.book(t, "9780306406157", 1)
+
+	status, body := s.do(t, alice, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: book.ID})
+	if status != fiber.StatusCreated {
+		t.Fatalf("lend = %d %s, want 201", status, body)
+	}
+	var loan models.LendingRecord
+	if err := json.Unmarshal(body, &loan); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if loan.UserID == nil || *loan.UserID != alice.ID || loan.BookID != book.ID || loan.ReturnDate != nil {
+		t.Fatalf("lend = %+v, want an open loan of book %d to user %d", loan, book.ID, alice.ID)
+	}
+	id := strconv.Itoa(loan.ID)
+
+	tests := []struct {
+		name   string
+		user   models.User
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{"lend without a book", alice, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{}, fiber.StatusBadRequest},
+		{"lend an unknown book", alice, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: 9999}, fiber.StatusNotFound},
+		{"lend a book out of stock", bob, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: book.ID}, fiber.StatusConflict},
+		{"lend to another user", bob, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: other.ID, UserID: alice.ID}, fiber.StatusForbidden},
+		{"admin lends without a borrower", admin, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: other.ID}, fiber.StatusBadRequest},
+		{"admin lends to an unknown borrower", admin, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: other.ID, CardNumber: "P99999999"}, fiber.StatusNotFound},
+		{"card number of another borrower", admin, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: other.ID, UserID: alice.ID, CardNumber: bob.CardNumber}, fiber.StatusBadRequest},
+		{"without a token", models.User{}, fiber.MethodPost, "/api/lending/renew/" + id, nil, fiber.StatusUnauthorized},
+		{"renew another user's loan", bob, fiber.MethodPost, "/api/lending/renew/" + id, nil, fiber.StatusForbidden},
+		{"return another user's loan", bob, fiber.MethodPost, "/api/lending/return/" + id, nil, fiber.StatusForbidden},
+		{"report another user's loan lost", bob, fiber.MethodPost, "/api/lending/lost/" + id, nil, fiber.StatusForbidden},
+		{"delete as a regular user", alice, fiber.MethodDelete, "/api/lending/" + id, nil, fiber.StatusForbidden},
+		{"renew an invalid ID", alice, fiber.MethodPost, "/api/lending/renew/abc", nil, fiber.StatusBadRequest},
+		{"renew an unknown loan", alice, fiber.MethodPost, "/api/lending/renew/9999", nil, fiber.StatusNotFound},
+		{"renew", alice, fiber.MethodPost, "/api/lending/renew/" + id, nil, fiber.StatusOK},
+		{"admin renews", admin, fiber.MethodPost, "/api/lending/renew/" + id, nil, fiber.StatusOK},
+		{"renew past the limit", alice, fiber.MethodPost, "/api/lending/renew/" + id, nil, fiber.StatusConflict},
+		{"return an invalid ID", alice, fiber.MethodPost, "/api/lending/return/abc", nil, fiber.StatusBadRequest},
+		{"return an unknown loan", alice, fiber.MethodPost, "/api/lending/return/9999", nil, fiber.StatusNotFound},
+		{"return", alice, fiber.MethodPost, "/api/lending/return/" + id, nil, fiber.StatusOK},
+		{"return twice", alice, fiber.MethodPost, "/api/lending/return/" + id, nil, fiber.StatusConflict},
+		{"renew a returned loan", alice, fiber.MethodPost, "/api/lending/renew/" + id, nil, fiber.StatusConflict},
+		{"lend the returned copy", bob, fiber.MethodPost, "/api/lending/lend", handlers.LendBookPayload{BookID: book.ID}, fiber.StatusCreated},
+		{"delete an unknown loan", admin, fiber.MethodDelete, "/api/lending/9999", nil, fiber.StatusNotFound},
+		{"admin deletes", admin, fiber.MethodDelete, "/api/lending/" + id, nil, fiber.StatusOK},
+	}
+	// The steps share the loan, so they run in order
+	for _, tt := range tests {
+		if status, body := s.do(t, tt.user, tt.method, tt.path, tt.body); status != tt.status {
+			t.Errorf("%s: %s %s = %d %s, want %d", tt.name, tt.method, tt.path, status, body, tt.status)
+		}
+	}
+}
+
+func TestRenewedDueDate(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.user(t, "alice", models.RoleUser)
+	bob := s.user(t, "bob", models.RoleUser)
+	book := s.book(t, "9780441172719", 1)
+
+	loan := s.lend(t, alice, book.ID, alice)
+	path := "/api/lending/renew/" + strconv.Itoa(loan.ID)
+
+	status, body := s.do(t, alice, fiber.MethodPost, path, nil)
+	var renewed models.LendingRecord
+	if err := json.Unmarshal(body, &renewed); status != fiber.StatusOK || err != nil {
+		t.Fatalf("renew = %d %s", status, body)
+	}
+	days := models.DefaultLoanPolicy.LoanDays
+	if want := loan.DueDate.AddDate(0, 0, days); !renewed.DueDate.Equal(want) || renewed.RenewalCount != 1 {
+		t.Errorf("renewed loan due %v after %d renewals, want due %v after 1", renewed.DueDate, renewed.RenewalCount, want)
+	}
+
+	// Another borrower waiting for the book blocks further renewals
+	if status, body := s.do(t, bob, fiber.MethodPost, "/api/holds", handlers.PlaceHoldPayload{BookID: book.ID}); status != fiber.StatusCreated {
+		t.Fatalf("hold = %d %s", status, body)
+	}
+	if status, body := s.do(t, alice, fiber.MethodPost, path, nil); status != fiber.StatusConflict {
+		t.Errorf("renew with a pending hold = %d %s, want 409", status, body)
+	}
+}
+
+func TestGetLendingRecords(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	bob := s.user(t, "Bob", models.RoleUser)
+	dune := s.book(t, "9780441172719", 2)
+
+	var loans []models.LendingRecord
+	for _, borrower := range []models.User{s.user(t, "Alice", models.RoleUser), bob} {
+		loans = append(loans, s.lend(t, admin, dune.ID, borrower))
+	}
+	if status, body := s.do(t, admin, fiber.MethodPost, "/api/lending/return/"+strconv.Itoa(loans[0].ID), nil); status != fiber.StatusOK {
+		t.Fatalf("return = %d %s", status, body)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Alice", "Bob"}},
+		{"?user_id=" + strconv.Itoa(bob.ID), []string{"Bob"}},
+		{"?card_number=" + bob.CardNumber, []string{"Bob"}},
+		{"?search=ali", []string{"Alice"}},
+		{"?search=dune", []string{"Alice", "Bob"}},
+		{"?status=active", []string{"Bob"}},
+		{"?status=returned", []string{"Alice"}},
+		{"?bookTitle=emma", nil},
+	}
+	for _, tt := range tests {
+		status, body := s.do(t, admin, fiber.MethodGet, "/api/lending"+tt.query, nil)
+		var page models.LendingRecordPage
+		if err := json.Unmarshal(body, &page); status != fiber.StatusOK || err != nil {
+			t.Errorf("GET /api/lending%s = %d %s", tt.query, status, body)
+			continue
+		}
+		var borrowers []string
+		for _, record := range page.Data {
+			borrowers = append(borrowers, record.Borrower)
+		}
+		sort.Strings(borrowers)
+		if !reflect.DeepEqual(borrowers, tt.want) {
+			t.Errorf("GET /api/lending%s borrowers = %q, want %q", tt.query, borrowers, tt.want)
+		}
+	}
+}
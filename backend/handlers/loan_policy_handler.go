@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// validateLoanPolicy checks and normalizes a loan policy from a request body
+func validateLoanPolicy(policy *models.LoanPolicy) *requestError {
+	if policy.Category != nil {
+		category := strings.TrimSpace(*policy.Category)
+		if category == "" {
+			policy.Category = nil
+		} else {
+			policy.Category = &category
+		}
+	}
+	if policy.Role != nil && *policy.Role != models.RoleAdmin && *policy.Role != models.RoleUser {
+		return &requestError{fiber.StatusBadRequest, "Role must be admin, user or null"}
+	}
+	if policy.LoanDays <= 0 {
+		return &requestError{fiber.StatusBadRequest, "Loan days must be greater than zero"}
+	}
+	if policy.MaxRenewals < 0 || policy.GraceDays < 0 {
+		return &requestError{fiber.StatusBadRequest, "Max renewals and grace days cannot be negative"}
+	}
+	if policy.DailyFineCents < 0 || policy.LostFeeCents < 0 || policy.DamagedFeeCents < 0 {
+		return &requestError{fiber.StatusBadRequest, "Fines and fees cannot be negative"}
+	}
+	if policy.HoldPickupDays == 0 {
+		policy.HoldPickupDays = models.DefaultLoanPolicy.HoldPickupDays
+	} else if policy.HoldPickupDays < 0 {
+		return &requestError{fiber.StatusBadRequest, "Hold pickup days must be greater than zero"}
+	}
+	return nil
+}
+
+// @Summary Get loan policies
+// @Description Get every loan policy. A null category or role matches any value; the most specific policy applies when a book is lent.
+// @Tags loan-policies
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.LoanPolicy
+// @Failure 500 {object} map[string]string
+// @Router /loan-policies [get]
+func (h *Handler) GetLoanPolicies(c *fiber.Ctx) error {
+	policies, err := h.Policies.ListLoanPolicies(c.UserContext())
+	if err != nil {
+		log.Printf("Error fetching loan policies: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not retrieve loan policies",
+		})
+	}
+
+	return c.JSON(policies)
+}
+
+// @Summary Create a loan policy
+// @Description Create a loan policy for a category and/or borrower role
+// @Tags loan-policies
+// @Accept json
+// @Produce json
+// @Param policy body models.LoanPolicy true "Loan policy object"
+// @Success 201 {object} models.LoanPolicy
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /loan-policies [post]
+func (h *Handler) CreateLoanPolicy(c *fiber.Ctx) error {
+	policy := new(models.LoanPolicy)
+	if err := c.BodyParser(policy); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot parse JSON",
+		})
+	}
+	if reqErr := validateLoanPolicy(policy); reqErr != nil {
+		return reqErr.send(c)
+	}
+
+	if err := h.Policies.CreateLoanPolicy(c.UserContext(), policy); err != nil {
+		if errors.Is(err, store.ErrDuplicatePolicy) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "A loan policy already exists for this category and role",
+			})
+		}
+		log.Printf("Error creating loan policy: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not create loan policy",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(policy)
+}
+
+// @Summary Update a loan policy
+// @Description Update an existing loan policy. Existing loans keep their due dates.
+// @Tags loan-policies
+// @Accept json
+// @Produce json
+// @Param id path int true "Loan Policy ID"
+// @Param policy body models.LoanPolicy true "Loan policy object"
+// @Success 200 {object} models.LoanPolicy
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /loan-policies/{id} [put]
+func (h *Handler) UpdateLoanPolicy(c *fiber.Ctx) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid loan policy ID",
+		})
+	}
+
+	policy := new(models.LoanPolicy)
+	if err := c.BodyParser(policy); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot parse JSON",
+		})
+	}
+	if reqErr := validateLoanPolicy(policy); reqErr != nil {
+		return reqErr.send(c)
+	}
+
+	updated, err := h.Policies.UpdateLoanPolicy(c.UserContext(), id, *policy)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Loan policy not found",
+			})
+		}
+		if errors.Is(err, store.ErrDuplicatePolicy) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "A loan policy already exists for this category and role",
+			})
+		}
+		log.Printf("Error updating loan policy %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not update loan policy",
+		})
+	}
+
+	return c.JSON(updated)
+}
+
+// @Summary Delete a loan policy
+// @Description Delete a loan policy by its ID. Existing loans keep their due dates.
+// @Tags loan-policies
+// @Accept json
+// @Produce json
+// @Param id path int true "Loan Policy ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /loan-policies/{id} [delete]
+func (h *Handler) DeleteLoanPolicy(c *fiber.Ctx) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid loan policy ID",
+		})
+	}
+
+	if err := h.Policies.DeleteLoanPolicy(c.UserContext(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Loan policy not found",
+			})
+		}
+		log.Printf("Error deleting loan policy %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Could not delete loan policy",
+		})
+	}
+
+	return c.JSON(fiber.Map{"message": "Loan policy deleted successfully", "id": id})
+}
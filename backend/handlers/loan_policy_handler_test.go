@@ -0,0 +1,105 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLoanPolicies(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	alice := s.user(t, "alice", models.RoleUser)
+
+	scifi, user := "scifi", models.RoleUser
+	status, body := s.do(t, admin, fiber.MethodPost, "/api/loan-policies", models.LoanPolicy{Category: &scifi, Role: &user, LoanDays: 7})
+	var policy models.LoanPolicy
+	if err := json.Unmarshal(body, &policy); status != fiber.StatusCreated || err != nil {
+		t.Fatalf("create policy = %d %s", status, body)
+	}
+	path := "/api/loan-policies/" + strconv.Itoa(policy.ID)
+
+	librarian, blank := "librarian", " "
+	steps := []struct {
+		name   string
+		user   models.User
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{"list as user", alice, fiber.MethodGet, "/api/loan-policies", nil, fiber.StatusOK},
+		{"create as user", alice, fiber.MethodPost, "/api/loan-policies", models.LoanPolicy{LoanDays: 7}, fiber.StatusForbidden},
+		{"duplicate", admin, fiber.MethodPost, "/api/loan-policies", models.LoanPolicy{Category: &scifi, Role: &user, LoanDays: 3}, fiber.StatusConflict},
+		{"zero loan days", admin, fiber.MethodPost, "/api/loan-policies", models.LoanPolicy{}, fiber.StatusBadRequest},
+		{"negative renewals", admin, fiber.MethodPost, "/api/loan-policies", models.LoanPolicy{LoanDays: 7, MaxRenewals: -1}, fiber.StatusBadRequest},
+		{"unknown role", admin, fiber.MethodPost, "/api/loan-policies", models.LoanPolicy{Role: &librarian, LoanDays: 7}, fiber.StatusBadRequest},
+		{"blank category is the seeded catch-all", admin, fiber.MethodPost, "/api/loan-policies", models.LoanPolicy{Category: &blank, LoanDays: 21}, fiber.StatusConflict},
+		{"update as user", alice, fiber.MethodPut, path, models.LoanPolicy{LoanDays: 10}, fiber.StatusForbidden},
+		{"update unknown", admin, fiber.MethodPut, "/api/loan-policies/9999", models.LoanPolicy{LoanDays: 10}, fiber.StatusNotFound},
+		{"update", admin, fiber.MethodPut, path, models.LoanPolicy{Category: &scifi, Role: &user, LoanDays: 10}, fiber.StatusOK},
+		{"delete as user", alice, fiber.MethodDelete, path, nil, fiber.StatusForbidden},
+		{"delete invalid id", admin, fiber.MethodDelete, "/api/loan-policies/abc", nil, fiber.StatusBadRequest},
+	}
+
+	for _, step := range steps {
+		if status, body := s.do(t, step.user, step.method, step.path, step.body); status != step.status {
+			t.Errorf("%s: %s %s = %d %s, want %d", step.name, step.method, step.path, status, body, step.status)
+		}
+	}
+
+	var policies []models.LoanPolicy
+	if _, body := s.do(t, alice, fiber.MethodGet, "/api/loan-policies", nil); json.Unmarshal(body, &policies) != nil || len(policies) != 2 {
+		t.Fatalf("policies = %s, want 2", body)
+	}
+}
+
+func TestLendDueDate(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	alice := s.user(t, "alice", models.RoleUser)
+
+	dune := &models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Category: "SciFi", Quantity: 5}
+	emma := &models.Book{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Category: "Classic", Quantity: 5}
+	for _, book := range []*models.Book{dune, emma} {
+		if err := s.store.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+	}
+
+	scifi, user := "scifi", models.RoleUser
+	for _, policy := range []models.LoanPolicy{
+		{Category: &scifi, LoanDays: 7},
+		{Category: &scifi, Role: &user, LoanDays: 3},
+	} {
+		if status, body := s.do(t, admin, fiber.MethodPost, "/api/loan-policies", policy); status != fiber.StatusCreated {
+			t.Fatalf("create policy = %d %s", status, body)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		user     models.User
+		bookID   int
+		borrower models.User
+		days     int
+	}{
+		{"category and role", alice, dune.ID, alice, 3},
+		{"category only", admin, dune.ID, admin, 7},
+		{"default", alice, emma.ID, alice, models.DefaultLoanPolicy.LoanDays},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loan := s.lend(t, tt.user, tt.bookID, tt.borrower)
+			if want := loan.BorrowDate.AddDate(0, 0, tt.days); !loan.DueDate.Equal(want) {
+				t.Errorf("due date = %v, want %v", loan.DueDate, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"digital-library/backend/marc"
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// marcReader reads the records of a MARC file in either format
+type marcReader interface {
+	Read() (*marc.Record, error)
+}
+
+// ImportMARC godoc
+// @Summary Import books from MARC
+// @Description Create or update books from a binary MARC 21 or MARCXML file, matching existing books by ISBN. The file is the request body or the file field of a multipart form, and is read as it streams in. The configured field mapping reads the title (default 245 $a $b), author (100 $a or 110 $a), ISBN (020 $a), category (650 $a), publisher (264 $b or 260 $b) and year (264 $c or 260 $c); title, author and ISBN are required. New books get no copies; existing books take the title, author and a non-empty category, publisher and year. Records are saved in transactions of 500. The report gives the outcome of every record and counts the fields and subfields the mapping does not read; a dry run validates and reports every record without saving anything.
+// @Tags books
+// @Accept application/marc
+// @Accept application/marcxml+xml
+// @Accept mpfd
+// @Produce json
+// @Param file formData file false "MARC file, when sent as a multipart form"
+// @Param format query string false "marc21 or marcxml; detected from the file by default"
+// @Param dry_run query bool false "Validate and report without saving"
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /books/import/marc [post]
+func (h *Handler) ImportMARC(c *fiber.Ctx) error {
+	dryRun := c.Query("dry_run", "") == "true"
+	body, closeBody, reqErr := importBody(c)
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+	defer closeBody()
+
+	in := bufio.NewReader(body)
+	format := c.Query("format", "")
+	if format == "" {
+		format = detectMARCFormat(in)
+	}
+	var records marcReader
+	switch format {
+	case exportMARC21:
+		records = marc.NewReader(in)
+	case exportMARCXML:
+		records = marc.NewXMLReader(in)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid format, expected marc21 or marcxml",
+		})
+	}
+
+	mapping := h.marcMapping()
+	imp := h.newBookImport(c, dryRun)
+	imp.report.Unmapped = make(map[string]int)
+	for n := 1; ; n++ {
+		record, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		var recordErr *marc.RecordError
+		if errors.As(err, &recordErr) {
+			imp.fail(n, "", "Invalid MARC record: "+recordErr.Message)
+			continue
+		}
+		if err != nil {
+			if n == 1 {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Could not read MARC: " + err.Error(),
+				})
+			}
+			// The rest of the file cannot be found again
+			imp.fail(n, "", "Could not read MARC: "+err.Error())
+			break
+		}
+
+		for _, key := range mapping.Unmapped(record) {
+			imp.report.Unmapped[key]++
+		}
+		book, reason := marcBook(record, mapping)
+		if reason != "" {
+			imp.fail(n, book.ISBN, reason)
+			continue
+		}
+		imp.add(n, book)
+	}
+	return c.JSON(imp.finish())
+}
+
+// detectMARCFormat tells MARCXML, which starts with <, from binary MARC 21,
+// which starts with the record length
+func detectMARCFormat(in *bufio.Reader) string {
+	start, _ := in.Peek(512)
+	start = bytes.TrimLeft(bytes.TrimPrefix(start, []byte("\ufeff")), " \t\r\n")
+	switch {
+	case len(start) == 0:
+		return ""
+	case start[0] == '<':
+		return exportMARCXML
+	case start[0] >= '0' && start[0] <= '9':
+		return exportMARC21
+	}
+	return ""
+}
+
+// marcBook reads a book from a MARC record, or returns why the record
+// cannot be imported
+func marcBook(record *marc.Record, mapping marc.Mapping) (models.Book, string) {
+	book := models.Book{
+		Title:     mapping.Value(record, marc.FieldTitle),
+		Author:    mapping.Value(record, marc.FieldAuthor),
+		Category:  mapping.Value(record, marc.FieldCategory),
+		Publisher: mapping.Value(record, marc.FieldPublisher),
+		Year:      marcYear(mapping.Value(record, marc.FieldYear)),
+	}
+	// 020 $a may qualify the ISBN, as in "0306406152 (pbk.)"
+	if words := strings.Fields(mapping.Value(record, marc.FieldISBN)); len(words) > 0 {
+		book.ISBN = words[0]
+	}
+
+	required := []struct{ field, value string }{
+		{marc.FieldTitle, book.Title},
+		{marc.FieldAuthor, book.Author},
+		{marc.FieldISBN, book.ISBN},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			sources := make([]string, len(mapping[r.field]))
+			for i, source := range mapping[r.field] {
+				sources[i] = source.String()
+			}
+			return book, "Record has no " + r.field + " in " + strings.Join(sources, " or ")
+		}
+	}
+	canonical, reqErr := canonicalISBN(book.ISBN)
+	if reqErr != nil {
+		return book, reqErr.message
+	}
+	book.ISBN = canonical
+	return book, ""
+}
+
+// marcYear reads the first year of a date of publication such as "c2004."
+// or "[1998?]", or returns nil when it has none
+func marcYear(date string) *int {
+	isDigit := func(r rune) bool { return r >= '0' && r <= '9' }
+	for _, run := range strings.FieldsFunc(date, func(r rune) bool { return !isDigit(r) }) {
+		if year, _ := strconv.Atoi(run); len(run) == 4 && year >= 1 {
+			return &year
+		}
+	}
+	return nil
+}
+
+// marcRecord builds the MARC record of a book
+func marcRecord(book models.Book, mapping marc.Mapping) *marc.Record {
+	return mapping.Record(strconv.Itoa(book.ID), book.UpdatedAt, map[string]string{
+		marc.FieldTitle:     book.Title,
+		marc.FieldAuthor:    book.Author,
+		marc.FieldISBN:      book.ISBN,
+		marc.FieldCategory:  book.Category,
+		marc.FieldPublisher: book.Publisher,
+		marc.FieldYear:      exportInt(book.Year),
+	})
+}
+
+// writeMARC writes the records each produces as binary MARC 21 or as a
+// MARCXML collection
+func writeMARC(w io.Writer, format string, each func(write func(*marc.Record) error) error) error {
+	if format == exportMARC21 {
+		return each(marc.NewWriter(w).Write)
+	}
+	collection := marc.NewXMLWriter(w)
+	if err := each(collection.Write); err != nil {
+		return err
+	}
+	return collection.Close()
+}
+
+// @Summary Get the MARC record of a book
+// @Description Get a book as a MARC record, built with the configured field mapping
+// @Tags books
+// @Produce application/marcxml+xml
+// @Produce application/marc
+// @Param id path int true "Book ID"
+// @Param format query string false "marcxml (default) or marc21"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /books/{id}/marc [get]
+func (h *Handler) GetBookMARC(c *fiber.Ctx) error {
+	id, ok := paramID(c, "id")
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid book ID"})
+	}
+	format, reqErr := exportFormat(c, exportMARCXML, exportMARC21)
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+
+	book, err := h.Books.GetBook(c.UserContext(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Book not found"})
+		}
+		log.Printf("Error fetching book %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve book"})
+	}
+
+	var out bytes.Buffer
+	record := marcRecord(book, h.marcMapping())
+	if err := writeMARC(&out, format, func(write func(*marc.Record) error) error { return write(record) }); err != nil {
+		log.Printf("Error writing MARC record of book %d: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not build MARC record"})
+	}
+	c.Set(fiber.HeaderContentType, exportTypes[format][0])
+	return c.Send(out.Bytes())
+}
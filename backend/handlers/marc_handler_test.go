@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBookMARCRoundTrip(t *testing.T) {
+	for _, format := range []string{"marcxml", "marc21"} {
+		t.Run(format, func(t *testing.T) {
+			from := newTestServer(t)
+			alice := from.user(t, "alice", models.RoleUser)
+			year := 1965
+			book := &models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Category: "scifi", Publisher: "Chilton Books", Year: &year, Quantity: 1}
+			if err := from.store.CreateBook(context.Background(), book); err != nil {
+				t.Fatalf("CreateBook: %v", err)
+			}
+			status, record := from.do(t, alice, fiber.MethodGet, "/api/books/"+strconv.Itoa(book.ID)+"/marc?format="+format, nil)
+			if status != fiber.StatusOK {
+				t.Fatalf("GET marc = %d %s", status, record)
+			}
+
+			// Import the record, detecting its format, into another library
+			to := newTestServer(t)
+			admin := to.user(t, "admin", models.RoleAdmin)
+			req := httptest.NewRequest(fiber.MethodPost, "/api/books/import/marc", bytes.NewReader(record))
+			status, body := to.send(t, admin, req)
+			var report models.ImportReport
+			if err := json.Unmarshal(body, &report); status != fiber.StatusOK || err != nil {
+				t.Fatalf("import = %d %s", status, body)
+			}
+			if want := map[string]int{"001": 1, "005": 1}; report.Created != 1 || report.Errors != 0 || !reflect.DeepEqual(report.Unmapped, want) {
+				t.Errorf("report = %+v, want 1 created with 001 and 005 unmapped", report)
+			}
+
+			books, err := to.store.ListBooks(context.Background(), store.BookFilter{}, store.PageRequest{})
+			if err != nil || len(books.Data) != 1 {
+				t.Fatalf("ListBooks = %+v, %v", books, err)
+			}
+			got := books.Data[0]
+			if got.Title != book.Title || got.Author != book.Author || got.ISBN != book.ISBN || got.Category != book.Category ||
+				got.Publisher != book.Publisher || got.Year == nil || *got.Year != year {
+				t.Errorf("imported book = %+v, want %+v", got, *book)
+			}
+		})
+	}
+}
+
+func TestImportMARCErrors(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user(t, "admin", models.RoleAdmin)
+	alice := s.user(t, "alice", models.RoleUser)
+
+	const noISBN = `<collection xmlns="http://www.loc.gov/MARC21/slim"><record><leader>00000nam a2200000 a 4500</leader>` +
+		`<datafield tag="245" ind1="1" ind2="0"><subfield code="a">Dune</subfield></datafield>` +
+		`<datafield tag="100" ind1="1" ind2=" "><subfield code="a">Herbert, Frank</subfield></datafield>` +
+		`</record></collection>`
+	tests := []struct {
+		name   string
+		user   models.User
+		query  string
+		body   string
+		status int
+		errors int
+	}{
+		{"as a regular user", alice, "", noISBN, fiber.StatusForbidden, 0},
+		{"unknown format", admin, "?format=unimarc", noISBN, fiber.StatusBadRequest, 0},
+		{"undetected format", admin, "", "title,author,isbn\n", fiber.StatusBadRequest, 0},
+		{"record without an ISBN", admin, "?dry_run=true", noISBN, fiber.StatusOK, 1},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(fiber.MethodPost, "/api/books/import/marc"+tt.query, bytes.NewReader([]byte(tt.body)))
+		status, body := s.send(t, tt.user, req)
+		if status != tt.status {
+			t.Errorf("%s: import = %d %s, want %d", tt.name, status, body, tt.status)
+			continue
+		}
+		var report models.ImportReport
+		if status == fiber.StatusOK && (json.Unmarshal(body, &report) != nil || report.Errors != tt.errors) {
+			t.Errorf("%s: report = %s, want %d errors", tt.name, body, tt.errors)
+		}
+	}
+}
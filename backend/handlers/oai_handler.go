@@ -0,0 +1,478 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"digital-library/backend/citation"
+	"digital-library/backend/config"
+	"digital-library/backend/marc"
+	"digital-library/backend/models"
+	"digital-library/backend/oai"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// oaiPageSize is the number of headers, records or sets per page of an
+// OAI-PMH list
+const oaiPageSize = 100
+
+// oaiMARCXML is the MARCXML metadata format, built with the configured MARC
+// field mapping
+var oaiMARCXML = oai.MetadataFormat{
+	Prefix:    "marc21",
+	Schema:    "http://www.loc.gov/standards/marcxml/schema/MARC21slim.xsd",
+	Namespace: marc.Namespace,
+}
+
+// oaiFormats lists the metadata formats every record is disseminated in
+var oaiFormats = []oai.MetadataFormat{oai.FormatDC, oaiMARCXML}
+
+// oaiArguments lists the arguments each verb accepts, true for the required
+// ones. A resumptionToken must be the only argument besides the verb.
+var oaiArguments = map[string]map[string]bool{
+	oai.VerbIdentify:            {},
+	oai.VerbListMetadataFormats: {"identifier": false},
+	oai.VerbListSets:            {"resumptionToken": false},
+	oai.VerbListIdentifiers:     {"metadataPrefix": true, "from": false, "until": false, "set": false, "resumptionToken": false},
+	oai.VerbListRecords:         {"metadataPrefix": true, "from": false, "until": false, "set": false, "resumptionToken": false},
+	oai.VerbGetRecord:           {"identifier": true, "metadataPrefix": true},
+}
+
+// oaiToken is the state of a list that a resumption token continues. It
+// records the verb it was made for so it cannot be replayed with another.
+type oaiToken struct {
+	Verb   string     `json:"v"`
+	Prefix string     `json:"p,omitempty"`
+	From   *time.Time `json:"f,omitempty"`
+	Before *time.Time `json:"b,omitempty"`
+	Set    string     `json:"s,omitempty"`
+
+	// Last change listed, or last set
+	Datestamp time.Time `json:"d,omitempty"`
+	ID        int       `json:"id,omitempty"`
+	After     string    `json:"a,omitempty"`
+}
+
+func (t oaiToken) encode() string {
+	raw, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// resume decodes the resumption token of a request
+func resume(req oai.Request) (oaiToken, error) {
+	var t oaiToken
+	raw, err := base64.RawURLEncoding.DecodeString(req.ResumptionToken)
+	if err != nil || json.Unmarshal(raw, &t) != nil || t.Verb != req.Verb {
+		return t, &oai.Error{Code: oai.ErrBadResumptionToken, Message: "Invalid resumption token"}
+	}
+	return t, nil
+}
+
+// @Summary OAI-PMH data provider
+// @Description OAI-PMH 2.0 endpoint for metadata harvesters, open without signing in. It supports the Identify, ListMetadataFormats, ListSets, ListIdentifiers, ListRecords and GetRecord verbs, records in oai_dc and marc21 (MARCXML), one set per category, selective harvesting by the date books were last changed, and persistent deleted records. Lists are paged 100 at a time with resumption tokens. Arguments may also be sent as a form with POST.
+// @Tags oai
+// @Produce xml
+// @Param verb query string true "OAI-PMH verb"
+// @Param identifier query string false "Item identifier, such as oai:digital-library:42"
+// @Param metadataPrefix query string false "oai_dc or marc21"
+// @Param from query string false "Changed on or after, as YYYY-MM-DD or YYYY-MM-DDThh:mm:ssZ"
+// @Param until query string false "Changed on or before, as YYYY-MM-DD or YYYY-MM-DDThh:mm:ssZ"
+// @Param set query string false "Set spec of a category"
+// @Param resumptionToken query string false "Token continuing a list"
+// @Success 200 {string} string "OAI-PMH response"
+// @Failure 500 {object} map[string]string
+// @Router /oai [get]
+func (h *Handler) OAI(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		resp := oai.Response{Date: time.Now()}
+		req, err := oaiRequest(c)
+		var result interface{}
+		if err == nil {
+			result, err = h.oaiVerb(c, cfg, req)
+		}
+		var oaiErr *oai.Error
+		switch {
+		case errors.As(err, &oaiErr):
+			resp.Errors = []*oai.Error{oaiErr}
+		case err != nil:
+			log.Printf("Error answering OAI-PMH %s request: %v", req.Verb, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not answer OAI-PMH request"})
+		}
+		resp.Request, resp.Result = req, result
+
+		var out bytes.Buffer
+		if err := oai.Write(&out, resp); err != nil {
+			log.Printf("Error writing OAI-PMH response: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not write OAI-PMH response"})
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextXMLCharsetUTF8)
+		return c.Send(out.Bytes())
+	}
+}
+
+// oaiRequest reads the arguments of a request from the query, or from the
+// form of a POST, and checks them against its verb
+func oaiRequest(c *fiber.Ctx) (oai.Request, error) {
+	args := c.Context().QueryArgs()
+	if c.Method() == fiber.MethodPost {
+		args = c.Request().PostArgs()
+	}
+	values := map[string]string{}
+	var repeated []string
+	args.VisitAll(func(key, value []byte) {
+		if _, ok := values[string(key)]; ok {
+			repeated = append(repeated, string(key))
+		}
+		values[string(key)] = string(value)
+	})
+
+	req := oai.Request{
+		BaseURL:         c.BaseURL() + "/api/oai",
+		Verb:            values["verb"],
+		Identifier:      values["identifier"],
+		MetadataPrefix:  values["metadataPrefix"],
+		From:            values["from"],
+		Until:           values["until"],
+		Set:             values["set"],
+		ResumptionToken: values["resumptionToken"],
+	}
+	accepted, ok := oaiArguments[req.Verb]
+	if !ok {
+		return req, &oai.Error{Code: oai.ErrBadVerb, Message: "Illegal or missing verb"}
+	}
+	if len(repeated) > 0 {
+		return req, &oai.Error{Code: oai.ErrBadArgument, Message: "Repeated argument " + repeated[0]}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := accepted[name]; !ok && name != "verb" {
+			return req, &oai.Error{Code: oai.ErrBadArgument, Message: "Illegal argument " + name}
+		}
+	}
+	if req.ResumptionToken != "" {
+		if len(values) > 2 {
+			return req, &oai.Error{Code: oai.ErrBadArgument, Message: "resumptionToken is an exclusive argument"}
+		}
+		return req, nil
+	}
+	// These are the only arguments a verb requires
+	for _, name := range []string{"identifier", "metadataPrefix"} {
+		if accepted[name] && values[name] == "" {
+			return req, &oai.Error{Code: oai.ErrBadArgument, Message: "Missing argument " + name}
+		}
+	}
+	return req, nil
+}
+
+// oaiVerb answers a checked request
+func (h *Handler) oaiVerb(c *fiber.Ctx, cfg *config.Config, req oai.Request) (interface{}, error) {
+	switch req.Verb {
+	case oai.VerbIdentify:
+		earliest, err := h.Books.EarliestBookChange(c.UserContext())
+		if err != nil {
+			return nil, err
+		}
+		return &oai.Identify{
+			RepositoryName:    cfg.OAIRepositoryName,
+			BaseURL:           req.BaseURL,
+			ProtocolVersion:   "2.0",
+			AdminEmails:       []string{cfg.OAIAdminEmail},
+			EarliestDatestamp: oai.Datestamp(earliest),
+			DeletedRecord:     "persistent",
+			Granularity:       oai.Granularity,
+		}, nil
+	case oai.VerbListMetadataFormats:
+		if req.Identifier != "" {
+			if _, err := h.oaiChange(c, cfg, req.Identifier); err != nil {
+				return nil, err
+			}
+		}
+		return &oai.ListMetadataFormats{Formats: oaiFormats}, nil
+	case oai.VerbListSets:
+		return h.oaiSets(c, req)
+	case oai.VerbGetRecord:
+		if err := checkOAIFormat(req.MetadataPrefix); err != nil {
+			return nil, err
+		}
+		change, err := h.oaiChange(c, cfg, req.Identifier)
+		if err != nil {
+			return nil, err
+		}
+		return &oai.GetRecord{Record: h.oaiRecord(cfg, change, req.MetadataPrefix)}, nil
+	}
+	return h.oaiList(c, cfg, req)
+}
+
+// oaiList answers ListIdentifiers and ListRecords with a page of the books
+// changed in the requested range and set
+func (h *Handler) oaiList(c *fiber.Ctx, cfg *config.Config, req oai.Request) (interface{}, error) {
+	state := oaiToken{Verb: req.Verb, Prefix: req.MetadataPrefix}
+	if req.ResumptionToken != "" {
+		var err error
+		if state, err = resume(req); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := checkOAIFormat(req.MetadataPrefix); err != nil {
+			return nil, err
+		}
+		var err error
+		if state.From, state.Before, err = oaiRange(req.From, req.Until); err != nil {
+			return nil, err
+		}
+		if req.Set != "" {
+			category, ok := setSpecCategory(req.Set)
+			if !ok {
+				return nil, &oai.Error{Code: oai.ErrNoRecordsMatch, Message: "No set " + req.Set}
+			}
+			state.Set = category
+		}
+	}
+
+	changes, err := h.Books.ListBookChanges(c.UserContext(), store.ChangeFilter{
+		From:           state.From,
+		Before:         state.Before,
+		Category:       state.Set,
+		AfterDatestamp: state.Datestamp,
+		AfterID:        state.ID,
+	}, oaiPageSize+1)
+	if err != nil {
+		return nil, err
+	}
+	// A list cannot be empty. A resumed list comes up empty when the books
+	// left to list changed again outside the range since the last page.
+	if len(changes) == 0 {
+		return nil, &oai.Error{Code: oai.ErrNoRecordsMatch, Message: "No books changed in the given range and set"}
+	}
+
+	// Continue after the last change of a full page; close a resumed list
+	var token *oai.ResumptionToken
+	if len(changes) > oaiPageSize {
+		changes = changes[:oaiPageSize]
+		last := changes[len(changes)-1]
+		state.Datestamp, state.ID = last.Datestamp, last.BookID
+		token = &oai.ResumptionToken{Value: state.encode()}
+	} else if req.ResumptionToken != "" {
+		token = &oai.ResumptionToken{}
+	}
+
+	if req.Verb == oai.VerbListIdentifiers {
+		list := &oai.ListIdentifiers{ResumptionToken: token}
+		for _, change := range changes {
+			list.Headers = append(list.Headers, oaiHeader(cfg, change))
+		}
+		return list, nil
+	}
+	list := &oai.ListRecords{ResumptionToken: token}
+	for _, change := range changes {
+		list.Records = append(list.Records, h.oaiRecord(cfg, change, state.Prefix))
+	}
+	return list, nil
+}
+
+// oaiSets answers ListSets with a page of the categories
+func (h *Handler) oaiSets(c *fiber.Ctx, req oai.Request) (interface{}, error) {
+	state := oaiToken{Verb: req.Verb}
+	if req.ResumptionToken != "" {
+		var err error
+		if state, err = resume(req); err != nil {
+			return nil, err
+		}
+	}
+	categories, err := h.Books.ListFacetValues(c.UserContext(), "category", state.After, oaiPageSize+1)
+	if err != nil {
+		return nil, err
+	}
+	// A list of sets cannot be empty either. A resumed one comes up empty
+	// when the categories left to list were removed since the last page.
+	if len(categories) == 0 {
+		if req.ResumptionToken != "" {
+			return nil, &oai.Error{Code: oai.ErrBadResumptionToken, Message: "The list of sets has changed, start it again"}
+		}
+		return nil, &oai.Error{Code: oai.ErrNoSetHierarchy, Message: "No books have a category to group them in sets"}
+	}
+
+	list := &oai.ListSets{}
+	if len(categories) > oaiPageSize {
+		categories = categories[:oaiPageSize]
+		state.After = categories[len(categories)-1].Value
+		list.ResumptionToken = &oai.ResumptionToken{Value: state.encode()}
+	} else if req.ResumptionToken != "" {
+		list.ResumptionToken = &oai.ResumptionToken{}
+	}
+	for _, category := range categories {
+		list.Sets = append(list.Sets, oai.Set{Spec: categorySetSpec(category.Value), Name: category.Value})
+	}
+	return list, nil
+}
+
+// oaiChange returns the latest change to the book an identifier names
+func (h *Handler) oaiChange(c *fiber.Ctx, cfg *config.Config, identifier string) (models.BookChange, error) {
+	notFound := &oai.Error{Code: oai.ErrIDDoesNotExist, Message: "No item " + identifier}
+	local, ok := strings.CutPrefix(identifier, "oai:"+cfg.OAIRepositoryID+":")
+	if !ok {
+		return models.BookChange{}, notFound
+	}
+	id, err := strconv.Atoi(local)
+	if err != nil || id <= 0 {
+		return models.BookChange{}, notFound
+	}
+	change, err := h.Books.GetBookChange(c.UserContext(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		return change, notFound
+	}
+	return change, err
+}
+
+// checkOAIFormat checks that records can be disseminated in a metadata format
+func checkOAIFormat(prefix string) error {
+	for _, format := range oaiFormats {
+		if format.Prefix == prefix {
+			return nil
+		}
+	}
+	return &oai.Error{Code: oai.ErrCannotDisseminateFormat, Message: "Unsupported metadata format " + prefix + ", expected oai_dc or marc21"}
+}
+
+// oaiRange parses the from and until arguments of a list into the times
+// changes must be at or after, and before. Until takes in the whole day or
+// second it names.
+func oaiRange(from, until string) (*time.Time, *time.Time, error) {
+	invalid := func(name string) error {
+		return &oai.Error{Code: oai.ErrBadArgument, Message: "Invalid " + name + ", expected YYYY-MM-DD or YYYY-MM-DDThh:mm:ssZ"}
+	}
+	var start, before *time.Time
+	var startDay, beforeDay bool
+	if from != "" {
+		t, day, err := oai.ParseDatestamp(from)
+		if err != nil {
+			return nil, nil, invalid("from")
+		}
+		start, startDay = &t, day
+	}
+	if until != "" {
+		t, day, err := oai.ParseDatestamp(until)
+		if err != nil {
+			return nil, nil, invalid("until")
+		}
+		if day {
+			t = t.AddDate(0, 0, 1)
+		} else {
+			t = t.Add(time.Second)
+		}
+		before, beforeDay = &t, day
+	}
+	if start != nil && before != nil {
+		if startDay != beforeDay {
+			return nil, nil, &oai.Error{Code: oai.ErrBadArgument, Message: "from and until must have the same granularity"}
+		}
+		if !start.Before(*before) {
+			return nil, nil, &oai.Error{Code: oai.ErrBadArgument, Message: "from must not be after until"}
+		}
+	}
+	return start, before, nil
+}
+
+// oaiHeader identifies the record of a book change. Deleted books keep the
+// set of their last category.
+func oaiHeader(cfg *config.Config, change models.BookChange) oai.Header {
+	header := oai.Header{
+		Identifier: "oai:" + cfg.OAIRepositoryID + ":" + strconv.Itoa(change.BookID),
+		Datestamp:  oai.Datestamp(change.Datestamp),
+	}
+	if change.Deleted {
+		header.Status = "deleted"
+	}
+	if change.Category != "" {
+		header.SetSpecs = []string{categorySetSpec(change.Category)}
+	}
+	return header
+}
+
+// oaiRecord builds the record of a book change in a metadata format
+func (h *Handler) oaiRecord(cfg *config.Config, change models.BookChange, prefix string) oai.Record {
+	record := oai.Record{Header: oaiHeader(cfg, change)}
+	if change.Deleted {
+		return record
+	}
+	if prefix == oaiMARCXML.Prefix {
+		record.Metadata = &oai.Metadata{Value: marcRecord(*change.Book, h.marcMapping())}
+	} else {
+		record.Metadata = &oai.Metadata{Value: dublinCore(*change.Book)}
+	}
+	return record
+}
+
+// dublinCore describes a book in simple Dublin Core, with authors written
+// family name first
+func dublinCore(book models.Book) *oai.DC {
+	dc := &oai.DC{
+		Titles:      []string{book.Title},
+		Types:       []string{"Text"},
+		Identifiers: []string{"urn:isbn:" + book.ISBN},
+	}
+	for _, name := range citation.ParseNames(book.Author) {
+		dc.Creators = append(dc.Creators, name.Inverted())
+	}
+	if book.Category != "" {
+		dc.Subjects = []string{book.Category}
+	}
+	if book.Publisher != "" {
+		dc.Publishers = []string{book.Publisher}
+	}
+	if book.Year != nil {
+		dc.Dates = []string{strconv.Itoa(*book.Year)}
+	}
+	return dc
+}
+
+// categorySetSpec makes the set spec of a category. Set specs only allow
+// letters, digits and -_.!*'(), so other bytes are written as ~ and two hex
+// digits: "Science Fiction" is Science~20Fiction.
+func categorySetSpec(category string) string {
+	var b strings.Builder
+	for i := 0; i < len(category); i++ {
+		ch := category[i]
+		if 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || strings.IndexByte("-_.!*'()", ch) >= 0 {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "~%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// setSpecCategory reads the category of a set spec made by categorySetSpec
+func setSpecCategory(spec string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(spec); i++ {
+		if spec[i] != '~' {
+			b.WriteByte(spec[i])
+			continue
+		}
+		if i+2 >= len(spec) {
+			return "", false
+		}
+		n, err := strconv.ParseUint(spec[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", false
+		}
+		b.WriteByte(byte(n))
+		i += 2
+	}
+	return b.String(), true
+}
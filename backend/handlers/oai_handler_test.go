@@ -0,0 +1,183 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"digital-library/backend/isbn"
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// oaiResponse is the part of an OAI-PMH response the tests read
+type oaiResponse struct {
+	Request string `xml:"request"`
+	Error   struct {
+		Code string `xml:"code,attr"`
+	} `xml:"error"`
+	Identify struct {
+		RepositoryName string `xml:"repositoryName"`
+		BaseURL        string `xml:"baseURL"`
+	} `xml:"Identify"`
+	Headers         []oaiHeader `xml:"ListIdentifiers>header"`
+	Records         []oaiRecord `xml:"ListRecords>record"`
+	Record          oaiRecord   `xml:"GetRecord>record"`
+	Sets            []string    `xml:"ListSets>set>setSpec"`
+	ResumptionToken string      `xml:"ListIdentifiers>resumptionToken"`
+}
+
+type oaiHeader struct {
+	Status     string   `xml:"status,attr"`
+	Identifier string   `xml:"identifier"`
+	SetSpecs   []string `xml:"setSpec"`
+}
+
+type oaiRecord struct {
+	Header  oaiHeader `xml:"header"`
+	Titles  []string  `xml:"metadata>dc>title"`
+	Leader  string    `xml:"metadata>record>leader"`
+	Creator []string  `xml:"metadata>dc>creator"`
+}
+
+// oai sends an OAI-PMH request with the query, or as a form when post is
+// set, and decodes the response
+func (s *testServer) oai(t *testing.T, query string, post bool) oaiResponse {
+	t.Helper()
+	req := httptest.NewRequest(fiber.MethodGet, "/api/oai?"+query, nil)
+	if post {
+		req = httptest.NewRequest(fiber.MethodPost, "/api/oai", strings.NewReader(query))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+	}
+	status, body := s.send(t, models.User{}, req)
+	var resp oaiResponse
+	if err := xml.Unmarshal(body, &resp); status != fiber.StatusOK || err != nil {
+		t.Fatalf("OAI-PMH %s = %d %s", query, status, body)
+	}
+	return resp
+}
+
+func TestOAI(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.OAIRepositoryName, s.cfg.OAIRepositoryID = "Test Library", "test"
+	admin := s.user(t, "admin", models.RoleAdmin)
+	var ids []string
+	for _, book := range []*models.Book{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Category: "Science Fiction"},
+		{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Category: "classic"},
+		{Title: "Persuasion", Author: "Jane Austen", ISBN: "9780141439686", Category: "classic"},
+	} {
+		if err := s.store.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+		ids = append(ids, "oai:test:"+strconv.Itoa(book.ID))
+	}
+	emma := strings.TrimPrefix(ids[1], "oai:test:")
+	if status, body := s.do(t, admin, fiber.MethodDelete, "/api/books/"+emma, nil); status != fiber.StatusOK {
+		t.Fatalf("delete = %d %s", status, body)
+	}
+
+	identify := s.oai(t, "verb=Identify", false)
+	if identify.Identify.RepositoryName != "Test Library" || !strings.HasSuffix(identify.Identify.BaseURL, "/api/oai") {
+		t.Errorf("Identify = %+v", identify.Identify)
+	}
+
+	list := s.oai(t, "verb=ListIdentifiers&metadataPrefix=oai_dc", false)
+	want := []oaiHeader{
+		{Identifier: ids[0], SetSpecs: []string{"Science~20Fiction"}},
+		{Identifier: ids[2], SetSpecs: []string{"classic"}},
+		{Status: "deleted", Identifier: ids[1], SetSpecs: []string{"classic"}},
+	}
+	if fmt.Sprint(list.Headers) != fmt.Sprint(want) {
+		t.Errorf("headers = %+v, want %+v", list.Headers, want)
+	}
+
+	sets := s.oai(t, "verb=ListSets", true)
+	if fmt.Sprint(sets.Sets) != "[Science~20Fiction classic]" {
+		t.Errorf("sets = %v, want Science~20Fiction and classic", sets.Sets)
+	}
+	records := s.oai(t, "verb=ListRecords&metadataPrefix=oai_dc&set=Science~20Fiction", true)
+	if len(records.Records) != 1 || fmt.Sprint(records.Records[0].Titles) != "[Dune]" || fmt.Sprint(records.Records[0].Creator) != "[Herbert, Frank]" {
+		t.Errorf("records of Science~20Fiction = %+v, want Dune", records.Records)
+	}
+
+	record := s.oai(t, "verb=GetRecord&metadataPrefix=marc21&identifier="+url.QueryEscape(ids[0]), false)
+	if record.Record.Header.Identifier != ids[0] || record.Record.Leader == "" {
+		t.Errorf("MARCXML record = %+v", record.Record)
+	}
+	deleted := s.oai(t, "verb=GetRecord&metadataPrefix=oai_dc&identifier="+url.QueryEscape(ids[1]), false)
+	if deleted.Record.Header.Status != "deleted" || len(deleted.Record.Titles) != 0 {
+		t.Errorf("deleted record = %+v, want a header without metadata", deleted.Record)
+	}
+
+	failures := []struct {
+		query string
+		code  string
+	}{
+		{"", "badVerb"},
+		{"verb=Explode", "badVerb"},
+		{"verb=ListRecords", "badArgument"},
+		{"verb=Identify&set=classic", "badArgument"},
+		{"verb=ListRecords&metadataPrefix=oai_dc&metadataPrefix=marc21", "badArgument"},
+		{"verb=ListRecords&metadataPrefix=oai_dc&resumptionToken=abc", "badArgument"},
+		{"verb=ListRecords&resumptionToken=abc", "badResumptionToken"},
+		{"verb=ListRecords&metadataPrefix=mods", "cannotDisseminateFormat"},
+		{"verb=ListRecords&metadataPrefix=oai_dc&from=2024-03-01&until=2024-03-01T00:00:00Z", "badArgument"},
+		{"verb=ListRecords&metadataPrefix=oai_dc&from=2999-01-01", "noRecordsMatch"},
+		{"verb=ListRecords&metadataPrefix=oai_dc&set=unknown", "noRecordsMatch"},
+		{"verb=GetRecord&metadataPrefix=oai_dc&identifier=oai:other:1", "idDoesNotExist"},
+		{"verb=ListMetadataFormats&identifier=oai:test:9999", "idDoesNotExist"},
+	}
+	for _, tt := range failures {
+		if resp := s.oai(t, tt.query, false); resp.Error.Code != tt.code {
+			t.Errorf("%s: error = %q, want %s", tt.query, resp.Error.Code, tt.code)
+		}
+	}
+}
+
+func TestOAIEmptyLists(t *testing.T) {
+	s := newTestServer(t)
+	s.book(t, "9780441172719", 1) // Without a category
+
+	failures := []struct {
+		query string
+		code  string
+	}{
+		{"verb=ListSets", "noSetHierarchy"},
+		{"verb=ListSets&resumptionToken=abc", "badResumptionToken"},
+		{"verb=ListIdentifiers&metadataPrefix=oai_dc&set=scifi", "noRecordsMatch"},
+	}
+	for _, tt := range failures {
+		if resp := s.oai(t, tt.query, false); resp.Error.Code != tt.code {
+			t.Errorf("%s: error = %q, want %s", tt.query, resp.Error.Code, tt.code)
+		}
+	}
+}
+
+func TestOAIResumption(t *testing.T) {
+	s := newTestServer(t)
+	for i := 0; i < 101; i++ {
+		book := &models.Book{Title: "Book", Author: "Author", ISBN: isbn.To13(fmt.Sprintf("%09d0", i))}
+		if err := s.store.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+	}
+
+	first := s.oai(t, "verb=ListIdentifiers&metadataPrefix=oai_dc", false)
+	if len(first.Headers) != 100 || first.ResumptionToken == "" {
+		t.Fatalf("first page = %d headers with token %q, want 100 and a token", len(first.Headers), first.ResumptionToken)
+	}
+	if resp := s.oai(t, "verb=ListRecords&resumptionToken="+first.ResumptionToken, false); resp.Error.Code != "badResumptionToken" {
+		t.Errorf("token of another verb = %q, want badResumptionToken", resp.Error.Code)
+	}
+	second := s.oai(t, "verb=ListIdentifiers&resumptionToken="+first.ResumptionToken, false)
+	if len(second.Headers) != 1 || second.ResumptionToken != "" || second.Headers[0].Identifier == first.Headers[99].Identifier {
+		t.Errorf("second page = %+v, want the last book and an empty token", second)
+	}
+}
@@ -0,0 +1,436 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"digital-library/backend/citation"
+	"digital-library/backend/config"
+	"digital-library/backend/middleware"
+	"digital-library/backend/models"
+	"digital-library/backend/opds"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// OPDS catalog versions, served as Atom feeds under /api/opds and as JSON
+// under /api/opds/v2
+const (
+	OPDS1 = "1.2"
+	OPDS2 = "2.0"
+)
+
+// opdsFacetPage is the number of categories or authors per page of their
+// navigation feeds
+const opdsFacetPage = 100
+
+// opdsCatalog builds the feeds of one OPDS request
+type opdsCatalog struct {
+	c       *fiber.Ctx
+	version string
+	base    string // URL of the catalog root
+	token   string // ?token= feed token the app authenticated with, kept in every link
+}
+
+func newOPDSCatalog(c *fiber.Ctx, version string) *opdsCatalog {
+	base := c.BaseURL() + "/api/opds"
+	if version == OPDS2 {
+		base += "/v2"
+	}
+	return &opdsCatalog{c: c, version: version, base: base, token: c.Query("token", "")}
+}
+
+// id returns the URL of a feed without the token, to identify it
+func (cat *opdsCatalog) id(path string, params url.Values) string {
+	u := cat.base + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	return u
+}
+
+// url returns the URL of a feed, carrying the token over
+func (cat *opdsCatalog) url(path string, params url.Values) string {
+	if cat.token != "" {
+		with := url.Values{"token": {cat.token}}
+		for key, values := range params {
+			with[key] = values
+		}
+		params = with
+	}
+	return cat.id(path, params)
+}
+
+// send writes a feed with the links every feed has
+func (cat *opdsCatalog) send(feed opds.Feed) error {
+	feed.Links = append(feed.Links, opds.Link{Rel: opds.RelStart, Href: cat.url("", nil), Kind: opds.KindNavigation, Title: "Digital Library"})
+	if cat.version == OPDS2 {
+		search := cat.base + "/books{?search}"
+		if cat.token != "" {
+			search = cat.base + "/books?token=" + url.QueryEscape(cat.token) + "{&search}"
+		}
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelSearch, Href: search, Kind: opds.KindAcquisition, Templated: true})
+	} else {
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelSearch, Href: cat.url("/opensearch.xml", nil), Type: opds.TypeOpenSearch})
+	}
+
+	var buf bytes.Buffer
+	var err error
+	if cat.version == OPDS2 {
+		cat.c.Set(fiber.HeaderContentType, opds.TypeJSON)
+		err = opds.WriteJSON(&buf, feed)
+	} else {
+		cat.c.Set(fiber.HeaderContentType, opds.AtomType(feed.Kind)+";charset=utf-8")
+		err = opds.WriteAtom(&buf, feed)
+	}
+	if err != nil {
+		log.Printf("Error writing OPDS feed %s: %v", feed.ID, err)
+		return cat.c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not write feed"})
+	}
+	return cat.c.Send(buf.Bytes())
+}
+
+// @Summary Issue an OPDS feed token
+// @Description Issue a token for reading apps that cannot send credentials. Passed as the token query parameter, it opens the OPDS catalog, and only the catalog, as the current user until it expires (FEED_TOKEN_TTL, 24 hours by default). The response includes the catalog URLs with the token, to add to an app.
+// @Tags opds
+// @Produce json
+// @Success 200 {object} models.FeedTokenResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /opds/token [post]
+func (h *Handler) CreateFeedToken(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := middleware.CurrentUser(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid or expired JWT"})
+		}
+		token, expires, err := middleware.NewFeedToken(cfg, claims)
+		if err != nil {
+			log.Printf("Error issuing feed token for user %d: %v", claims.UserID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate token"})
+		}
+		query := "?token=" + url.QueryEscape(token)
+		return c.JSON(models.FeedTokenResponse{
+			Token:     token,
+			ExpiresAt: expires,
+			OPDSURL:   newOPDSCatalog(c, OPDS1).base + query,
+			OPDS2URL:  newOPDSCatalog(c, OPDS2).base + query,
+		})
+	}
+}
+
+// @Summary OPDS catalog
+// @Description Root navigation feed of the OPDS catalog for reading apps, as an OPDS 1.2 Atom feed under /opds or OPDS 2.0 JSON under /opds/v2. Apps sign in with HTTP Basic or a Bearer JWT, or pass a feed token from POST /opds/token as the token query parameter, which the links of every feed keep.
+// @Tags opds
+// @Produce xml
+// @Produce json
+// @Param token query string false "Feed token from POST /opds/token, for apps that cannot send headers"
+// @Success 200 {string} string "OPDS navigation feed"
+// @Failure 401 {object} map[string]string
+// @Router /opds [get]
+func (h *Handler) OPDSRoot(version string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		cat := newOPDSCatalog(c, version)
+		now := time.Now()
+		entry := func(path, title, summary, kind string) opds.Navigation {
+			return opds.Navigation{ID: cat.id(path, nil), Title: title, Summary: summary, Href: cat.url(path, nil), Kind: kind, Updated: now}
+		}
+		return cat.send(opds.Feed{
+			ID:      cat.id("", nil),
+			Title:   "Digital Library",
+			Kind:    opds.KindNavigation,
+			Updated: now,
+			Links:   []opds.Link{{Rel: opds.RelSelf, Href: cat.url("", nil), Kind: opds.KindNavigation}},
+			Navigation: []opds.Navigation{
+				entry("/new", "New arrivals", "Books most recently added to the library", opds.KindAcquisition),
+				entry("/books", "All books", "Every book in the catalog by title", opds.KindAcquisition),
+				entry("/categories", "By category", "Books grouped by category", opds.KindNavigation),
+				entry("/authors", "By author", "Books grouped by author", opds.KindNavigation),
+			},
+		})
+	}
+}
+
+// @Summary OPDS acquisition feed
+// @Description Books of the OPDS catalog, filtered like GET /books and paged with next and first links. Each book links to its record with the number of available copies and to its MARCXML record.
+// @Tags opds
+// @Produce xml
+// @Produce json
+// @Param search query string false "Search title, author and ISBN"
+// @Param category query string false "Filter by category"
+// @Param author query string false "Filter by author"
+// @Param available query bool false "Filter by availability"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "Cursor of the page to fetch"
+// @Param sort query string false "Sort field: title, author, created_at, quantity or relevance"
+// @Param order query string false "Sort order: asc or desc"
+// @Param token query string false "Feed token from POST /opds/token, for apps that cannot send headers"
+// @Success 200 {string} string "OPDS acquisition feed"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /opds/books [get]
+func (h *Handler) OPDSBooks(version string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return h.opdsBooks(c, newOPDSCatalog(c, version), "/books", opdsTitle(c), store.PageRequest{})
+	}
+}
+
+// @Summary OPDS new arrivals
+// @Description Books of the OPDS catalog newest first, filtered and paged like /opds/books
+// @Tags opds
+// @Produce xml
+// @Produce json
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Param cursor query string false "Cursor of the page to fetch"
+// @Param token query string false "Feed token from POST /opds/token, for apps that cannot send headers"
+// @Success 200 {string} string "OPDS acquisition feed"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /opds/new [get]
+func (h *Handler) OPDSNewArrivals(version string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return h.opdsBooks(c, newOPDSCatalog(c, version), "/new", "New arrivals", store.PageRequest{Sort: "created_at", Order: "desc"})
+	}
+}
+
+// opdsTitle names an acquisition feed after its filter
+func opdsTitle(c *fiber.Ctx) string {
+	switch {
+	case c.Query("search", "") != "":
+		return "Search: " + c.Query("search", "")
+	case c.Query("category", "") != "":
+		return c.Query("category", "")
+	case c.Query("author", "") != "":
+		return c.Query("author", "")
+	}
+	return "All books"
+}
+
+// opdsBooks writes an acquisition feed of the books at path, in the order
+// of sort unless the request asks for another
+func (h *Handler) opdsBooks(c *fiber.Ctx, cat *opdsCatalog, path, title string, sort store.PageRequest) error {
+	filter, errBody := bookFilter(c)
+	if errBody != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errBody)
+	}
+	page, reqErr := queryPage(c)
+	if reqErr != nil {
+		return reqErr.send(c)
+	}
+	if page.Sort == "" {
+		page.Sort, page.Order = sort.Sort, sort.Order
+	}
+	if page.Sort == "relevance" && filter.Search == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Sorting by relevance requires a search"})
+	}
+
+	books, err := h.Books.ListBooks(c.UserContext(), filter, page)
+	if err != nil {
+		if reqErr := pageError(err, store.BookSortFields); reqErr != nil {
+			return reqErr.send(c)
+		}
+		log.Printf("Error fetching OPDS books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve books"})
+	}
+
+	// Links keep the request's filters and move its cursor
+	params := url.Values{}
+	for key, value := range c.Queries() {
+		if key != "token" && key != "cursor" {
+			params.Set(key, value)
+		}
+	}
+	first := cat.url(path, params)
+	self := first
+	if page.Cursor != "" {
+		params.Set("cursor", page.Cursor)
+		self = cat.url(path, params)
+	}
+
+	feed := opds.Feed{
+		ID:           cat.id(path, params),
+		Title:        title,
+		Kind:         opds.KindAcquisition,
+		Links:        []opds.Link{{Rel: opds.RelSelf, Href: self, Kind: opds.KindAcquisition}},
+		TotalResults: books.Total,
+		ItemsPerPage: page.Limit,
+	}
+	if page.Cursor != "" {
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelFirst, Href: first, Kind: opds.KindAcquisition})
+	}
+	if books.NextCursor != "" {
+		params.Set("cursor", books.NextCursor)
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelNext, Href: cat.url(path, params), Kind: opds.KindAcquisition})
+	}
+
+	// Each book links to its files, through the catalog so apps can
+	// download them with the credentials they browse with
+	ids := make([]int, len(books.Data))
+	for i, book := range books.Data {
+		ids[i] = book.ID
+	}
+	files, err := h.Files.ListFilesOfBooks(c.UserContext(), ids)
+	if err != nil {
+		log.Printf("Error fetching files of OPDS books: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve books"})
+	}
+	bookFiles := make(map[int][]models.BookFile)
+	for _, file := range files {
+		bookFiles[file.BookID] = append(bookFiles[file.BookID], file)
+	}
+
+	for _, book := range books.Data {
+		feed.Publications = append(feed.Publications, opdsPublication(c, cat, book, bookFiles[book.ID]))
+		if book.UpdatedAt.After(feed.Updated) {
+			feed.Updated = book.UpdatedAt
+		}
+	}
+	return cat.send(feed)
+}
+
+// opdsPublication describes a book for an acquisition feed. Borrowing
+// happens in the library, so the borrow link leads to the book's record
+// with its available copies. Acquisition links lead to the book's files,
+// which download while the user has the book on loan.
+func opdsPublication(c *fiber.Ctx, cat *opdsCatalog, book models.Book, files []models.BookFile) opds.Publication {
+	record := c.BaseURL() + "/api/books/" + strconv.Itoa(book.ID)
+	available := book.Quantity > 0
+	pub := opds.Publication{
+		ID:         "urn:isbn:" + book.ISBN,
+		Title:      book.Title,
+		Publisher:  book.Publisher,
+		Identifier: "urn:isbn:" + book.ISBN,
+		Updated:    book.UpdatedAt,
+		Links: []opds.Link{
+			{Rel: opds.RelBorrow, Href: record, Type: fiber.MIMEApplicationJSON, Available: &available, Copies: book.Quantity},
+			{Rel: opds.RelDescribedBy, Href: record + "/marc", Type: "application/marcxml+xml", Title: "MARCXML record"},
+		},
+	}
+	for _, file := range files {
+		path := "/books/" + strconv.Itoa(book.ID) + "/files/" + strconv.Itoa(file.ID)
+		pub.Links = append(pub.Links, opds.Link{Rel: opds.RelAcquisition, Href: cat.url(path, nil), Type: file.MimeType, Title: file.Filename})
+	}
+	for _, name := range citation.ParseNames(book.Author) {
+		pub.Authors = append(pub.Authors, name.Direct())
+	}
+	if book.Year != nil {
+		pub.Year = *book.Year
+	}
+	if book.Category != "" {
+		pub.Subjects = []string{book.Category}
+	}
+	return pub
+}
+
+// @Summary OPDS categories
+// @Description Navigation feed of the categories of the OPDS catalog, 100 per page in order, each leading to its books
+// @Tags opds
+// @Produce xml
+// @Produce json
+// @Param after query string false "Last category of the previous page"
+// @Param token query string false "Feed token from POST /opds/token, for apps that cannot send headers"
+// @Success 200 {string} string "OPDS navigation feed"
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /opds/categories [get]
+func (h *Handler) OPDSCategories(version string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return h.opdsFacet(c, newOPDSCatalog(c, version), "/categories", "category", "By category")
+	}
+}
+
+// @Summary OPDS authors
+// @Description Navigation feed of the authors of the OPDS catalog, 100 per page in order, each leading to their books
+// @Tags opds
+// @Produce xml
+// @Produce json
+// @Param after query string false "Last author of the previous page"
+// @Param token query string false "Feed token from POST /opds/token, for apps that cannot send headers"
+// @Success 200 {string} string "OPDS navigation feed"
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /opds/authors [get]
+func (h *Handler) OPDSAuthors(version string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return h.opdsFacet(c, newOPDSCatalog(c, version), "/authors", "author", "By author")
+	}
+}
+
+// opdsFacet writes a navigation feed of the values of a book facet, each
+// leading to the acquisition feed filtered by it
+func (h *Handler) opdsFacet(c *fiber.Ctx, cat *opdsCatalog, path, facet, title string) error {
+	after := c.Query("after", "")
+	values, err := h.Books.ListFacetValues(c.UserContext(), facet, after, opdsFacetPage)
+	if err != nil {
+		log.Printf("Error fetching OPDS %s values: %v", facet, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not retrieve " + title})
+	}
+
+	params := url.Values{}
+	if after != "" {
+		params.Set("after", after)
+	}
+	now := time.Now()
+	feed := opds.Feed{
+		ID:      cat.id(path, params),
+		Title:   title,
+		Kind:    opds.KindNavigation,
+		Updated: now,
+		Links: []opds.Link{
+			{Rel: opds.RelSelf, Href: cat.url(path, params), Kind: opds.KindNavigation},
+			{Rel: opds.RelUp, Href: cat.url("", nil), Kind: opds.KindNavigation},
+		},
+	}
+	if after != "" {
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelFirst, Href: cat.url(path, nil), Kind: opds.KindNavigation})
+	}
+	if len(values) == opdsFacetPage {
+		next := url.Values{"after": {values[len(values)-1].Value}}
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelNext, Href: cat.url(path, next), Kind: opds.KindNavigation})
+	}
+	for _, value := range values {
+		books := url.Values{facet: {value.Value}}
+		feed.Navigation = append(feed.Navigation, opds.Navigation{
+			ID:      cat.id("/books", books),
+			Title:   value.Value,
+			Href:    cat.url("/books", books),
+			Kind:    opds.KindAcquisition,
+			Count:   value.Count,
+			Updated: now,
+		})
+	}
+	return cat.send(feed)
+}
+
+// @Summary OPDS search description
+// @Description OpenSearch description of the OPDS 1.2 catalog search. OPDS 2.0 feeds link to a templated search instead.
+// @Tags opds
+// @Produce xml
+// @Param token query string false "Feed token from POST /opds/token, for apps that cannot send headers"
+// @Success 200 {string} string "OpenSearch description"
+// @Failure 401 {object} map[string]string
+// @Router /opds/opensearch.xml [get]
+func (h *Handler) OPDSSearchDescription(c *fiber.Ctx) error {
+	cat := newOPDSCatalog(c, OPDS1)
+	// The template is built by hand, as encoding it would escape its braces
+	template := cat.url("/books", nil)
+	if cat.token != "" {
+		template += "&search={searchTerms}"
+	} else {
+		template += "?search={searchTerms}"
+	}
+
+	var buf bytes.Buffer
+	if err := opds.WriteOpenSearch(&buf, "Digital Library", "Search the Digital Library catalog by title, author or ISBN", template); err != nil {
+		log.Printf("Error writing OpenSearch description: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not write search description"})
+	}
+	c.Set(fiber.HeaderContentType, opds.TypeOpenSearch+";charset=utf-8")
+	return c.Send(buf.Bytes())
+}
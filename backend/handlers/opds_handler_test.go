@@ -0,0 +1,209 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"digital-library/backend/middleware"
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// atomFeed is the part of an OPDS 1.2 feed the tests read
+type atomFeed struct {
+	Title        string `xml:"title"`
+	TotalResults int    `xml:"totalResults"`
+	Links        []struct {
+		Rel  string `xml:"rel,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"link"`
+	Entries []struct {
+		Title string `xml:"title"`
+		ID    string `xml:"id"`
+	} `xml:"entry"`
+}
+
+// link returns the href of the first link of the feed with the relation
+func (f atomFeed) link(rel string) string {
+	for _, link := range f.Links {
+		if link.Rel == rel {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+func TestOPDSAuth(t *testing.T) {
+	s := newTestServer(t)
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+	alice, err := s.store.CreateUser(context.Background(), "alice", string(hash), "alice@example.com", models.RoleUser)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	basic := func(credentials string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	}
+
+	feedToken, _, err := middleware.NewFeedToken(s.cfg, middleware.Claims{UserID: alice.ID, Username: alice.Username, Role: alice.Role})
+	if err != nil {
+		t.Fatalf("NewFeedToken: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		status        int
+	}{
+		{"no credentials", "/api/opds", "", fiber.StatusUnauthorized},
+		{"Basic", "/api/opds", basic("alice:secret"), fiber.StatusOK},
+		{"Basic by email", "/api/opds/v2", basic("alice@example.com:secret"), fiber.StatusOK},
+		{"wrong password", "/api/opds", basic("alice:guess"), fiber.StatusUnauthorized},
+		{"malformed Basic", "/api/opds", "Basic !!!", fiber.StatusUnauthorized},
+		{"Bearer", "/api/opds", "Bearer " + s.token(t, alice), fiber.StatusOK},
+		{"feed token", "/api/opds?token=" + feedToken, "", fiber.StatusOK},
+		{"feed token as Bearer", "/api/opds", "Bearer " + feedToken, fiber.StatusUnauthorized},
+		{"login token parameter", "/api/opds?token=" + s.token(t, alice), "", fiber.StatusUnauthorized},
+		{"invalid token", "/api/opds?token=bogus", "", fiber.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(fiber.MethodGet, tt.path, nil)
+		if tt.authorization != "" {
+			req.Header.Set(fiber.HeaderAuthorization, tt.authorization)
+		}
+		resp, err := s.app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: GET %s = %d, want %d", tt.name, tt.path, resp.StatusCode, tt.status)
+		}
+		if challenge := resp.Header.Get(fiber.HeaderWWWAuthenticate); (tt.status == fiber.StatusUnauthorized) != strings.HasPrefix(challenge, "Basic ") {
+			t.Errorf("%s: WWW-Authenticate = %q", tt.name, challenge)
+		}
+	}
+}
+
+func TestOPDSFeeds(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.user(t, "alice", models.RoleUser)
+	for _, book := range []*models.Book{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Category: "scifi", Quantity: 1},
+		{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Category: "classic"},
+		{Title: "Persuasion", Author: "Jane Austen", ISBN: "9780141439686", Category: "classic", Quantity: 2},
+	} {
+		if err := s.store.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+	}
+	atom := func(path string) atomFeed {
+		t.Helper()
+		status, body := s.do(t, alice, fiber.MethodGet, path, nil)
+		var feed atomFeed
+		if err := xml.Unmarshal(body, &feed); status != fiber.StatusOK || err != nil {
+			t.Fatalf("GET %s = %d %s", path, status, body)
+		}
+		return feed
+	}
+
+	root := atom("/api/opds")
+	if len(root.Entries) != 4 || root.link("search") == "" {
+		t.Errorf("root = %+v, want 4 entries and a search link", root)
+	}
+
+	books := atom("/api/opds/books?author=Jane%20Austen&sort=title&limit=1")
+	if books.TotalResults != 2 || len(books.Entries) != 1 || books.Entries[0].ID != "urn:isbn:9780141439587" {
+		t.Fatalf("first page = %+v, want Emma of 2 books", books)
+	}
+	next := books.link("next")
+	if !strings.Contains(next, "author=Jane+Austen") {
+		t.Fatalf("next link = %q, want the author filter kept", next)
+	}
+	next = next[strings.Index(next, "/api/"):]
+	if page := atom(next); len(page.Entries) != 1 || page.Entries[0].Title != "Persuasion" || page.link("first") == "" {
+		t.Errorf("second page = %+v, want Persuasion with a first link", page)
+	}
+
+	categories := atom("/api/opds/categories")
+	if len(categories.Entries) != 2 || categories.Entries[0].Title != "classic" || categories.Entries[1].Title != "scifi" {
+		t.Errorf("categories = %+v, want classic and scifi", categories.Entries)
+	}
+
+	status, body := s.do(t, alice, fiber.MethodGet, "/api/opds/v2/new", nil)
+	var feed struct {
+		Publications []struct {
+			Metadata struct{ Title string }
+		}
+		Links []struct {
+			Rel       string
+			Href      string
+			Templated bool
+		}
+	}
+	if err := json.Unmarshal(body, &feed); status != fiber.StatusOK || err != nil || len(feed.Publications) != 3 {
+		t.Fatalf("OPDS 2.0 new arrivals = %d %s", status, body)
+	}
+	if feed.Publications[0].Metadata.Title != "Persuasion" {
+		t.Errorf("newest = %q, want Persuasion", feed.Publications[0].Metadata.Title)
+	}
+	var templated bool
+	for _, link := range feed.Links {
+		templated = templated || link.Rel == "search" && link.Templated && strings.HasSuffix(link.Href, "{?search}")
+	}
+	if !templated {
+		t.Errorf("links = %+v, want a templated search link", feed.Links)
+	}
+
+	status, body = s.do(t, alice, fiber.MethodGet, "/api/opds/opensearch.xml", nil)
+	if status != fiber.StatusOK || !strings.Contains(string(body), "/api/opds/books?search={searchTerms}") {
+		t.Errorf("OpenSearch description = %d %s", status, body)
+	}
+}
+
+func TestFeedToken(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.user(t, "alice", models.RoleUser)
+	s.book(t, "9780441172719", 1)
+
+	status, body := s.do(t, alice, fiber.MethodPost, "/api/opds/token", nil)
+	var issued models.FeedTokenResponse
+	if err := json.Unmarshal(body, &issued); status != fiber.StatusOK || err != nil {
+		t.Fatalf("issue feed token = %d %s", status, body)
+	}
+	if !strings.HasSuffix(issued.OPDSURL, "/api/opds?token="+issued.Token) || !strings.HasSuffix(issued.OPDS2URL, "/api/opds/v2?token="+issued.Token) {
+		t.Errorf("catalog URLs = %q, %q", issued.OPDSURL, issued.OPDS2URL)
+	}
+	if status, body := s.do(t, models.User{}, fiber.MethodPost, "/api/opds/token", nil); status != fiber.StatusUnauthorized {
+		t.Errorf("issue without credentials = %d %s, want 401", status, body)
+	}
+
+	// The links of a feed opened with the token keep it
+	status, body = s.do(t, models.User{}, fiber.MethodGet, "/api/opds/books?token="+issued.Token, nil)
+	var feed atomFeed
+	if err := xml.Unmarshal(body, &feed); status != fiber.StatusOK || err != nil {
+		t.Fatalf("books = %d %s", status, body)
+	}
+	for _, link := range feed.Links {
+		if !strings.Contains(link.Href, "token="+issued.Token) {
+			t.Errorf("link %s = %q, want the feed token", link.Rel, link.Href)
+		}
+	}
+
+	// A feed token is no Bearer token for the rest of the API
+	req := httptest.NewRequest(fiber.MethodGet, "/api/books", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+issued.Token)
+	if status, body := s.send(t, models.User{}, req); status != fiber.StatusUnauthorized {
+		t.Errorf("GET /api/books with a feed token = %d %s, want 401", status, body)
+	}
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"digital-library/backend/config"
+	"digital-library/backend/query"
+	"digital-library/backend/sru"
+	"digital-library/backend/store"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Records of an SRU response when the request does not say, and at most
+const (
+	sruDefaultRecords = 10
+	sruMaxRecords     = 100
+)
+
+// sruMaxStartRecord is the furthest position a response can start from. The
+// database sorts and skips every record before it, so deeper pages are
+// refused rather than left to load the catalog.
+const sruMaxStartRecord = 10000
+
+// sruSchemas maps the short and full names of the record schemas to the full
+// names
+var sruSchemas = map[string]string{
+	"dc":              sru.SchemaDC,
+	sru.SchemaDC:      sru.SchemaDC,
+	"marcxml":         sru.SchemaMARCXML,
+	sru.SchemaMARCXML: sru.SchemaMARCXML,
+}
+
+// sruIndexes describes the CQL indexes for explain
+var sruIndexes = []sru.Index{
+	{Title: "Title", Names: []sru.IndexName{{Set: "dc", Name: "title"}}},
+	{Title: "Author", Names: []sru.IndexName{{Set: "dc", Name: "creator"}}},
+	{Title: "Subject", Names: []sru.IndexName{{Set: "dc", Name: "subject"}}},
+	{Title: "ISBN", Names: []sru.IndexName{{Set: "bath", Name: "isbn"}}},
+	{Title: "Any text", Names: []sru.IndexName{{Set: "cql", Name: "serverChoice"}}},
+	{Title: "All records", Names: []sru.IndexName{{Set: "cql", Name: "allRecords"}}},
+}
+
+// sruUnsupported maps the kinds of CQL the catalog cannot search by to SRU
+// diagnostics
+var sruUnsupported = map[string]int{
+	query.UnsupportedIndex:            sru.DiagUnsupportedIndex,
+	query.UnsupportedRelation:         sru.DiagUnsupportedRelation,
+	query.UnsupportedRelationModifier: sru.DiagUnsupportedRelationModifier,
+	query.UnsupportedBoolean:          sru.DiagUnsupportedBoolean,
+	query.UnsupportedBooleanModifier:  sru.DiagUnsupportedBooleanModifier,
+	query.UnsupportedMasking:          sru.DiagMaskingUnsupported,
+	query.UnsupportedAnchoring:        sru.DiagAnchoringUnsupported,
+	query.UnsupportedEmptyTerm:        sru.DiagEmptyTerm,
+	query.UnsupportedPrefix:           sru.DiagUnsupportedPrefix,
+}
+
+// @Summary SRU search
+// @Description SRU 1.2 endpoint for searching the catalog with CQL, open without signing in. It supports the explain and searchRetrieve operations. CQL queries may use the title (dc.title), author (dc.creator), subject (dc.subject, the category) and isbn (bath.isbn) indexes or none, the =, scr, ==, <>, adj, all and any relations, and, or and not, parentheses and * masking. Records are Dublin Core (dc) or MARCXML (marcxml), packed as XML or as escaped strings. Errors are reported as SRU diagnostics.
+// @Tags sru
+// @Produce xml
+// @Param operation query string false "explain (default) or searchRetrieve"
+// @Param version query string false "1.1 or 1.2"
+// @Param query query string false "CQL query, required by searchRetrieve, e.g. dc.title=dune and author=herbert"
+// @Param startRecord query int false "Position of the first record, from 1 to 10000"
+// @Param maximumRecords query int false "Records to return, at most 100 (default 10)"
+// @Param recordSchema query string false "dc (default) or marcxml"
+// @Param recordPacking query string false "xml (default) or string"
+// @Success 200 {string} string "SRU response"
+// @Failure 500 {object} map[string]string
+// @Router /sru [get]
+func (h *Handler) SRU(cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var out bytes.Buffer
+		var err error
+		operation := c.Query("operation", "explain")
+		diag := sruVersion(c.Query("version"))
+		switch {
+		case operation == "searchRetrieve":
+			var resp sru.SearchRetrieve
+			if diag == nil {
+				resp, err = h.sruSearch(c)
+			} else {
+				resp.Diagnostics = []*sru.Diagnostic{diag}
+			}
+			if err == nil {
+				err = sru.WriteSearchRetrieve(&out, resp)
+			}
+		default:
+			if diag == nil && operation != "explain" {
+				diag = &sru.Diagnostic{Code: sru.DiagUnsupportedOperation, Details: operation}
+			}
+			explain := sruExplain(c, cfg)
+			if diag != nil {
+				explain.Diagnostics = []*sru.Diagnostic{diag}
+			}
+			err = sru.WriteExplain(&out, explain)
+		}
+		if err != nil {
+			log.Printf("Error answering SRU %s request: %v", operation, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not answer SRU request"})
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextXMLCharsetUTF8)
+		return c.Send(out.Bytes())
+	}
+}
+
+// sruVersion checks the version a request asks for, which may be omitted
+func sruVersion(version string) *sru.Diagnostic {
+	if version == "" || version == "1.1" || version == sru.Version {
+		return nil
+	}
+	return &sru.Diagnostic{Code: sru.DiagUnsupportedVersion, Details: sru.Version}
+}
+
+// sruExplain describes the server
+func sruExplain(c *fiber.Ctx, cfg *config.Config) sru.Explain {
+	host, port := c.Hostname(), 80
+	if c.Protocol() == "https" {
+		port = 443
+	}
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		host = h
+		if n, err := strconv.Atoi(p); err == nil {
+			port = n
+		}
+	}
+	return sru.Explain{
+		Host:     host,
+		Port:     port,
+		Database: "api/sru",
+		Title:    cfg.OAIRepositoryName,
+		Indexes:  sruIndexes,
+		Default:  sruDefaultRecords,
+		Maximum:  sruMaxRecords,
+	}
+}
+
+// sruSearch answers a searchRetrieve request. Requests the server cannot
+// answer give a response with a diagnostic rather than an error.
+func (h *Handler) sruSearch(c *fiber.Ctx) (sru.SearchRetrieve, error) {
+	params, diag := sruSearchParams(c)
+	if diag != nil {
+		return sru.SearchRetrieve{Diagnostics: []*sru.Diagnostic{diag}}, nil
+	}
+
+	// Books are listed in the catalog's default order, so positions stay the
+	// same from page to page. A limit of zero would list every book, so a
+	// request for no records still reads one to count the matches.
+	filter := store.BookFilter{Query: params.query}
+	page := store.PageRequest{Limit: max(params.maximum, 1), Offset: params.start - 1}
+	books, err := h.Books.ListBooks(c.UserContext(), filter, page)
+	if err != nil {
+		return sru.SearchRetrieve{}, err
+	}
+	resp := sru.SearchRetrieve{NumberOfRecords: books.Total}
+	if params.maximum == 0 {
+		return resp, nil
+	}
+	if params.start > books.Total {
+		if books.Total > 0 {
+			resp.Diagnostics = []*sru.Diagnostic{{Code: sru.DiagFirstRecordOutOfRange, Details: strconv.Itoa(params.start)}}
+		}
+		return resp, nil
+	}
+
+	mapping := h.marcMapping()
+	for i, book := range books.Data {
+		record := sru.Record{Schema: params.schema, Packing: params.packing, Position: params.start + i}
+		if params.schema == sru.SchemaMARCXML {
+			record.Data = marcRecord(book, mapping)
+		} else {
+			dc := sru.DC(*dublinCore(book))
+			record.Data = &dc
+		}
+		resp.Records = append(resp.Records, record)
+	}
+	if next := params.start + len(resp.Records); next <= books.Total {
+		resp.NextRecordPosition = next
+	}
+	return resp, nil
+}
+
+// sruParams are the parameters of a searchRetrieve request
+type sruParams struct {
+	query   query.Node
+	start   int
+	maximum int
+	schema  string
+	packing string
+}
+
+// sruSearchParams reads and checks the parameters of a searchRetrieve
+// request
+func sruSearchParams(c *fiber.Ctx) (sruParams, *sru.Diagnostic) {
+	params := sruParams{start: 1, maximum: sruDefaultRecords, schema: sru.SchemaDC, packing: sru.PackingXML}
+	for _, name := range []string{"sortKeys", "resultSetTTL", "stylesheet"} {
+		if c.Query(name) != "" {
+			return params, &sru.Diagnostic{Code: sru.DiagUnsupportedParameter, Details: name}
+		}
+	}
+
+	cql := c.Query("query")
+	if strings.TrimSpace(cql) == "" {
+		return params, &sru.Diagnostic{Code: sru.DiagMissingParameter, Details: "query"}
+	}
+	node, err := query.ParseCQL(cql)
+	if err != nil {
+		var unsupported *query.UnsupportedError
+		if errors.As(err, &unsupported) {
+			return params, &sru.Diagnostic{Code: sruUnsupported[unsupported.Kind], Details: unsupported.Value}
+		}
+		return params, &sru.Diagnostic{Code: sru.DiagQuerySyntax, Details: err.Error()}
+	}
+	params.query = canonicalISBNTerms(node)
+
+	if value := c.Query("startRecord"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return params, &sru.Diagnostic{Code: sru.DiagUnsupportedParameterValue, Details: "startRecord"}
+		}
+		if n > sruMaxStartRecord {
+			return params, &sru.Diagnostic{Code: sru.DiagFirstRecordOutOfRange, Details: value}
+		}
+		params.start = n
+	}
+	if value := c.Query("maximumRecords"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return params, &sru.Diagnostic{Code: sru.DiagUnsupportedParameterValue, Details: "maximumRecords"}
+		}
+		params.maximum = min(n, sruMaxRecords)
+	}
+	if value := c.Query("recordSchema"); value != "" {
+		schema, ok := sruSchemas[value]
+		if !ok {
+			return params, &sru.Diagnostic{Code: sru.DiagUnknownSchema, Details: value}
+		}
+		params.schema = schema
+	}
+	if value := c.Query("recordPacking"); value != "" {
+		if value != sru.PackingXML && value != sru.PackingString {
+			return params, &sru.Diagnostic{Code: sru.DiagUnsupportedPacking, Details: value}
+		}
+		params.packing = value
+	}
+	return params, nil
+}
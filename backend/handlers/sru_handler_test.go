@@ -0,0 +1,104 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"digital-library/backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sruResponse is the part of an SRU response the tests read
+type sruResponse struct {
+	NumberOfRecords    int `xml:"numberOfRecords"`
+	NextRecordPosition int `xml:"nextRecordPosition"`
+	Records            []struct {
+		Position int `xml:"recordPosition"`
+		Data     struct {
+			Titles []string `xml:"dc>title"`
+			Text   string   `xml:",chardata"` // A record packed as a string
+		} `xml:"recordData"`
+	} `xml:"records>record"`
+	Diagnostic string `xml:"diagnostics>diagnostic>uri"`
+	Explain    struct {
+		Host     string `xml:"serverInfo>host"`
+		Database string `xml:"serverInfo>database"`
+	} `xml:"record>recordData>explain"`
+}
+
+// sru sends an SRU request with the query and decodes the response
+func (s *testServer) sru(t *testing.T, query string) sruResponse {
+	t.Helper()
+	status, body := s.send(t, models.User{}, httptest.NewRequest(fiber.MethodGet, "/api/sru?"+query, nil))
+	var resp sruResponse
+	if err := xml.Unmarshal(body, &resp); status != fiber.StatusOK || err != nil {
+		t.Fatalf("SRU %s = %d %s", query, status, body)
+	}
+	return resp
+}
+
+func TestSRU(t *testing.T) {
+	s := newTestServer(t)
+	for _, book := range []*models.Book{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Category: "scifi"},
+		{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Category: "classic"},
+		{Title: "Persuasion", Author: "Jane Austen", ISBN: "9780141439686", Category: "classic"},
+	} {
+		if err := s.store.CreateBook(context.Background(), book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+	}
+
+	explain := s.sru(t, "")
+	if explain.Explain.Host != "example.com" || explain.Explain.Database != "api/sru" {
+		t.Errorf("explain = %+v, want example.com and the api/sru database", explain.Explain)
+	}
+
+	search := func(cql, params string) string {
+		return "operation=searchRetrieve&version=1.2&query=" + url.QueryEscape(cql) + params
+	}
+	first := s.sru(t, search(`dc.creator = austen`, "&maximumRecords=1"))
+	if first.NumberOfRecords != 2 || first.NextRecordPosition != 2 || len(first.Records) != 1 || fmt.Sprint(first.Records[0].Data.Titles) != "[Emma]" {
+		t.Errorf("first page = %+v, want Emma of 2 records", first)
+	}
+	second := s.sru(t, search(`dc.creator = austen`, "&maximumRecords=1&startRecord=2"))
+	if len(second.Records) != 1 || second.Records[0].Position != 2 || fmt.Sprint(second.Records[0].Data.Titles) != "[Persuasion]" || second.NextRecordPosition != 0 {
+		t.Errorf("second page = %+v, want Persuasion at position 2 and no next page", second)
+	}
+	if isbn := s.sru(t, search(`bath.isbn = 0-441-17271-7`, "")); isbn.NumberOfRecords != 1 {
+		t.Errorf("ISBN-10 search = %d records, want Dune", isbn.NumberOfRecords)
+	}
+	if count := s.sru(t, search(`subject = classic not title = emma`, "&maximumRecords=0")); count.NumberOfRecords != 1 || len(count.Records) != 0 {
+		t.Errorf("count only = %+v, want 1 match and no records", count)
+	}
+	if marc := s.sru(t, search(`dune`, "&recordSchema=marcxml&recordPacking=string")); len(marc.Records) != 1 || marc.Records[0].Data.Text == "" {
+		t.Errorf("MARCXML as a string = %+v, want the escaped record", marc)
+	}
+
+	diagnostics := []struct {
+		query string
+		code  int
+	}{
+		{"operation=scan", 4},
+		{"version=2.0", 5},
+		{search(`dune`, "&startRecord=0"), 6},
+		{"operation=searchRetrieve", 7},
+		{search(`dune`, "&sortKeys=title"), 8},
+		{search(`(dune`, ""), 10},
+		{search(`publisher = chilton`, ""), 16},
+		{search(`title within dune`, ""), 19},
+		{search(`title = du?e`, ""), 28},
+		{search(`dune`, "&recordSchema=mods"), 66},
+		{search(`dune`, "&recordPacking=json"), 71},
+	}
+	for _, tt := range diagnostics {
+		if resp := s.sru(t, tt.query); resp.Diagnostic != fmt.Sprintf("info:srw/diagnostic/1/%d", tt.code) {
+			t.Errorf("%s: diagnostic = %q, want %d", tt.query, resp.Diagnostic, tt.code)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package isbn validates International Standard Book Numbers and converts
+// them between the ISBN-10 and ISBN-13 forms. Books store the canonical form:
+// the 13 digits of the ISBN-13 without hyphens.
+package isbn
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errors returned by Normalize
+var (
+	ErrInvalidLength    = errors.New("ISBN must have 10 or 13 digits")
+	ErrInvalidCharacter = errors.New("ISBN may only contain digits, hyphens and spaces, and X as the last character of an ISBN-10")
+	ErrInvalidPrefix    = errors.New("ISBN-13 must start with 978 or 979")
+	ErrInvalidChecksum  = errors.New("ISBN check digit does not match")
+)
+
+// Normalize validates an ISBN-10 or ISBN-13, written with or without hyphens
+// and spaces, and returns its canonical ISBN-13
+func Normalize(s string) (string, error) {
+	digits := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(s))
+	for i, r := range digits {
+		if (r < '0' || r > '9') && !(r == 'X' && i == 9 && len(digits) == 10) {
+			return "", ErrInvalidCharacter
+		}
+	}
+
+	switch len(digits) {
+	case 10:
+		if checkDigit10(digits[:9]) != digits[9] {
+			return "", ErrInvalidChecksum
+		}
+		return To13(digits), nil
+	case 13:
+		if !strings.HasPrefix(digits, "978") && !strings.HasPrefix(digits, "979") {
+			return "", ErrInvalidPrefix
+		}
+		if checkDigit13(digits[:12]) != digits[12] {
+			return "", ErrInvalidChecksum
+		}
+		return digits, nil
+	}
+	return "", ErrInvalidLength
+}
+
+// To13 converts a valid ISBN-10 without hyphens to its ISBN-13
+func To13(isbn10 string) string {
+	body := "978" + isbn10[:9]
+	return body + string(checkDigit13(body))
+}
+
+// To10 converts a canonical ISBN-13 to its ISBN-10. Only ISBNs starting with
+// 978 have one.
+func To10(isbn13 string) (string, bool) {
+	if len(isbn13) != 13 || !strings.HasPrefix(isbn13, "978") {
+		return "", false
+	}
+	body := isbn13[3:12]
+	return body + string(checkDigit10(body)), true
+}
+
+// checkDigit10 returns the check digit of the first 9 digits of an ISBN-10
+func checkDigit10(body string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += (10 - i) * int(body[i]-'0')
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// checkDigit13 returns the check digit of the first 12 digits of an ISBN-13
+func checkDigit13(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += weight * int(body[i]-'0')
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,76 @@
+package isbn
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"9780306406157", "9780306406157", nil},
+		{"978-0-306-40615-7", "9780306406157", nil},
+		{" 978 0 306 40615 7 ", "9780306406157", nil},
+		{"0306406152", "9780306406157", nil},
+		{"0-306-40615-2", "9780306406157", nil},
+		{"080442957X", "9780804429573", nil},
+		{"080442957x", "9780804429573", nil},
+		{"9791090636071", "9791090636071", nil},
+		{"", "", ErrInvalidLength},
+		{"030640615", "", ErrInvalidLength},
+		{"97803064061570", "", ErrInvalidLength},
+		{"0306406153", "", ErrInvalidChecksum},
+		{"9780306406158", "", ErrInvalidChecksum},
+		{"9770306406157", "", ErrInvalidPrefix},
+		{"03064X6152", "", ErrInvalidCharacter},
+		{"978030640615X", "", ErrInvalidCharacter},
+		{"978-0-306-40615-7a", "", ErrInvalidCharacter},
+		{"+306406152", "", ErrInvalidCharacter},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if got != tt.want || err != tt.err {
+			t.Errorf("Normalize(%q) = %q, %v, want %q, %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestTo13(t *testing.T) {
+	tests := map[string]string{
+		"0306406152": "9780306406157",
+		"080442957X": "9780804429573",
+		"0441172717": "9780441172719",
+	}
+	for in, want := range tests {
+		if got := To13(in); got != want {
+			t.Errorf("To13(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTo10(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"9780306406157", "0306406152", true},
+		{"9780804429573", "080442957X", true},
+		{"9791090636071", "", false},
+		{"978030640615", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := To10(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("To10(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	// Converting back and forth gives the same ISBN
+	for _, isbn13 := range []string{"9780306406157", "9780804429573", "9780441172719"} {
+		isbn10, _ := To10(isbn13)
+		if got := To13(isbn10); got != isbn13 {
+			t.Errorf("To13(To10(%q)) = %q", isbn13, got)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"digital-library/backend/store"
+)
+
+// ExpireHolds returns a job that expires ready holds that were not picked up
+// in time, passing their copies on to the next borrowers in the queue
+func ExpireHolds(holds store.HoldStore, interval time.Duration) Job {
+	return Job{
+		Name:     "expire-holds",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+			expired, err := holds.ExpireHolds(ctx, today)
+			if err != nil {
+				return err
+			}
+			if expired > 0 {
+				log.Printf("Expired %d hold(s)", expired)
+			}
+			return nil
+		},
+	}
+}
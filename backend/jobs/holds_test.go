@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+)
+
+func TestExpireHolds(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	book := &models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Quantity: 1}
+	if err := s.CreateBook(ctx, book); err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+
+	borrowers := make(map[string]models.User)
+	for _, username := range []string{"alice", "bob", "carol"} {
+		user, err := s.CreateUser(ctx, username, "", username+"@example.com", models.RoleUser)
+		if err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		borrowers[username] = user
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	loan, err := s.LendBook(ctx, book.ID, "", borrowers["alice"], today)
+	if err != nil {
+		t.Fatalf("LendBook: %v", err)
+	}
+	var holds []models.Hold
+	for _, borrower := range []string{"bob", "carol"} {
+		hold, err := s.PlaceHold(ctx, book.ID, borrowers[borrower])
+		if err != nil {
+			t.Fatalf("PlaceHold: %v", err)
+		}
+		holds = append(holds, hold)
+	}
+	if _, err := s.ReturnBook(ctx, loan.ID, today, models.ConditionGood); err != nil {
+		t.Fatalf("ReturnBook: %v", err)
+	}
+
+	status := func(id int) string {
+		t.Helper()
+		hold, err := s.GetHold(ctx, id)
+		if err != nil {
+			t.Fatalf("GetHold: %v", err)
+		}
+		return hold.Status
+	}
+
+	// The pickup window has not ended yet
+	if err := ExpireHolds(s, time.Hour).Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got := status(holds[0].ID); got != models.HoldStatusReady {
+		t.Fatalf("bob's hold = %s, want ready", got)
+	}
+
+	afterPickup := today.AddDate(0, 0, models.DefaultLoanPolicy.HoldPickupDays+1)
+	if expired, err := s.ExpireHolds(ctx, afterPickup); err != nil || expired != 1 {
+		t.Fatalf("ExpireHolds = %d, %v; want 1", expired, err)
+	}
+	if got := status(holds[0].ID); got != models.HoldStatusExpired {
+		t.Errorf("bob's hold = %s, want expired", got)
+	}
+	if got := status(holds[1].ID); got != models.HoldStatusReady {
+		t.Errorf("carol's hold = %s, want ready", got)
+	}
+}
@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"digital-library/backend/store"
+)
+
+// MarkOverdue returns a job that flags loans that went past their due date
+func MarkOverdue(lending store.LendingStore, interval time.Duration) Job {
+	return Job{
+		Name:     "mark-overdue",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+			marked, err := lending.MarkOverdueLoans(ctx, today)
+			if err != nil {
+				return err
+			}
+			if marked > 0 {
+				log.Printf("Marked %d loan(s) overdue", marked)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"digital-library/backend/models"
+	"digital-library/backend/store"
+)
+
+func TestMarkOverdue(t *testing.T) {
+	ctx := context.Background()
+	s := store.NewMemory()
+	book := &models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Quantity: 3}
+	if err := s.CreateBook(ctx, book); err != nil {
+		t.Fatalf("CreateBook: %v", err)
+	}
+
+	alice, err := s.CreateUser(ctx, "alice", "", "alice@example.com", models.RoleUser)
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	lend := func(borrowed time.Time) models.LendingRecord {
+		t.Helper()
+		record, err := s.LendBook(ctx, book.ID, "", alice, borrowed)
+		if err != nil {
+			t.Fatalf("LendBook: %v", err)
+		}
+		return record
+	}
+	overdue := lend(today.AddDate(0, 0, -30))
+	returned := lend(today.AddDate(0, 0, -30))
+	lend(today)
+	if _, err := s.ReturnBook(ctx, returned.ID, today, models.ConditionGood); err != nil {
+		t.Fatalf("ReturnBook: %v", err)
+	}
+
+	if err := MarkOverdue(s, time.Hour).Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if marked, err := s.MarkOverdueLoans(ctx, today); err != nil || marked != 0 {
+		t.Errorf("MarkOverdueLoans after the job = %d, %v; want 0", marked, err)
+	}
+
+	loans, err := s.OverdueLoans(ctx, store.AnalyticsScope{All: true}, today)
+	if err != nil {
+		t.Fatalf("OverdueLoans: %v", err)
+	}
+	if len(loans) != 1 || loans[0].LendingRecordID != overdue.ID || loans[0].OverdueAt == nil {
+		t.Fatalf("OverdueLoans = %+v, want flagged loan %d", loans, overdue.ID)
+	}
+	if want := 30 - models.DefaultLoanPolicy.LoanDays; loans[0].DaysOverdue != want {
+		t.Errorf("days overdue = %d, want %d", loans[0].DaysOverdue, want)
+	}
+}
@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	var runs, panics, disabled atomic.Int32
+	s := NewScheduler(
+		Job{Name: "count", Interval: time.Millisecond, Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		}},
+		Job{Name: "panic", Interval: time.Millisecond, Run: func(ctx context.Context) error {
+			panics.Add(1)
+			panic("boom")
+		}},
+		Job{Name: "disabled", Run: func(ctx context.Context) error {
+			disabled.Add(1)
+			return nil
+		}},
+	)
+	s.Start(context.Background())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runs.Load() < 3 || panics.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("runs = %d, panics = %d after 5s", runs.Load(), panics.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.Stop()
+
+	stopped := runs.Load()
+	time.Sleep(10 * time.Millisecond)
+	if runs.Load() != stopped {
+		t.Errorf("job ran after Stop")
+	}
+	if disabled.Load() != 0 {
+		t.Errorf("disabled job ran %d times", disabled.Load())
+	}
+}
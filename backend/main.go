@@ -6,12 +6,16 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
 	"digital-library/backend/app"
+	"digital-library/backend/config"
 	"digital-library/backend/database"
 	_ "digital-library/backend/docs" // Import generated docs
+	"digital-library/backend/jobs"
+	"digital-library/backend/store"
 
 	"github.com/joho/godotenv"
 )
@@ -22,6 +26,11 @@ func main() {
 		log.Println("No .env file found, reading config from environment variables")
 	}
 
+	// Run CLI subcommands such as "migrate up" instead of the server
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	// Get port from environment variable or use default
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -29,9 +38,20 @@ func main() {
 	}
 
 	// Setup and start the application
-	app := app.SetupApp()
+	cfg := config.LoadConfig()
+	app := app.New(cfg)
 	defer database.Close()
 
+	// Start background jobs. They only run in the long-lived server process,
+	// not in the Vercel handler which builds a new app per request.
+	db := store.NewPostgres(database.DB)
+	scheduler := jobs.NewScheduler(
+		jobs.MarkOverdue(db, cfg.OverdueScanInterval),
+		jobs.ExpireHolds(db, cfg.HoldExpiryInterval),
+	)
+	scheduler.Start(context.Background())
+	defer scheduler.Stop()
+
 	log.Println("Starting server on port " + port + "...")
 	log.Fatal(app.Listen(":" + port))
 }
@@ -0,0 +1,231 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Delimiters of the ISO 2709 format
+const (
+	subfieldDelimiter = 0x1F
+	fieldTerminator   = 0x1E
+	recordTerminator  = 0x1D
+)
+
+// Limits of the five digit record length and four digit field lengths
+const (
+	maxRecordLength = 99999
+	maxFieldLength  = 9999
+)
+
+// ErrRecordTooLong is returned by Writer.Write for a record that does not fit
+// the lengths of the ISO 2709 directory
+var ErrRecordTooLong = errors.New("MARC record is too long for ISO 2709")
+
+// Reader reads binary MARC 21 records
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a Reader reading records from r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read returns the next record, or io.EOF after the last one. A malformed
+// record whose length can be read gives a *RecordError and is skipped; any
+// other error ends the stream.
+func (r *Reader) Read() (*Record, error) {
+	// Some files put line breaks between records
+	for {
+		b, err := r.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != '\n' && b[0] != '\r' {
+			break
+		}
+		r.r.Discard(1)
+	}
+
+	prefix := make([]byte, 5)
+	if _, err := io.ReadFull(r.r, prefix); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	length, ok := number(prefix)
+	if !ok || length < 24+2 {
+		return nil, errors.New("invalid MARC record length " + strconv.Quote(string(prefix)))
+	}
+	data := make([]byte, length)
+	copy(data, prefix)
+	if _, err := io.ReadFull(r.r, data[5:]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return parseRecord(data)
+}
+
+// number parses the unsigned decimal numbers of the leader and directory,
+// which unlike strconv.Atoi does not accept signs
+func number(digits []byte) (int, bool) {
+	n := 0
+	for _, d := range digits {
+		if d < '0' || d > '9' {
+			return 0, false
+		}
+		n = n*10 + int(d-'0')
+	}
+	return n, len(digits) > 0
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// parseRecord parses a whole ISO 2709 record
+func parseRecord(data []byte) (*Record, error) {
+	if data[len(data)-1] != recordTerminator {
+		return nil, &RecordError{"record does not end with a record terminator"}
+	}
+	leader := data[:24]
+	base, ok := number(leader[12:17])
+	if !ok || base <= 24 || base > len(data)-1 {
+		return nil, &RecordError{"invalid base address of data"}
+	}
+	if data[base-1] != fieldTerminator || (base-25)%12 != 0 {
+		return nil, &RecordError{"invalid directory"}
+	}
+	if leader[9] != 'a' && !isASCII(data) {
+		return nil, &RecordError{"MARC-8 encoded records are not supported, convert them to Unicode"}
+	}
+	if !utf8.Valid(data) {
+		return nil, &RecordError{"record is not valid UTF-8"}
+	}
+
+	record := &Record{Leader: string(leader)}
+	body := data[base : len(data)-1]
+	for dir := data[24 : base-1]; len(dir) > 0; dir = dir[12:] {
+		tag := string(dir[:3])
+		length, ok1 := number(dir[3:7])
+		start, ok2 := number(dir[7:12])
+		if !ok1 || !ok2 || !validTag(tag) || start+length > len(body) || length == 0 {
+			return nil, &RecordError{"invalid directory entry " + strconv.Quote(string(dir[:12]))}
+		}
+		value := bytes.TrimSuffix(body[start:start+length], []byte{fieldTerminator})
+
+		field := Field{Tag: tag}
+		if isControlTag(tag) {
+			field.Value = string(value)
+			record.Fields = append(record.Fields, field)
+			continue
+		}
+		if len(value) < 2 {
+			return nil, &RecordError{"field " + tag + " has no indicators"}
+		}
+		field.Ind1, field.Ind2 = value[0], value[1]
+		for _, sub := range bytes.Split(value[2:], []byte{subfieldDelimiter})[1:] {
+			if len(sub) == 0 {
+				continue
+			}
+			field.Subfields = append(field.Subfields, Subfield{Code: sub[0], Value: string(sub[1:])})
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	return record, nil
+}
+
+func isASCII(data []byte) bool {
+	for _, b := range data {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Writer writes binary MARC 21 records in Unicode
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a Writer writing records to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes a record. An empty leader is replaced by one for a new
+// monograph; the lengths, base address and encoding of the leader are
+// always computed.
+func (w *Writer) Write(record *Record) error {
+	var dir, body bytes.Buffer
+	for _, field := range record.Fields {
+		if !validTag(field.Tag) {
+			return errors.New("invalid MARC tag " + strconv.Quote(field.Tag))
+		}
+		start := body.Len()
+		if field.IsControl() {
+			body.WriteString(field.Value)
+		} else {
+			body.WriteByte(indicator(field.Ind1))
+			body.WriteByte(indicator(field.Ind2))
+			for _, sub := range field.Subfields {
+				body.WriteByte(subfieldDelimiter)
+				body.WriteByte(sub.Code)
+				body.WriteString(sub.Value)
+			}
+		}
+		body.WriteByte(fieldTerminator)
+		length := body.Len() - start
+		if length > maxFieldLength {
+			return ErrRecordTooLong
+		}
+		dir.WriteString(field.Tag + pad(length, 4) + pad(start, 5))
+	}
+	dir.WriteByte(fieldTerminator)
+
+	base := 24 + dir.Len()
+	length := base + body.Len() + 1
+	if length > maxRecordLength {
+		return ErrRecordTooLong
+	}
+	leader := []byte(defaultLeader)
+	if len(record.Leader) == 24 {
+		leader = []byte(record.Leader)
+	}
+	copy(leader[0:5], pad(length, 5))
+	leader[9] = 'a'
+	copy(leader[10:12], "22")
+	copy(leader[12:17], pad(base, 5))
+	copy(leader[20:24], "4500")
+
+	out := make([]byte, 0, length)
+	out = append(out, leader...)
+	out = append(out, dir.Bytes()...)
+	out = append(out, body.Bytes()...)
+	out = append(out, recordTerminator)
+	_, err := w.w.Write(out)
+	return err
+}
+
+// indicator returns an indicator, blank when unset
+func indicator(b byte) byte {
+	if b == 0 {
+		return ' '
+	}
+	return b
+}
+
+// pad formats n with leading zeros to the given width
+func pad(n, width int) string {
+	s := strconv.Itoa(n)
+	for len(s) < width {
+		s = "0" + s
+	}
+	return s
+}
@@ -0,0 +1,144 @@
+package marc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// rawRecord builds an ISO 2709 record from its directory and body, computing
+// the record length and base address of the leader
+func rawRecord(dir, body string) []byte {
+	base := 24 + len(dir) + 1
+	length := base + len(body) + 1
+	leader := pad(length, 5) + "nam a22" + pad(base, 5) + "7u 4500"
+	return []byte(leader + dir + "\x1e" + body + "\x1d")
+}
+
+// withLeader replaces the leader of a record from position pos
+func withLeader(data []byte, pos int, value string) []byte {
+	copy(data[pos:], value)
+	return data
+}
+
+func testRecord() *Record {
+	return &Record{
+		Leader: "00000nam a22000007u 4500",
+		Fields: []Field{
+			{Tag: "001", Value: "42"},
+			{Tag: "020", Ind1: ' ', Ind2: ' ', Subfields: []Subfield{{Code: 'a', Value: "9780441172719"}}},
+			{Tag: "100", Ind1: '1', Ind2: ' ', Subfields: []Subfield{{Code: 'a', Value: "Herbert, Frank,"}}},
+			{Tag: "245", Ind1: '1', Ind2: '0', Subfields: []Subfield{
+				{Code: 'a', Value: "Dune /"},
+				{Code: 'c', Value: "Frank Herbert ; préface de Gérard Klein."},
+			}},
+		},
+	}
+}
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for i := 0; i < 2; i++ {
+		if err := w.Write(testRecord()); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	r := NewReader(&buf)
+	for i := 0; i < 2; i++ {
+		got, err := r.Read()
+		if err != nil {
+			t.Fatalf("Read record %d: %v", i, err)
+		}
+		want := testRecord()
+		if !reflect.DeepEqual(got.Fields, want.Fields) {
+			t.Errorf("record %d fields = %+v, want %+v", i, got.Fields, want.Fields)
+		}
+		if got.Leader[9] != 'a' || got.Leader[5:9] != "nam " {
+			t.Errorf("record %d leader = %q", i, got.Leader)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read after the last record = %v, want io.EOF", err)
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewXMLWriter(&buf)
+	if err := w.Write(testRecord()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got, err := NewXMLReader(&buf).Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if want := testRecord(); !reflect.DeepEqual(got, want) {
+		t.Errorf("record = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriterRejectsLongFields(t *testing.T) {
+	record := &Record{Fields: []Field{{Tag: "500", Subfields: []Subfield{{Code: 'a', Value: strings.Repeat("x", maxFieldLength)}}}}}
+	if err := NewWriter(io.Discard).Write(record); err != ErrRecordTooLong {
+		t.Errorf("Write = %v, want ErrRecordTooLong", err)
+	}
+}
+
+func TestReaderMalformedRecords(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"negative field length", rawRecord("245-00100000", "10\x1fadune\x1e")},
+		{"signed field start", rawRecord("2450010+0000", "10\x1fadune\x1e")},
+		{"field past the end", rawRecord("245999900000", "10\x1fadune\x1e")},
+		{"empty field", rawRecord("245000000000", "10\x1fadune\x1e")},
+		{"invalid tag", rawRecord("2$5001000000", "10\x1fadune\x1e")},
+		{"no indicators", rawRecord("245000200000", "1\x1e")},
+		{"no record terminator", bytes.Replace(rawRecord("245001000000", "10\x1fadune\x1e"), []byte{recordTerminator}, []byte{fieldTerminator}, 1)},
+		{"signed base address", withLeader(rawRecord("245001000000", "10\x1fadune\x1e"), 12, "+0037")},
+		{"directory not terminated", rawRecord("245001000000x", "10\x1fadune\x1e")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The malformed record is skipped and the next one is read
+			data := append(append([]byte{}, tt.data...), rawRecord("001000300000", "42\x1e")...)
+			r := NewReader(bytes.NewReader(data))
+
+			_, err := r.Read()
+			var recordErr *RecordError
+			if !errors.As(err, &recordErr) {
+				t.Fatalf("Read = %v, want a *RecordError", err)
+			}
+			record, err := r.Read()
+			if err != nil {
+				t.Fatalf("Read after the malformed record: %v", err)
+			}
+			if field := record.Field("001"); field == nil || field.Value != "42" {
+				t.Errorf("next record = %+v", record)
+			}
+		})
+	}
+}
+
+func TestReaderInvalidLength(t *testing.T) {
+	for _, prefix := range []string{"abcde", "-0100", "+0100", "00010"} {
+		_, err := NewReader(strings.NewReader(prefix + strings.Repeat(" ", 100))).Read()
+		var recordErr *RecordError
+		if err == nil || errors.As(err, &recordErr) {
+			t.Errorf("Read with length %q = %v, want a stream error", prefix, err)
+		}
+	}
+	if _, err := NewReader(strings.NewReader("00100nam")).Read(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Read of a truncated record = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
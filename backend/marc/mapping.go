@@ -0,0 +1,230 @@
+package marc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Book fields a mapping reads from and writes to MARC records
+const (
+	FieldTitle     = "title"
+	FieldAuthor    = "author"
+	FieldISBN      = "isbn"
+	FieldCategory  = "category"
+	FieldPublisher = "publisher"
+	FieldYear      = "year"
+)
+
+// Fields lists the book fields in the order records are checked for them
+var Fields = []string{FieldTitle, FieldAuthor, FieldISBN, FieldCategory, FieldPublisher, FieldYear}
+
+// Source is a MARC field and the subfields read from it, written 245$ab, or
+// a control field such as 001
+type Source struct {
+	Tag   string
+	Codes string // Subfield codes; empty for control fields
+}
+
+func (s Source) String() string {
+	if s.Codes == "" {
+		return s.Tag
+	}
+	return s.Tag + "$" + s.Codes
+}
+
+// ParseSource parses a source written TAG$codes, or TAG for a control field
+func ParseSource(s string) (Source, error) {
+	tag, codes, _ := strings.Cut(strings.TrimSpace(s), "$")
+	if !validTag(tag) || isControlTag(tag) != (codes == "") {
+		return Source{}, fmt.Errorf("invalid MARC source %q, expected e.g. 245$ab, or 001 for a control field", s)
+	}
+	return Source{Tag: tag, Codes: codes}, nil
+}
+
+// Mapping maps each book field to the MARC sources it is read from, in order
+// of preference. Exports write a field to its first source, into the first
+// subfield code.
+type Mapping map[string][]Source
+
+// DefaultMapping reads the title from 245 $a and $b, the author from the
+// personal or corporate name main entry, the ISBN from 020 $a, the category
+// from the first topical subject heading, and the publisher and year from
+// the publication statement of RDA (264) or AACR2 (260) records
+var DefaultMapping = Mapping{
+	FieldTitle:     {{"245", "ab"}},
+	FieldAuthor:    {{"100", "a"}, {"110", "a"}},
+	FieldISBN:      {{"020", "a"}},
+	FieldCategory:  {{"650", "a"}},
+	FieldPublisher: {{"264", "b"}, {"260", "b"}},
+	FieldYear:      {{"264", "c"}, {"260", "c"}},
+}
+
+// Indicators of the fields exports write, by tag. Other fields get blanks.
+var exportIndicators = map[string][2]byte{
+	"100": {'1', ' '}, // Surname first
+	"245": {'1', '0'}, // Title added entry, no nonfiling characters
+	"650": {' ', '4'}, // Source of the heading not specified
+	"264": {' ', '1'}, // Publication
+}
+
+// LoadMapping reads a mapping from JSON such as
+//
+//	{"title": ["245$abnp"], "category": ["650$a", "655$a"]}
+//
+// Book fields the JSON leaves out keep their DefaultMapping sources.
+func LoadMapping(r io.Reader) (Mapping, error) {
+	var raw map[string][]string
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid MARC mapping: %w", err)
+	}
+	mapping := make(Mapping, len(Fields))
+	for field, sources := range DefaultMapping {
+		mapping[field] = sources
+	}
+	for field, values := range raw {
+		if _, ok := DefaultMapping[field]; !ok {
+			return nil, fmt.Errorf("invalid MARC mapping: unknown field %q, expected one of %s", field, strings.Join(Fields, ", "))
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("invalid MARC mapping: %s has no sources", field)
+		}
+		sources := make([]Source, len(values))
+		for i, value := range values {
+			source, err := ParseSource(value)
+			if err != nil {
+				return nil, err
+			}
+			sources[i] = source
+		}
+		mapping[field] = sources
+	}
+	return mapping, nil
+}
+
+// LoadMappingFile reads a mapping from a JSON file
+func LoadMappingFile(path string) (Mapping, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadMapping(f)
+}
+
+// Value returns a book field read from the first of its sources the record
+// has: the subfields of the first matching field joined by spaces, without
+// the trailing ISBD punctuation
+func (m Mapping) Value(record *Record, field string) string {
+	for _, source := range m[field] {
+		for _, f := range record.Fields {
+			if f.Tag != source.Tag {
+				continue
+			}
+			if f.IsControl() {
+				if value := strings.TrimSpace(f.Value); value != "" {
+					return value
+				}
+				continue
+			}
+			var parts []string
+			for _, sub := range f.Subfields {
+				if strings.IndexByte(source.Codes, sub.Code) >= 0 {
+					if value := trimPunctuation(sub.Value); value != "" {
+						parts = append(parts, value)
+					}
+				}
+			}
+			if len(parts) > 0 {
+				return strings.Join(parts, " ")
+			}
+		}
+	}
+	return ""
+}
+
+// trimPunctuation removes the spaces and the ISBD punctuation ending a
+// subfield, as in "Dune /" or "Science fiction."
+func trimPunctuation(s string) string {
+	s = strings.TrimSpace(s)
+	if n := len(s); n > 0 && strings.IndexByte("/:;,=.", s[n-1]) >= 0 {
+		s = strings.TrimSpace(s[:n-1])
+	}
+	return s
+}
+
+// Unmapped returns the fields and subfields of a record that no source of
+// the mapping reads, as tags such as 260 or subfields such as 245$c, sorted
+func (m Mapping) Unmapped(record *Record) []string {
+	codes := make(map[string]string)
+	for _, sources := range m {
+		for _, source := range sources {
+			codes[source.Tag] += source.Codes
+		}
+	}
+
+	found := make(map[string]bool)
+	for _, f := range record.Fields {
+		read, mapped := codes[f.Tag]
+		switch {
+		case !mapped:
+			found[f.Tag] = true
+		case !f.IsControl():
+			for _, sub := range f.Subfields {
+				if strings.IndexByte(read, sub.Code) < 0 {
+					found[f.Tag+"$"+string(sub.Code)] = true
+				}
+			}
+		}
+	}
+	unmapped := make([]string, 0, len(found))
+	for key := range found {
+		unmapped = append(unmapped, key)
+	}
+	sort.Strings(unmapped)
+	return unmapped
+}
+
+// Record builds the MARC record of a book from its fields: the control
+// number in 001, the last change in 005 and each non-empty field in its
+// first source, in tag order. Fields sharing a tag, such as the publisher
+// and year in 264, are written as subfields of one field.
+func (m Mapping) Record(id string, updated time.Time, values map[string]string) *Record {
+	record := &Record{
+		Leader: defaultLeader,
+		Fields: []Field{
+			{Tag: "001", Value: id},
+			{Tag: "005", Value: updated.UTC().Format("20060102150405") + ".0"},
+		},
+	}
+	for _, field := range Fields {
+		value := values[field]
+		if value == "" || len(m[field]) == 0 {
+			continue
+		}
+		source := m[field][0]
+		if isControlTag(source.Tag) {
+			record.Fields = append(record.Fields, Field{Tag: source.Tag, Value: value})
+			continue
+		}
+		if f := record.Field(source.Tag); f != nil {
+			f.Subfields = append(f.Subfields, Subfield{Code: source.Codes[0], Value: value})
+			continue
+		}
+		ind := exportIndicators[source.Tag]
+		record.Fields = append(record.Fields, Field{
+			Tag:       source.Tag,
+			Ind1:      ind[0],
+			Ind2:      ind[1],
+			Subfields: []Subfield{{Code: source.Codes[0], Value: value}},
+		})
+	}
+	sort.SliceStable(record.Fields, func(i, j int) bool {
+		return record.Fields[i].Tag < record.Fields[j].Tag
+	})
+	return record
+}
@@ -0,0 +1,108 @@
+package marc
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSource(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Source
+		ok   bool
+	}{
+		{"245$ab", Source{"245", "ab"}, true},
+		{" 001 ", Source{"001", ""}, true},
+		{"245", Source{}, false},
+		{"001$a", Source{}, false},
+		{"24$a", Source{}, false},
+		{"", Source{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSource(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseSource(%q) = %v, %v, want %v (ok %v)", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLoadMapping(t *testing.T) {
+	mapping, err := LoadMapping(strings.NewReader(`{"title": ["245$abnp"], "category": ["650$a", "655$a"]}`))
+	if err != nil {
+		t.Fatalf("LoadMapping: %v", err)
+	}
+	want := Mapping{
+		FieldTitle:     {{"245", "abnp"}},
+		FieldAuthor:    DefaultMapping[FieldAuthor],
+		FieldISBN:      DefaultMapping[FieldISBN],
+		FieldCategory:  {{"650", "a"}, {"655", "a"}},
+		FieldPublisher: DefaultMapping[FieldPublisher],
+		FieldYear:      DefaultMapping[FieldYear],
+	}
+	if !reflect.DeepEqual(mapping, want) {
+		t.Errorf("mapping = %v, want %v", mapping, want)
+	}
+
+	for _, in := range []string{`{"pages": ["300$a"]}`, `{"title": []}`, `{"title": ["245"]}`, `[]`} {
+		if _, err := LoadMapping(strings.NewReader(in)); err == nil {
+			t.Errorf("LoadMapping(%s) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestMappingValue(t *testing.T) {
+	record := testRecord()
+	record.Fields = append(record.Fields,
+		Field{Tag: "650", Ind2: '0', Subfields: []Subfield{{Code: 'a', Value: "Science fiction."}, {Code: 'v', Value: "Fiction."}}},
+		Field{Tag: "650", Ind2: '0', Subfields: []Subfield{{Code: 'a', Value: "Ecology"}}},
+	)
+
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{FieldTitle, "Dune"},
+		{FieldAuthor, "Herbert, Frank"},
+		{FieldISBN, "9780441172719"},
+		{FieldCategory, "Science fiction"},
+	}
+	for _, tt := range tests {
+		if got := DefaultMapping.Value(record, tt.field); got != tt.want {
+			t.Errorf("Value(%s) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+
+	control := Mapping{FieldISBN: {{"024", "a"}, {"001", ""}}}
+	if got := control.Value(record, FieldISBN); got != "42" {
+		t.Errorf("Value from a control field = %q, want 42", got)
+	}
+
+	if got, want := DefaultMapping.Unmapped(record), []string{"001", "245$c", "650$v"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmapped = %v, want %v", got, want)
+	}
+}
+
+func TestMappingRecord(t *testing.T) {
+	updated := time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC)
+	record := DefaultMapping.Record("7", updated, map[string]string{
+		FieldTitle:     "Dune",
+		FieldAuthor:    "Frank Herbert",
+		FieldISBN:      "9780441172719",
+		FieldPublisher: "Chilton Books",
+		FieldYear:      "1965",
+	})
+
+	want := []Field{
+		{Tag: "001", Value: "7"},
+		{Tag: "005", Value: "20240301123000.0"},
+		{Tag: "020", Subfields: []Subfield{{Code: 'a', Value: "9780441172719"}}}, // Blank indicators when written
+		{Tag: "100", Ind1: '1', Ind2: ' ', Subfields: []Subfield{{Code: 'a', Value: "Frank Herbert"}}},
+		{Tag: "245", Ind1: '1', Ind2: '0', Subfields: []Subfield{{Code: 'a', Value: "Dune"}}},
+		{Tag: "264", Ind1: ' ', Ind2: '1', Subfields: []Subfield{{Code: 'b', Value: "Chilton Books"}, {Code: 'c', Value: "1965"}}},
+	}
+	if !reflect.DeepEqual(record.Fields, want) {
+		t.Errorf("fields = %+v, want %+v", record.Fields, want)
+	}
+}
@@ -0,0 +1,85 @@
+// Package marc reads and writes MARC 21 bibliographic records, in the binary
+// ISO 2709 exchange format and in MARCXML, and maps their fields to the
+// fields of a book.
+package marc
+
+import "strings"
+
+// Record is a MARC record: a leader followed by control and data fields in
+// the order they appear
+type Record struct {
+	Leader string
+	Fields []Field
+}
+
+// Field is a control field, which has a Value, or a data field, which has
+// indicators and subfields. Control fields have tags 001 to 009.
+type Field struct {
+	Tag        string
+	Value      string
+	Ind1, Ind2 byte
+	Subfields  []Subfield
+}
+
+// Subfield is a subfield of a data field, such as $a
+type Subfield struct {
+	Code  byte
+	Value string
+}
+
+// RecordError is a malformed record that was skipped. Reading continues
+// with the next record.
+type RecordError struct {
+	Message string
+}
+
+func (e *RecordError) Error() string {
+	return e.Message
+}
+
+// IsControl reports whether the field is a control field
+func (f Field) IsControl() bool {
+	return isControlTag(f.Tag)
+}
+
+// Subfield returns the value of the first subfield with the given code
+func (f Field) Subfield(code byte) string {
+	for _, sub := range f.Subfields {
+		if sub.Code == code {
+			return sub.Value
+		}
+	}
+	return ""
+}
+
+// Field returns the first field with the given tag, or nil
+func (r *Record) Field(tag string) *Field {
+	for i := range r.Fields {
+		if r.Fields[i].Tag == tag {
+			return &r.Fields[i]
+		}
+	}
+	return nil
+}
+
+func isControlTag(tag string) bool {
+	return strings.HasPrefix(tag, "00")
+}
+
+// validTag reports whether tag is three ASCII letters or digits
+func validTag(tag string) bool {
+	if len(tag) != 3 {
+		return false
+	}
+	for i := 0; i < 3; i++ {
+		c := tag[i]
+		if (c < '0' || c > '9') && (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultLeader is the leader of new records: a new (n) language material
+// (a) monograph (m), in Unicode (a), at minimal level (7)
+const defaultLeader = "00000nam a22000007u 4500"
@@ -0,0 +1,181 @@
+package marc
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+// Namespace is the XML namespace of MARCXML
+const Namespace = "http://www.loc.gov/MARC21/slim"
+
+// xmlRecord is a MARCXML record element. Elements match in any namespace,
+// so records with a marc: prefix read the same.
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"record"`
+	Xmlns         string            `xml:"xmlns,attr,omitempty"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// XMLReader reads the records of a MARCXML collection or single record
+type XMLReader struct {
+	d *xml.Decoder
+}
+
+// NewXMLReader returns an XMLReader reading records from r
+func NewXMLReader(r io.Reader) *XMLReader {
+	return &XMLReader{d: xml.NewDecoder(r)}
+}
+
+// Read returns the next record, or io.EOF after the last one. A record with
+// invalid tags or codes gives a *RecordError and is skipped; malformed XML
+// ends the stream.
+func (r *XMLReader) Read() (*Record, error) {
+	for {
+		token, err := r.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+		var x xmlRecord
+		if err := r.d.DecodeElement(&x, &start); err != nil {
+			return nil, err
+		}
+		return x.record()
+	}
+}
+
+// record converts a MARCXML record. Control fields come before data fields,
+// as MARCXML lists them.
+func (x xmlRecord) record() (*Record, error) {
+	record := &Record{Leader: x.Leader}
+	for _, f := range x.ControlFields {
+		if !validTag(f.Tag) {
+			return nil, &RecordError{"invalid control field tag " + f.Tag}
+		}
+		record.Fields = append(record.Fields, Field{Tag: f.Tag, Value: f.Value})
+	}
+	for _, f := range x.DataFields {
+		if !validTag(f.Tag) {
+			return nil, &RecordError{"invalid data field tag " + f.Tag}
+		}
+		field := Field{Tag: f.Tag, Ind1: xmlIndicator(f.Ind1), Ind2: xmlIndicator(f.Ind2)}
+		for _, sub := range f.Subfields {
+			if len(sub.Code) != 1 {
+				return nil, &RecordError{"invalid subfield code in field " + f.Tag}
+			}
+			field.Subfields = append(field.Subfields, Subfield{Code: sub.Code[0], Value: sub.Value})
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	return record, nil
+}
+
+func xmlIndicator(s string) byte {
+	if len(s) != 1 {
+		return ' '
+	}
+	return s[0]
+}
+
+// XMLWriter writes records as a MARCXML collection. Close ends the
+// collection.
+type XMLWriter struct {
+	e       *xml.Encoder
+	started bool
+}
+
+// NewXMLWriter returns an XMLWriter writing a collection to w
+func NewXMLWriter(w io.Writer) *XMLWriter {
+	return &XMLWriter{e: xml.NewEncoder(w)}
+}
+
+var collection = xml.StartElement{
+	Name: xml.Name{Local: "collection"},
+	Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: Namespace}},
+}
+
+func (w *XMLWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if err := w.e.EncodeToken(xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8"`)}); err != nil {
+		return err
+	}
+	return w.e.EncodeToken(collection)
+}
+
+// Write writes a record of the collection. An empty leader is replaced by
+// one for a new monograph.
+func (w *XMLWriter) Write(record *Record) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	return w.e.Encode(newXMLRecord(record))
+}
+
+// MarshalXML writes the record as a MARCXML record element declaring the
+// MARCXML namespace, so records can be embedded in other XML documents
+func (r *Record) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	x := newXMLRecord(r)
+	x.Xmlns = Namespace
+	return e.Encode(x)
+}
+
+// newXMLRecord converts a record to its MARCXML element. An empty leader is
+// replaced by one for a new monograph.
+func newXMLRecord(record *Record) xmlRecord {
+	x := xmlRecord{Leader: record.Leader}
+	if len(x.Leader) != 24 {
+		x.Leader = defaultLeader
+	}
+	for _, field := range record.Fields {
+		if field.IsControl() {
+			x.ControlFields = append(x.ControlFields, xmlControlField{Tag: field.Tag, Value: field.Value})
+			continue
+		}
+		f := xmlDataField{
+			Tag:  field.Tag,
+			Ind1: string(indicator(field.Ind1)),
+			Ind2: string(indicator(field.Ind2)),
+		}
+		for _, sub := range field.Subfields {
+			f.Subfields = append(f.Subfields, xmlSubfield{Code: string(sub.Code), Value: sub.Value})
+		}
+		x.DataFields = append(x.DataFields, f)
+	}
+	return x
+}
+
+// Close ends the collection and flushes it
+func (w *XMLWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if err := w.e.EncodeToken(collection.End()); err != nil {
+		return err
+	}
+	return w.e.Flush()
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"digital-library/backend/config"
+
+	jwtware "github.com/gofiber/contrib/jwt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// FeedLogin checks the username and password of HTTP Basic authentication
+// and returns the identity of the user
+type FeedLogin func(c *fiber.Ctx, username, password string) (Claims, bool)
+
+// feedScope marks the claims of a feed token
+const feedScope = "feed"
+
+// feedKey signs feed tokens. It differs from the key of login JWTs, so a
+// feed token is no Bearer token for the rest of the API and a login JWT is
+// not accepted in the token query parameter, where it would end up in links
+// and logs.
+func feedKey(cfg *config.Config) []byte {
+	key := sha256.Sum256([]byte("feed-token:" + cfg.JWTSecret))
+	return key[:]
+}
+
+// NewFeedToken issues a token that authenticates the user to the catalog
+// feeds, and only to them, until it expires after cfg.FeedTokenTTL
+func NewFeedToken(cfg *config.Config, claims Claims) (string, time.Time, error) {
+	now := time.Now()
+	expires := now.Add(cfg.FeedTokenTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id":  claims.UserID,
+		"username": claims.Username,
+		"role":     claims.Role,
+		"scope":    feedScope,
+		"exp":      expires.Unix(),
+		"iat":      now.Unix(),
+	})
+	signed, err := token.SignedString(feedKey(cfg))
+	return signed, expires, err
+}
+
+// ProtectedFeed authenticates the reading apps that browse the catalog
+// feeds. They may send a JWT as Protected takes it, HTTP Basic credentials
+// checked by login, or, for apps that cannot set headers, a feed token from
+// NewFeedToken in the token query parameter. Either way the identity is
+// stored where Protected stores it, so CurrentUser and RequireRole work
+// after it. Requests without valid credentials get a Basic challenge, so
+// apps ask the user for them.
+func ProtectedFeed(cfg *config.Config, login FeedLogin) fiber.Handler {
+	challenge := func(c *fiber.Ctx, _ error) error {
+		return feedChallenge(c)
+	}
+	jwtAuth := jwtware.New(jwtware.Config{
+		SigningKey: jwtware.SigningKey{
+			JWTAlg: jwtware.HS256,
+			Key:    []byte(cfg.JWTSecret),
+		},
+		ErrorHandler: challenge,
+	})
+	feedTokenAuth := jwtware.New(jwtware.Config{
+		SigningKey: jwtware.SigningKey{
+			JWTAlg: jwtware.HS256,
+			Key:    feedKey(cfg),
+		},
+		TokenLookup:  "query:token",
+		ErrorHandler: challenge,
+	})
+
+	return func(c *fiber.Ctx) error {
+		authorization := c.Get(fiber.HeaderAuthorization)
+		if authorization == "" && c.Query("token") != "" {
+			return feedTokenAuth(c)
+		}
+		scheme, credentials, _ := strings.Cut(authorization, " ")
+		if !strings.EqualFold(scheme, "Basic") {
+			return jwtAuth(c)
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(credentials))
+		if err != nil {
+			return feedChallenge(c)
+		}
+		username, password, ok := strings.Cut(string(decoded), ":")
+		if !ok {
+			return feedChallenge(c)
+		}
+		claims, ok := login(c, username, password)
+		if !ok {
+			return feedChallenge(c)
+		}
+		// The claims of a token issued by handlers.Login, as the JWT
+		// middleware would have decoded them
+		c.Locals("user", &jwt.Token{Valid: true, Claims: jwt.MapClaims{
+			"user_id":  float64(claims.UserID),
+			"username": claims.Username,
+			"role":     claims.Role,
+		}})
+		return c.Next()
+	}
+}
+
+// feedChallenge asks a reading app to authenticate
+func feedChallenge(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="Digital Library", charset="UTF-8"`)
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": "Authentication required",
+	})
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims holds the identity that handlers.Login puts into the JWT
+type Claims struct {
+	UserID   int
+	Username string
+	Role     string
+}
+
+// CurrentUser returns the claims of the token validated by Protected
+func CurrentUser(c *fiber.Ctx) (Claims, bool) {
+	token, ok := c.Locals("user").(*jwt.Token)
+	if !ok {
+		return Claims{}, false
+	}
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return Claims{}, false
+	}
+
+	var claims Claims
+	// JSON numbers are decoded as float64
+	if id, ok := mapClaims["user_id"].(float64); ok {
+		claims.UserID = int(id)
+	}
+	claims.Username, _ = mapClaims["username"].(string)
+	claims.Role, _ = mapClaims["role"].(string)
+	return claims, claims.Username != "" && claims.Role != ""
+}
+
+// RequireRole only lets requests through when the authenticated user has one
+// of the given roles. It must run after Protected. With no roles, any
+// authenticated user is allowed.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := CurrentUser(c)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid or expired JWT",
+			})
+		}
+		if len(roles) == 0 {
+			return c.Next()
+		}
+		for _, role := range roles {
+			if claims.Role == role {
+				return c.Next()
+			}
+		}
+		return Forbidden(c, "Insufficient permissions for this action")
+	}
+}
+
+// Forbidden writes the 403 response used for every authorization failure
+func Forbidden(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+		"error": message,
+	})
+}
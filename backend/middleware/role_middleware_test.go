@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims // nil for a request without a token
+		roles  []string
+		status int
+	}{
+		{"admin on an admin route", jwt.MapClaims{"user_id": 1.0, "username": "admin", "role": "admin"}, []string{"admin"}, fiber.StatusOK},
+		{"user on an admin route", jwt.MapClaims{"user_id": 2.0, "username": "alice", "role": "user"}, []string{"admin"}, fiber.StatusForbidden},
+		{"user on a user route", jwt.MapClaims{"user_id": 2.0, "username": "alice", "role": "user"}, []string{"admin", "user"}, fiber.StatusOK},
+		{"any role", jwt.MapClaims{"user_id": 3.0, "username": "carol", "role": "librarian"}, nil, fiber.StatusOK},
+		{"no role claim", jwt.MapClaims{"user_id": 2.0, "username": "alice"}, nil, fiber.StatusUnauthorized},
+		{"no token", nil, []string{"user"}, fiber.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		app := fiber.New()
+		app.Use(func(c *fiber.Ctx) error {
+			if tt.claims != nil {
+				c.Locals("user", jwt.NewWithClaims(jwt.SigningMethodHS256, tt.claims))
+			}
+			return c.Next()
+		})
+		app.Get("/", RequireRole(tt.roles...), func(c *fiber.Ctx) error {
+			claims, _ := CurrentUser(c)
+			return c.SendString(claims.Username)
+		})
+
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+func TestCurrentUser(t *testing.T) {
+	app := fiber.New()
+	var got Claims
+	var ok bool
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Locals("user", jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": 7.0, "username": "alice", "role": "user"}))
+		got, ok = CurrentUser(c)
+		return nil
+	})
+	if _, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Claims{UserID: 7, Username: "alice", Role: "user"}); !ok || got != want {
+		t.Errorf("CurrentUser = %+v, %v, want %+v, true", got, ok, want)
+	}
+}
@@ -10,21 +10,313 @@ type Book struct {
 	Title     string    `json:"title"`
 	Author    string    `json:"author"`
 	ISBN      string    `json:"isbn"`
-	Quantity  int       `json:"quantity"`
+	Quantity  int       `json:"quantity"` // Available copies; sets the initial number of copies on create
 	Category  string    `json:"category"`
+	Publisher string    `json:"publisher"`
+	Year      *int      `json:"year,omitempty"` // Year of publication; nil when unknown
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Set on search results only
+	Rank      float32        `json:"rank,omitempty"`      // Relevance to the search, higher is better
+	Highlight *BookHighlight `json:"highlight,omitempty"` // Matched terms marked up
+}
+
+// BookHighlight holds the searchable fields of a book with every matched term
+// wrapped in <mark></mark>. The text itself is not HTML-escaped.
+type BookHighlight struct {
+	Title    string `json:"title"`
+	Author   string `json:"author"`
+	Category string `json:"category"`
+}
+
+// Copy statuses. Only available copies count towards Book.Quantity.
+const (
+	ItemStatusAvailable = "available"
+	ItemStatusOnLoan    = "on_loan"
+	ItemStatusLost      = "lost"
+	ItemStatusRetired   = "retired" // Withdrawn from the collection
+)
+
+// BookItem is a physical copy of a book
+type BookItem struct {
+	ID           int        `json:"id"`
+	BookID       int        `json:"book_id"`
+	Barcode      string     `json:"barcode"`
+	Status       string     `json:"status"`
+	Condition    string     `json:"condition"` // good or damaged
+	Location     string     `json:"location"`  // Shelf or branch
+	AcquiredDate time.Time  `json:"acquired_date"`
+	RetiredAt    *time.Time `json:"retired_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// BookFile is a digital file of a book, such as an EPUB, PDF or audiobook.
+// Its content is kept in the blob store under StorageKey.
+type BookFile struct {
+	ID         int       `json:"id"`
+	BookID     int       `json:"book_id"`
+	Filename   string    `json:"filename"`
+	MimeType   string    `json:"mime_type"`
+	Size       int64     `json:"size"`     // In bytes
+	Checksum   string    `json:"checksum"` // SHA-256, in hex
+	StorageKey string    `json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// BookPage is a page of books
+type BookPage struct {
+	Data       []Book `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"` // Empty on the last page
+	Total      int    `json:"total"`                 // Books matching the filters on every page
+
+	// Set when nothing matched the search itself and the page holds the
+	// books whose title or author is spelled most like it instead
+	Similar bool `json:"similar,omitempty"`
+
+	// Counts of the matching books by facet name, when requested
+	Facets map[string][]FacetBucket `json:"facets,omitempty"`
+}
+
+// FacetBucket counts the matching books with one value of a facet. The value
+// can be passed back as the filter of the same name to drill down.
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// BookChange is the latest change to a book, for metadata harvesting: the
+// book as it is now, or its deletion
+type BookChange struct {
+	BookID    int       `json:"book_id"`
+	Datestamp time.Time `json:"datestamp"` // When the book was last updated or deleted
+	Deleted   bool      `json:"deleted"`
+	Category  string    `json:"category"`
+	Book      *Book     `json:"book,omitempty"` // nil when deleted
+}
+
+// BookSuggestion is an autocomplete match for a partly typed title or author
+type BookSuggestion struct {
+	BookID int     `json:"book_id"`
+	Title  string  `json:"title"`
+	Author string  `json:"author"`
+	Score  float32 `json:"score"` // Trigram similarity to the query, from 0 to 1
+}
+
+// Citation is a book formatted in a citation style
+type Citation struct {
+	BookID int    `json:"book_id"`
+	Style  string `json:"style"`
+	Text   string `json:"text"`
+	HTML   string `json:"html"` // The text, HTML-escaped, with the title in <i></i>
+}
+
+// Outcomes of an imported row
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped" // The book already matches the row
+	ImportError   = "error"
+)
+
+// ImportRow is the outcome of one row of a book import
+type ImportRow struct {
+	Row    int    `json:"row"` // Line of a CSV record, where the header is row 1, or number of a MARC record
+	ISBN   string `json:"isbn,omitempty"`
+	Status string `json:"status"`            // One of the Import* constants
+	BookID int    `json:"book_id,omitempty"` // Book created, updated or skipped; unset for books a dry run would create
+	Reason string `json:"reason,omitempty"`  // Why the row was skipped or failed
+}
+
+// ImportReport summarizes a book import row by row
+type ImportReport struct {
+	DryRun  bool        `json:"dry_run"` // Nothing was saved
+	Created int         `json:"created"`
+	Updated int         `json:"updated"`
+	Skipped int         `json:"skipped"`
+	Errors  int         `json:"errors"`
+	Rows    []ImportRow `json:"rows"`
+
+	// MARC fields and subfields the mapping does not read, such as 260 or
+	// 245$c, with the number of records that have them
+	Unmapped map[string]int `json:"unmapped,omitempty"`
 }
 
 // LendingRecord represents the structure for a lending record
 type LendingRecord struct {
-	ID         int        `json:"id"`
-	BookID     int        `json:"book_id"` // Foreign key to Book
-	Borrower   string     `json:"borrower"`
-	BorrowDate time.Time  `json:"borrow_date"`
-	ReturnDate *time.Time `json:"return_date,omitempty"` // Pointer to allow null
-	CreatedAt  time.Time  `json:"created_at"`
-	UpdatedAt  time.Time  `json:"updated_at"`
+	ID           int        `json:"id"`
+	BookID       int        `json:"book_id"`           // Foreign key to Book
+	ItemID       *int       `json:"item_id,omitempty"` // Copy lent; nil for loans closed before copies were tracked
+	UserID       *int       `json:"user_id,omitempty"` // Borrower's account; nil for loans that predate accounts
+	Borrower     string     `json:"borrower"`          // Borrower's name at the time of the loan
+	BorrowDate   time.Time  `json:"borrow_date"`
+	DueDate      time.Time  `json:"due_date"`
+	ReturnDate   *time.Time `json:"return_date,omitempty"`    // Pointer to allow null
+	LoanPolicyID *int       `json:"loan_policy_id,omitempty"` // Policy resolved at lend time
+	OverdueAt    *time.Time `json:"overdue_at,omitempty"`     // Set when the overdue job flags the loan
+	// Condition of the copy when the loan was closed: good, damaged or lost
+	ReturnCondition *string   `json:"return_condition,omitempty"`
+	RenewalCount    int       `json:"renewal_count"` // Times the due date was extended
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Lending statuses reported in LendingRecordDetail.Status
+const (
+	LendingStatusActive   = "active"
+	LendingStatusOverdue  = "overdue"
+	LendingStatusReturned = "returned"
+	LendingStatusLost     = "lost"
+)
+
+// Conditions recorded when a loan is closed
+const (
+	ConditionGood    = "good"
+	ConditionDamaged = "damaged"
+	ConditionLost    = "lost"
+)
+
+// LendingStatus returns the status of the record on the given day
+func (r LendingRecord) LendingStatus(today time.Time) string {
+	if r.ReturnDate != nil {
+		if r.ReturnCondition != nil && *r.ReturnCondition == ConditionLost {
+			return LendingStatusLost
+		}
+		return LendingStatusReturned
+	}
+	if r.DueDate.Before(today) {
+		return LendingStatusOverdue
+	}
+	return LendingStatusActive
+}
+
+// LendingRecordDetail extends LendingRecord to include book details
+type LendingRecordDetail struct {
+	LendingRecord         // Embed LendingRecord
+	BookTitle     string  `json:"book_title"`
+	BookAuthor    string  `json:"book_author"`
+	ItemBarcode   *string `json:"item_barcode,omitempty"`
+	Status        string  `json:"status"` // active, overdue, returned or lost
+}
+
+// LendingRecordPage is a page of lending records
+type LendingRecordPage struct {
+	Data       []LendingRecordDetail `json:"data"`
+	NextCursor string                `json:"next_cursor,omitempty"` // Empty on the last page
+	Total      int                   `json:"total"`                 // Records matching the filters on every page
+}
+
+// LoanPolicy sets the loan period, renewal limit, grace period and fines for
+// loans. A nil Category or Role matches any value. Amounts are in cents.
+type LoanPolicy struct {
+	ID              int       `json:"id"`
+	Category        *string   `json:"category"`
+	Role            *string   `json:"role"`
+	LoanDays        int       `json:"loan_days"`
+	MaxRenewals     int       `json:"max_renewals"`
+	GraceDays       int       `json:"grace_days"`
+	DailyFineCents  int       `json:"daily_fine_cents"`  // Charged per day late beyond the grace period
+	LostFeeCents    int       `json:"lost_fee_cents"`    // Charged when a copy is reported lost
+	DamagedFeeCents int       `json:"damaged_fee_cents"` // Charged when a copy is returned damaged
+	HoldPickupDays  int       `json:"hold_pickup_days"`  // Days to pick up a copy set aside for a hold
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// DefaultLoanPolicy applies when no stored policy matches a loan
+var DefaultLoanPolicy = LoanPolicy{LoanDays: 14, MaxRenewals: 2, GraceDays: 0, HoldPickupDays: 3}
+
+// BorrowCount represents the structure for book borrow counts
+type BorrowCount struct {
+	BookID    int    `json:"book_id"`
+	BookTitle string `json:"book_title"`
+	Borrows   int    `json:"borrows"`
+}
+
+// Hold statuses. Waiting and ready holds are open; the rest are closed.
+const (
+	HoldStatusWaiting   = "waiting"   // Queued until a copy is available
+	HoldStatusReady     = "ready"     // A copy is set aside for pickup
+	HoldStatusFulfilled = "fulfilled" // The borrower borrowed the book
+	HoldStatusCancelled = "cancelled"
+	HoldStatusExpired   = "expired" // Not picked up in time
+)
+
+// Hold is a borrower's place in the queue for a book
+type Hold struct {
+	ID        int        `json:"id"`
+	BookID    int        `json:"book_id"`
+	UserID    *int       `json:"user_id,omitempty"`
+	Borrower  string     `json:"borrower"`
+	Status    string     `json:"status"`
+	ReadyAt   *time.Time `json:"ready_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Last day a ready hold can be picked up
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// IsOpen reports whether the hold is still waiting or ready
+func (h Hold) IsOpen() bool {
+	return h.Status == HoldStatusWaiting || h.Status == HoldStatusReady
+}
+
+// HoldDetail adds the book title and queue position to a hold
+type HoldDetail struct {
+	Hold
+	BookTitle string `json:"book_title"`
+	Position  *int   `json:"position,omitempty"` // 1-based place in the queue of waiting holds
+}
+
+// Fine ledger entry types
+const (
+	FineEntryCharge  = "charge"
+	FineEntryPayment = "payment"
+	FineEntryWaiver  = "waiver"
+)
+
+// Reasons for fine charges
+const (
+	FineReasonOverdue = "overdue"
+	FineReasonLost    = "lost"
+	FineReasonDamaged = "damaged"
+)
+
+// FineEntry is a charge, payment or waiver in a borrower's fines ledger.
+// Amounts are positive cents; the entry type decides the sign.
+type FineEntry struct {
+	ID              int       `json:"id"`
+	UserID          *int      `json:"user_id,omitempty"`
+	Borrower        string    `json:"borrower"`
+	LendingRecordID *int      `json:"lending_record_id,omitempty"`
+	EntryType       string    `json:"entry_type"`       // charge, payment or waiver
+	Reason          *string   `json:"reason,omitempty"` // overdue, lost or damaged for charges
+	AmountCents     int       `json:"amount_cents"`
+	Note            string    `json:"note"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// FineBalance sums a borrower's fines ledger
+type FineBalance struct {
+	UserID       int `json:"user_id"`
+	ChargedCents int `json:"charged_cents"`
+	PaidCents    int `json:"paid_cents"`
+	WaivedCents  int `json:"waived_cents"`
+	BalanceCents int `json:"balance_cents"` // Outstanding amount
+}
+
+// OverdueLoan is a row of the overdue loans report
+type OverdueLoan struct {
+	LendingRecordID int        `json:"lending_record_id"`
+	BookID          int        `json:"book_id"`
+	BookTitle       string     `json:"book_title"`
+	BookAuthor      string     `json:"book_author"`
+	UserID          *int       `json:"user_id,omitempty"`
+	Borrower        string     `json:"borrower"`
+	BorrowDate      time.Time  `json:"borrow_date"`
+	DueDate         time.Time  `json:"due_date"`
+	DaysOverdue     int        `json:"days_overdue"`
+	OverdueAt       *time.Time `json:"overdue_at,omitempty"`
 }
 
 // MonthlyTrend represents the structure for monthly lending counts
@@ -39,14 +331,21 @@ type CategoryDistribution struct {
 	Count    int    `json:"count"`
 }
 
+// User roles stored in users.role and in the JWT "role" claim
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
 // User represents a user in the system
 type User struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         int       `json:"id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	Role       string    `json:"role"`
+	CardNumber string    `json:"card_number"` // Patron card number
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
 // LoginRequest represents the structure for login requests
@@ -61,6 +360,15 @@ type LoginResponse struct {
 	User  User   `json:"user"`
 }
 
+// FeedTokenResponse is a token for the OPDS catalog and the catalog URLs
+// that carry it
+type FeedTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	OPDSURL   string    `json:"opds_url"`
+	OPDS2URL  string    `json:"opds2_url"`
+}
+
 // RegisterRequest represents the structure for registration requests
 type RegisterRequest struct {
 	Username string `json:"username"`
@@ -70,10 +378,11 @@ type RegisterRequest struct {
 
 // RegisterResponse represents the structure for registration responses
 type RegisterResponse struct {
-	ID        int       `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Role      string    `json:"role"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         int       `json:"id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	Role       string    `json:"role"`
+	CardNumber string    `json:"card_number"` // Patron card number
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
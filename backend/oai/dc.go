@@ -0,0 +1,61 @@
+package oai
+
+import "encoding/xml"
+
+// FormatDC is the simple Dublin Core format every repository must support
+var FormatDC = MetadataFormat{
+	Prefix:    "oai_dc",
+	Schema:    "http://www.openarchives.org/OAI/2.0/oai_dc.xsd",
+	Namespace: "http://www.openarchives.org/OAI/2.0/oai_dc/",
+}
+
+const (
+	dcNamespace      = "http://purl.org/dc/elements/1.1/"
+	dcSchemaLocation = "http://www.openarchives.org/OAI/2.0/oai_dc/ http://www.openarchives.org/OAI/2.0/oai_dc.xsd"
+)
+
+// DC is a simple Dublin Core record in the oai_dc format. Every element is
+// optional and repeatable.
+type DC struct {
+	Titles      []string
+	Creators    []string
+	Subjects    []string
+	Publishers  []string
+	Dates       []string
+	Types       []string
+	Identifiers []string
+}
+
+// The elements name their prefixes literally, as encoding/xml would
+// otherwise declare the namespace again on every element.
+type dcRecord struct {
+	XMLName        xml.Name `xml:"oai_dc:dc"`
+	XmlnsOAIDC     string   `xml:"xmlns:oai_dc,attr"`
+	XmlnsDC        string   `xml:"xmlns:dc,attr"`
+	XmlnsXSI       string   `xml:"xmlns:xsi,attr"`
+	SchemaLocation string   `xml:"xsi:schemaLocation,attr"`
+	Titles         []string `xml:"dc:title"`
+	Creators       []string `xml:"dc:creator"`
+	Subjects       []string `xml:"dc:subject"`
+	Publishers     []string `xml:"dc:publisher"`
+	Dates          []string `xml:"dc:date"`
+	Types          []string `xml:"dc:type"`
+	Identifiers    []string `xml:"dc:identifier"`
+}
+
+// MarshalXML writes the record as an oai_dc:dc element
+func (dc *DC) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(dcRecord{
+		XmlnsOAIDC:     FormatDC.Namespace,
+		XmlnsDC:        dcNamespace,
+		XmlnsXSI:       xsiNamespace,
+		SchemaLocation: dcSchemaLocation,
+		Titles:         dc.Titles,
+		Creators:       dc.Creators,
+		Subjects:       dc.Subjects,
+		Publishers:     dc.Publishers,
+		Dates:          dc.Dates,
+		Types:          dc.Types,
+		Identifiers:    dc.Identifiers,
+	})
+}
@@ -0,0 +1,214 @@
+// Package oai writes the responses of an OAI-PMH 2.0 data provider, through
+// which harvesters such as union catalogs collect the metadata of a
+// repository.
+package oai
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+// Verbs of the protocol
+const (
+	VerbIdentify            = "Identify"
+	VerbListMetadataFormats = "ListMetadataFormats"
+	VerbListSets            = "ListSets"
+	VerbListIdentifiers     = "ListIdentifiers"
+	VerbListRecords         = "ListRecords"
+	VerbGetRecord           = "GetRecord"
+)
+
+// Error codes of the protocol
+const (
+	ErrBadArgument             = "badArgument"
+	ErrBadResumptionToken      = "badResumptionToken"
+	ErrBadVerb                 = "badVerb"
+	ErrCannotDisseminateFormat = "cannotDisseminateFormat"
+	ErrIDDoesNotExist          = "idDoesNotExist"
+	ErrNoRecordsMatch          = "noRecordsMatch"
+	ErrNoMetadataFormats       = "noMetadataFormats"
+	ErrNoSetHierarchy          = "noSetHierarchy"
+)
+
+// Granularity of datestamps: the repository gives them to the second
+const Granularity = "YYYY-MM-DDThh:mm:ssZ"
+
+// DatestampLayout formats datestamps at the repository's granularity
+const DatestampLayout = "2006-01-02T15:04:05Z"
+
+const (
+	namespace      = "http://www.openarchives.org/OAI/2.0/"
+	schemaLocation = "http://www.openarchives.org/OAI/2.0/ http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd"
+	xsiNamespace   = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// Error is an OAI-PMH error, which the response reports in place of the
+// verb's results
+type Error struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Request holds the arguments of a request, echoed in its response
+type Request struct {
+	Verb            string `xml:"verb,attr,omitempty"`
+	Identifier      string `xml:"identifier,attr,omitempty"`
+	MetadataPrefix  string `xml:"metadataPrefix,attr,omitempty"`
+	From            string `xml:"from,attr,omitempty"`
+	Until           string `xml:"until,attr,omitempty"`
+	Set             string `xml:"set,attr,omitempty"`
+	ResumptionToken string `xml:"resumptionToken,attr,omitempty"`
+	BaseURL         string `xml:",chardata"`
+}
+
+// Response is an OAI-PMH response: the results of a verb, such as
+// *Identify or *ListRecords, or the errors the request gave
+type Response struct {
+	Date    time.Time
+	Request Request
+	Errors  []*Error
+	Result  interface{}
+}
+
+type envelope struct {
+	XMLName        xml.Name    `xml:"OAI-PMH"`
+	Xmlns          string      `xml:"xmlns,attr"`
+	XmlnsXSI       string      `xml:"xmlns:xsi,attr"`
+	SchemaLocation string      `xml:"xsi:schemaLocation,attr"`
+	ResponseDate   string      `xml:"responseDate"`
+	Request        Request     `xml:"request"`
+	Errors         []*Error    `xml:"error"`
+	Result         interface{} `xml:",omitempty"`
+}
+
+// Write writes a response. Requests with a badVerb or badArgument error are
+// echoed without their arguments, as the protocol requires.
+func Write(w io.Writer, resp Response) error {
+	x := envelope{
+		Xmlns:          namespace,
+		XmlnsXSI:       xsiNamespace,
+		SchemaLocation: schemaLocation,
+		ResponseDate:   Datestamp(resp.Date),
+		Request:        resp.Request,
+		Errors:         resp.Errors,
+	}
+	for _, err := range resp.Errors {
+		if err.Code == ErrBadVerb || err.Code == ErrBadArgument {
+			x.Request = Request{BaseURL: resp.Request.BaseURL}
+		}
+	}
+	if len(resp.Errors) == 0 {
+		x.Result = resp.Result
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(x)
+}
+
+// Datestamp formats a time at the repository's granularity
+func Datestamp(t time.Time) string {
+	return t.UTC().Format(DatestampLayout)
+}
+
+// ParseDatestamp parses the from or until argument of a request, given as a
+// day or to the second. It reports whether the argument was only a day.
+func ParseDatestamp(s string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(DatestampLayout, s)
+	return t, false, err
+}
+
+// Identify describes the repository
+type Identify struct {
+	XMLName           xml.Name `xml:"Identify"`
+	RepositoryName    string   `xml:"repositoryName"`
+	BaseURL           string   `xml:"baseURL"`
+	ProtocolVersion   string   `xml:"protocolVersion"`
+	AdminEmails       []string `xml:"adminEmail"`
+	EarliestDatestamp string   `xml:"earliestDatestamp"`
+	DeletedRecord     string   `xml:"deletedRecord"` // no, transient or persistent
+	Granularity       string   `xml:"granularity"`
+}
+
+// MetadataFormat is a metadata format records are disseminated in
+type MetadataFormat struct {
+	Prefix    string `xml:"metadataPrefix"`
+	Schema    string `xml:"schema"`
+	Namespace string `xml:"metadataNamespace"`
+}
+
+// ListMetadataFormats lists the metadata formats of the repository or of
+// one item
+type ListMetadataFormats struct {
+	XMLName xml.Name         `xml:"ListMetadataFormats"`
+	Formats []MetadataFormat `xml:"metadataFormat"`
+}
+
+// Set is a set of items that can be harvested selectively
+type Set struct {
+	Spec string `xml:"setSpec"`
+	Name string `xml:"setName"`
+}
+
+// ListSets lists a page of the sets of the repository
+type ListSets struct {
+	XMLName         xml.Name         `xml:"ListSets"`
+	Sets            []Set            `xml:"set"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken"`
+}
+
+// Header identifies a record. Headers of deleted records have Status
+// "deleted".
+type Header struct {
+	Status     string   `xml:"status,attr,omitempty"`
+	Identifier string   `xml:"identifier"`
+	Datestamp  string   `xml:"datestamp"`
+	SetSpecs   []string `xml:"setSpec"`
+}
+
+// Record is the metadata of an item in one format. Deleted records have no
+// metadata.
+type Record struct {
+	Header   Header    `xml:"header"`
+	Metadata *Metadata `xml:"metadata"`
+}
+
+// Metadata holds the metadata of a record, as a value that encodes itself
+// as the root element of its format, such as *DC
+type Metadata struct {
+	Value interface{}
+}
+
+// ListIdentifiers lists a page of record headers
+type ListIdentifiers struct {
+	XMLName         xml.Name         `xml:"ListIdentifiers"`
+	Headers         []Header         `xml:"header"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken"`
+}
+
+// ListRecords lists a page of records
+type ListRecords struct {
+	XMLName         xml.Name         `xml:"ListRecords"`
+	Records         []Record         `xml:"record"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken"`
+}
+
+// GetRecord holds a single record
+type GetRecord struct {
+	XMLName xml.Name `xml:"GetRecord"`
+	Record  Record   `xml:"record"`
+}
+
+// ResumptionToken continues an incomplete list. The last page of a list
+// that was resumed has an empty token.
+type ResumptionToken struct {
+	Value string `xml:",chardata"`
+}
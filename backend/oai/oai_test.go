@@ -0,0 +1,56 @@
+package oai
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDatestamp(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+		day  bool
+		ok   bool
+	}{
+		{"2024-03-01", time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), true, true},
+		{"2024-03-01T12:30:05Z", time.Date(2024, time.March, 1, 12, 30, 5, 0, time.UTC), false, true},
+		{"2024-03-01T12:30Z", time.Time{}, false, false},
+		{"2024-03-01T12:30:05+01:00", time.Time{}, false, false},
+		{"yesterday", time.Time{}, false, false},
+	}
+	for _, tt := range tests {
+		got, day, err := ParseDatestamp(tt.in)
+		if (err == nil) != tt.ok || tt.ok && (!got.Equal(tt.want) || day != tt.day) {
+			t.Errorf("ParseDatestamp(%q) = %v, %v, %v, want %v, %v (ok %v)", tt.in, got, day, err, tt.want, tt.day, tt.ok)
+		}
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	var out bytes.Buffer
+	err := Write(&out, Response{
+		Date:    time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+		Request: Request{Verb: "Explode", Set: "scifi", BaseURL: "http://example.com/oai"},
+		Errors:  []*Error{{Code: ErrBadVerb, Message: "Illegal or missing verb"}},
+		Result:  &Identify{RepositoryName: "Never written"},
+	})
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	// The arguments of a request with a bad verb are not echoed
+	for _, want := range []string{
+		"<responseDate>2024-03-01T12:00:00Z</responseDate>",
+		"<request>http://example.com/oai</request>",
+		`<error code="badVerb">Illegal or missing verb</error>`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("response = %s, want it to contain %s", out.String(), want)
+		}
+	}
+	if strings.Contains(out.String(), "Never written") {
+		t.Errorf("response = %s, want no results with an error", out.String())
+	}
+}
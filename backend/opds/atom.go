@@ -0,0 +1,184 @@
+package opds
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Namespaces of OPDS 1.2 feeds
+const (
+	atomNamespace       = "http://www.w3.org/2005/Atom"
+	opdsNamespace       = "http://opds-spec.org/2010/catalog"
+	dcNamespace         = "http://purl.org/dc/terms/"
+	openSearchNamespace = "http://a9.com/-/spec/opensearch/1.1/"
+	threadNamespace     = "http://purl.org/syndication/thread/1.0"
+)
+
+// The Atom elements below name the prefixed elements and attributes of the
+// other namespaces literally, as encoding/xml would otherwise declare the
+// namespace again on every element.
+
+type atomFeed struct {
+	XMLName      xml.Name    `xml:"feed"`
+	Xmlns        string      `xml:"xmlns,attr"`
+	XmlnsOPDS    string      `xml:"xmlns:opds,attr"`
+	XmlnsDC      string      `xml:"xmlns:dc,attr"`
+	XmlnsSearch  string      `xml:"xmlns:opensearch,attr"`
+	XmlnsThread  string      `xml:"xmlns:thr,attr"`
+	ID           string      `xml:"id"`
+	Title        string      `xml:"title"`
+	Updated      string      `xml:"updated"`
+	Links        []atomLink  `xml:"link"`
+	TotalResults *int        `xml:"opensearch:totalResults"`
+	ItemsPerPage *int        `xml:"opensearch:itemsPerPage"`
+	Entries      []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+	Count string `xml:"thr:count,attr,omitempty"`
+
+	Availability *atomAvailability `xml:"opds:availability"`
+	Copies       *atomCopies       `xml:"opds:copies"`
+}
+
+type atomAvailability struct {
+	Status string `xml:"status,attr"`
+}
+
+type atomCopies struct {
+	Available int `xml:"available,attr"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomAuthor   `xml:"author"`
+	Identifier string         `xml:"dc:identifier,omitempty"`
+	Publisher  string         `xml:"dc:publisher,omitempty"`
+	Issued     string         `xml:"dc:issued,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Content    *atomContent   `xml:"content"`
+	Links      []atomLink     `xml:"link"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// WriteAtom writes a feed as an OPDS 1.2 Atom feed. Templated links are
+// left out, as OPDS 1.2 searches through an OpenSearch description.
+func WriteAtom(w io.Writer, feed Feed) error {
+	x := atomFeed{
+		Xmlns:       atomNamespace,
+		XmlnsOPDS:   opdsNamespace,
+		XmlnsDC:     dcNamespace,
+		XmlnsSearch: openSearchNamespace,
+		XmlnsThread: threadNamespace,
+		ID:          feed.ID,
+		Title:       feed.Title,
+		Updated:     atomTime(feed.Updated),
+		Links:       atomLinks(feed.Links),
+	}
+	if feed.ItemsPerPage > 0 {
+		x.TotalResults, x.ItemsPerPage = &feed.TotalResults, &feed.ItemsPerPage
+	}
+	for _, nav := range feed.Navigation {
+		entry := atomEntry{
+			Title:   nav.Title,
+			ID:      nav.ID,
+			Updated: atomTime(nav.Updated),
+			Links:   []atomLink{{Rel: RelSubsection, Href: nav.Href, Type: AtomType(nav.Kind)}},
+		}
+		if nav.Count > 0 {
+			entry.Links[0].Count = strconv.Itoa(nav.Count)
+		}
+		if nav.Summary != "" {
+			entry.Content = &atomContent{Type: "text", Text: nav.Summary}
+		}
+		x.Entries = append(x.Entries, entry)
+	}
+	for _, pub := range feed.Publications {
+		entry := atomEntry{
+			Title:      pub.Title,
+			ID:         pub.ID,
+			Updated:    atomTime(pub.Updated),
+			Identifier: pub.Identifier,
+			Publisher:  pub.Publisher,
+			Links:      atomLinks(pub.Links),
+		}
+		for _, author := range pub.Authors {
+			entry.Authors = append(entry.Authors, atomAuthor{Name: author})
+		}
+		if pub.Year != 0 {
+			entry.Issued = strconv.Itoa(pub.Year)
+		}
+		for _, subject := range pub.Subjects {
+			entry.Categories = append(entry.Categories, atomCategory{Term: subject, Label: subject})
+		}
+		x.Entries = append(x.Entries, entry)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(x)
+}
+
+func atomLinks(links []Link) []atomLink {
+	var x []atomLink
+	for _, link := range links {
+		if link.Templated {
+			continue
+		}
+		if link.Kind != "" {
+			link.Type = AtomType(link.Kind)
+		}
+		l := atomLink{Rel: link.Rel, Href: link.Href, Type: link.Type, Title: link.Title}
+		if link.Available != nil {
+			l.Availability = &atomAvailability{Status: availability(*link.Available)}
+			l.Copies = &atomCopies{Available: link.Copies}
+		}
+		x = append(x, l)
+	}
+	return x
+}
+
+// availability returns the state of a borrow link
+func availability(available bool) string {
+	if available {
+		return "available"
+	}
+	return "unavailable"
+}
+
+// AtomType returns the media type of an Atom feed of a kind
+func AtomType(kind string) string {
+	if kind == KindNavigation {
+		return TypeAtomNavigation
+	}
+	return TypeAtomAcquisition
+}
+
+func atomTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(time.RFC3339)
+}
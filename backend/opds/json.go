@@ -0,0 +1,131 @@
+package opds
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+type jsonFeed struct {
+	Metadata     jsonFeedMetadata  `json:"metadata"`
+	Links        []jsonLink        `json:"links"`
+	Navigation   []jsonLink        `json:"navigation,omitempty"`
+	Publications []jsonPublication `json:"publications,omitempty"`
+}
+
+type jsonFeedMetadata struct {
+	Title         string `json:"title"`
+	Modified      string `json:"modified,omitempty"`
+	NumberOfItems *int   `json:"numberOfItems,omitempty"`
+	ItemsPerPage  *int   `json:"itemsPerPage,omitempty"`
+}
+
+type jsonLink struct {
+	Rel        string          `json:"rel,omitempty"`
+	Href       string          `json:"href"`
+	Type       string          `json:"type,omitempty"`
+	Title      string          `json:"title,omitempty"`
+	Templated  bool            `json:"templated,omitempty"`
+	Properties *jsonProperties `json:"properties,omitempty"`
+}
+
+type jsonProperties struct {
+	NumberOfItems int               `json:"numberOfItems,omitempty"`
+	Availability  *jsonAvailability `json:"availability,omitempty"`
+	Copies        *jsonCopies       `json:"copies,omitempty"`
+}
+
+type jsonAvailability struct {
+	State string `json:"state"`
+}
+
+type jsonCopies struct {
+	Available int `json:"available"`
+}
+
+type jsonPublication struct {
+	Metadata jsonPublicationMetadata `json:"metadata"`
+	Links    []jsonLink              `json:"links"`
+}
+
+type jsonPublicationMetadata struct {
+	Type       string        `json:"@type"`
+	Identifier string        `json:"identifier,omitempty"`
+	Title      string        `json:"title"`
+	Author     []jsonContrib `json:"author,omitempty"`
+	Publisher  string        `json:"publisher,omitempty"`
+	Published  string        `json:"published,omitempty"`
+	Subject    []jsonContrib `json:"subject,omitempty"`
+	Modified   string        `json:"modified,omitempty"`
+}
+
+type jsonContrib struct {
+	Name string `json:"name"`
+}
+
+// WriteJSON writes a feed as an OPDS 2.0 feed
+func WriteJSON(w io.Writer, feed Feed) error {
+	x := jsonFeed{
+		Metadata: jsonFeedMetadata{Title: feed.Title, Modified: jsonTime(feed.Updated)},
+		Links:    jsonLinks(feed.Links),
+	}
+	if feed.ItemsPerPage > 0 {
+		x.Metadata.NumberOfItems, x.Metadata.ItemsPerPage = &feed.TotalResults, &feed.ItemsPerPage
+	}
+	for _, nav := range feed.Navigation {
+		link := jsonLink{Rel: RelSubsection, Href: nav.Href, Type: TypeJSON, Title: nav.Title}
+		if nav.Count > 0 {
+			link.Properties = &jsonProperties{NumberOfItems: nav.Count}
+		}
+		x.Navigation = append(x.Navigation, link)
+	}
+	for _, pub := range feed.Publications {
+		p := jsonPublication{
+			Metadata: jsonPublicationMetadata{
+				Type:       "http://schema.org/Book",
+				Identifier: pub.Identifier,
+				Title:      pub.Title,
+				Publisher:  pub.Publisher,
+				Modified:   jsonTime(pub.Updated),
+			},
+			Links: jsonLinks(pub.Links),
+		}
+		for _, author := range pub.Authors {
+			p.Metadata.Author = append(p.Metadata.Author, jsonContrib{Name: author})
+		}
+		if pub.Year != 0 {
+			p.Metadata.Published = strconv.Itoa(pub.Year)
+		}
+		for _, subject := range pub.Subjects {
+			p.Metadata.Subject = append(p.Metadata.Subject, jsonContrib{Name: subject})
+		}
+		x.Publications = append(x.Publications, p)
+	}
+	return json.NewEncoder(w).Encode(x)
+}
+
+func jsonLinks(links []Link) []jsonLink {
+	x := make([]jsonLink, 0, len(links))
+	for _, link := range links {
+		l := jsonLink{Rel: link.Rel, Href: link.Href, Type: link.Type, Title: link.Title, Templated: link.Templated}
+		if link.Kind != "" {
+			l.Type = TypeJSON
+		}
+		if link.Available != nil {
+			l.Properties = &jsonProperties{
+				Availability: &jsonAvailability{State: availability(*link.Available)},
+				Copies:       &jsonCopies{Available: link.Copies},
+			}
+		}
+		x = append(x, l)
+	}
+	return x
+}
+
+func jsonTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,94 @@
+// Package opds writes OPDS catalog feeds for reading apps, as OPDS 1.2 Atom
+// feeds or OPDS 2.0 JSON, and the OpenSearch description of their search.
+package opds
+
+import "time"
+
+// Feed kinds. Navigation feeds list other feeds; acquisition feeds list
+// publications.
+const (
+	KindNavigation  = "navigation"
+	KindAcquisition = "acquisition"
+)
+
+// Link relations used by the feeds
+const (
+	RelSelf        = "self"
+	RelStart       = "start"
+	RelUp          = "up"
+	RelNext        = "next"
+	RelFirst       = "first"
+	RelSearch      = "search"
+	RelSubsection  = "subsection"
+	RelAlternate   = "alternate"
+	RelDescribedBy = "describedby"
+	RelBorrow      = "http://opds-spec.org/acquisition/borrow"
+	RelAcquisition = "http://opds-spec.org/acquisition"
+	RelNew         = "http://opds-spec.org/sort/new"
+)
+
+// Media types of the feeds and the documents they link to
+const (
+	TypeAtomNavigation  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	TypeAtomAcquisition = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	TypeJSON            = "application/opds+json"
+	TypeOpenSearch      = "application/opensearchdescription+xml"
+)
+
+// Feed is a navigation or acquisition feed, written by WriteAtom or
+// WriteJSON
+type Feed struct {
+	ID      string
+	Title   string
+	Kind    string
+	Updated time.Time
+	Links   []Link
+
+	Navigation   []Navigation  // Entries of a navigation feed
+	Publications []Publication // Entries of an acquisition feed
+
+	// Paging of an acquisition feed; zero when not paged
+	TotalResults int
+	ItemsPerPage int
+}
+
+// Link links a feed or publication to another document. Links to other
+// feeds set the Kind of the feed instead of a Type, which depends on the
+// version written. Templated links hold a URI template, such as the search
+// link of an OPDS 2.0 feed.
+type Link struct {
+	Rel       string
+	Href      string
+	Type      string
+	Kind      string
+	Title     string
+	Templated bool
+
+	// Availability of a borrow link: nil when unknown
+	Available *bool
+	Copies    int // Available copies
+}
+
+// Navigation is an entry of a navigation feed leading to another feed
+type Navigation struct {
+	ID      string
+	Title   string
+	Summary string
+	Href    string
+	Kind    string // Kind of the feed it leads to
+	Count   int    // Number of books behind it, when known
+	Updated time.Time
+}
+
+// Publication is a book listed by an acquisition feed
+type Publication struct {
+	ID         string // A URN such as urn:isbn:9780306406157
+	Title      string
+	Authors    []string
+	Publisher  string
+	Year       int // Zero when unknown
+	Identifier string
+	Subjects   []string
+	Updated    time.Time
+	Links      []Link
+}
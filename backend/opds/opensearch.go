@@ -0,0 +1,39 @@
+package opds
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+type openSearchDescription struct {
+	XMLName     xml.Name        `xml:"OpenSearchDescription"`
+	Xmlns       string          `xml:"xmlns,attr"`
+	ShortName   string          `xml:"ShortName"`
+	Description string          `xml:"Description"`
+	InputEnc    string          `xml:"InputEncoding"`
+	OutputEnc   string          `xml:"OutputEncoding"`
+	URLs        []openSearchURL `xml:"Url"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// WriteOpenSearch writes the OpenSearch description of a catalog search.
+// The template holds {searchTerms} where the search goes, as in
+// https://example.com/opds/books?search={searchTerms}.
+func WriteOpenSearch(w io.Writer, shortName, description, template string) error {
+	x := openSearchDescription{
+		Xmlns:       openSearchNamespace,
+		ShortName:   shortName,
+		Description: description,
+		InputEnc:    "UTF-8",
+		OutputEnc:   "UTF-8",
+		URLs:        []openSearchURL{{Type: TypeAtomAcquisition, Template: template}},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(x)
+}
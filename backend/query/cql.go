@@ -0,0 +1,374 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Kinds of CQL a catalog cannot search by, reported by UnsupportedError
+const (
+	UnsupportedIndex            = "index"
+	UnsupportedRelation         = "relation"
+	UnsupportedRelationModifier = "relation modifier"
+	UnsupportedBoolean          = "boolean operator"
+	UnsupportedBooleanModifier  = "boolean modifier"
+	UnsupportedMasking          = "masking character"
+	UnsupportedAnchoring        = "anchoring character"
+	UnsupportedEmptyTerm        = "empty term"
+	UnsupportedPrefix           = "prefix assignment"
+)
+
+// UnsupportedError is valid CQL that the catalog cannot search by, such as
+// an index it does not have
+type UnsupportedError struct {
+	Kind  string
+	Value string // The index, relation or operator as given
+}
+
+func (e *UnsupportedError) Error() string {
+	if e.Value == "" {
+		return "unsupported " + e.Kind
+	}
+	return "unsupported " + e.Kind + " \"" + e.Value + "\""
+}
+
+// cqlIndexes maps the CQL indexes ParseCQL accepts, in lower case, to fields
+var cqlIndexes = map[string]string{
+	"cql.serverchoice": FieldAny,
+	"title":            FieldTitle,
+	"dc.title":         FieldTitle,
+	"author":           FieldAuthor,
+	"creator":          FieldAuthor,
+	"dc.creator":       FieldAuthor,
+	"isbn":             FieldISBN,
+	"bath.isbn":        FieldISBN,
+	"subject":          FieldCategory,
+	"dc.subject":       FieldCategory,
+}
+
+// CQLIndexes lists the indexes ParseCQL accepts besides cql.serverChoice and
+// cql.allRecords
+var CQLIndexes = []string{"title", "dc.title", "author", "creator", "dc.creator", "isbn", "bath.isbn", "subject", "dc.subject"}
+
+// Kinds of CQL tokens
+const (
+	cqlEnd = iota
+	cqlWord
+	cqlQuoted
+	cqlSymbol // A comparison such as = or <>
+	cqlOpen
+	cqlClose
+	cqlSlash
+)
+
+type cqlToken struct {
+	kind int
+	text string // Unquoted text, with backslash escapes kept
+	pos  int    // 1-based character position
+}
+
+// ParseCQL parses a CQL search, as sent to SRU, into the same syntax tree as
+// Parse. Indexes are title, author, isbn and subject (the category) with
+// their dc and bath names; a term without an index searches any text field,
+// as does cql.serverChoice. The =, scr (taken as =), ==, <>, adj, all and
+// any relations are supported, and * masks any text. and, or and not bind equally, from left
+// to right. Valid CQL the catalog cannot search gives an *UnsupportedError.
+func ParseCQL(s string) (Node, error) {
+	if len([]rune(s)) > maxLength {
+		return nil, &Error{Pos: maxLength + 1, Message: "query is too long"}
+	}
+	tokens, err := lexCQL(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &cqlParser{tokens: tokens}
+	if p.peek().kind == cqlEnd {
+		return nil, &Error{Pos: p.peek().pos, Message: "query is empty"}
+	}
+	if p.peek().kind == cqlSymbol && p.peek().text == ">" {
+		return nil, &UnsupportedError{Kind: UnsupportedPrefix}
+	}
+	node, err := p.query(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != cqlEnd {
+		return nil, &Error{Pos: t.pos, Message: "unexpected " + describeCQL(t)}
+	}
+	return node, nil
+}
+
+// lexCQL splits a CQL query into tokens
+func lexCQL(s string) ([]cqlToken, error) {
+	var tokens []cqlToken
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, cqlToken{cqlOpen, "(", pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, cqlToken{cqlClose, ")", pos})
+			i++
+		case r == '/':
+			tokens = append(tokens, cqlToken{cqlSlash, "/", pos})
+			i++
+		case r == '=' || r == '<' || r == '>':
+			end := i + 1
+			if end < len(runes) && (runes[end] == '=' || r == '<' && runes[end] == '>') {
+				end++
+			}
+			tokens = append(tokens, cqlToken{cqlSymbol, string(runes[i:end]), pos})
+			i = end
+		case r == '"':
+			var b strings.Builder
+			end := i + 1
+			for ; end < len(runes) && runes[end] != '"'; end++ {
+				if runes[end] == '\\' && end+1 < len(runes) {
+					b.WriteRune(runes[end])
+					end++
+				}
+				b.WriteRune(runes[end])
+			}
+			if end == len(runes) {
+				return nil, &Error{Pos: pos, Message: "unterminated quoted term"}
+			}
+			tokens = append(tokens, cqlToken{cqlQuoted, b.String(), pos})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()/=<>"`, runes[end]) {
+				end++
+			}
+			tokens = append(tokens, cqlToken{cqlWord, string(runes[i:end]), pos})
+			i = end
+		}
+	}
+	return append(tokens, cqlToken{cqlEnd, "", len(runes) + 1}), nil
+}
+
+// cqlParser is a recursive descent parser over the tokens of a CQL query:
+//
+//	query    = clause { boolean [ modifiers ] clause }
+//	clause   = "(" query ")" | [ index relation [ modifiers ] ] term
+//	boolean  = "and" | "or" | "not" | "prox"
+//	relation = symbol | "adj" | "all" | "any" | "within" | "encloses"
+type cqlParser struct {
+	tokens []cqlToken
+	next   int
+}
+
+func (p *cqlParser) peek() cqlToken {
+	return p.tokens[p.next]
+}
+
+func (p *cqlParser) take() cqlToken {
+	t := p.tokens[p.next]
+	if t.kind != cqlEnd {
+		p.next++
+	}
+	return t
+}
+
+func (p *cqlParser) query(depth int) (Node, error) {
+	left, err := p.clause(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == cqlWord {
+		operator := strings.ToLower(p.peek().text)
+		if operator != "and" && operator != "or" && operator != "not" && operator != "prox" {
+			return left, nil
+		}
+		t := p.take()
+		if operator == "prox" {
+			return nil, &UnsupportedError{Kind: UnsupportedBoolean, Value: t.text}
+		}
+		if p.peek().kind == cqlSlash {
+			return nil, &UnsupportedError{Kind: UnsupportedBooleanModifier, Value: p.modifier()}
+		}
+		right, err := p.clause(depth)
+		if err != nil {
+			return nil, err
+		}
+		switch operator {
+		case "and":
+			left = &And{Left: left, Right: right}
+		case "or":
+			left = &Or{Left: left, Right: right}
+		default:
+			left = &And{Left: left, Right: &Not{Node: right}}
+		}
+	}
+	return left, nil
+}
+
+func (p *cqlParser) clause(depth int) (Node, error) {
+	if depth > maxDepth {
+		return nil, &Error{Pos: p.peek().pos, Message: "query is nested too deeply"}
+	}
+	t := p.take()
+	switch t.kind {
+	case cqlOpen:
+		node, err := p.query(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.take(); closing.kind != cqlClose {
+			return nil, &Error{Pos: closing.pos, Message: "expected ) to close the ( at position " + strconv.Itoa(t.pos) + ", found " + describeCQL(closing)}
+		}
+		return node, nil
+	case cqlWord, cqlQuoted:
+		if t.kind == cqlQuoted || !p.atRelation() {
+			return cqlTerm(FieldAny, "=", t)
+		}
+		index := strings.ToLower(t.text)
+		relation := strings.ToLower(p.take().text)
+		if p.peek().kind == cqlSlash {
+			return nil, &UnsupportedError{Kind: UnsupportedRelationModifier, Value: p.modifier()}
+		}
+		term := p.take()
+		if term.kind != cqlWord && term.kind != cqlQuoted {
+			return nil, &Error{Pos: term.pos, Message: "expected a term after " + t.text + " " + relation + ", found " + describeCQL(term)}
+		}
+		if index == "cql.allrecords" {
+			// Matches every book, as every title matches *
+			return &Term{Field: FieldTitle, Match: MatchPattern, Value: "*"}, nil
+		}
+		field, ok := cqlIndexes[index]
+		if !ok {
+			return nil, &UnsupportedError{Kind: UnsupportedIndex, Value: t.text}
+		}
+		return cqlTerm(field, relation, term)
+	}
+	return nil, &Error{Pos: t.pos, Message: "expected a search term, found " + describeCQL(t)}
+}
+
+// atRelation reports whether the next token is a relation, which makes the
+// token before it an index
+func (p *cqlParser) atRelation() bool {
+	t := p.peek()
+	if t.kind == cqlSymbol {
+		return true
+	}
+	if t.kind != cqlWord {
+		return false
+	}
+	switch strings.ToLower(t.text) {
+	case "scr", "adj", "all", "any", "within", "encloses":
+		after := p.tokens[p.next+1]
+		return after.kind == cqlWord || after.kind == cqlQuoted || after.kind == cqlSlash
+	}
+	return false
+}
+
+// modifier reads a /modifier for error messages
+func (p *cqlParser) modifier() string {
+	p.take()
+	return "/" + p.take().text
+}
+
+// cqlTerm builds the term comparing a field with a search term by a relation
+func cqlTerm(field, relation string, t cqlToken) (Node, error) {
+	value, pattern, err := cqlValue(t.text)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(value) == "" {
+		return nil, &UnsupportedError{Kind: UnsupportedEmptyTerm}
+	}
+
+	// Title, author and unindexed terms match text a field contains, while
+	// isbn and subject match the whole field, as in Parse. A masked word
+	// matches anywhere in a field it would be contained in.
+	contains := field != FieldISBN && field != FieldCategory
+	term := func(word string, match int) *Term {
+		if !pattern || !strings.Contains(word, "*") {
+			return &Term{Field: field, Match: match, Value: word}
+		}
+		if match == MatchContains {
+			word = "*" + strings.Trim(word, "*") + "*"
+		}
+		return &Term{Field: field, Match: MatchPattern, Value: word}
+	}
+
+	switch relation {
+	case "=", "scr":
+		if contains {
+			return term(value, MatchContains), nil
+		}
+		return term(value, MatchEquals), nil
+	case "adj":
+		return term(value, MatchContains), nil
+	case "==":
+		if pattern {
+			return &Term{Field: field, Match: MatchPattern, Value: value}, nil
+		}
+		return &Term{Field: field, Match: MatchEquals, Value: value}, nil
+	case "<>":
+		if pattern {
+			return &Not{Node: &Term{Field: field, Match: MatchPattern, Value: value}}, nil
+		}
+		return &Not{Node: &Term{Field: field, Match: MatchEquals, Value: value}}, nil
+	case "all", "any":
+		var node Node
+		for _, word := range strings.Fields(value) {
+			next := Node(term(word, MatchContains))
+			switch {
+			case node == nil:
+				node = next
+			case relation == "all":
+				node = &And{Left: node, Right: next}
+			default:
+				node = &Or{Left: node, Right: next}
+			}
+		}
+		return node, nil
+	}
+	return nil, &UnsupportedError{Kind: UnsupportedRelation, Value: relation}
+}
+
+// cqlValue resolves the escapes of a search term and reports whether it
+// masks text with *. Masking with ? and anchoring with ^ are unsupported, as
+// is a literal * in a masked term.
+func cqlValue(text string) (string, bool, error) {
+	var b strings.Builder
+	var masked, literalStar bool
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			if runes[i] == '*' {
+				literalStar = true
+			}
+			b.WriteRune(runes[i])
+		case r == '*':
+			masked = true
+			b.WriteRune(r)
+		case r == '?':
+			return "", false, &UnsupportedError{Kind: UnsupportedMasking, Value: "?"}
+		case r == '^':
+			return "", false, &UnsupportedError{Kind: UnsupportedAnchoring, Value: "^"}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if masked && literalStar {
+		return "", false, &UnsupportedError{Kind: UnsupportedMasking, Value: `\*`}
+	}
+	return b.String(), masked, nil
+}
+
+// describeCQL names a token for error messages
+func describeCQL(t cqlToken) string {
+	if t.kind == cqlEnd {
+		return "end of query"
+	}
+	return "\"" + t.text + "\""
+}
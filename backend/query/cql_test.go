@@ -0,0 +1,109 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseCQL(t *testing.T) {
+	tests := []struct {
+		cql  string
+		want Node
+	}{
+		{`dune`, &Term{Field: FieldAny, Match: MatchContains, Value: "dune"}},
+		{`"left hand"`, &Term{Field: FieldAny, Match: MatchContains, Value: "left hand"}},
+		{`title = dune`, &Term{Field: FieldTitle, Match: MatchContains, Value: "dune"}},
+		{`dc.title scr dune`, &Term{Field: FieldTitle, Match: MatchContains, Value: "dune"}},
+		{`TITLE SCR dune`, &Term{Field: FieldTitle, Match: MatchContains, Value: "dune"}},
+		{`dc.title == "Dune"`, &Term{Field: FieldTitle, Match: MatchEquals, Value: "Dune"}},
+		{`bath.isbn = 9780441172719`, &Term{Field: FieldISBN, Match: MatchEquals, Value: "9780441172719"}},
+		{`subject = scifi`, &Term{Field: FieldCategory, Match: MatchEquals, Value: "scifi"}},
+		{`title = dun*`, &Term{Field: FieldTitle, Match: MatchPattern, Value: "*dun*"}},
+		{`author <> herbert`, &Not{Node: &Term{Field: FieldAuthor, Match: MatchEquals, Value: "herbert"}}},
+		{`cql.allRecords = 1`, &Term{Field: FieldTitle, Match: MatchPattern, Value: "*"}},
+		{`title all "left hand"`, &And{
+			Left:  &Term{Field: FieldTitle, Match: MatchContains, Value: "left"},
+			Right: &Term{Field: FieldTitle, Match: MatchContains, Value: "hand"},
+		}},
+		{`title any "left hand"`, &Or{
+			Left:  &Term{Field: FieldTitle, Match: MatchContains, Value: "left"},
+			Right: &Term{Field: FieldTitle, Match: MatchContains, Value: "hand"},
+		}},
+		// Booleans bind equally, from left to right
+		{`a or b and c`, &And{
+			Left: &Or{
+				Left:  &Term{Field: FieldAny, Match: MatchContains, Value: "a"},
+				Right: &Term{Field: FieldAny, Match: MatchContains, Value: "b"},
+			},
+			Right: &Term{Field: FieldAny, Match: MatchContains, Value: "c"},
+		}},
+		{`dune not (author = herbert)`, &And{
+			Left:  &Term{Field: FieldAny, Match: MatchContains, Value: "dune"},
+			Right: &Not{Node: &Term{Field: FieldAuthor, Match: MatchContains, Value: "herbert"}},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := ParseCQL(tt.cql)
+		if err != nil {
+			t.Errorf("ParseCQL(%q): %v", tt.cql, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseCQL(%q) = %#v, want %#v", tt.cql, got, tt.want)
+		}
+	}
+}
+
+func TestParseCQLUnsupported(t *testing.T) {
+	tests := []struct {
+		cql   string
+		kind  string
+		value string
+	}{
+		{`publisher = scribner`, UnsupportedIndex, "publisher"},
+		{`title within dune`, UnsupportedRelation, "within"},
+		{`title =/stem dune`, UnsupportedRelationModifier, "/stem"},
+		{`a prox b`, UnsupportedBoolean, "prox"},
+		{`a and/rel.algorithm=cori b`, UnsupportedBooleanModifier, "/rel.algorithm"},
+		{`title = du?e`, UnsupportedMasking, "?"},
+		{`title = ^dune`, UnsupportedAnchoring, "^"},
+		{`title = ""`, UnsupportedEmptyTerm, ""},
+		{`> dc = "info:srw/cql-context-set/1/dc-v1.1" dc.title = dune`, UnsupportedPrefix, ""},
+	}
+	for _, tt := range tests {
+		_, err := ParseCQL(tt.cql)
+		var unsupported *UnsupportedError
+		if !errors.As(err, &unsupported) {
+			t.Errorf("ParseCQL(%q) = %v, want an *UnsupportedError", tt.cql, err)
+			continue
+		}
+		if unsupported.Kind != tt.kind || (tt.value != "" && unsupported.Value != tt.value) {
+			t.Errorf("ParseCQL(%q) = %q %q, want %q %q", tt.cql, unsupported.Kind, unsupported.Value, tt.kind, tt.value)
+		}
+	}
+}
+
+func TestParseCQLSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		cql string
+		pos int
+	}{
+		{`title = (dune)`, 9},
+		{`(dune`, 6},
+		{`dune)`, 5},
+		{`dune and`, 9},
+		{`"dune`, 1},
+	}
+	for _, tt := range tests {
+		_, err := ParseCQL(tt.cql)
+		var syntax *Error
+		if !errors.As(err, &syntax) {
+			t.Errorf("ParseCQL(%q) = %v, want a syntax error", tt.cql, err)
+			continue
+		}
+		if syntax.Pos != tt.pos {
+			t.Errorf("ParseCQL(%q) error at %d (%v), want %d", tt.cql, syntax.Pos, err, tt.pos)
+		}
+	}
+}
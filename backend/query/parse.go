@@ -0,0 +1,261 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Limits on the queries Parse accepts, so a request cannot build a huge
+// predicate or recurse without bound
+const (
+	maxLength = 1000
+	maxDepth  = 32
+)
+
+// Kinds of tokens
+const (
+	tokenEnd = iota
+	tokenWord
+	tokenPhrase
+	tokenColon
+	tokenOpen
+	tokenClose
+	tokenMinus
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type token struct {
+	kind int
+	text string
+	pos  int // 1-based character position
+}
+
+// Parse parses a query. Fields are title, author, category, isbn and
+// available; a term without a field matches any text field. Title, author
+// and unfielded terms match text the field contains, category and isbn terms
+// the whole field. A * in an unquoted value matches any text, so isbn:978*
+// matches every ISBN starting with 978. AND, OR and NOT must be upper case;
+// AND binds tighter than OR.
+func Parse(s string) (Node, error) {
+	if len([]rune(s)) > maxLength {
+		return nil, &Error{Pos: maxLength + 1, Message: "query is too long"}
+	}
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEnd {
+		return nil, &Error{Pos: p.peek().pos, Message: "query is empty"}
+	}
+	node, err := p.or(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, &Error{Pos: t.pos, Message: "unexpected " + describe(t)}
+	}
+	return node, nil
+}
+
+// lex splits a query into tokens
+func lex(s string) ([]token, error) {
+	var tokens []token
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenOpen, "(", pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenClose, ")", pos})
+			i++
+		case r == ':':
+			tokens = append(tokens, token{tokenColon, ":", pos})
+			i++
+		case r == '-':
+			tokens = append(tokens, token{tokenMinus, "-", pos})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, &Error{Pos: pos, Message: "unterminated quoted phrase"}
+			}
+			tokens = append(tokens, token{tokenPhrase, string(runes[i+1 : end]), pos})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`():"`, runes[end]) {
+				end++
+			}
+			word := string(runes[i:end])
+			kind := tokenWord
+			switch word {
+			case "AND":
+				kind = tokenAnd
+			case "OR":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+			tokens = append(tokens, token{kind, word, pos})
+			i = end
+		}
+	}
+	return append(tokens, token{tokenEnd, "", len(runes) + 1}), nil
+}
+
+// parser is a recursive descent parser over the tokens of a query:
+//
+//	or      = and { "OR" and }
+//	and     = unary { [ "AND" ] unary }
+//	unary   = ( "NOT" | "-" ) unary | primary
+//	primary = "(" or ")" | [ word ":" ] ( word | phrase )
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEnd {
+		p.next++
+	}
+	return t
+}
+
+func (p *parser) or(depth int) (Node, error) {
+	left, err := p.and(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.take()
+		right, err := p.and(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and(depth int) (Node, error) {
+	left, err := p.unary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.take()
+		case tokenWord, tokenPhrase, tokenOpen, tokenMinus, tokenNot:
+			// Adjacent terms are ANDed
+		default:
+			return left, nil
+		}
+		right, err := p.unary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+}
+
+func (p *parser) unary(depth int) (Node, error) {
+	if depth > maxDepth {
+		return nil, &Error{Pos: p.peek().pos, Message: "query is nested too deeply"}
+	}
+	switch p.peek().kind {
+	case tokenNot, tokenMinus:
+		p.take()
+		node, err := p.unary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Node: node}, nil
+	}
+	return p.primary(depth)
+}
+
+func (p *parser) primary(depth int) (Node, error) {
+	t := p.take()
+	switch t.kind {
+	case tokenOpen:
+		node, err := p.or(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.take(); closing.kind != tokenClose {
+			return nil, &Error{Pos: closing.pos, Message: "expected ) to close the ( at position " + strconv.Itoa(t.pos) + ", found " + describe(closing)}
+		}
+		return node, nil
+	case tokenPhrase:
+		return &Term{Field: FieldAny, Match: MatchContains, Value: t.text}, nil
+	case tokenWord:
+		if p.peek().kind != tokenColon {
+			return valueTerm(FieldAny, t), nil
+		}
+		field := strings.ToLower(t.text)
+		if !isField(field) {
+			return nil, &Error{Pos: t.pos, Message: "unknown field \"" + t.text + "\", expected one of " + strings.Join(Fields, ", ")}
+		}
+		p.take()
+		value := p.take()
+		if value.kind != tokenWord && value.kind != tokenPhrase {
+			return nil, &Error{Pos: value.pos, Message: "expected a value for " + field + ", found " + describe(value)}
+		}
+		if field == FieldAvailable {
+			switch strings.ToLower(value.text) {
+			case "true", "false":
+				return &Term{Field: field, Match: MatchEquals, Value: strings.ToLower(value.text)}, nil
+			}
+			return nil, &Error{Pos: value.pos, Message: "available must be true or false"}
+		}
+		return valueTerm(field, value), nil
+	}
+	return nil, &Error{Pos: t.pos, Message: "expected a term, found " + describe(t)}
+}
+
+// valueTerm returns the term comparing a field with a word or phrase
+func valueTerm(field string, t token) *Term {
+	term := &Term{Field: field, Match: MatchContains, Value: t.text}
+	if field == FieldCategory || field == FieldISBN {
+		term.Match = MatchEquals
+	}
+	if t.kind == tokenWord && strings.Contains(t.text, "*") {
+		term.Match = MatchPattern
+	}
+	return term
+}
+
+func isField(name string) bool {
+	for _, field := range Fields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// describe names a token for error messages
+func describe(t token) string {
+	if t.kind == tokenEnd {
+		return "end of query"
+	}
+	return "\"" + t.text + "\""
+}
@@ -0,0 +1,88 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  Node
+	}{
+		{`dune`, &Term{Field: FieldAny, Match: MatchContains, Value: "dune"}},
+		{`"left hand"`, &Term{Field: FieldAny, Match: MatchContains, Value: "left hand"}},
+		{`title:dune`, &Term{Field: FieldTitle, Match: MatchContains, Value: "dune"}},
+		{`Author:"Le Guin"`, &Term{Field: FieldAuthor, Match: MatchContains, Value: "Le Guin"}},
+		{`category:scifi`, &Term{Field: FieldCategory, Match: MatchEquals, Value: "scifi"}},
+		{`isbn:978*`, &Term{Field: FieldISBN, Match: MatchPattern, Value: "978*"}},
+		{`available:TRUE`, &Term{Field: FieldAvailable, Match: MatchEquals, Value: "true"}},
+		{`-dune`, &Not{Node: &Term{Field: FieldAny, Match: MatchContains, Value: "dune"}}},
+		{`dune herbert`, &And{
+			Left:  &Term{Field: FieldAny, Match: MatchContains, Value: "dune"},
+			Right: &Term{Field: FieldAny, Match: MatchContains, Value: "herbert"},
+		}},
+		// AND binds tighter than OR
+		{`a OR b AND c`, &Or{
+			Left: &Term{Field: FieldAny, Match: MatchContains, Value: "a"},
+			Right: &And{
+				Left:  &Term{Field: FieldAny, Match: MatchContains, Value: "b"},
+				Right: &Term{Field: FieldAny, Match: MatchContains, Value: "c"},
+			},
+		}},
+		{`(a OR b) NOT c`, &And{
+			Left: &Or{
+				Left:  &Term{Field: FieldAny, Match: MatchContains, Value: "a"},
+				Right: &Term{Field: FieldAny, Match: MatchContains, Value: "b"},
+			},
+			Right: &Not{Node: &Term{Field: FieldAny, Match: MatchContains, Value: "c"}},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %#v, want %#v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+	}{
+		{``, 1},
+		{`   `, 4},
+		{`dune "left hand`, 6},
+		{`(dune`, 6},
+		{`dune)`, 5},
+		{`(dune herbert]`, 15},
+		{`dune OR`, 8},
+		{`publisher:scribner`, 1},
+		{`dune title:`, 12},
+		{`title:(dune)`, 7},
+		{`available:maybe`, 11},
+		{`NOT`, 4},
+		{`é title:`, 9},
+		{strings.Repeat("a", maxLength+1), maxLength + 1},
+		{strings.Repeat("(", maxDepth+2) + "a", maxDepth + 2},
+		{strings.Repeat("-", maxDepth+2) + "a", maxDepth + 2},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		var parseErr *Error
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Parse(%q) = %v, want an *Error", tt.query, err)
+			continue
+		}
+		if parseErr.Pos != tt.pos {
+			t.Errorf("Parse(%q) error at %d (%v), want %d", tt.query, parseErr.Pos, err, tt.pos)
+		}
+	}
+}
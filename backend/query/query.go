@@ -0,0 +1,73 @@
+// Package query parses catalog queries such as
+//
+//	author:"Le Guin" category:scifi available:true isbn:978*
+//
+// into a syntax tree the stores turn into a parameterized SQL predicate or
+// evaluate in memory. Terms are joined with AND, OR and NOT (or a leading -)
+// and grouped with parentheses; adjacent terms are ANDed.
+package query
+
+import "fmt"
+
+// Fields a term can be restricted to
+const (
+	FieldAny       = "" // Any text field of the book
+	FieldTitle     = "title"
+	FieldAuthor    = "author"
+	FieldCategory  = "category"
+	FieldISBN      = "isbn"
+	FieldAvailable = "available"
+)
+
+// Fields lists the field names accepted before a colon
+var Fields = []string{FieldTitle, FieldAuthor, FieldCategory, FieldISBN, FieldAvailable}
+
+// Ways a term compares its value with a field, ignoring case
+const (
+	MatchContains = iota // The field contains the value
+	MatchEquals          // The field is the value
+	MatchPattern         // The field matches the value, where * stands for any text
+)
+
+// Node is a node of a query syntax tree: *And, *Or, *Not or *Term
+type Node interface {
+	node()
+}
+
+// And matches when both sides match
+type And struct {
+	Left, Right Node
+}
+
+// Or matches when either side matches
+type Or struct {
+	Left, Right Node
+}
+
+// Not matches when its operand does not
+type Not struct {
+	Node Node
+}
+
+// Term compares a field with a value. Terms on FieldAvailable have the value
+// "true" or "false".
+type Term struct {
+	Field string
+	Match int
+	Value string
+}
+
+func (*And) node()  {}
+func (*Or) node()   {}
+func (*Not) node()  {}
+func (*Term) node() {}
+
+// Error is a syntax error in a query
+type Error struct {
+	Pos     int // 1-based character position of the error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Pos)
+}
@@ -4,6 +4,7 @@ import (
 	"digital-library/backend/config"     // Import config
 	"digital-library/backend/handlers"   // Import handlers
 	"digital-library/backend/middleware" // Import middleware
+	"digital-library/backend/models"
 	"os"
 	"path/filepath"
 
@@ -11,15 +12,112 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
+// Roles allowed to call a protected route
+var (
+	adminOnly    = []string{models.RoleAdmin}
+	anyUserRoles = []string{models.RoleAdmin, models.RoleUser}
+)
+
+// Route declares a protected endpoint and the roles allowed to call it
+type Route struct {
+	Method  string
+	Path    string
+	Handler fiber.Handler
+	Roles   []string
+}
+
+// protectedRoutes is the route table for every endpoint behind the JWT middleware
+func protectedRoutes(h *handlers.Handler, cfg *config.Config) []Route {
+	return []Route{
+		// Book routes: anyone can browse, only admins can change the catalog
+		{fiber.MethodGet, "/books", h.GetBooks, anyUserRoles},
+		{fiber.MethodGet, "/books/suggest", h.SuggestBooks, anyUserRoles},
+		{fiber.MethodGet, "/books/export", h.ExportBooks, anyUserRoles},
+		{fiber.MethodGet, "/books/isbn/:isbn", h.GetBookByISBN, anyUserRoles},
+		{fiber.MethodGet, "/books/:id", h.GetBook, anyUserRoles},
+		{fiber.MethodPost, "/books", h.CreateBook, adminOnly},
+		{fiber.MethodPost, "/books/import", h.ImportBooks, adminOnly},
+		{fiber.MethodPost, "/books/import/marc", h.ImportMARC, adminOnly},
+		{fiber.MethodPut, "/books/:id", h.UpdateBook, adminOnly},
+		{fiber.MethodDelete, "/books/:id", h.DeleteBook, adminOnly},
+		{fiber.MethodGet, "/books/:id/marc", h.GetBookMARC, anyUserRoles},
+		{fiber.MethodGet, "/books/:id/cite", h.CiteBook, anyUserRoles},
+		{fiber.MethodGet, "/books/:id/items", h.GetBookItems, anyUserRoles},
+		{fiber.MethodPost, "/books/:id/items", h.AddBookItem, adminOnly},
+		{fiber.MethodPost, "/books/:id/items/retire/:itemId", h.RetireBookItem, adminOnly},
+		{fiber.MethodGet, "/books/:id/files", h.GetBookFiles, anyUserRoles},
+		{fiber.MethodPost, "/books/:id/files", h.UploadBookFile, adminOnly},
+		{fiber.MethodGet, "/books/:id/files/:fileId", h.DownloadBookFile, anyUserRoles},
+		{fiber.MethodDelete, "/books/:id/files/:fileId", h.DeleteBookFile, adminOnly},
+
+		// Lending routes: users may borrow and return their own books
+		{fiber.MethodGet, "/lending", h.GetLendingRecords, anyUserRoles},
+		{fiber.MethodGet, "/lending/export", h.ExportLendingRecords, anyUserRoles},
+		{fiber.MethodPost, "/lending/lend", h.LendBook, anyUserRoles},
+		{fiber.MethodPost, "/lending/return/:id", h.ReturnBook, anyUserRoles},
+		{fiber.MethodPost, "/lending/lost/:id", h.ReportLostBook, anyUserRoles},
+		{fiber.MethodPost, "/lending/renew/:id", h.RenewBook, anyUserRoles},
+		{fiber.MethodDelete, "/lending/:id", h.DeleteLendingRecord, adminOnly},
+
+		// Loan policy routes
+		{fiber.MethodGet, "/loan-policies", h.GetLoanPolicies, anyUserRoles},
+		{fiber.MethodPost, "/loan-policies", h.CreateLoanPolicy, adminOnly},
+		{fiber.MethodPut, "/loan-policies/:id", h.UpdateLoanPolicy, adminOnly},
+		{fiber.MethodDelete, "/loan-policies/:id", h.DeleteLoanPolicy, adminOnly},
+
+		// Hold routes: users may place and cancel their own holds
+		{fiber.MethodGet, "/holds", h.GetHolds, anyUserRoles},
+		{fiber.MethodPost, "/holds", h.PlaceHold, anyUserRoles},
+		{fiber.MethodPost, "/holds/cancel/:id", h.CancelHold, anyUserRoles},
+
+		// Fine routes: users may view their own fines, only admins take payments
+		{fiber.MethodGet, "/fines", h.GetFines, anyUserRoles},
+		{fiber.MethodGet, "/fines/balance", h.GetFineBalance, anyUserRoles},
+		{fiber.MethodPost, "/fines/payments", h.RecordFinePayment, adminOnly},
+		{fiber.MethodPost, "/fines/waivers", h.WaiveFines, adminOnly},
+
+		// Analytics routes
+		{fiber.MethodGet, "/analytics/most-borrowed", h.GetMostBorrowedBooks, anyUserRoles},
+		{fiber.MethodGet, "/analytics/monthly-trends", h.GetMonthlyLendingTrends, anyUserRoles},
+		{fiber.MethodGet, "/analytics/category-distribution", h.GetCategoryDistribution, anyUserRoles},
+		{fiber.MethodGet, "/analytics/overdue", h.GetOverdueLoans, anyUserRoles},
+
+		// Tokens for reading apps that pass credentials in catalog links
+		{fiber.MethodPost, "/opds/token", h.CreateFeedToken(cfg), anyUserRoles},
+
+		// Archive of the whole library
+		{fiber.MethodGet, "/export/archive", h.ExportArchive, adminOnly},
+	}
+}
+
+// opdsRoutes is the route table of one version of the OPDS catalog,
+// relative to the catalog root
+func opdsRoutes(h *handlers.Handler, version string) []Route {
+	routes := []Route{
+		{fiber.MethodGet, "", h.OPDSRoot(version), anyUserRoles},
+		{fiber.MethodGet, "/books", h.OPDSBooks(version), anyUserRoles},
+		{fiber.MethodGet, "/new", h.OPDSNewArrivals(version), anyUserRoles},
+		{fiber.MethodGet, "/categories", h.OPDSCategories(version), anyUserRoles},
+		{fiber.MethodGet, "/authors", h.OPDSAuthors(version), anyUserRoles},
+		// Downloads of the files acquisition links lead to, for apps that
+		// cannot send a Bearer token
+		{fiber.MethodGet, "/books/:id/files/:fileId", h.DownloadBookFile, anyUserRoles},
+	}
+	if version == handlers.OPDS1 {
+		routes = append(routes, Route{fiber.MethodGet, "/opensearch.xml", h.OPDSSearchDescription, anyUserRoles})
+	}
+	return routes
+}
+
 // SetupRoutes sets up all the routes for the application
-func SetupRoutes(app *fiber.App, cfg *config.Config) { // Accept config
+func SetupRoutes(app *fiber.App, cfg *config.Config, h *handlers.Handler) { // Accept config and handlers
 	// Middleware
 	app.Use(logger.New()) // Add basic request logging
 
 	// Public routes
 	api := app.Group("/api")
-	api.Post("/register", handlers.Register) // Add registration route
-	api.Post("/login", handlers.Login(cfg))  // Add login route, pass config
+	api.Post("/register", h.Register) // Add registration route
+	api.Post("/login", h.Login(cfg))  // Add login route, pass config
 
 	// Serve Swagger documentation
 	api.Get("/apidocs", func(c *fiber.Ctx) error {
@@ -37,30 +135,31 @@ func SetupRoutes(app *fiber.App, cfg *config.Config) { // Accept config
 		return c.Send(swaggerData)
 	})
 
+	// --- OPDS catalog for reading apps ---
+	// Apps sign in with HTTP Basic, a JWT or a feed token. Registered before
+	// the protected group, whose middleware would otherwise ask for a Bearer
+	// token.
+	feedAuth := middleware.ProtectedFeed(cfg, h.FeedLogin)
+	for root, version := range map[string]string{"/opds": handlers.OPDS1, "/opds/v2": handlers.OPDS2} {
+		for _, r := range opdsRoutes(h, version) {
+			api.Add(r.Method, root+r.Path, feedAuth, middleware.RequireRole(r.Roles...), r.Handler)
+		}
+	}
+
+	// OAI-PMH for metadata harvesters, which take arguments by GET or as a
+	// form by POST, and SRU for discovery layers and other catalogs
+	// searching with CQL. Open to all, so also registered before the
+	// protected group.
+	api.Get("/oai", h.OAI(cfg))
+	api.Post("/oai", h.OAI(cfg))
+	api.Get("/sru", h.SRU(cfg))
+
 	// --- JWT Protected Routes ---
-	// Apply JWT middleware to groups below
+	// Apply JWT middleware to the group, then each route's role check
 	protected := api.Group("", middleware.Protected(cfg)) // Create a group with the middleware
-
-	// Book routes (now protected)
-	book := protected.Group("/books")
-	book.Post("/", handlers.CreateBook)      // Connect CreateBook handler
-	book.Get("/", handlers.GetBooks)         // Connect GetBooks handler
-	book.Get("/:id", handlers.GetBook)       // Connect GetBook handler
-	book.Put("/:id", handlers.UpdateBook)    // Connect UpdateBook handler
-	book.Delete("/:id", handlers.DeleteBook) // Connect DeleteBook handler
-
-	// Lending routes (now protected)
-	lending := protected.Group("/lending")
-	lending.Post("/lend", handlers.LendBook)             // Connect LendBook handler
-	lending.Post("/return/:id", handlers.ReturnBook)     // Connect ReturnBook handler
-	lending.Get("/", handlers.GetLendingRecords)         // Connect GetLendingRecords handler
-	lending.Delete("/:id", handlers.DeleteLendingRecord) // Connect DeleteLendingRecord handler
-
-	// Analytics routes (now protected)
-	analytics := protected.Group("/analytics")
-	analytics.Get("/most-borrowed", handlers.GetMostBorrowedBooks)            // Connect analytics handler
-	analytics.Get("/monthly-trends", handlers.GetMonthlyLendingTrends)        // Connect analytics handler
-	analytics.Get("/category-distribution", handlers.GetCategoryDistribution) // Connect analytics handler
+	for _, r := range protectedRoutes(h, cfg) {
+		protected.Add(r.Method, r.Path, middleware.RequireRole(r.Roles...), r.Handler)
+	}
 
 	// Health Check (optional - public)
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -0,0 +1,48 @@
+package sru
+
+import "encoding/xml"
+
+const (
+	dcSchemaNamespace = "info:srw/schema/1/dc-schema"
+	dcNamespace       = "http://purl.org/dc/elements/1.1/"
+)
+
+// DC is a simple Dublin Core record in the SRU dc schema. It has the same
+// elements as oai.DC, which converts to it.
+type DC struct {
+	Titles      []string
+	Creators    []string
+	Subjects    []string
+	Publishers  []string
+	Dates       []string
+	Types       []string
+	Identifiers []string
+}
+
+type dcRecord struct {
+	XMLName     xml.Name `xml:"srw_dc:dc"`
+	XmlnsSRWDC  string   `xml:"xmlns:srw_dc,attr"`
+	XmlnsDC     string   `xml:"xmlns:dc,attr"`
+	Titles      []string `xml:"dc:title"`
+	Creators    []string `xml:"dc:creator"`
+	Subjects    []string `xml:"dc:subject"`
+	Publishers  []string `xml:"dc:publisher"`
+	Dates       []string `xml:"dc:date"`
+	Types       []string `xml:"dc:type"`
+	Identifiers []string `xml:"dc:identifier"`
+}
+
+// MarshalXML writes the record as an srw_dc:dc element
+func (dc *DC) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.Encode(dcRecord{
+		XmlnsSRWDC:  dcSchemaNamespace,
+		XmlnsDC:     dcNamespace,
+		Titles:      dc.Titles,
+		Creators:    dc.Creators,
+		Subjects:    dc.Subjects,
+		Publishers:  dc.Publishers,
+		Dates:       dc.Dates,
+		Types:       dc.Types,
+		Identifiers: dc.Identifiers,
+	})
+}
@@ -0,0 +1,319 @@
+// Package sru writes the responses of an SRU 1.2 (Search/Retrieve via URL)
+// server, which discovery layers use to search other libraries' catalogs
+// with CQL.
+package sru
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+)
+
+// Version is the SRU version served
+const Version = "1.2"
+
+// Record schemas
+const (
+	SchemaDC      = "info:srw/schema/1/dc-v1.1"
+	SchemaMARCXML = "info:srw/schema/1/marcxml-v1.1"
+)
+
+// Record packings: records as XML, or as escaped XML text
+const (
+	PackingXML    = "xml"
+	PackingString = "string"
+)
+
+const (
+	namespace           = "http://www.loc.gov/zing/srw/"
+	diagnosticNamespace = "http://www.loc.gov/zing/srw/diagnostic/"
+	explainNamespace    = "http://explain.z3950.org/dtd/2.0/"
+)
+
+// Diagnostic codes, from the SRU diagnostics list at
+// info:srw/diagnostic/1/
+const (
+	DiagGeneralError                = 1
+	DiagUnsupportedOperation        = 4
+	DiagUnsupportedVersion          = 5
+	DiagUnsupportedParameterValue   = 6
+	DiagMissingParameter            = 7
+	DiagUnsupportedParameter        = 8
+	DiagQuerySyntax                 = 10
+	DiagUnsupportedPrefix           = 15
+	DiagUnsupportedIndex            = 16
+	DiagUnsupportedRelation         = 19
+	DiagUnsupportedRelationModifier = 20
+	DiagEmptyTerm                   = 27
+	DiagMaskingUnsupported          = 28
+	DiagAnchoringUnsupported        = 32
+	DiagUnsupportedBoolean          = 37
+	DiagUnsupportedBooleanModifier  = 46
+	DiagFirstRecordOutOfRange       = 61
+	DiagUnknownSchema               = 66
+	DiagUnsupportedPacking          = 71
+)
+
+// diagnosticMessages are the standard messages of the diagnostics
+var diagnosticMessages = map[int]string{
+	DiagGeneralError:                "General system error",
+	DiagUnsupportedOperation:        "Unsupported operation",
+	DiagUnsupportedVersion:          "Unsupported version",
+	DiagUnsupportedParameterValue:   "Unsupported parameter value",
+	DiagMissingParameter:            "Mandatory parameter not supplied",
+	DiagUnsupportedParameter:        "Unsupported parameter",
+	DiagQuerySyntax:                 "Query syntax error",
+	DiagUnsupportedPrefix:           "Unsupported context set",
+	DiagUnsupportedIndex:            "Unsupported index",
+	DiagUnsupportedRelation:         "Unsupported relation",
+	DiagUnsupportedRelationModifier: "Unsupported relation modifier",
+	DiagEmptyTerm:                   "Empty term unsupported",
+	DiagMaskingUnsupported:          "Masking character not supported",
+	DiagAnchoringUnsupported:        "Anchoring character not supported in this position",
+	DiagUnsupportedBoolean:          "Unsupported boolean operator",
+	DiagUnsupportedBooleanModifier:  "Unsupported boolean modifier",
+	DiagFirstRecordOutOfRange:       "First record position out of range",
+	DiagUnknownSchema:               "Unknown schema for retrieval",
+	DiagUnsupportedPacking:          "Unsupported record packing",
+}
+
+// Diagnostic reports why a request failed. Details names the parameter,
+// index or value at fault.
+type Diagnostic struct {
+	Code    int
+	Details string
+}
+
+func (d *Diagnostic) Error() string {
+	return diagnosticMessages[d.Code] + ": " + d.Details
+}
+
+type xmlDiagnostic struct {
+	XMLName xml.Name `xml:"diag:diagnostic"`
+	Xmlns   string   `xml:"xmlns:diag,attr"`
+	URI     string   `xml:"diag:uri"`
+	Details string   `xml:"diag:details,omitempty"`
+	Message string   `xml:"diag:message"`
+}
+
+// Record is a record of a response. Data encodes itself as the root element
+// of its schema, such as *DC.
+type Record struct {
+	Schema   string
+	Packing  string
+	Data     interface{}
+	Position int
+}
+
+type xmlRecord struct {
+	Schema   string        `xml:"recordSchema"`
+	Packing  string        `xml:"recordPacking"`
+	Data     xmlRecordData `xml:"recordData"`
+	Position int           `xml:"recordPosition,omitempty"`
+}
+
+type xmlRecordData struct {
+	Value interface{}
+	Text  string `xml:",chardata"`
+}
+
+func newXMLRecord(record Record) (xmlRecord, error) {
+	x := xmlRecord{Schema: record.Schema, Packing: record.Packing, Position: record.Position}
+	if record.Packing != PackingString {
+		x.Data.Value = record.Data
+		return x, nil
+	}
+	text, err := xml.Marshal(record.Data)
+	x.Data.Text = string(text)
+	return x, err
+}
+
+// SearchRetrieve is the response to a searchRetrieve request. Requests that
+// failed have a diagnostic and no records.
+type SearchRetrieve struct {
+	NumberOfRecords    int
+	Records            []Record
+	NextRecordPosition int // Zero on the last page
+	Diagnostics        []*Diagnostic
+}
+
+type xmlSearchRetrieve struct {
+	XMLName            xml.Name        `xml:"searchRetrieveResponse"`
+	Xmlns              string          `xml:"xmlns,attr"`
+	Version            string          `xml:"version"`
+	NumberOfRecords    int             `xml:"numberOfRecords"`
+	Records            *xmlRecords     `xml:"records"`
+	NextRecordPosition int             `xml:"nextRecordPosition,omitempty"`
+	Diagnostics        *xmlDiagnostics `xml:"diagnostics"`
+}
+
+type xmlRecords struct {
+	Records []xmlRecord `xml:"record"`
+}
+
+type xmlDiagnostics struct {
+	Diagnostics []xmlDiagnostic
+}
+
+// WriteSearchRetrieve writes a searchRetrieve response
+func WriteSearchRetrieve(w io.Writer, resp SearchRetrieve) error {
+	x := xmlSearchRetrieve{
+		Xmlns:              namespace,
+		Version:            Version,
+		NumberOfRecords:    resp.NumberOfRecords,
+		NextRecordPosition: resp.NextRecordPosition,
+		Diagnostics:        diagnostics(resp.Diagnostics),
+	}
+	if len(resp.Records) > 0 {
+		x.Records = &xmlRecords{}
+		for _, record := range resp.Records {
+			r, err := newXMLRecord(record)
+			if err != nil {
+				return err
+			}
+			x.Records.Records = append(x.Records.Records, r)
+		}
+	}
+	return write(w, x)
+}
+
+// Explain describes the server for an explain request
+type Explain struct {
+	Host        string
+	Port        int
+	Database    string // Path of the server, without the leading slash
+	Title       string
+	Indexes     []Index
+	Default     int // Records returned when a request does not say
+	Maximum     int // Most records returned at once
+	Diagnostics []*Diagnostic
+}
+
+// Index is a searchable index, with the names CQL can give it
+type Index struct {
+	Title string
+	Names []IndexName
+}
+
+// IndexName names an index within a context set, such as dc.title
+type IndexName struct {
+	Set  string
+	Name string
+}
+
+// Context sets of the index names
+var contextSets = []xmlSet{
+	{Name: "cql", Identifier: "info:srw/cql-context-set/1/cql-v1.2"},
+	{Name: "dc", Identifier: "info:srw/cql-context-set/1/dc-v1.1"},
+	{Name: "bath", Identifier: "http://zing.z3950.org/cql/bath/2.0/"},
+}
+
+type xmlExplainResponse struct {
+	XMLName     xml.Name         `xml:"explainResponse"`
+	Xmlns       string           `xml:"xmlns,attr"`
+	Version     string           `xml:"version"`
+	Record      xmlExplainRecord `xml:"record"`
+	Diagnostics *xmlDiagnostics  `xml:"diagnostics"`
+}
+
+type xmlExplainRecord struct {
+	Schema  string     `xml:"recordSchema"`
+	Packing string     `xml:"recordPacking"`
+	Data    xmlExplain `xml:"recordData>explain"`
+}
+
+type xmlExplain struct {
+	Xmlns      string `xml:"xmlns,attr"`
+	ServerInfo struct {
+		Protocol string `xml:"protocol,attr"`
+		Version  string `xml:"version,attr"`
+		Host     string `xml:"host"`
+		Port     int    `xml:"port"`
+		Database string `xml:"database"`
+	} `xml:"serverInfo"`
+	Title    string          `xml:"databaseInfo>title"`
+	Sets     []xmlSet        `xml:"indexInfo>set"`
+	Indexes  []xmlIndex      `xml:"indexInfo>index"`
+	Schemas  []xmlSchema     `xml:"schemaInfo>schema"`
+	Defaults []xmlConfigItem `xml:"configInfo>default"`
+	Settings []xmlConfigItem `xml:"configInfo>setting"`
+}
+
+type xmlSet struct {
+	Name       string `xml:"name,attr"`
+	Identifier string `xml:"identifier,attr"`
+}
+
+type xmlIndex struct {
+	Title string         `xml:"title"`
+	Names []xmlIndexName `xml:"map>name"`
+}
+
+type xmlIndexName struct {
+	Set  string `xml:"set,attr"`
+	Name string `xml:",chardata"`
+}
+
+type xmlSchema struct {
+	Identifier string `xml:"identifier,attr"`
+	Name       string `xml:"name,attr"`
+	Title      string `xml:"title"`
+}
+
+type xmlConfigItem struct {
+	Type  string `xml:"type,attr"`
+	Value int    `xml:",chardata"`
+}
+
+// WriteExplain writes an explain response
+func WriteExplain(w io.Writer, explain Explain) error {
+	x := xmlExplainResponse{
+		Xmlns:       namespace,
+		Version:     Version,
+		Record:      xmlExplainRecord{Schema: explainNamespace, Packing: PackingXML},
+		Diagnostics: diagnostics(explain.Diagnostics),
+	}
+	e := &x.Record.Data
+	e.Xmlns = explainNamespace
+	e.ServerInfo.Protocol, e.ServerInfo.Version = "SRU", Version
+	e.ServerInfo.Host, e.ServerInfo.Port, e.ServerInfo.Database = explain.Host, explain.Port, explain.Database
+	e.Title = explain.Title
+	e.Sets = contextSets
+	for _, index := range explain.Indexes {
+		i := xmlIndex{Title: index.Title}
+		for _, name := range index.Names {
+			i.Names = append(i.Names, xmlIndexName{Set: name.Set, Name: name.Name})
+		}
+		e.Indexes = append(e.Indexes, i)
+	}
+	e.Schemas = []xmlSchema{
+		{Identifier: SchemaDC, Name: "dc", Title: "Dublin Core"},
+		{Identifier: SchemaMARCXML, Name: "marcxml", Title: "MARCXML"},
+	}
+	e.Defaults = []xmlConfigItem{{Type: "numberOfRecords", Value: explain.Default}}
+	e.Settings = []xmlConfigItem{{Type: "maximumRecords", Value: explain.Maximum}}
+	return write(w, x)
+}
+
+func diagnostics(diags []*Diagnostic) *xmlDiagnostics {
+	if len(diags) == 0 {
+		return nil
+	}
+	x := &xmlDiagnostics{}
+	for _, d := range diags {
+		x.Diagnostics = append(x.Diagnostics, xmlDiagnostic{
+			Xmlns:   diagnosticNamespace,
+			URI:     "info:srw/diagnostic/1/" + strconv.Itoa(d.Code),
+			Details: d.Details,
+			Message: diagnosticMessages[d.Code],
+		})
+	}
+	return x
+}
+
+func write(w io.Writer, x interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(x)
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"sort"
+	"strconv"
+
+	"digital-library/backend/models"
+)
+
+// Facets ListBooks can count. Category and author facets hold the most
+// common values only; books without a category are not counted.
+var BookFacets = []string{"category", "author", "available"}
+
+// facetLimit is the number of values returned for the category and author
+// facets
+const facetLimit = 20
+
+// countFacets counts books by the value of each facet, most common value
+// first, like the facet queries of the Postgres store
+func countFacets(books []models.Book, facets []string) map[string][]models.FacetBucket {
+	result := make(map[string][]models.FacetBucket)
+	for _, facet := range facets {
+		counts := facetCounts(books, facet)
+		buckets := make([]models.FacetBucket, 0, len(counts))
+		for value, count := range counts {
+			buckets = append(buckets, models.FacetBucket{Value: value, Count: count})
+		}
+		sort.Slice(buckets, func(i, j int) bool {
+			if buckets[i].Count != buckets[j].Count {
+				return buckets[i].Count > buckets[j].Count
+			}
+			return buckets[i].Value < buckets[j].Value
+		})
+		if facet != "available" && len(buckets) > facetLimit {
+			buckets = buckets[:facetLimit]
+		}
+		result[facet] = buckets
+	}
+	return result
+}
+
+// facetCounts counts books by their value of a facet
+func facetCounts(books []models.Book, facet string) map[string]int {
+	counts := make(map[string]int)
+	for _, book := range books {
+		switch facet {
+		case "category":
+			if book.Category != "" {
+				counts[book.Category]++
+			}
+		case "author":
+			counts[book.Author]++
+		case "available":
+			counts[strconv.FormatBool(book.Quantity > 0)]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"digital-library/backend/models"
+)
+
+func TestCountFacets(t *testing.T) {
+	books := []models.Book{
+		{Author: "Jane Austen", Category: "classic", Quantity: 1},
+		{Author: "Jane Austen", Category: "classic"},
+		{Author: "Frank Herbert", Category: "scifi", Quantity: 2},
+		{Author: "Anonymous"},
+	}
+
+	got := countFacets(books, []string{"category", "author", "available"})
+	want := map[string][]models.FacetBucket{
+		"category":  {{Value: "classic", Count: 2}, {Value: "scifi", Count: 1}},
+		"author":    {{Value: "Jane Austen", Count: 2}, {Value: "Anonymous", Count: 1}, {Value: "Frank Herbert", Count: 1}},
+		"available": {{Value: "false", Count: 2}, {Value: "true", Count: 2}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("countFacets = %+v, want %+v", got, want)
+	}
+
+	if got := countFacets(nil, []string{"category"}); len(got["category"]) != 0 {
+		t.Errorf("countFacets of no books = %+v, want an empty category facet", got)
+	}
+}
+
+func TestCountFacetsLimit(t *testing.T) {
+	var books []models.Book
+	for i := 0; i < facetLimit+5; i++ {
+		books = append(books, models.Book{Author: fmt.Sprintf("Author %02d", i)})
+	}
+	// The most common author sorts last by name and must survive the cut
+	books = append(books, models.Book{Author: "Author 24"})
+
+	buckets := countFacets(books, []string{"author"})["author"]
+	if len(buckets) != facetLimit || buckets[0] != (models.FacetBucket{Value: "Author 24", Count: 2}) {
+		t.Errorf("author facet = %d buckets starting with %+v, want %d starting with Author 24", len(buckets), buckets[0], facetLimit)
+	}
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"strconv"
+	"time"
+
+	"digital-library/backend/models"
+)
+
+// fineCharges returns the charges owed when a loan is closed on closeDate with
+// the copy in the given condition. Days late within the policy's grace period
+// are free; every day beyond it is charged at the daily rate.
+func fineCharges(record models.LendingRecord, policy models.LoanPolicy, closeDate time.Time, condition string) []models.FineEntry {
+	var charges []models.FineEntry
+	charge := func(reason string, amount int, note string) {
+		if amount <= 0 {
+			return
+		}
+		recordID := record.ID
+		charges = append(charges, models.FineEntry{
+			UserID:          record.UserID,
+			Borrower:        record.Borrower,
+			LendingRecordID: &recordID,
+			EntryType:       models.FineEntryCharge,
+			Reason:          &reason,
+			AmountCents:     amount,
+			Note:            note,
+		})
+	}
+
+	daysLate := int(closeDate.Sub(record.DueDate).Hours() / 24)
+	if chargeable := daysLate - policy.GraceDays; daysLate > 0 && chargeable > 0 {
+		charge(models.FineReasonOverdue, chargeable*policy.DailyFineCents, strconv.Itoa(daysLate)+" days late")
+	}
+	switch condition {
+	case models.ConditionDamaged:
+		charge(models.FineReasonDamaged, policy.DamagedFeeCents, "Returned damaged")
+	case models.ConditionLost:
+		charge(models.FineReasonLost, policy.LostFeeCents, "Reported lost")
+	}
+	return charges
+}
+
+// addToBalance adds a ledger entry to a running balance
+func addToBalance(balance *models.FineBalance, entry models.FineEntry) {
+	switch entry.EntryType {
+	case models.FineEntryCharge:
+		balance.ChargedCents += entry.AmountCents
+	case models.FineEntryPayment:
+		balance.PaidCents += entry.AmountCents
+	case models.FineEntryWaiver:
+		balance.WaivedCents += entry.AmountCents
+	}
+	balance.BalanceCents = balance.ChargedCents - balance.PaidCents - balance.WaivedCents
+}
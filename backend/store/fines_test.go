@@ -0,0 +1,69 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"digital-library/backend/models"
+)
+
+func TestFineCharges(t *testing.T) {
+	due := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	userID := 3
+	record := models.LendingRecord{ID: 7, UserID: &userID, Borrower: "alice", DueDate: due}
+	policy := models.LoanPolicy{GraceDays: 2, DailyFineCents: 25, LostFeeCents: 2000, DamagedFeeCents: 500}
+
+	type charge struct {
+		reason string
+		amount int
+	}
+	tests := []struct {
+		name      string
+		days      int
+		condition string
+		policy    models.LoanPolicy
+		want      []charge
+	}{
+		{"on time", 0, models.ConditionGood, policy, nil},
+		{"early", -3, models.ConditionGood, policy, nil},
+		{"within grace", 2, models.ConditionGood, policy, nil},
+		{"past grace", 5, models.ConditionGood, policy, []charge{{models.FineReasonOverdue, 75}}},
+		{"no daily fine", 5, models.ConditionGood, models.LoanPolicy{GraceDays: 2}, nil},
+		{"damaged on time", 0, models.ConditionDamaged, policy, []charge{{models.FineReasonDamaged, 500}}},
+		{"lost late", 10, models.ConditionLost, policy, []charge{{models.FineReasonOverdue, 200}, {models.FineReasonLost, 2000}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []charge
+			for _, entry := range fineCharges(record, tt.policy, due.AddDate(0, 0, tt.days), tt.condition) {
+				if entry.EntryType != models.FineEntryCharge || !isUser(entry.UserID, userID) ||
+					entry.LendingRecordID == nil || *entry.LendingRecordID != record.ID || entry.Reason == nil {
+					t.Fatalf("unexpected entry %+v", entry)
+				}
+				got = append(got, charge{*entry.Reason, entry.AmountCents})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("charges = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddToBalance(t *testing.T) {
+	balance := models.FineBalance{UserID: 3}
+	for _, entry := range []models.FineEntry{
+		{EntryType: models.FineEntryCharge, AmountCents: 750},
+		{EntryType: models.FineEntryPayment, AmountCents: 200},
+		{EntryType: models.FineEntryWaiver, AmountCents: 50},
+		{EntryType: models.FineEntryCharge, AmountCents: 100},
+	} {
+		addToBalance(&balance, entry)
+	}
+
+	want := models.FineBalance{UserID: 3, ChargedCents: 850, PaidCents: 200, WaivedCents: 50, BalanceCents: 600}
+	if balance != want {
+		t.Errorf("balance = %+v, want %+v", balance, want)
+	}
+}
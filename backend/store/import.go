@@ -0,0 +1,53 @@
+package store
+
+import "digital-library/backend/models"
+
+// ImportDryRun tracks the books a dry run of an import would have created or
+// updated, so a book repeated in a later batch is reported as it would be
+// saved: updated or unchanged rather than created again. The zero value is
+// an empty dry run.
+type ImportDryRun struct {
+	books map[string]models.Book // By ISBN
+}
+
+// book returns the book a dry run would have saved with the ISBN. A nil dry
+// run has none.
+func (d *ImportDryRun) book(isbn string) (models.Book, bool) {
+	if d == nil {
+		return models.Book{}, false
+	}
+	book, ok := d.books[isbn]
+	return book, ok
+}
+
+// save records a book the dry run would have saved
+func (d *ImportDryRun) save(book models.Book) {
+	if d.books == nil {
+		d.books = make(map[string]models.Book)
+	}
+	d.books[book.ISBN] = book
+}
+
+// importChanges merges an imported book into the stored one, keeping the
+// stored category, publisher and year when the import leaves them empty, and
+// reports whether anything changed
+func importChanges(existing models.Book, book *models.Book) bool {
+	if book.Category == "" {
+		book.Category = existing.Category
+	}
+	if book.Publisher == "" {
+		book.Publisher = existing.Publisher
+	}
+	if book.Year == nil {
+		book.Year = existing.Year
+	}
+	return book.Title != existing.Title || book.Author != existing.Author || book.Category != existing.Category ||
+		book.Publisher != existing.Publisher || !sameYear(book.Year, existing.Year)
+}
+
+func sameYear(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
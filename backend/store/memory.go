@@ -0,0 +1,76 @@
+package store
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"digital-library/backend/models"
+)
+
+// Memory implements every store interface in process memory. It mirrors the
+// behaviour of the Postgres store and is meant for tests and local development.
+type Memory struct {
+	mu       sync.Mutex
+	nextID   int
+	books    map[int]models.Book
+	deleted  map[int]models.BookChange // Deleted books, like deleted_books
+	items    map[int]models.BookItem
+	files    map[int]models.BookFile
+	records  map[int]models.LendingRecord
+	policies map[int]models.LoanPolicy
+	holds    map[int]models.Hold
+	fines    []models.FineEntry
+	users    map[int]memoryUser
+
+	barcodeSeq int // Numbers generated barcodes, like book_item_barcode_seq
+}
+
+type memoryUser struct {
+	models.User
+	passwordHash string
+}
+
+var _ Store = (*Memory)(nil)
+
+// NewMemory creates an in-memory store holding only the default loan policy,
+// matching a freshly migrated database
+func NewMemory() *Memory {
+	m := &Memory{
+		books:    make(map[int]models.Book),
+		deleted:  make(map[int]models.BookChange),
+		items:    make(map[int]models.BookItem),
+		files:    make(map[int]models.BookFile),
+		records:  make(map[int]models.LendingRecord),
+		policies: make(map[int]models.LoanPolicy),
+		holds:    make(map[int]models.Hold),
+		users:    make(map[int]memoryUser),
+	}
+	policy := models.DefaultLoanPolicy
+	policy.ID = m.newID()
+	policy.CreatedAt = now()
+	policy.UpdatedAt = policy.CreatedAt
+	m.policies[policy.ID] = policy
+	return m
+}
+
+// newID returns the next identifier; callers must hold the lock
+func (m *Memory) newID() int {
+	m.nextID++
+	return m.nextID
+}
+
+// now returns the timestamp used for created_at/updated_at columns
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// containsFold mirrors LOWER(column) LIKE LOWER('%substr%')
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// isUser mirrors a user_id = $n comparison against a nullable column
+func isUser(ref *int, userID int) bool {
+	return ref != nil && *ref == userID
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"digital-library/backend/models"
+)
+
+// scopedRecords returns the lending records visible in the scope; callers must hold the lock
+func (m *Memory) scopedRecords(scope AnalyticsScope) []models.LendingRecord {
+	records := make([]models.LendingRecord, 0, len(m.records))
+	for _, record := range m.records {
+		if scope.All || isUser(record.UserID, scope.UserID) {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// MostBorrowedBooks returns books ordered by number of times borrowed
+func (m *Memory) MostBorrowedBooks(ctx context.Context, scope AnalyticsScope, limit int) ([]models.BorrowCount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[int]int)
+	for _, record := range m.scopedRecords(scope) {
+		counts[record.BookID]++
+	}
+
+	results := make([]models.BorrowCount, 0, len(counts))
+	for bookID, borrows := range counts {
+		results = append(results, models.BorrowCount{
+			BookID:    bookID,
+			BookTitle: m.books[bookID].Title,
+			Borrows:   borrows,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Borrows != results[j].Borrows {
+			return results[i].Borrows > results[j].Borrows
+		}
+		return results[i].BookID < results[j].BookID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// MonthlyLendingTrends returns lending counts grouped by month
+func (m *Memory) MonthlyLendingTrends(ctx context.Context, scope AnalyticsScope) ([]models.MonthlyTrend, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, record := range m.scopedRecords(scope) {
+		counts[record.BorrowDate.Format("2006-01")]++
+	}
+
+	results := make([]models.MonthlyTrend, 0, len(counts))
+	for month, count := range counts {
+		results = append(results, models.MonthlyTrend{Month: month, Count: count})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Month < results[j].Month })
+	return results, nil
+}
+
+// CategoryDistribution returns the number of books per category. A user
+// scope only counts the books that user has borrowed.
+func (m *Memory) CategoryDistribution(ctx context.Context, scope AnalyticsScope) ([]models.CategoryDistribution, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bookIDs := make(map[int]bool)
+	if scope.All {
+		for id := range m.books {
+			bookIDs[id] = true
+		}
+	} else {
+		for _, record := range m.scopedRecords(scope) {
+			bookIDs[record.BookID] = true
+		}
+	}
+
+	counts := make(map[string]int)
+	for id := range bookIDs {
+		counts[m.books[id].Category]++
+	}
+
+	results := make([]models.CategoryDistribution, 0, len(counts))
+	for category, count := range counts {
+		results = append(results, models.CategoryDistribution{Category: category, Count: count})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Count != results[j].Count {
+			return results[i].Count > results[j].Count
+		}
+		return results[i].Category < results[j].Category
+	})
+	return results, nil
+}
+
+// OverdueLoans lists unreturned loans due before asOf, most overdue first
+func (m *Memory) OverdueLoans(ctx context.Context, scope AnalyticsScope, asOf time.Time) ([]models.OverdueLoan, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := make([]models.OverdueLoan, 0)
+	for _, record := range m.scopedRecords(scope) {
+		if record.ReturnDate != nil || !record.DueDate.Before(asOf) {
+			continue
+		}
+		book := m.books[record.BookID]
+		results = append(results, models.OverdueLoan{
+			LendingRecordID: record.ID,
+			BookID:          book.ID,
+			BookTitle:       book.Title,
+			BookAuthor:      book.Author,
+			UserID:          record.UserID,
+			Borrower:        record.Borrower,
+			BorrowDate:      record.BorrowDate,
+			DueDate:         record.DueDate,
+			DaysOverdue:     int(asOf.Sub(record.DueDate).Hours() / 24),
+			OverdueAt:       record.OverdueAt,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if !results[i].DueDate.Equal(results[j].DueDate) {
+			return results[i].DueDate.Before(results[j].DueDate)
+		}
+		return results[i].LendingRecordID < results[j].LendingRecordID
+	})
+	return results, nil
+}
@@ -0,0 +1,430 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"digital-library/backend/models"
+)
+
+// ListBooks returns a page of the books matching the filter. Searches are
+// ordered by relevance and other listings by title unless another sort is
+// requested. A search that no book matches with full-text search, whatever
+// the other filters, falls back to the books whose title or author is spelled
+// most like it.
+func (m *Memory) ListBooks(ctx context.Context, filter BookFilter, page PageRequest) (models.BookPage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := models.BookPage{Data: make([]models.Book, 0)}
+	l := bookListing
+	search := searchNone
+	if filter.Search != "" {
+		l = bookSearchListing
+		search = searchFullText
+	}
+	q, err := l.resolve(page)
+	if err != nil {
+		return result, err
+	}
+
+	if search == searchFullText && !m.fullTextMatches(filter.Search) {
+		search = searchSimilar
+		result.Similar = true
+	}
+	books := m.matchBooks(filter, search)
+	sort.Slice(books, func(i, j int) bool {
+		return q.before(bookSortValue(books[i], q.sort), books[i].ID, bookSortValue(books[j], q.sort), books[j].ID)
+	})
+
+	result.Total = len(books)
+	if len(filter.Facets) > 0 {
+		result.Facets = countFacets(books, filter.Facets)
+	}
+	skipped := 0
+	for _, book := range books {
+		if !q.continues(bookSortValue(book, q.sort), book.ID) || q.skip(&skipped) {
+			continue
+		}
+		if q.limit > 0 && len(result.Data) == q.limit {
+			last := result.Data[q.limit-1]
+			result.NextCursor = q.nextCursor(bookSortValue(last, q.sort), last.ID)
+			break
+		}
+		result.Data = append(result.Data, book)
+	}
+	return result, nil
+}
+
+// ExportBooks calls fn with every book ListBooks would list for the filter.
+// The books are copied first, so fn runs without the lock.
+func (m *Memory) ExportBooks(ctx context.Context, filter BookFilter, page PageRequest, fn func(models.Book) error) error {
+	page.Limit, page.Cursor, page.Offset = 0, "", 0
+	result, err := m.ListBooks(ctx, filter, page)
+	if err != nil {
+		return err
+	}
+	for _, book := range result.Data {
+		if err := fn(book); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fullTextMatches reports whether any book matches a search with full-text
+// search, whatever the other filters; callers must hold the lock
+func (m *Memory) fullTextMatches(search string) bool {
+	query := parseTextQuery(search)
+	for _, book := range m.books {
+		if _, ok := query.rank(book); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchBooks returns the books matching the filter, using the given way to
+// match its search; callers must hold the lock
+func (m *Memory) matchBooks(filter BookFilter, search int) []models.Book {
+	var query textQuery
+	if search == searchFullText {
+		query = parseTextQuery(filter.Search)
+	}
+
+	books := make([]models.Book, 0)
+	for _, book := range m.books {
+		switch search {
+		case searchFullText:
+			rank, ok := query.rank(book)
+			if !ok {
+				continue
+			}
+			book.Rank = rank
+			book.Highlight = query.highlight(book)
+		case searchSimilar:
+			rank, ok := similarity(filter.Search, book)
+			if !ok {
+				continue
+			}
+			book.Rank = rank
+		}
+		if filter.IDs != nil && !slices.Contains(filter.IDs, book.ID) {
+			continue
+		}
+		if filter.Category != "" && !strings.EqualFold(book.Category, filter.Category) {
+			continue
+		}
+		if filter.Author != "" && !strings.EqualFold(book.Author, filter.Author) {
+			continue
+		}
+		if filter.Available != nil && *filter.Available != (book.Quantity > 0) {
+			continue
+		}
+		if filter.Query != nil && !matchBookQuery(filter.Query, book) {
+			continue
+		}
+		books = append(books, book)
+	}
+	return books
+}
+
+// ListFacetValues returns up to limit values of the category or author
+// facet after the given value, in order, with their number of books
+func (m *Memory) ListFacetValues(ctx context.Context, facet, after string, limit int) ([]models.FacetBucket, error) {
+	m.mu.Lock()
+	books := make([]models.Book, 0, len(m.books))
+	for _, book := range m.books {
+		books = append(books, book)
+	}
+	m.mu.Unlock()
+
+	buckets := make([]models.FacetBucket, 0)
+	if facet != "category" && facet != "author" {
+		return buckets, nil
+	}
+	for value, count := range facetCounts(books, facet) {
+		if value > after {
+			buckets = append(buckets, models.FacetBucket{Value: value, Count: count})
+		}
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Value < buckets[j].Value })
+	if len(buckets) > limit {
+		buckets = buckets[:limit]
+	}
+	return buckets, nil
+}
+
+// SuggestBooks returns up to limit books whose title or author is spelled
+// like the query, best match first
+func (m *Memory) SuggestBooks(ctx context.Context, query string, limit int) ([]models.BookSuggestion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	suggestions := make([]models.BookSuggestion, 0)
+	for _, book := range m.books {
+		if score, ok := similarity(query, book); ok {
+			suggestions = append(suggestions, models.BookSuggestion{BookID: book.ID, Title: book.Title, Author: book.Author, Score: score})
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+		return a.BookID < b.BookID
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// GetBook returns a single book by ID
+func (m *Memory) GetBook(ctx context.Context, id int) (models.Book, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, ok := m.books[id]
+	if !ok {
+		return models.Book{}, ErrNotFound
+	}
+	return book, nil
+}
+
+// GetBookByISBN returns the book with the given canonical ISBN
+func (m *Memory) GetBookByISBN(ctx context.Context, isbn string) (models.Book, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if book, ok := m.bookByISBN(isbn); ok {
+		return book, nil
+	}
+	return models.Book{}, ErrNotFound
+}
+
+// bookByISBN finds the book with an ISBN; callers must hold the lock
+func (m *Memory) bookByISBN(isbn string) (models.Book, bool) {
+	for _, book := range m.books {
+		if book.ISBN == isbn {
+			return book, true
+		}
+	}
+	return models.Book{}, false
+}
+
+// CreateBook stores the book with book.Quantity available copies and fills
+// in its generated fields
+func (m *Memory) CreateBook(ctx context.Context, book *models.Book) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.isbnTaken(book.ISBN, 0) {
+		return ErrDuplicateISBN
+	}
+	m.createBook(book)
+	return nil
+}
+
+// createBook stores a new book with Quantity copies; callers must hold the lock
+func (m *Memory) createBook(book *models.Book) {
+	book.ID = m.newID()
+	book.CreatedAt = now()
+	book.UpdatedAt = book.CreatedAt
+	m.books[book.ID] = *book
+	for i := 0; i < book.Quantity; i++ {
+		m.newItem(models.BookItem{BookID: book.ID})
+	}
+}
+
+// UpdateBook replaces the editable fields of a book and returns the stored
+// book. The quantity follows the book's copies and is not editable.
+func (m *Memory) UpdateBook(ctx context.Context, id int, book models.Book) (models.Book, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.books[id]
+	if !ok {
+		return models.Book{}, ErrNotFound
+	}
+	if m.isbnTaken(book.ISBN, id) {
+		return models.Book{}, ErrDuplicateISBN
+	}
+	existing.Title = book.Title
+	existing.Author = book.Author
+	existing.ISBN = book.ISBN
+	existing.Category = book.Category
+	existing.Publisher = book.Publisher
+	existing.Year = book.Year
+	existing.UpdatedAt = now()
+	m.books[id] = existing
+	return existing, nil
+}
+
+// DeleteBook removes a book together with its copies, lending records and holds
+func (m *Memory) DeleteBook(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, ok := m.books[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.books, id)
+	m.deleted[id] = models.BookChange{BookID: id, Datestamp: now(), Deleted: true, Category: book.Category}
+	for itemID, item := range m.items {
+		if item.BookID == id {
+			delete(m.items, itemID)
+		}
+	}
+	for fileID, file := range m.files {
+		if file.BookID == id {
+			delete(m.files, fileID)
+		}
+	}
+	for recordID, record := range m.records {
+		if record.BookID == id {
+			delete(m.records, recordID)
+		}
+	}
+	for holdID, hold := range m.holds {
+		if hold.BookID == id {
+			delete(m.holds, holdID)
+		}
+	}
+	return nil
+}
+
+// ListBookChanges returns up to limit changes to the books matching the
+// filter, oldest first
+func (m *Memory) ListBookChanges(ctx context.Context, filter ChangeFilter, limit int) ([]models.BookChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changes := make([]models.BookChange, 0)
+	for _, change := range m.bookChanges() {
+		if filter.From != nil && change.Datestamp.Before(*filter.From) ||
+			filter.Before != nil && !change.Datestamp.Before(*filter.Before) ||
+			filter.Category != "" && change.Category != filter.Category {
+			continue
+		}
+		if filter.AfterID != 0 && (change.Datestamp.Before(filter.AfterDatestamp) ||
+			change.Datestamp.Equal(filter.AfterDatestamp) && change.BookID <= filter.AfterID) {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].Datestamp.Equal(changes[j].Datestamp) {
+			return changes[i].Datestamp.Before(changes[j].Datestamp)
+		}
+		return changes[i].BookID < changes[j].BookID
+	})
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+	return changes, nil
+}
+
+// GetBookChange returns the book with the given ID, or its deletion
+func (m *Memory) GetBookChange(ctx context.Context, id int) (models.BookChange, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if book, ok := m.books[id]; ok {
+		return models.BookChange{BookID: id, Datestamp: book.UpdatedAt, Category: book.Category, Book: &book}, nil
+	}
+	if change, ok := m.deleted[id]; ok {
+		return change, nil
+	}
+	return models.BookChange{}, ErrNotFound
+}
+
+// EarliestBookChange returns the datestamp of the oldest change to a book
+func (m *Memory) EarliestBookChange(ctx context.Context) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	earliest := now()
+	for _, change := range m.bookChanges() {
+		if change.Datestamp.Before(earliest) {
+			earliest = change.Datestamp
+		}
+	}
+	return earliest, nil
+}
+
+// bookChanges returns the latest change to every book, unordered; callers
+// must hold the lock
+func (m *Memory) bookChanges() []models.BookChange {
+	changes := make([]models.BookChange, 0, len(m.books)+len(m.deleted))
+	for _, book := range m.books {
+		changes = append(changes, models.BookChange{BookID: book.ID, Datestamp: book.UpdatedAt, Category: book.Category, Book: &book})
+	}
+	for _, change := range m.deleted {
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// isbnTaken reports whether another book already uses the ISBN; callers must hold the lock
+func (m *Memory) isbnTaken(isbn string, exceptID int) bool {
+	for _, book := range m.books {
+		if book.ID != exceptID && book.ISBN == isbn {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportBooks upserts a batch of books by ISBN. A dry run tracks what the
+// batch would change without touching the stored books.
+func (m *Memory) ImportBooks(ctx context.Context, books []models.Book, dryRun *ImportDryRun) ([]models.ImportRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	results := make([]models.ImportRow, len(books))
+	for i, book := range books {
+		results[i].ISBN = book.ISBN
+
+		existing, ok := dryRun.book(book.ISBN)
+		if !ok {
+			existing, ok = m.bookByISBN(book.ISBN)
+		}
+		switch {
+		case !ok:
+			results[i].Status = models.ImportCreated
+			if dryRun != nil {
+				dryRun.save(book)
+				continue
+			}
+			m.createBook(&book)
+			results[i].BookID = book.ID
+		case !importChanges(existing, &book):
+			results[i].Status = models.ImportSkipped
+			results[i].Reason = "Book is unchanged"
+			results[i].BookID = existing.ID
+		default:
+			results[i].Status = models.ImportUpdated
+			results[i].BookID = existing.ID
+			existing.Title = book.Title
+			existing.Author = book.Author
+			existing.Category = book.Category
+			existing.Publisher = book.Publisher
+			existing.Year = book.Year
+			if dryRun != nil {
+				dryRun.save(existing)
+				continue
+			}
+			existing.UpdatedAt = now()
+			m.books[existing.ID] = existing
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"sort"
+
+	"digital-library/backend/models"
+)
+
+// ListBookFiles returns the files of a book, oldest first
+func (m *Memory) ListBookFiles(ctx context.Context, bookID int) ([]models.BookFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.books[bookID]; !ok {
+		return nil, ErrNotFound
+	}
+	return m.listFiles([]int{bookID}), nil
+}
+
+// ListFilesOfBooks returns the files of several books, oldest first
+func (m *Memory) ListFilesOfBooks(ctx context.Context, bookIDs []int) ([]models.BookFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.listFiles(bookIDs), nil
+}
+
+// listFiles returns the files of the books in ID order; callers must hold
+// the lock
+func (m *Memory) listFiles(bookIDs []int) []models.BookFile {
+	files := make([]models.BookFile, 0)
+	for _, file := range m.files {
+		if slices.Contains(bookIDs, file.BookID) {
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ID < files[j].ID })
+	return files
+}
+
+// GetBookFile returns a file of a book
+func (m *Memory) GetBookFile(ctx context.Context, bookID, fileID int) (models.BookFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.bookFile(bookID, fileID)
+}
+
+// bookFile looks a file of a book up; callers must hold the lock
+func (m *Memory) bookFile(bookID, fileID int) (models.BookFile, error) {
+	if _, ok := m.books[bookID]; !ok {
+		return models.BookFile{}, ErrNotFound
+	}
+	file, ok := m.files[fileID]
+	if !ok || file.BookID != bookID {
+		return models.BookFile{}, ErrFileNotFound
+	}
+	return file, nil
+}
+
+// CreateBookFile records a file of a book
+func (m *Memory) CreateBookFile(ctx context.Context, file *models.BookFile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.books[file.BookID]; !ok {
+		return ErrNotFound
+	}
+	file.ID = m.newID()
+	file.CreatedAt = now()
+	m.files[file.ID] = *file
+	return nil
+}
+
+// DeleteBookFile removes the record of a file of a book
+func (m *Memory) DeleteBookFile(ctx context.Context, bookID, fileID int) (models.BookFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := m.bookFile(bookID, fileID)
+	if err != nil {
+		return file, err
+	}
+	delete(m.files, fileID)
+	return file, nil
+}
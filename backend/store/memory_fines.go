@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+
+	"digital-library/backend/models"
+)
+
+// ListFineEntries returns the fines ledger in the order it was written,
+// limited to one user unless userID is zero
+func (m *Memory) ListFineEntries(ctx context.Context, userID int) ([]models.FineEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := make([]models.FineEntry, 0)
+	for _, entry := range m.fines {
+		if userID == 0 || isUser(entry.UserID, userID) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// ExportFineEntries calls fn with every entry of the ledger in the order it
+// was written. The entries are copied first, so fn runs without the lock.
+func (m *Memory) ExportFineEntries(ctx context.Context, fn func(models.FineEntry) error) error {
+	entries, err := m.ListFineEntries(ctx, 0)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FineBalance sums a user's ledger
+func (m *Memory) FineBalance(ctx context.Context, userID int) (models.FineBalance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	balance := models.FineBalance{UserID: userID}
+	for _, entry := range m.fines {
+		if isUser(entry.UserID, userID) {
+			addToBalance(&balance, entry)
+		}
+	}
+	return balance, nil
+}
+
+// AddFineEntry appends an entry to the ledger and fills in its generated fields
+func (m *Memory) AddFineEntry(ctx context.Context, entry *models.FineEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addFineEntry(entry)
+	return nil
+}
+
+// addFineEntry appends an entry to the ledger; callers must hold the lock
+func (m *Memory) addFineEntry(entry *models.FineEntry) {
+	entry.ID = m.newID()
+	entry.CreatedAt = now()
+	m.fines = append(m.fines, *entry)
+}
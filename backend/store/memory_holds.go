@@ -0,0 +1,213 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"digital-library/backend/models"
+)
+
+// holdQueue returns the open holds of a book, oldest first; callers must hold the lock
+func (m *Memory) holdQueue(bookID int) []models.Hold {
+	queue := make([]models.Hold, 0)
+	for _, hold := range m.holds {
+		if hold.BookID == bookID && hold.IsOpen() {
+			queue = append(queue, hold)
+		}
+	}
+	sort.Slice(queue, func(i, j int) bool { return holdBefore(queue[i], queue[j]) })
+	return queue
+}
+
+// holdBefore orders holds by creation, oldest first
+func holdBefore(a, b models.Hold) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// unreservedCopies returns the available copies of a book not set aside for
+// ready holds; callers must hold the lock
+func (m *Memory) unreservedCopies(bookID int) int {
+	free := m.books[bookID].Quantity
+	for _, hold := range m.holdQueue(bookID) {
+		if hold.Status == models.HoldStatusReady {
+			free--
+		}
+	}
+	return free
+}
+
+// promoteHolds sets unreserved copies of a book aside for the oldest waiting
+// holds; callers must hold the lock
+func (m *Memory) promoteHolds(bookID int) {
+	book, ok := m.books[bookID]
+	if !ok {
+		return
+	}
+	free := m.unreservedCopies(bookID)
+	for _, hold := range m.holdQueue(bookID) {
+		if free <= 0 {
+			return
+		}
+		if hold.Status != models.HoldStatusWaiting {
+			continue
+		}
+		policy := mostSpecificPolicy(m.policyList(), book.Category, m.userRole(hold.UserID))
+		readyAt, expiresAt := now(), holdExpiry(today(), policy)
+		hold.Status = models.HoldStatusReady
+		hold.ReadyAt = &readyAt
+		hold.ExpiresAt = &expiresAt
+		hold.UpdatedAt = readyAt
+		m.holds[hold.ID] = hold
+		free--
+	}
+}
+
+// openHold returns the user's open hold on a book; callers must hold the lock
+func (m *Memory) openHold(bookID, userID int) (models.Hold, bool) {
+	for _, hold := range m.holds {
+		if hold.BookID == bookID && hold.IsOpen() && isUser(hold.UserID, userID) {
+			return hold, true
+		}
+	}
+	return models.Hold{}, false
+}
+
+// GetHold returns a single hold by ID
+func (m *Memory) GetHold(ctx context.Context, id int) (models.Hold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hold, ok := m.holds[id]
+	if !ok {
+		return models.Hold{}, ErrNotFound
+	}
+	return hold, nil
+}
+
+// PlaceHold queues the borrower for a book whose copies are all lent or reserved
+func (m *Memory) PlaceHold(ctx context.Context, bookID int, borrower models.User) (models.Hold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.books[bookID]; !ok {
+		return models.Hold{}, ErrNotFound
+	}
+	if m.unreservedCopies(bookID) > 0 {
+		return models.Hold{}, ErrBookAvailable
+	}
+	if _, ok := m.openHold(bookID, borrower.ID); ok {
+		return models.Hold{}, ErrDuplicateHold
+	}
+
+	hold := models.Hold{
+		ID:        m.newID(),
+		BookID:    bookID,
+		UserID:    &borrower.ID,
+		Borrower:  borrower.Username,
+		Status:    models.HoldStatusWaiting,
+		CreatedAt: now(),
+	}
+	hold.UpdatedAt = hold.CreatedAt
+	m.holds[hold.ID] = hold
+	return hold, nil
+}
+
+// ListHolds returns holds joined with their book, oldest first
+func (m *Memory) ListHolds(ctx context.Context, filter HoldFilter) ([]models.HoldDetail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	holds := make([]models.HoldDetail, 0)
+	for _, hold := range m.holds {
+		if filter.BookID != 0 && hold.BookID != filter.BookID {
+			continue
+		}
+		if filter.UserID != 0 && !isUser(hold.UserID, filter.UserID) {
+			continue
+		}
+		if filter.Status == "open" && !hold.IsOpen() ||
+			filter.Status != "" && filter.Status != "open" && hold.Status != filter.Status {
+			continue
+		}
+		detail := models.HoldDetail{Hold: hold, BookTitle: m.books[hold.BookID].Title}
+		if hold.Status == models.HoldStatusWaiting {
+			position := 0
+			for _, queued := range m.holdQueue(hold.BookID) {
+				if queued.Status == models.HoldStatusWaiting {
+					position++
+				}
+				if queued.ID == hold.ID {
+					break
+				}
+			}
+			detail.Position = &position
+		}
+		holds = append(holds, detail)
+	}
+	sort.Slice(holds, func(i, j int) bool { return holdBefore(holds[i].Hold, holds[j].Hold) })
+	return holds, nil
+}
+
+// ExportHolds calls fn with every hold joined with its book, oldest first.
+// The holds are copied first, so fn runs without the lock.
+func (m *Memory) ExportHolds(ctx context.Context, fn func(models.HoldDetail) error) error {
+	holds, err := m.ListHolds(ctx, HoldFilter{})
+	if err != nil {
+		return err
+	}
+	for _, hold := range holds {
+		if err := fn(hold); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CancelHold closes an open hold and passes a reserved copy on to the queue
+func (m *Memory) CancelHold(ctx context.Context, id int) (models.Hold, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hold, ok := m.holds[id]
+	if !ok {
+		return models.Hold{}, ErrNotFound
+	}
+	if !hold.IsOpen() {
+		return models.Hold{}, ErrHoldClosed
+	}
+	wasReady := hold.Status == models.HoldStatusReady
+	hold.Status = models.HoldStatusCancelled
+	hold.UpdatedAt = now()
+	m.holds[id] = hold
+	if wasReady {
+		m.promoteHolds(hold.BookID)
+	}
+	return hold, nil
+}
+
+// ExpireHolds expires ready holds not picked up by their last pickup day and
+// passes the copies on to the next borrowers in each queue
+func (m *Memory) ExpireHolds(ctx context.Context, asOf time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expired := 0
+	books := make(map[int]bool)
+	for id, hold := range m.holds {
+		if hold.Status == models.HoldStatusReady && hold.ExpiresAt != nil && hold.ExpiresAt.Before(asOf) {
+			hold.Status = models.HoldStatusExpired
+			hold.UpdatedAt = now()
+			m.holds[id] = hold
+			books[hold.BookID] = true
+			expired++
+		}
+	}
+	for bookID := range books {
+		m.promoteHolds(bookID)
+	}
+	return expired, nil
+}
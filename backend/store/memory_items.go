@@ -0,0 +1,202 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"digital-library/backend/models"
+)
+
+// newItem stores an available copy of a book, generating a barcode when it
+// has none; callers must hold the lock and sync the book quantity
+func (m *Memory) newItem(item models.BookItem) models.BookItem {
+	item.ID = m.newID()
+	if item.Barcode == "" {
+		m.barcodeSeq++
+		item.Barcode = fmt.Sprintf("C%08d", m.barcodeSeq)
+	}
+	if item.Condition == "" {
+		item.Condition = models.ConditionGood
+	}
+	if item.AcquiredDate.IsZero() {
+		item.AcquiredDate = today()
+	}
+	item.Status = models.ItemStatusAvailable
+	item.CreatedAt = now()
+	item.UpdatedAt = item.CreatedAt
+	m.items[item.ID] = item
+	return item
+}
+
+// syncQuantity sets the quantity of a book to its number of available copies,
+// like the sync_book_items_quantity trigger; callers must hold the lock
+func (m *Memory) syncQuantity(bookID int) {
+	book, ok := m.books[bookID]
+	if !ok {
+		return
+	}
+	available := 0
+	for _, item := range m.items {
+		if item.BookID == bookID && item.Status == models.ItemStatusAvailable {
+			available++
+		}
+	}
+	if book.Quantity != available {
+		book.Quantity = available
+		book.UpdatedAt = now()
+		m.books[bookID] = book
+	}
+}
+
+// setItemStatus changes the status of a copy and syncs the book quantity;
+// callers must hold the lock
+func (m *Memory) setItemStatus(item models.BookItem, status string) {
+	item.Status = status
+	item.UpdatedAt = now()
+	m.items[item.ID] = item
+	m.syncQuantity(item.BookID)
+}
+
+// bookItems returns the copies of a book, oldest first; callers must hold the lock
+func (m *Memory) bookItems(bookID int) []models.BookItem {
+	items := make([]models.BookItem, 0)
+	for _, item := range m.items {
+		if item.BookID == bookID {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].AcquiredDate.Equal(items[j].AcquiredDate) {
+			return items[i].AcquiredDate.Before(items[j].AcquiredDate)
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items
+}
+
+// availableItem returns the available copy of a book with the given barcode,
+// or the longest-held available copy when barcode is empty; callers must
+// hold the lock
+func (m *Memory) availableItem(bookID int, barcode string) (models.BookItem, error) {
+	if barcode == "" {
+		for _, item := range m.bookItems(bookID) {
+			if item.Status == models.ItemStatusAvailable {
+				return item, nil
+			}
+		}
+		return models.BookItem{}, ErrOutOfStock
+	}
+	for _, item := range m.items {
+		if item.Barcode != barcode {
+			continue
+		}
+		if item.BookID != bookID {
+			break
+		}
+		if item.Status != models.ItemStatusAvailable {
+			return item, ErrItemUnavailable
+		}
+		return item, nil
+	}
+	return models.BookItem{}, ErrItemNotFound
+}
+
+// releaseItem puts the copy of a closed loan back on the shelf, or marks it
+// lost; callers must hold the lock
+func (m *Memory) releaseItem(record models.LendingRecord, condition string) {
+	if record.ItemID == nil {
+		return
+	}
+	item, ok := m.items[*record.ItemID]
+	if !ok {
+		return
+	}
+	status := models.ItemStatusAvailable
+	if condition == models.ConditionLost {
+		status = models.ItemStatusLost
+	}
+	if condition == models.ConditionDamaged {
+		item.Condition = models.ConditionDamaged
+	}
+	m.setItemStatus(item, status)
+}
+
+// ListItems returns every copy of a book, oldest first
+func (m *Memory) ListItems(ctx context.Context, bookID int) ([]models.BookItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.books[bookID]; !ok {
+		return nil, ErrNotFound
+	}
+	return m.bookItems(bookID), nil
+}
+
+// ExportItems calls fn with every copy in ID order
+func (m *Memory) ExportItems(ctx context.Context, fn func(models.BookItem) error) error {
+	m.mu.Lock()
+	items := make([]models.BookItem, 0, len(m.items))
+	for _, item := range m.items {
+		items = append(items, item)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddItem adds an available copy to a book and passes it on to the hold queue
+func (m *Memory) AddItem(ctx context.Context, item *models.BookItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.books[item.BookID]; !ok {
+		return ErrNotFound
+	}
+	if item.Barcode != "" {
+		for _, existing := range m.items {
+			if existing.Barcode == item.Barcode {
+				return ErrDuplicateBarcode
+			}
+		}
+	}
+	*item = m.newItem(*item)
+	m.syncQuantity(item.BookID)
+	m.promoteHolds(item.BookID)
+	return nil
+}
+
+// RetireItem withdraws an available or lost copy from the collection
+func (m *Memory) RetireItem(ctx context.Context, bookID, itemID int) (models.BookItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.books[bookID]; !ok {
+		return models.BookItem{}, ErrNotFound
+	}
+	item, ok := m.items[itemID]
+	if !ok || item.BookID != bookID {
+		return models.BookItem{}, ErrItemNotFound
+	}
+	switch item.Status {
+	case models.ItemStatusOnLoan:
+		return item, ErrItemOnLoan
+	case models.ItemStatusRetired:
+		return item, ErrItemRetired
+	case models.ItemStatusAvailable:
+		if m.unreservedCopies(bookID) <= 0 {
+			return item, ErrItemReserved
+		}
+	}
+
+	retiredAt := now()
+	item.RetiredAt = &retiredAt
+	m.setItemStatus(item, models.ItemStatusRetired)
+	return m.items[itemID], nil
+}
@@ -0,0 +1,284 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"digital-library/backend/models"
+)
+
+// GetLendingRecord returns a single lending record by ID
+func (m *Memory) GetLendingRecord(ctx context.Context, id int) (models.LendingRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[id]
+	if !ok {
+		return models.LendingRecord{}, ErrNotFound
+	}
+	return record, nil
+}
+
+// LendBook puts a copy of the book on loan and creates a lending record due
+// according to the matching loan policy. Copies reserved for ready holds are
+// only lent to their holders, and the borrower's own hold is marked fulfilled.
+func (m *Memory) LendBook(ctx context.Context, bookID int, barcode string, borrower models.User, borrowDate time.Time) (models.LendingRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, ok := m.books[bookID]
+	if !ok {
+		return models.LendingRecord{}, ErrNotFound
+	}
+	if book.Quantity <= 0 {
+		return models.LendingRecord{}, ErrOutOfStock
+	}
+	hold, held := m.openHold(bookID, borrower.ID)
+	if hold.Status != models.HoldStatusReady && m.unreservedCopies(bookID) <= 0 {
+		return models.LendingRecord{}, ErrOnHold
+	}
+	item, err := m.availableItem(bookID, barcode)
+	if err != nil {
+		return models.LendingRecord{}, err
+	}
+	m.setItemStatus(item, models.ItemStatusOnLoan)
+
+	policy := mostSpecificPolicy(m.policyList(), book.Category, borrower.Role)
+
+	record := models.LendingRecord{
+		ID:         m.newID(),
+		BookID:     bookID,
+		ItemID:     &item.ID,
+		UserID:     &borrower.ID,
+		Borrower:   borrower.Username,
+		BorrowDate: borrowDate,
+		DueDate:    dueDate(borrowDate, policy),
+		CreatedAt:  now(),
+	}
+	if policy.ID != 0 {
+		record.LoanPolicyID = &policy.ID
+	}
+	record.UpdatedAt = record.CreatedAt
+	m.records[record.ID] = record
+
+	if held {
+		hold.Status = models.HoldStatusFulfilled
+		hold.UpdatedAt = now()
+		m.holds[hold.ID] = hold
+	}
+	return record, nil
+}
+
+// ReturnBook closes a loan, gives the copy back to the book unless it was
+// lost and charges any fines due under the loan's policy
+func (m *Memory) ReturnBook(ctx context.Context, id int, returnDate time.Time, condition string) ([]models.FineEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if record.ReturnDate != nil {
+		return nil, ErrAlreadyReturned
+	}
+	record.ReturnDate = &returnDate
+	record.ReturnCondition = &condition
+	record.UpdatedAt = now()
+	m.records[id] = record
+	m.releaseItem(record, condition)
+	if condition != models.ConditionLost {
+		m.promoteHolds(record.BookID)
+	}
+
+	charges := fineCharges(record, m.loanPolicy(record.LoanPolicyID), returnDate, condition)
+	for i := range charges {
+		m.addFineEntry(&charges[i])
+	}
+	return charges, nil
+}
+
+// RenewLoan extends the due date of an active loan under its loan policy
+func (m *Memory) RenewLoan(ctx context.Context, id int, asOf time.Time) (models.LendingRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[id]
+	if !ok {
+		return models.LendingRecord{}, ErrNotFound
+	}
+	if record.ReturnDate != nil {
+		return models.LendingRecord{}, ErrAlreadyReturned
+	}
+	if record.DueDate.Before(asOf) {
+		return models.LendingRecord{}, ErrLoanOverdue
+	}
+	policy := m.loanPolicy(record.LoanPolicyID)
+	if record.RenewalCount >= policy.MaxRenewals {
+		return models.LendingRecord{}, ErrRenewalLimit
+	}
+	for _, hold := range m.holdQueue(record.BookID) {
+		if record.UserID == nil || !isUser(hold.UserID, *record.UserID) {
+			return models.LendingRecord{}, ErrPendingHolds
+		}
+	}
+
+	record.DueDate = renewedDueDate(record, asOf, policy)
+	record.RenewalCount++
+	record.UpdatedAt = now()
+	m.records[id] = record
+	return record, nil
+}
+
+// ListLendingRecords returns a page of lending records joined with their book,
+// newest loan first unless another sort is requested
+func (m *Memory) ListLendingRecords(ctx context.Context, filter LendingFilter, page PageRequest) (models.LendingRecordPage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := models.LendingRecordPage{Data: make([]models.LendingRecordDetail, 0)}
+	q, err := lendingListing.resolve(page)
+	if err != nil {
+		return result, err
+	}
+
+	today := today()
+	records := make([]models.LendingRecordDetail, 0)
+	for _, record := range m.records {
+		status := record.LendingStatus(today)
+		book := m.books[record.BookID]
+		if filter.Search != "" && !containsFold(record.Borrower, filter.Search) && !containsFold(book.Title, filter.Search) {
+			continue
+		}
+		if filter.UserID != 0 && !isUser(record.UserID, filter.UserID) {
+			continue
+		}
+		if filter.Status == models.LendingStatusActive && record.ReturnDate != nil {
+			continue
+		}
+		if (filter.Status == models.LendingStatusOverdue || filter.Status == models.LendingStatusReturned ||
+			filter.Status == models.LendingStatusLost) && status != filter.Status {
+			continue
+		}
+		if filter.DueBefore != nil && record.DueDate.After(*filter.DueBefore) {
+			continue
+		}
+		if filter.DueAfter != nil && record.DueDate.Before(*filter.DueAfter) {
+			continue
+		}
+		if filter.BookTitle != "" && !strings.EqualFold(book.Title, filter.BookTitle) {
+			continue
+		}
+		records = append(records, models.LendingRecordDetail{
+			LendingRecord: record,
+			BookTitle:     book.Title,
+			BookAuthor:    book.Author,
+			ItemBarcode:   m.itemBarcode(record.ItemID),
+			Status:        status,
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return q.before(lendingSortValue(records[i], q.sort), records[i].ID, lendingSortValue(records[j], q.sort), records[j].ID)
+	})
+
+	result.Total = len(records)
+	skipped := 0
+	for _, record := range records {
+		if !q.continues(lendingSortValue(record, q.sort), record.ID) || q.skip(&skipped) {
+			continue
+		}
+		if q.limit > 0 && len(result.Data) == q.limit {
+			last := result.Data[q.limit-1]
+			result.NextCursor = q.nextCursor(lendingSortValue(last, q.sort), last.ID)
+			break
+		}
+		result.Data = append(result.Data, record)
+	}
+	return result, nil
+}
+
+// ExportLendingRecords calls fn with every lending record ListLendingRecords
+// would list for the filter. The records are copied first, so fn runs without
+// the lock.
+func (m *Memory) ExportLendingRecords(ctx context.Context, filter LendingFilter, page PageRequest, fn func(models.LendingRecordDetail) error) error {
+	page.Limit, page.Cursor, page.Offset = 0, "", 0
+	result, err := m.ListLendingRecords(ctx, filter, page)
+	if err != nil {
+		return err
+	}
+	for _, record := range result.Data {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteLendingRecord removes a lending record, putting the copy back on the
+// shelf if the book had not been returned yet
+func (m *Memory) DeleteLendingRecord(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.records[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.records, id)
+	if record.ReturnDate == nil {
+		m.releaseItem(record, models.ConditionGood)
+		m.promoteHolds(record.BookID)
+	}
+	// Fines outlive the loan they were charged for
+	for i, entry := range m.fines {
+		if entry.LendingRecordID != nil && *entry.LendingRecordID == id {
+			m.fines[i].LendingRecordID = nil
+		}
+	}
+	return nil
+}
+
+// itemBarcode mirrors the LEFT JOIN of a loan's copy; callers must hold the lock
+func (m *Memory) itemBarcode(itemID *int) *string {
+	if itemID == nil {
+		return nil
+	}
+	item, ok := m.items[*itemID]
+	if !ok {
+		return nil
+	}
+	return &item.Barcode
+}
+
+// HasActiveLoan reports whether a user has an unreturned loan of a book
+func (m *Memory) HasActiveLoan(ctx context.Context, userID, bookID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, record := range m.records {
+		if record.BookID == bookID && isUser(record.UserID, userID) && record.ReturnDate == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MarkOverdueLoans flags unreturned loans due before asOf that have not been flagged yet
+func (m *Memory) MarkOverdueLoans(ctx context.Context, asOf time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	marked := 0
+	for id, record := range m.records {
+		if record.ReturnDate == nil && record.OverdueAt == nil && record.DueDate.Before(asOf) {
+			flaggedAt := now()
+			record.OverdueAt = &flaggedAt
+			record.UpdatedAt = flaggedAt
+			m.records[id] = record
+			marked++
+		}
+	}
+	return marked, nil
+}
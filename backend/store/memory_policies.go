@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"digital-library/backend/models"
+)
+
+// policyList returns the stored policies, catch-all policies first; callers must hold the lock
+func (m *Memory) policyList() []models.LoanPolicy {
+	policies := make([]models.LoanPolicy, 0, len(m.policies))
+	for _, p := range m.policies {
+		policies = append(policies, p)
+	}
+	key := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return "\x00" + *s
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		a, b := policies[i], policies[j]
+		if key(a.Category) != key(b.Category) {
+			return key(a.Category) < key(b.Category)
+		}
+		if key(a.Role) != key(b.Role) {
+			return key(a.Role) < key(b.Role)
+		}
+		return a.ID < b.ID
+	})
+	return policies
+}
+
+// policyScopeTaken reports whether another policy covers the same category and role; callers must hold the lock
+func (m *Memory) policyScopeTaken(policy models.LoanPolicy, exceptID int) bool {
+	same := func(a, b *string, fold bool) bool {
+		if a == nil || b == nil {
+			return a == nil && b == nil
+		}
+		if fold {
+			return strings.EqualFold(*a, *b)
+		}
+		return *a == *b
+	}
+	for _, p := range m.policies {
+		if p.ID != exceptID && same(p.Category, policy.Category, true) && same(p.Role, policy.Role, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// loanPolicy returns the policy a loan was made under, falling back to
+// models.DefaultLoanPolicy when the loan has none or it was deleted; callers
+// must hold the lock
+func (m *Memory) loanPolicy(id *int) models.LoanPolicy {
+	if id != nil {
+		if policy, ok := m.policies[*id]; ok {
+			return policy
+		}
+	}
+	return models.DefaultLoanPolicy
+}
+
+// ListLoanPolicies returns every loan policy, catch-all policies first
+func (m *Memory) ListLoanPolicies(ctx context.Context) ([]models.LoanPolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.policyList(), nil
+}
+
+// ExportLoanPolicies calls fn with every loan policy, catch-all policies
+// first. The policies are copied first, so fn runs without the lock.
+func (m *Memory) ExportLoanPolicies(ctx context.Context, fn func(models.LoanPolicy) error) error {
+	policies, err := m.ListLoanPolicies(ctx)
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		if err := fn(policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateLoanPolicy stores the policy and fills in its generated fields
+func (m *Memory) CreateLoanPolicy(ctx context.Context, policy *models.LoanPolicy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.policyScopeTaken(*policy, 0) {
+		return ErrDuplicatePolicy
+	}
+	policy.ID = m.newID()
+	policy.CreatedAt = now()
+	policy.UpdatedAt = policy.CreatedAt
+	m.policies[policy.ID] = *policy
+	return nil
+}
+
+// UpdateLoanPolicy replaces a policy and returns the stored policy
+func (m *Memory) UpdateLoanPolicy(ctx context.Context, id int, policy models.LoanPolicy) (models.LoanPolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.policies[id]
+	if !ok {
+		return models.LoanPolicy{}, ErrNotFound
+	}
+	if m.policyScopeTaken(policy, id) {
+		return models.LoanPolicy{}, ErrDuplicatePolicy
+	}
+	existing.Category = policy.Category
+	existing.Role = policy.Role
+	existing.LoanDays = policy.LoanDays
+	existing.MaxRenewals = policy.MaxRenewals
+	existing.GraceDays = policy.GraceDays
+	existing.DailyFineCents = policy.DailyFineCents
+	existing.LostFeeCents = policy.LostFeeCents
+	existing.DamagedFeeCents = policy.DamagedFeeCents
+	existing.HoldPickupDays = policy.HoldPickupDays
+	existing.UpdatedAt = now()
+	m.policies[id] = existing
+	return existing, nil
+}
+
+// DeleteLoanPolicy removes a policy; loans made under it keep their due dates
+func (m *Memory) DeleteLoanPolicy(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.policies[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.policies, id)
+	for recordID, record := range m.records {
+		if record.LoanPolicyID != nil && *record.LoanPolicyID == id {
+			record.LoanPolicyID = nil
+			m.records[recordID] = record
+		}
+	}
+	return nil
+}
+
+// ResolveLoanPolicy returns the most specific policy for a category and role
+func (m *Memory) ResolveLoanPolicy(ctx context.Context, category, role string) (models.LoanPolicy, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return mostSpecificPolicy(m.policyList(), category, role), nil
+}
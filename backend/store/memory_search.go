@@ -0,0 +1,232 @@
+package store
+
+import (
+	"strings"
+	"unicode"
+
+	"digital-library/backend/models"
+)
+
+// textQuery is a parsed web search query, mirroring websearch_to_tsquery: a
+// book matches if it matches every term of any one alternative. The memory
+// store matches whole words, ignoring case, without stemming.
+type textQuery [][]textTerm
+
+// textTerm is a word or quoted phrase of a text query
+type textTerm struct {
+	words  []string
+	negate bool
+}
+
+// Weights of matches in each searchable field, as ts_rank weighs the A to D
+// labels of books.search_vector
+var textWeights = []float32{1.0, 0.4, 0.2, 0.1}
+
+// parseTextQuery parses unquoted words, "quoted phrases", -negated terms and
+// the OR operator
+func parseTextQuery(s string) textQuery {
+	query := textQuery{nil}
+	for s != "" {
+		negate := false
+		if s[0] == '-' {
+			negate = true
+			s = s[1:]
+		}
+		var text string
+		if s != "" && s[0] == '"' {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				text, s = s[1:], ""
+			} else {
+				text, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(s, unicode.IsSpace)
+			if end < 0 {
+				end = len(s)
+			}
+			text, s = s[:end], s[end:]
+		}
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+
+		if !negate && strings.EqualFold(text, "or") {
+			if len(query[len(query)-1]) > 0 {
+				query = append(query, nil)
+			}
+			continue
+		}
+		if words := textWords(text); len(words) > 0 {
+			query[len(query)-1] = append(query[len(query)-1], textTerm{words: words, negate: negate})
+		}
+	}
+	if len(query[len(query)-1]) == 0 {
+		query = query[:len(query)-1]
+	}
+	return query
+}
+
+// textWords splits text into lower case words
+func textWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// searchFields returns the searchable fields of a book in weight order
+func searchFields(book models.Book) []string {
+	return []string{book.Title, book.Author, book.Category, book.ISBN}
+}
+
+// rank returns the relevance of a book to the query, or false if it does not
+// match
+func (q textQuery) rank(book models.Book) (float32, bool) {
+	fields := searchFields(book)
+	words := make([][]string, len(fields))
+	for i, field := range fields {
+		words[i] = textWords(field)
+	}
+
+	matched := false
+	var rank float32
+	for _, terms := range q {
+		var score float32
+		ok := true
+		for _, term := range terms {
+			found := false
+			for i := range words {
+				if containsPhrase(words[i], term.words) {
+					found = true
+					if !term.negate {
+						score += textWeights[i]
+					}
+				}
+			}
+			if found == term.negate {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = true
+			if score > rank {
+				rank = score
+			}
+		}
+	}
+	return rank, matched
+}
+
+// highlight returns the searchable fields of a book with the words of every
+// term that is not negated wrapped in <mark></mark>
+func (q textQuery) highlight(book models.Book) *models.BookHighlight {
+	marked := make(map[string]bool)
+	for _, terms := range q {
+		for _, term := range terms {
+			if !term.negate {
+				for _, word := range term.words {
+					marked[word] = true
+				}
+			}
+		}
+	}
+	mark := func(s string) string {
+		var b strings.Builder
+		start := -1
+		for i, r := range s + " " {
+			inWord := i < len(s) && (unicode.IsLetter(r) || unicode.IsNumber(r))
+			switch {
+			case inWord && start < 0:
+				start = i
+			case !inWord && start >= 0:
+				if marked[strings.ToLower(s[start:i])] {
+					b.WriteString("<mark>" + s[start:i] + "</mark>")
+				} else {
+					b.WriteString(s[start:i])
+				}
+				start = -1
+			}
+			if !inWord && i < len(s) {
+				b.WriteRune(r)
+			}
+		}
+		return b.String()
+	}
+	return &models.BookHighlight{Title: mark(book.Title), Author: mark(book.Author), Category: mark(book.Category)}
+}
+
+// containsPhrase reports whether phrase occurs in words
+func containsPhrase(words, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		match := true
+		for j, word := range phrase {
+			if words[i+j] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// trigrams returns the trigrams of every word of s in order, as pg_trgm
+// extracts them: lower case, with each word padded by two spaces in front
+// and one behind
+func trigrams(s string) []string {
+	var result []string
+	for _, word := range textWords(s) {
+		r := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			result = append(result, string(r[i:i+3]))
+		}
+	}
+	return result
+}
+
+// similarityScore is similarityThreshold as a number
+const similarityScore = 0.3
+
+// wordSimilarity mirrors word_similarity(a, b) of pg_trgm: the greatest
+// similarity between the trigrams of a and those of any continuous extent of
+// the trigrams of b
+func wordSimilarity(a, b string) float32 {
+	want := make(map[string]bool)
+	for _, t := range trigrams(a) {
+		want[t] = true
+	}
+	if len(want) == 0 {
+		return 0
+	}
+
+	seq := trigrams(b)
+	var best float32
+	for i := range seq {
+		extent := make(map[string]bool)
+		shared := 0
+		for j := i; j < len(seq); j++ {
+			if extent[seq[j]] {
+				continue
+			}
+			extent[seq[j]] = true
+			if want[seq[j]] {
+				shared++
+			}
+			if sml := float32(shared) / float32(len(want)+len(extent)-shared); sml > best {
+				best = sml
+			}
+		}
+	}
+	return best
+}
+
+// similarity returns how closely the title or author of a book is spelled
+// like the query, or false if neither reaches similarityThreshold
+func similarity(query string, book models.Book) (float32, bool) {
+	score := wordSimilarity(query, book.Title)
+	if author := wordSimilarity(query, book.Author); author > score {
+		score = author
+	}
+	return score, score >= similarityScore
+}
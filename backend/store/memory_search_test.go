@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"digital-library/backend/models"
+)
+
+func TestParseTextQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  textQuery
+	}{
+		{"dune", textQuery{{{words: []string{"dune"}}}}},
+		{"Frank  Herbert", textQuery{{{words: []string{"frank"}}, {words: []string{"herbert"}}}}},
+		{`"jane austen" -emma`, textQuery{{{words: []string{"jane", "austen"}}, {words: []string{"emma"}, negate: true}}}},
+		{"dune or emma", textQuery{{{words: []string{"dune"}}}, {{words: []string{"emma"}}}}},
+		{"or dune OR", textQuery{{{words: []string{"dune"}}}}},
+		{`"unclosed phrase`, textQuery{{{words: []string{"unclosed", "phrase"}}}}},
+		{"  -- ", textQuery{}},
+	}
+	for _, tt := range tests {
+		if got := parseTextQuery(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTextQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestTextQueryRank(t *testing.T) {
+	dune := models.Book{Title: "Dune", Author: "Frank Herbert", Category: "Science Fiction", ISBN: "9780441172719"}
+	tests := []struct {
+		query string
+		match bool
+		rank  float32
+	}{
+		{"dune", true, 1.0},
+		{"DUNE herbert", true, 1.4},
+		{"fiction", true, 0.2},
+		{"9780441172719", true, 0.1},
+		{`"frank herbert"`, true, 0.4},
+		{`"herbert frank"`, false, 0},
+		{"dune -herbert", false, 0},
+		{"dune -emma", true, 1.0},
+		{"emma or herbert", true, 0.4},
+		{"emma", false, 0},
+		{"dun", false, 0},
+	}
+	for _, tt := range tests {
+		rank, ok := parseTextQuery(tt.query).rank(dune)
+		if ok != tt.match || rank != tt.rank {
+			t.Errorf("rank(%q) = %v, %v; want %v, %v", tt.query, rank, ok, tt.rank, tt.match)
+		}
+	}
+}
+
+func TestTextQueryHighlight(t *testing.T) {
+	book := models.Book{Title: "The Left Hand of Darkness", Author: "Ursula K. Le Guin", Category: "sci-fi"}
+	got := parseTextQuery(`"left hand" -guin le fi`).highlight(book)
+	want := &models.BookHighlight{
+		Title:    "The <mark>Left</mark> <mark>Hand</mark> of Darkness",
+		Author:   "Ursula K. <mark>Le</mark> Guin",
+		Category: "sci-<mark>fi</mark>",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("highlight = %+v, want %+v", got, want)
+	}
+}
+
+func TestTrigrams(t *testing.T) {
+	want := []string{"  a", " ab", "ab ", "  c", " cd", "cde", "de "}
+	if got := trigrams("AB, cde"); !reflect.DeepEqual(got, want) {
+		t.Errorf("trigrams = %q, want %q", got, want)
+	}
+}
+
+func TestWordSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float32
+	}{
+		{"word", "two words", 0.8},
+		{"dune", "Dune", 1},
+		{"dune", "Emma", 0},
+		{"", "Dune", 0},
+	}
+	for _, tt := range tests {
+		if got := wordSimilarity(tt.a, tt.b); got != tt.want {
+			t.Errorf("wordSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		query string
+		match bool
+	}{
+		{"herbret", true},
+		{"dnue", false},
+		{"austen", false},
+	} {
+		if _, ok := similarity(tt.query, models.Book{Title: "Dune", Author: "Frank Herbert"}); ok != tt.match {
+			t.Errorf("similarity(%q) matched = %v, want %v", tt.query, ok, tt.match)
+		}
+	}
+}
+
+func TestListBooksSimilarFallback(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	for _, book := range []*models.Book{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719", Category: "scifi"},
+		{Title: "Emma", Author: "Jane Austen", ISBN: "9780141439587", Category: "classic"},
+	} {
+		if err := m.CreateBook(ctx, book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		filter   BookFilter
+		titles   []string
+		fallback bool
+	}{
+		{"full-text match", BookFilter{Search: "herbert"}, []string{"Dune"}, false},
+		{"full-text match outside the filters", BookFilter{Search: "herbert", Category: "classic"}, nil, false},
+		{"misspelled", BookFilter{Search: "herbret"}, []string{"Dune"}, true},
+		{"misspelled outside the filters", BookFilter{Search: "herbret", Category: "classic"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := m.ListBooks(ctx, tt.filter, PageRequest{})
+			if err != nil {
+				t.Fatalf("ListBooks: %v", err)
+			}
+			var titles []string
+			for _, book := range result.Data {
+				titles = append(titles, book.Title)
+			}
+			if !reflect.DeepEqual(titles, tt.titles) || result.Similar != tt.fallback {
+				t.Errorf("books = %v similar %v, want %v similar %v", titles, result.Similar, tt.titles, tt.fallback)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"digital-library/backend/models"
+)
+
+// CreateUser stores a new user with an already hashed password and assigns
+// the next patron card number
+func (m *Memory) CreateUser(ctx context.Context, username, passwordHash, email, role string) (models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Username == username {
+			return models.User{}, ErrDuplicateUsername
+		}
+		if u.Email == email {
+			return models.User{}, ErrDuplicateEmail
+		}
+	}
+
+	user := models.User{
+		ID:         m.newID(),
+		Username:   username,
+		Email:      email,
+		Role:       role,
+		CardNumber: fmt.Sprintf("P%08d", len(m.users)+1),
+		CreatedAt:  now(),
+	}
+	user.UpdatedAt = user.CreatedAt
+	m.users[user.ID] = memoryUser{User: user, passwordHash: passwordHash}
+	return user, nil
+}
+
+// GetUserByLogin looks a user up by username or email
+func (m *Memory) GetUserByLogin(ctx context.Context, login string) (models.User, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.Username == login || u.Email == login {
+			return u.User, u.passwordHash, nil
+		}
+	}
+	return models.User{}, "", ErrNotFound
+}
+
+// GetUser returns a single user by ID
+func (m *Memory) GetUser(ctx context.Context, id int) (models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	u, ok := m.users[id]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+	return u.User, nil
+}
+
+// GetUserByCardNumber returns the user holding a patron card
+func (m *Memory) GetUserByCardNumber(ctx context.Context, cardNumber string) (models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.CardNumber == cardNumber {
+			return u.User, nil
+		}
+	}
+	return models.User{}, ErrNotFound
+}
+
+// userRole returns the role of a user, or the user role for loans and holds
+// without an account; callers must hold the lock
+func (m *Memory) userRole(userID *int) string {
+	if userID != nil {
+		if u, ok := m.users[*userID]; ok {
+			return u.Role
+		}
+	}
+	return models.RoleUser
+}
@@ -0,0 +1,302 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"digital-library/backend/models"
+)
+
+// PageRequest selects a page of a sorted listing. Rows are ordered by the
+// sort field and then by ID, so the cursor of the last row on a page picks up
+// exactly where that page ended even when rows are added in between.
+type PageRequest struct {
+	Limit  int    // Maximum rows to return; zero means every remaining row
+	Cursor string // NextCursor of the previous page; empty for the first page
+	Offset int    // Rows to skip, after the cursor if any, for clients that page by position
+	Sort   string // Field from the listing's whitelist; empty for its default sort
+	Order  string // "asc" or "desc"; empty for ascending, or the default order of the default sort
+}
+
+// Sort fields accepted by ListBooks and ListLendingRecords. Books can only be
+// sorted by relevance when searching.
+var (
+	BookSortFields    = []string{"title", "author", "created_at", "quantity", "relevance"}
+	LendingSortFields = []string{"borrow_date", "created_at", "title", "author"}
+)
+
+// Kinds of sort values, which decide how cursor values are decoded and compared
+const (
+	sortString = iota
+	sortInt
+	sortTime
+	sortDate
+	sortFloat
+)
+
+// sortField is a column a listing can be sorted on
+type sortField struct {
+	column string
+	kind   int
+}
+
+// listing describes the sort fields of a listing and its default order
+type listing struct {
+	idColumn    string
+	fields      map[string]sortField
+	defaultSort string
+	defaultDesc bool
+}
+
+var bookListing = listing{
+	idColumn: "id",
+	fields: map[string]sortField{
+		"title":      {"title", sortString},
+		"author":     {"author", sortString},
+		"created_at": {"created_at", sortTime},
+		"quantity":   {"quantity", sortInt},
+	},
+	defaultSort: "title",
+}
+
+// bookSearchListing is bookListing for a search, which can also be sorted by
+// the rank of each match and is by default
+var bookSearchListing = listing{
+	idColumn: "id",
+	fields: map[string]sortField{
+		"title":      {"title", sortString},
+		"author":     {"author", sortString},
+		"created_at": {"created_at", sortTime},
+		"quantity":   {"quantity", sortInt},
+		"relevance":  {"rank", sortFloat},
+	},
+	defaultSort: "relevance",
+	defaultDesc: true,
+}
+
+var lendingListing = listing{
+	idColumn: "lr.id",
+	fields: map[string]sortField{
+		"borrow_date": {"lr.borrow_date", sortDate},
+		"created_at":  {"lr.created_at", sortTime},
+		"title":       {"b.title", sortString},
+		"author":      {"b.author", sortString},
+	},
+	defaultSort: "borrow_date",
+	defaultDesc: true,
+}
+
+// cursor is the position after the last row of a page. It records the sort it
+// was made for so it cannot be replayed against a different order.
+type cursor struct {
+	Sort  string          `json:"s"`
+	Desc  bool            `json:"d,omitempty"`
+	Value json.RawMessage `json:"v"`
+	ID    int             `json:"id"`
+}
+
+// pageQuery is a validated PageRequest
+type pageQuery struct {
+	sort   string
+	field  sortField
+	desc   bool
+	limit  int
+	offset int
+
+	// Position to continue after; set when the request has a cursor
+	after      bool
+	afterValue interface{}
+	afterID    int
+}
+
+// resolve validates the sort and decodes the cursor of a page request
+func (l listing) resolve(page PageRequest) (pageQuery, error) {
+	q := pageQuery{sort: page.Sort, limit: page.Limit, offset: max(page.Offset, 0)}
+	if q.sort == "" {
+		q.sort = l.defaultSort
+		if page.Order == "" {
+			q.desc = l.defaultDesc
+		}
+	}
+	field, ok := l.fields[q.sort]
+	if !ok {
+		return q, ErrInvalidSort
+	}
+	q.field = field
+	switch page.Order {
+	case "":
+	case "asc":
+		q.desc = false
+	case "desc":
+		q.desc = true
+	default:
+		return q, ErrInvalidSort
+	}
+
+	if page.Cursor == "" {
+		return q, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(page.Cursor)
+	if err != nil {
+		return q, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Sort != q.sort || c.Desc != q.desc {
+		return q, ErrInvalidCursor
+	}
+	value, err := decodeSortValue(c.Value, field.kind)
+	if err != nil {
+		return q, ErrInvalidCursor
+	}
+	q.after, q.afterValue, q.afterID = true, value, c.ID
+	return q, nil
+}
+
+func decodeSortValue(raw json.RawMessage, kind int) (interface{}, error) {
+	switch kind {
+	case sortInt:
+		var v int
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	case sortTime, sortDate:
+		var v time.Time
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	case sortFloat:
+		var v float32
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	default:
+		var v string
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	}
+}
+
+// nextCursor encodes the position after a row with the given sort value and ID
+func (q pageQuery) nextCursor(value interface{}, id int) string {
+	v, _ := json.Marshal(value)
+	raw, _ := json.Marshal(cursor{Sort: q.sort, Desc: q.desc, Value: v, ID: id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// sql returns the keyset condition continuing after the cursor (empty on the
+// first page), the ORDER BY, LIMIT and OFFSET clauses, and the arguments they
+// use, numbered from argCount
+func (q pageQuery) sql(l listing, argCount int) (string, string, []interface{}) {
+	direction, compare := " ASC", " > "
+	if q.desc {
+		direction, compare = " DESC", " < "
+	}
+	var where string
+	var args []interface{}
+	if q.after {
+		value := q.afterValue
+		cast := "::text"
+		switch q.field.kind {
+		case sortInt:
+			cast = "::int"
+		case sortFloat:
+			cast = "::real"
+		case sortTime:
+			cast = "::timestamptz"
+		case sortDate:
+			// Compare calendar days, whatever the session time zone
+			cast = "::date"
+			value = value.(time.Time).Format("2006-01-02")
+		}
+		where = ` AND (` + q.field.column + `, ` + l.idColumn + `)` + compare +
+			`($` + strconv.Itoa(argCount) + cast + `, $` + strconv.Itoa(argCount+1) + `)`
+		args = append(args, value, q.afterID)
+		argCount += 2
+	}
+	order := ` ORDER BY ` + q.field.column + direction + `, ` + l.idColumn + direction
+	if q.limit > 0 {
+		// One extra row tells whether there is a next page
+		order += ` LIMIT $` + strconv.Itoa(argCount)
+		args = append(args, q.limit+1)
+		argCount++
+	}
+	if q.offset > 0 {
+		order += ` OFFSET $` + strconv.Itoa(argCount)
+		args = append(args, q.offset)
+	}
+	return where, order, args
+}
+
+// before reports whether a row sorts before another in the page order
+func (q pageQuery) before(aValue interface{}, aID int, bValue interface{}, bID int) bool {
+	c := compareSortValues(aValue, bValue)
+	if c == 0 {
+		c = aID - bID
+	}
+	if q.desc {
+		return c > 0
+	}
+	return c < 0
+}
+
+// continues reports whether a row comes after the cursor
+func (q pageQuery) continues(value interface{}, id int) bool {
+	return !q.after || q.before(q.afterValue, q.afterID, value, id)
+}
+
+// skip reports whether a row after the cursor is still within the offset,
+// counting the rows skipped so far in skipped
+func (q pageQuery) skip(skipped *int) bool {
+	if *skipped < q.offset {
+		*skipped++
+		return true
+	}
+	return false
+}
+
+func compareSortValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int:
+		return a - b.(int)
+	case float32:
+		if b := b.(float32); a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+// bookSortValue returns the value a book is sorted on
+func bookSortValue(book models.Book, sort string) interface{} {
+	switch sort {
+	case "author":
+		return book.Author
+	case "created_at":
+		return book.CreatedAt
+	case "quantity":
+		return book.Quantity
+	case "relevance":
+		return book.Rank
+	}
+	return book.Title
+}
+
+// lendingSortValue returns the value a lending record is sorted on
+func lendingSortValue(record models.LendingRecordDetail, sort string) interface{} {
+	switch sort {
+	case "created_at":
+		return record.CreatedAt
+	case "title":
+		return record.BookTitle
+	case "author":
+		return record.BookAuthor
+	}
+	return record.BorrowDate
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"digital-library/backend/models"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		l     listing
+		sort  string
+		value interface{}
+	}{
+		{bookListing, "title", "Dune"},
+		{bookListing, "quantity", 3},
+		{bookListing, "created_at", time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)},
+		{bookSearchListing, "relevance", float32(0.25)},
+		{lendingListing, "borrow_date", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		for _, order := range []string{"asc", "desc"} {
+			page := PageRequest{Sort: tt.sort, Order: order}
+			q, err := tt.l.resolve(page)
+			if err != nil {
+				t.Fatalf("resolve(%+v): %v", page, err)
+			}
+			page.Cursor = q.nextCursor(tt.value, 42)
+			next, err := tt.l.resolve(page)
+			if err != nil {
+				t.Errorf("resolve(%+v): %v", page, err)
+				continue
+			}
+			if !next.after || next.afterID != 42 || !reflect.DeepEqual(next.afterValue, tt.value) || next.desc != (order == "desc") {
+				t.Errorf("resolve(%+v) = %+v, want the position after %v, 42", page, next, tt.value)
+			}
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	q, _ := bookListing.resolve(PageRequest{Sort: "title"})
+	titleCursor := q.nextCursor("Dune", 1)
+
+	tests := []struct {
+		page PageRequest
+		err  error
+	}{
+		{PageRequest{Sort: "isbn"}, ErrInvalidSort},
+		{PageRequest{Sort: "relevance"}, ErrInvalidSort},
+		{PageRequest{Order: "up"}, ErrInvalidSort},
+		{PageRequest{Cursor: "not a cursor!"}, ErrInvalidCursor},
+		{PageRequest{Cursor: base64.RawURLEncoding.EncodeToString([]byte("{"))}, ErrInvalidCursor},
+		// A cursor only continues the sort and order it was made for
+		{PageRequest{Sort: "author", Cursor: titleCursor}, ErrInvalidCursor},
+		{PageRequest{Sort: "title", Order: "desc", Cursor: titleCursor}, ErrInvalidCursor},
+		{PageRequest{Sort: "quantity", Cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"s":"quantity","v":"three","id":1}`))}, ErrInvalidCursor},
+	}
+	for _, tt := range tests {
+		if _, err := bookListing.resolve(tt.page); err != tt.err {
+			t.Errorf("resolve(%+v) = %v, want %v", tt.page, err, tt.err)
+		}
+	}
+}
+
+func TestMemoryListBooksPages(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	for i, title := range []string{"Emma", "Dune", "Beloved", "Dune", "Anathem"} {
+		book := &models.Book{Title: title, Author: "Author", ISBN: fmt.Sprintf("97800000000%02d", i)}
+		if err := m.CreateBook(ctx, book); err != nil {
+			t.Fatalf("CreateBook: %v", err)
+		}
+	}
+
+	tests := []struct {
+		page PageRequest
+		want []string
+	}{
+		{PageRequest{Limit: 2}, []string{"Anathem", "Beloved", "Dune", "Dune", "Emma"}},
+		{PageRequest{Limit: 2, Order: "desc"}, []string{"Emma", "Dune", "Dune", "Beloved", "Anathem"}},
+		{PageRequest{Limit: 3, Offset: 1}, []string{"Beloved", "Dune", "Dune", "Emma"}},
+	}
+	for _, tt := range tests {
+		var titles []string
+		page := tt.page
+		for i := 0; ; i++ {
+			result, err := m.ListBooks(ctx, BookFilter{}, page)
+			if err != nil {
+				t.Fatalf("ListBooks(%+v): %v", page, err)
+			}
+			if result.Total != 5 || len(result.Data) > page.Limit {
+				t.Errorf("ListBooks(%+v) = %d of %d books", page, len(result.Data), result.Total)
+			}
+			for _, book := range result.Data {
+				titles = append(titles, book.Title)
+			}
+			if result.NextCursor == "" || i == 5 {
+				break
+			}
+			// The offset skips rows after the cursor, so only the first page has one
+			page.Cursor, page.Offset = result.NextCursor, 0
+		}
+		if !reflect.DeepEqual(titles, tt.want) {
+			t.Errorf("pages of %+v = %q, want %q", tt.page, titles, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"strings"
+	"time"
+
+	"digital-library/backend/models"
+)
+
+// mostSpecificPolicy picks the policy that best matches a book category and
+// borrower role. A policy naming both wins over one naming only the category,
+// which wins over one naming only the role, which wins over the catch-all.
+func mostSpecificPolicy(policies []models.LoanPolicy, category, role string) models.LoanPolicy {
+	best, bestScore := models.DefaultLoanPolicy, -1
+	for _, p := range policies {
+		score := 0
+		if p.Category != nil {
+			if !strings.EqualFold(*p.Category, category) {
+				continue
+			}
+			score += 2
+		}
+		if p.Role != nil {
+			if *p.Role != role {
+				continue
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = p, score
+		}
+	}
+	return best
+}
+
+// dueDate returns the day a loan made on borrowDate under the policy is due
+func dueDate(borrowDate time.Time, policy models.LoanPolicy) time.Time {
+	return borrowDate.AddDate(0, 0, policy.LoanDays)
+}
+
+// renewedDueDate returns the due date of a loan renewed on asOf under the
+// policy. The loan period starts from the current due date, or from asOf if
+// that is later.
+func renewedDueDate(record models.LendingRecord, asOf time.Time, policy models.LoanPolicy) time.Time {
+	from := record.DueDate
+	if asOf.After(from) {
+		from = asOf
+	}
+	return dueDate(from, policy)
+}
+
+// holdExpiry returns the last day a hold that became ready on readyDate can be
+// picked up under the policy
+func holdExpiry(readyDate time.Time, policy models.LoanPolicy) time.Time {
+	return readyDate.AddDate(0, 0, policy.HoldPickupDays)
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"digital-library/backend/models"
+)
+
+func TestMostSpecificPolicy(t *testing.T) {
+	scifi, admin, user := "SciFi", models.RoleAdmin, models.RoleUser
+	policies := []models.LoanPolicy{
+		{ID: 1, LoanDays: 21},
+		{ID: 2, Role: &admin, LoanDays: 28},
+		{ID: 3, Category: &scifi, LoanDays: 7},
+		{ID: 4, Category: &scifi, Role: &user, LoanDays: 3},
+	}
+
+	tests := []struct {
+		name     string
+		policies []models.LoanPolicy
+		category string
+		role     string
+		want     int
+	}{
+		{"category and role", policies, "scifi", models.RoleUser, 4},
+		{"category over role", policies, "SciFi", models.RoleAdmin, 3},
+		{"role only", policies, "classic", models.RoleAdmin, 2},
+		{"catch-all", policies, "classic", models.RoleUser, 1},
+		{"no policies", nil, "scifi", models.RoleUser, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mostSpecificPolicy(tt.policies, tt.category, tt.role); got.ID != tt.want {
+				t.Errorf("mostSpecificPolicy(%q, %q) = policy %d, want %d", tt.category, tt.role, got.ID, tt.want)
+			}
+		})
+	}
+
+	if got := mostSpecificPolicy(nil, "", models.RoleUser); got.LoanDays != models.DefaultLoanPolicy.LoanDays {
+		t.Errorf("default loan days = %d, want %d", got.LoanDays, models.DefaultLoanPolicy.LoanDays)
+	}
+}
+
+func TestDueDate(t *testing.T) {
+	borrowed := time.Date(2024, time.February, 20, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		loanDays int
+		want     time.Time
+	}{
+		{1, time.Date(2024, time.February, 21, 15, 0, 0, 0, time.UTC)},
+		{14, time.Date(2024, time.March, 5, 15, 0, 0, 0, time.UTC)},
+		{365, time.Date(2025, time.February, 19, 15, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := dueDate(borrowed, models.LoanPolicy{LoanDays: tt.loanDays}); !got.Equal(tt.want) {
+			t.Errorf("dueDate(%d days) = %v, want %v", tt.loanDays, got, tt.want)
+		}
+	}
+}
+
+func TestRenewedDueDate(t *testing.T) {
+	due := time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)
+	record := models.LendingRecord{DueDate: due}
+	policy := models.LoanPolicy{LoanDays: 7}
+
+	tests := []struct {
+		name string
+		asOf time.Time
+		want time.Time
+	}{
+		{"before the due date", due.AddDate(0, 0, -3), due.AddDate(0, 0, 7)},
+		{"on the due date", due, due.AddDate(0, 0, 7)},
+		{"after the due date", due.AddDate(0, 0, 2), due.AddDate(0, 0, 9)},
+	}
+
+	for _, tt := range tests {
+		if got := renewedDueDate(record, tt.asOf, policy); !got.Equal(tt.want) {
+			t.Errorf("%s: renewedDueDate = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
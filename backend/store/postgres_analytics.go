@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"digital-library/backend/models"
+)
+
+// MostBorrowedBooks returns books ordered by number of times borrowed
+func (s *Postgres) MostBorrowedBooks(ctx context.Context, scope AnalyticsScope, limit int) ([]models.BorrowCount, error) {
+	query := `SELECT 
+		b.id AS book_id, 
+		b.title AS book_title,
+		COUNT(lr.id) AS borrows
+	FROM books b
+	JOIN lending_records lr ON b.id = lr.book_id`
+	args := []interface{}{limit}
+	if !scope.All {
+		query += `
+	WHERE lr.user_id = $2`
+		args = append(args, scope.UserID)
+	}
+	query += `
+	GROUP BY b.id, b.title
+	ORDER BY borrows DESC
+	LIMIT $1`
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]models.BorrowCount, 0)
+	for rows.Next() {
+		var bc models.BorrowCount
+		if err := rows.Scan(&bc.BookID, &bc.BookTitle, &bc.Borrows); err != nil {
+			return nil, err
+		}
+		results = append(results, bc)
+	}
+	return results, rows.Err()
+}
+
+// MonthlyLendingTrends returns lending counts grouped by month
+func (s *Postgres) MonthlyLendingTrends(ctx context.Context, scope AnalyticsScope) ([]models.MonthlyTrend, error) {
+	query := `SELECT 
+		to_char(lr.borrow_date, 'YYYY-MM') AS month, 
+		COUNT(*) AS count
+	FROM lending_records lr`
+	args := []interface{}{}
+	if !scope.All {
+		query += `
+	WHERE lr.user_id = $1`
+		args = append(args, scope.UserID)
+	}
+	query += `
+	GROUP BY month
+	ORDER BY month ASC`
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]models.MonthlyTrend, 0)
+	for rows.Next() {
+		var mt models.MonthlyTrend
+		if err := rows.Scan(&mt.Month, &mt.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, mt)
+	}
+	return results, rows.Err()
+}
+
+// CategoryDistribution returns the number of books per category. A user
+// scope only counts the books that user has borrowed.
+func (s *Postgres) CategoryDistribution(ctx context.Context, scope AnalyticsScope) ([]models.CategoryDistribution, error) {
+	query := `SELECT 
+		COALESCE(b.category, 'Uncategorized') AS category,
+		COUNT(DISTINCT b.id) AS count
+	FROM books b`
+	args := []interface{}{}
+	if !scope.All {
+		query += `
+	JOIN lending_records lr ON b.id = lr.book_id
+	WHERE lr.user_id = $1`
+		args = append(args, scope.UserID)
+	}
+	query += `
+	GROUP BY COALESCE(b.category, 'Uncategorized')
+	ORDER BY count DESC`
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]models.CategoryDistribution, 0)
+	for rows.Next() {
+		var cd models.CategoryDistribution
+		if err := rows.Scan(&cd.Category, &cd.Count); err != nil {
+			return nil, err
+		}
+		results = append(results, cd)
+	}
+	return results, rows.Err()
+}
+
+// OverdueLoans lists unreturned loans due before asOf, most overdue first
+func (s *Postgres) OverdueLoans(ctx context.Context, scope AnalyticsScope, asOf time.Time) ([]models.OverdueLoan, error) {
+	query := `SELECT 
+		lr.id, b.id, b.title, b.author, lr.user_id, lr.borrower_name,
+		lr.borrow_date, lr.due_date, $1::date - lr.due_date AS days_overdue, lr.overdue_at
+	FROM lending_records lr
+	JOIN books b ON b.id = lr.book_id`
+	args := []interface{}{asOf}
+	query += `
+	WHERE lr.return_date IS NULL AND lr.due_date < $1`
+	if !scope.All {
+		query += ` AND lr.user_id = $2`
+		args = append(args, scope.UserID)
+	}
+	query += `
+	ORDER BY lr.due_date ASC, lr.id ASC`
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]models.OverdueLoan, 0)
+	for rows.Next() {
+		var ol models.OverdueLoan
+		err := rows.Scan(&ol.LendingRecordID, &ol.BookID, &ol.BookTitle, &ol.BookAuthor, &ol.UserID, &ol.Borrower,
+			&ol.BorrowDate, &ol.DueDate, &ol.DaysOverdue, &ol.OverdueAt)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ol)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,653 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"digital-library/backend/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const bookColumns = `id, title, author, isbn, quantity, category, publisher, year, created_at, updated_at`
+
+func scanBook(row pgx.Row, book *models.Book, extra ...interface{}) error {
+	dest := []interface{}{
+		&book.ID, &book.Title, &book.Author, &book.ISBN,
+		&book.Quantity, &book.Category, &book.Publisher, &book.Year, &book.CreatedAt, &book.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// headlineOptions marks every matched term of the short fields searched
+const headlineOptions = `'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'`
+
+// Ways ListBooks matches the search of a filter
+const (
+	searchNone     = iota // No search
+	searchFullText        // Full-text search over books.search_vector
+	searchSimilar         // Titles and authors spelled like the search
+)
+
+// ListBooks returns a page of the books matching the filter. Searches are
+// ordered by relevance and other listings by title unless another sort is
+// requested. A search that no book matches with full-text search, whatever
+// the other filters, falls back to the books whose title or author is spelled
+// most like it.
+func (s *Postgres) ListBooks(ctx context.Context, filter BookFilter, page PageRequest) (models.BookPage, error) {
+	if filter.Search == "" {
+		q, err := bookListing.resolve(page)
+		if err != nil {
+			return models.BookPage{Data: make([]models.Book, 0)}, err
+		}
+		return s.listBooks(ctx, s.db, filter, q, searchNone)
+	}
+
+	q, err := bookSearchListing.resolve(page)
+	if err != nil {
+		return models.BookPage{Data: make([]models.Book, 0)}, err
+	}
+	matched, err := fullTextMatches(ctx, s.db, filter.Search)
+	if err != nil {
+		return models.BookPage{Data: make([]models.Book, 0)}, err
+	}
+	if matched {
+		return s.listBooks(ctx, s.db, filter, q, searchFullText)
+	}
+
+	tx, err := s.similarityTx(ctx)
+	if err != nil {
+		return models.BookPage{Data: make([]models.Book, 0)}, err
+	}
+	defer tx.Rollback(ctx)
+	result, err := s.listBooks(ctx, tx, filter, q, searchSimilar)
+	result.Similar = true
+	return result, err
+}
+
+// ExportBooks calls fn with every book ListBooks would list for the filter,
+// in the order of the requested sort, as the rows are read
+func (s *Postgres) ExportBooks(ctx context.Context, filter BookFilter, page PageRequest, fn func(models.Book) error) error {
+	page.Limit, page.Cursor, page.Offset = 0, "", 0
+	if filter.Search == "" {
+		q, err := bookListing.resolve(page)
+		if err != nil {
+			return err
+		}
+		_, err = eachBook(ctx, s.db, selectBooks(filter, searchNone), q, fn)
+		return err
+	}
+
+	q, err := bookSearchListing.resolve(page)
+	if err != nil {
+		return err
+	}
+	matched, err := fullTextMatches(ctx, s.db, filter.Search)
+	if err != nil {
+		return err
+	}
+	if matched {
+		_, err = eachBook(ctx, s.db, selectBooks(filter, searchFullText), q, fn)
+		return err
+	}
+
+	tx, err := s.similarityTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	_, err = eachBook(ctx, tx, selectBooks(filter, searchSimilar), q, fn)
+	return err
+}
+
+// fullTextMatches reports whether any book matches a search with full-text
+// search. The other filters are left out: a search that matches books they
+// exclude finds no books rather than books spelled like it.
+func fullTextMatches(ctx context.Context, db querier, search string) (bool, error) {
+	var matched bool
+	err := db.QueryRow(ctx, `SELECT EXISTS (
+		SELECT 1 FROM books WHERE search_vector @@ websearch_to_tsquery('english', $1))`, search).Scan(&matched)
+	return matched, err
+}
+
+// similarityThreshold is the least word_similarity of a title or author to a
+// search for the <% operator to match. The pg_trgm default of 0.6 misses
+// most misspellings.
+const similarityThreshold = "0.3"
+
+// similarityTx begins a read-only transaction in which <% matches at
+// similarityThreshold
+func (s *Postgres) similarityTx(ctx context.Context) (pgx.Tx, error) {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx, `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`, similarityThreshold)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	return tx, nil
+}
+
+// listBooks returns a page of the books matching the filter, using the given
+// way to match its search
+func (s *Postgres) listBooks(ctx context.Context, db querier, filter BookFilter, q pageQuery, search int) (models.BookPage, error) {
+	result := models.BookPage{Data: make([]models.Book, 0)}
+	sel := selectBooks(filter, search)
+
+	// Count every match, then fetch the page after the cursor
+	if err := db.QueryRow(ctx, `SELECT COUNT(*)`+sel.from+sel.where, sel.args...).Scan(&result.Total); err != nil {
+		return result, err
+	}
+	if len(filter.Facets) > 0 {
+		facets, err := bookFacets(ctx, db, sel.from+sel.where, sel.args, filter.Facets)
+		if err != nil {
+			return result, err
+		}
+		result.Facets = facets
+	}
+	_, err := eachBook(ctx, db, sel, q, func(book models.Book) error {
+		result.Data = append(result.Data, book)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if q.limit > 0 && len(result.Data) > q.limit {
+		result.Data = result.Data[:q.limit]
+		last := result.Data[q.limit-1]
+		result.NextCursor = q.nextCursor(bookSortValue(last, q.sort), last.ID)
+	}
+	return result, nil
+}
+
+// bookSelect is the query selecting the books that match a filter
+type bookSelect struct {
+	listing              listing
+	search               int
+	columns, from, where string
+	args                 []interface{}
+	argCount             int
+}
+
+// selectBooks builds the query selecting the books that match the filter,
+// using the given way to match its search
+func selectBooks(filter BookFilter, search int) bookSelect {
+	sel := bookSelect{
+		listing:  bookSearchListing,
+		search:   search,
+		columns:  bookColumns,
+		from:     ` FROM books`,
+		where:    ` WHERE 1=1`,
+		args:     []interface{}{},
+		argCount: 1,
+	}
+
+	// Add the search. The subqueries rank the matches and keep the columns
+	// of books, so the filters and sorts below apply unchanged.
+	switch search {
+	case searchNone:
+		sel.listing = bookListing
+	case searchFullText:
+		sel.from = ` FROM (
+		SELECT books.*, query, ts_rank(search_vector, query) AS rank
+		FROM books, websearch_to_tsquery('english', $` + strconv.Itoa(sel.argCount) + `) query
+		WHERE search_vector @@ query) books`
+		sel.columns += `, rank,
+		ts_headline('english', title, query, ` + headlineOptions + `),
+		ts_headline('english', author, query, ` + headlineOptions + `),
+		ts_headline('english', COALESCE(category, ''), query, ` + headlineOptions + `)`
+		sel.args = append(sel.args, filter.Search)
+		sel.argCount++
+	case searchSimilar:
+		sel.from = ` FROM (
+		SELECT books.*, GREATEST(word_similarity($` + strconv.Itoa(sel.argCount) + `, title), word_similarity($` + strconv.Itoa(sel.argCount) + `, author)) AS rank
+		FROM books
+		WHERE $` + strconv.Itoa(sel.argCount) + ` <% title OR $` + strconv.Itoa(sel.argCount) + ` <% author) books`
+		sel.columns += `, rank`
+		sel.args = append(sel.args, filter.Search)
+		sel.argCount++
+	}
+
+	// Add selection
+	if filter.IDs != nil {
+		sel.where += ` AND id = ANY($` + strconv.Itoa(sel.argCount) + `)`
+		sel.args = append(sel.args, filter.IDs)
+		sel.argCount++
+	}
+
+	// Add category filter
+	if filter.Category != "" {
+		sel.where += ` AND LOWER(category) = LOWER($` + strconv.Itoa(sel.argCount) + `)`
+		sel.args = append(sel.args, filter.Category)
+		sel.argCount++
+	}
+
+	// Add author filter
+	if filter.Author != "" {
+		sel.where += ` AND LOWER(author) = LOWER($` + strconv.Itoa(sel.argCount) + `)`
+		sel.args = append(sel.args, filter.Author)
+		sel.argCount++
+	}
+
+	// Add availability filter
+	if filter.Available != nil {
+		if *filter.Available {
+			sel.where += ` AND quantity > 0`
+		} else {
+			sel.where += ` AND quantity = 0`
+		}
+	}
+
+	// Add catalog query
+	if filter.Query != nil {
+		condition, queryArgs := bookQuerySQL(filter.Query, sel.argCount)
+		sel.where += ` AND ` + condition
+		sel.args = append(sel.args, queryArgs...)
+		sel.argCount += len(queryArgs)
+	}
+	return sel
+}
+
+// eachBook calls fn with each selected book after the cursor, in order, as
+// the rows are read, and returns the number of books read
+func eachBook(ctx context.Context, db querier, sel bookSelect, q pageQuery, fn func(models.Book) error) (int, error) {
+	after, order, pageArgs := q.sql(sel.listing, sel.argCount)
+	rows, err := db.Query(ctx, `SELECT `+sel.columns+sel.from+sel.where+after+order, append(sel.args, pageArgs...)...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var book models.Book
+		switch sel.search {
+		case searchFullText:
+			book.Highlight = &models.BookHighlight{}
+			err = scanBook(rows, &book, &book.Rank, &book.Highlight.Title, &book.Highlight.Author, &book.Highlight.Category)
+		case searchSimilar:
+			err = scanBook(rows, &book, &book.Rank)
+		default:
+			err = scanBook(rows, &book)
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := fn(book); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// bookFacets counts the books selected by the FROM and WHERE clauses of a
+// listing by the value of each facet, most common value first
+func bookFacets(ctx context.Context, db querier, fromWhere string, args []interface{}, facets []string) (map[string][]models.FacetBucket, error) {
+	result := make(map[string][]models.FacetBucket)
+	for _, facet := range facets {
+		var query string
+		switch facet {
+		case "category":
+			query = `SELECT category, COUNT(*)` + fromWhere + ` AND category <> ''
+			GROUP BY category ORDER BY COUNT(*) DESC, category LIMIT ` + strconv.Itoa(facetLimit)
+		case "author":
+			query = `SELECT author, COUNT(*)` + fromWhere + `
+			GROUP BY author ORDER BY COUNT(*) DESC, author LIMIT ` + strconv.Itoa(facetLimit)
+		case "available":
+			query = `SELECT (quantity > 0)::text AS available, COUNT(*)` + fromWhere + `
+			GROUP BY available ORDER BY COUNT(*) DESC, available`
+		default:
+			continue
+		}
+
+		rows, err := db.Query(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		buckets := make([]models.FacetBucket, 0)
+		for rows.Next() {
+			var bucket models.FacetBucket
+			if err := rows.Scan(&bucket.Value, &bucket.Count); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			buckets = append(buckets, bucket)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		result[facet] = buckets
+	}
+	return result, nil
+}
+
+// ListFacetValues returns up to limit values of the category or author
+// facet after the given value, in order, with their number of books
+func (s *Postgres) ListFacetValues(ctx context.Context, facet, after string, limit int) ([]models.FacetBucket, error) {
+	buckets := make([]models.FacetBucket, 0)
+	if facet != "category" && facet != "author" {
+		return buckets, nil
+	}
+	// The facet is one of the two column names, so it is safe to splice in
+	rows, err := s.db.Query(ctx, `SELECT `+facet+`, COUNT(*) FROM books
+		WHERE `+facet+` <> '' AND `+facet+` > $1
+		GROUP BY `+facet+` ORDER BY `+facet+` LIMIT $2`, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucket models.FacetBucket
+		if err := rows.Scan(&bucket.Value, &bucket.Count); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
+
+// bookChanges lists the latest change to every book: its last update, or
+// its deletion
+const bookChanges = `(
+	SELECT id, updated_at AS datestamp, FALSE AS deleted, COALESCE(category, '') AS category FROM books
+	UNION ALL
+	SELECT book_id, deleted_at, TRUE, category FROM deleted_books
+) changes`
+
+// ListBookChanges returns up to limit changes to the books matching the
+// filter, oldest first
+func (s *Postgres) ListBookChanges(ctx context.Context, filter ChangeFilter, limit int) ([]models.BookChange, error) {
+	where := ` WHERE 1=1`
+	args := []interface{}{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return `$` + strconv.Itoa(len(args))
+	}
+	if filter.From != nil {
+		where += ` AND datestamp >= ` + arg(*filter.From)
+	}
+	if filter.Before != nil {
+		where += ` AND datestamp < ` + arg(*filter.Before)
+	}
+	if filter.Category != "" {
+		where += ` AND category = ` + arg(filter.Category)
+	}
+	if filter.AfterID != 0 {
+		where += ` AND (datestamp, id) > (` + arg(filter.AfterDatestamp) + `, ` + arg(filter.AfterID) + `)`
+	}
+
+	rows, err := s.db.Query(ctx, `SELECT id, datestamp, deleted, category FROM `+bookChanges+
+		where+` ORDER BY datestamp, id LIMIT `+arg(limit), args...)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]models.BookChange, 0)
+	var ids []int
+	for rows.Next() {
+		var change models.BookChange
+		if err := rows.Scan(&change.BookID, &change.Datestamp, &change.Deleted, &change.Category); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		changes = append(changes, change)
+		if !change.Deleted {
+			ids = append(ids, change.BookID)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return changes, nil
+	}
+
+	// Read the changed books. One deleted in between drops out of the page;
+	// its deletion comes later in the order.
+	books := make(map[int]*models.Book, len(ids))
+	rows, err = s.db.Query(ctx, `SELECT `+bookColumns+` FROM books WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var book models.Book
+		if err := scanBook(rows, &book); err != nil {
+			return nil, err
+		}
+		books[book.ID] = &book
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	read := changes[:0]
+	for _, change := range changes {
+		if !change.Deleted {
+			if change.Book = books[change.BookID]; change.Book == nil {
+				continue
+			}
+		}
+		read = append(read, change)
+	}
+	return read, nil
+}
+
+// GetBookChange returns the book with the given ID, or its deletion
+func (s *Postgres) GetBookChange(ctx context.Context, id int) (models.BookChange, error) {
+	book, err := s.GetBook(ctx, id)
+	if err == nil {
+		return models.BookChange{BookID: id, Datestamp: book.UpdatedAt, Category: book.Category, Book: &book}, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return models.BookChange{}, err
+	}
+	change := models.BookChange{BookID: id, Deleted: true}
+	err = s.db.QueryRow(ctx, `SELECT deleted_at, category FROM deleted_books WHERE book_id = $1`, id).
+		Scan(&change.Datestamp, &change.Category)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return change, ErrNotFound
+	}
+	return change, err
+}
+
+// EarliestBookChange returns the datestamp of the oldest change to a book
+func (s *Postgres) EarliestBookChange(ctx context.Context) (time.Time, error) {
+	var earliest *time.Time
+	if err := s.db.QueryRow(ctx, `SELECT MIN(datestamp) FROM `+bookChanges).Scan(&earliest); err != nil {
+		return time.Time{}, err
+	}
+	if earliest == nil {
+		return time.Now(), nil
+	}
+	return *earliest, nil
+}
+
+// SuggestBooks returns up to limit books whose title or author is spelled
+// like the query, best match first
+func (s *Postgres) SuggestBooks(ctx context.Context, query string, limit int) ([]models.BookSuggestion, error) {
+	tx, err := s.similarityTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT id, title, author,
+		GREATEST(word_similarity($1, title), word_similarity($1, author)) AS score
+	FROM books
+	WHERE $1 <% title OR $1 <% author
+	ORDER BY score DESC, title, id
+	LIMIT $2`, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := make([]models.BookSuggestion, 0)
+	for rows.Next() {
+		var suggestion models.BookSuggestion
+		if err := rows.Scan(&suggestion.BookID, &suggestion.Title, &suggestion.Author, &suggestion.Score); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, rows.Err()
+}
+
+// GetBook returns a single book by ID
+func (s *Postgres) GetBook(ctx context.Context, id int) (models.Book, error) {
+	var book models.Book
+	err := scanBook(s.db.QueryRow(ctx, `SELECT `+bookColumns+` FROM books WHERE id = $1`, id), &book)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return book, ErrNotFound
+	}
+	return book, err
+}
+
+// GetBookByISBN returns the book with the given canonical ISBN
+func (s *Postgres) GetBookByISBN(ctx context.Context, isbn string) (models.Book, error) {
+	var book models.Book
+	err := scanBook(s.db.QueryRow(ctx, `SELECT `+bookColumns+` FROM books WHERE isbn = $1`, isbn), &book)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return book, ErrNotFound
+	}
+	return book, err
+}
+
+// CreateBook inserts the book with book.Quantity available copies and fills
+// in its generated fields
+func (s *Postgres) CreateBook(ctx context.Context, book *models.Book) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := createBook(ctx, tx, book); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// createBook inserts a book with Quantity copies; db should be a transaction
+func createBook(ctx context.Context, db querier, book *models.Book) error {
+	query := `INSERT INTO books (title, author, isbn, category, publisher, year) 
+	          VALUES ($1, $2, $3, $4, $5, $6) 
+	          RETURNING id, created_at, updated_at`
+
+	err := db.QueryRow(ctx, query,
+		book.Title, book.Author, book.ISBN, book.Category, book.Publisher, book.Year).
+		Scan(&book.ID, &book.CreatedAt, &book.UpdatedAt)
+	if violatesConstraint(err, "books_isbn_key") {
+		return ErrDuplicateISBN
+	}
+	if err != nil {
+		return err
+	}
+
+	// The copies keep books.quantity in sync
+	_, err = db.Exec(ctx, `INSERT INTO book_items (book_id) SELECT $1 FROM generate_series(1, $2)`,
+		book.ID, book.Quantity)
+	return err
+}
+
+// UpdateBook replaces the editable fields of a book and returns the stored
+// row. The quantity follows the book's copies and is not editable.
+func (s *Postgres) UpdateBook(ctx context.Context, id int, book models.Book) (models.Book, error) {
+	query := `UPDATE books 
+	          SET title = $1, author = $2, isbn = $3, category = $4, publisher = $5, year = $6, updated_at = NOW() 
+	          WHERE id = $7
+	          RETURNING ` + bookColumns
+
+	var updated models.Book
+	err := scanBook(s.db.QueryRow(ctx, query,
+		book.Title, book.Author, book.ISBN, book.Category, book.Publisher, book.Year, id), &updated)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return updated, ErrNotFound
+	case violatesConstraint(err, "books_isbn_key"):
+		return updated, ErrDuplicateISBN
+	}
+	return updated, err
+}
+
+// DeleteBook removes a book; its copies, lending records and holds are removed by ON DELETE CASCADE
+func (s *Postgres) DeleteBook(ctx context.Context, id int) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM books WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ImportBooks upserts a batch of books by ISBN in one transaction. A dry run
+// only reads the stored books and tracks what the batch would change.
+func (s *Postgres) ImportBooks(ctx context.Context, books []models.Book, dryRun *ImportDryRun) ([]models.ImportRow, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	results := make([]models.ImportRow, len(books))
+	for i, book := range books {
+		results[i].ISBN = book.ISBN
+
+		existing, ok := dryRun.book(book.ISBN)
+		if !ok {
+			err := scanBook(tx.QueryRow(ctx, `SELECT `+bookColumns+` FROM books WHERE isbn = $1 FOR UPDATE`, book.ISBN), &existing)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return nil, err
+			}
+			ok = err == nil
+		}
+		switch {
+		case !ok:
+			results[i].Status = models.ImportCreated
+			if dryRun != nil {
+				dryRun.save(book)
+				continue
+			}
+			if err := createBook(ctx, tx, &book); err != nil {
+				return nil, err
+			}
+			results[i].BookID = book.ID
+		case !importChanges(existing, &book):
+			results[i].Status = models.ImportSkipped
+			results[i].Reason = "Book is unchanged"
+			results[i].BookID = existing.ID
+		default:
+			results[i].Status = models.ImportUpdated
+			results[i].BookID = existing.ID
+			if dryRun != nil {
+				existing.Title = book.Title
+				existing.Author = book.Author
+				existing.Category = book.Category
+				existing.Publisher = book.Publisher
+				existing.Year = book.Year
+				dryRun.save(existing)
+				continue
+			}
+			_, err := tx.Exec(ctx, `UPDATE books SET title = $1, author = $2, category = $3, publisher = $4, year = $5,
+				updated_at = NOW() WHERE id = $6`,
+				book.Title, book.Author, book.Category, book.Publisher, book.Year, existing.ID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if dryRun != nil {
+		return results, nil
+	}
+	return results, tx.Commit(ctx)
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"digital-library/backend/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const fileColumns = `id, book_id, filename, mime_type, size, checksum, storage_key, created_at`
+
+func scanFile(row pgx.Row, file *models.BookFile) error {
+	return row.Scan(
+		&file.ID, &file.BookID, &file.Filename, &file.MimeType, &file.Size, &file.Checksum,
+		&file.StorageKey, &file.CreatedAt,
+	)
+}
+
+// bookExists gives ErrNotFound for a missing book
+func bookExists(ctx context.Context, db querier, bookID int) error {
+	var exists bool
+	if err := db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM books WHERE id = $1)`, bookID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListBookFiles returns the files of a book, oldest first
+func (s *Postgres) ListBookFiles(ctx context.Context, bookID int) ([]models.BookFile, error) {
+	if err := bookExists(ctx, s.db, bookID); err != nil {
+		return nil, err
+	}
+
+	return s.listFiles(ctx, `book_id = $1`, bookID)
+}
+
+// ListFilesOfBooks returns the files of several books, oldest first
+func (s *Postgres) ListFilesOfBooks(ctx context.Context, bookIDs []int) ([]models.BookFile, error) {
+	return s.listFiles(ctx, `book_id = ANY($1)`, bookIDs)
+}
+
+// listFiles returns the files matching a condition, oldest first
+func (s *Postgres) listFiles(ctx context.Context, where string, args ...interface{}) ([]models.BookFile, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+fileColumns+` FROM book_files WHERE `+where+`
+		ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := make([]models.BookFile, 0)
+	for rows.Next() {
+		var file models.BookFile
+		if err := scanFile(rows, &file); err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// GetBookFile returns a file of a book
+func (s *Postgres) GetBookFile(ctx context.Context, bookID, fileID int) (models.BookFile, error) {
+	var file models.BookFile
+	err := scanFile(s.db.QueryRow(ctx, `SELECT `+fileColumns+` FROM book_files
+		WHERE id = $1 AND book_id = $2`, fileID, bookID), &file)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if err := bookExists(ctx, s.db, bookID); err != nil {
+			return file, err
+		}
+		return file, ErrFileNotFound
+	}
+	return file, err
+}
+
+// CreateBookFile records a file of a book
+func (s *Postgres) CreateBookFile(ctx context.Context, file *models.BookFile) error {
+	if err := bookExists(ctx, s.db, file.BookID); err != nil {
+		return err
+	}
+	return scanFile(s.db.QueryRow(ctx, `INSERT INTO book_files
+		(book_id, filename, mime_type, size, checksum, storage_key)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+fileColumns,
+		file.BookID, file.Filename, file.MimeType, file.Size, file.Checksum, file.StorageKey), file)
+}
+
+// DeleteBookFile removes the record of a file of a book
+func (s *Postgres) DeleteBookFile(ctx context.Context, bookID, fileID int) (models.BookFile, error) {
+	var file models.BookFile
+	err := scanFile(s.db.QueryRow(ctx, `DELETE FROM book_files WHERE id = $1 AND book_id = $2
+		RETURNING `+fileColumns, fileID, bookID), &file)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if err := bookExists(ctx, s.db, bookID); err != nil {
+			return file, err
+		}
+		return file, ErrFileNotFound
+	}
+	return file, err
+}
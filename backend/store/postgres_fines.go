@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+
+	"digital-library/backend/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const fineColumns = `id, user_id, borrower_name, lending_record_id, entry_type, reason, amount_cents, note, created_at`
+
+func scanFineEntry(row pgx.Row, entry *models.FineEntry) error {
+	return row.Scan(
+		&entry.ID, &entry.UserID, &entry.Borrower, &entry.LendingRecordID, &entry.EntryType,
+		&entry.Reason, &entry.AmountCents, &entry.Note, &entry.CreatedAt,
+	)
+}
+
+// ListFineEntries returns the fines ledger in the order it was written,
+// limited to one user unless userID is zero
+func (s *Postgres) ListFineEntries(ctx context.Context, userID int) ([]models.FineEntry, error) {
+	query := `SELECT ` + fineColumns + ` FROM fine_entries`
+	args := []interface{}{}
+	if userID != 0 {
+		query += ` WHERE user_id = $1`
+		args = append(args, userID)
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.FineEntry, 0)
+	err = eachFineEntry(rows, func(entry models.FineEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	return entries, err
+}
+
+// ExportFineEntries calls fn with every entry of the ledger in the order it
+// was written, as the rows are read
+func (s *Postgres) ExportFineEntries(ctx context.Context, fn func(models.FineEntry) error) error {
+	rows, err := s.db.Query(ctx, `SELECT `+fineColumns+` FROM fine_entries ORDER BY created_at, id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return eachFineEntry(rows, fn)
+}
+
+// eachFineEntry calls fn with the entry of each row
+func eachFineEntry(rows pgx.Rows, fn func(models.FineEntry) error) error {
+	for rows.Next() {
+		var entry models.FineEntry
+		if err := scanFineEntry(rows, &entry); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// FineBalance sums a user's ledger
+func (s *Postgres) FineBalance(ctx context.Context, userID int) (models.FineBalance, error) {
+	query := `SELECT
+	            COALESCE(SUM(amount_cents) FILTER (WHERE entry_type = 'charge'), 0),
+	            COALESCE(SUM(amount_cents) FILTER (WHERE entry_type = 'payment'), 0),
+	            COALESCE(SUM(amount_cents) FILTER (WHERE entry_type = 'waiver'), 0)
+	          FROM fine_entries
+	          WHERE user_id = $1`
+
+	balance := models.FineBalance{UserID: userID}
+	err := s.db.QueryRow(ctx, query, userID).
+		Scan(&balance.ChargedCents, &balance.PaidCents, &balance.WaivedCents)
+	balance.BalanceCents = balance.ChargedCents - balance.PaidCents - balance.WaivedCents
+	return balance, err
+}
+
+// AddFineEntry appends an entry to the ledger and fills in its generated fields
+func (s *Postgres) AddFineEntry(ctx context.Context, entry *models.FineEntry) error {
+	return insertFineEntry(ctx, s.db, entry)
+}
+
+func insertFineEntry(ctx context.Context, db querier, entry *models.FineEntry) error {
+	query := `INSERT INTO fine_entries (user_id, borrower_name, lending_record_id, entry_type, reason, amount_cents, note)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)
+	          RETURNING id, created_at`
+
+	return db.QueryRow(ctx, query,
+		entry.UserID, entry.Borrower, entry.LendingRecordID, entry.EntryType, entry.Reason, entry.AmountCents, entry.Note).
+		Scan(&entry.ID, &entry.CreatedAt)
+}
@@ -0,0 +1,288 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"digital-library/backend/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const holdColumns = `h.id, h.book_id, h.user_id, h.borrower_name, h.status, h.ready_at, h.expires_at, h.created_at, h.updated_at`
+
+func scanHold(row pgx.Row, hold *models.Hold, extra ...interface{}) error {
+	dest := []interface{}{
+		&hold.ID, &hold.BookID, &hold.UserID, &hold.Borrower, &hold.Status,
+		&hold.ReadyAt, &hold.ExpiresAt, &hold.CreatedAt, &hold.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// unreservedCopies locks the book row and returns the number of available
+// copies not set aside for ready holds, along with the book category
+func unreservedCopies(ctx context.Context, db querier, bookID int) (int, string, error) {
+	var free int
+	var category string
+	err := db.QueryRow(ctx, `SELECT b.quantity - (SELECT COUNT(*) FROM holds WHERE book_id = b.id AND status = 'ready'),
+		COALESCE(b.category, '')
+		FROM books b WHERE b.id = $1 FOR UPDATE`, bookID).Scan(&free, &category)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, "", ErrNotFound
+	}
+	return free, category, err
+}
+
+// promoteHolds sets unreserved copies of a book aside for the oldest waiting
+// holds. It must run in the transaction that made copies available.
+func promoteHolds(ctx context.Context, db querier, bookID int) error {
+	free, category, err := unreservedCopies(ctx, db, bookID)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	if free <= 0 {
+		return nil
+	}
+
+	rows, err := db.Query(ctx, `SELECT h.id, COALESCE(u.role, $3)
+		FROM holds h
+		LEFT JOIN users u ON u.id = h.user_id
+		WHERE h.book_id = $1 AND h.status = 'waiting'
+		ORDER BY h.created_at, h.id
+		LIMIT $2
+		FOR UPDATE OF h`, bookID, free, models.RoleUser)
+	if err != nil {
+		return err
+	}
+	type queued struct {
+		id   int
+		role string
+	}
+	var next []queued
+	for rows.Next() {
+		var q queued
+		if err := rows.Scan(&q.id, &q.role); err != nil {
+			rows.Close()
+			return err
+		}
+		next = append(next, q)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, q := range next {
+		policy, err := resolveLoanPolicy(ctx, db, category, q.role)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(ctx, `UPDATE holds SET status = 'ready', ready_at = NOW(), expires_at = $1 WHERE id = $2`,
+			holdExpiry(today(), policy), q.id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetHold returns a single hold by ID
+func (s *Postgres) GetHold(ctx context.Context, id int) (models.Hold, error) {
+	var hold models.Hold
+	err := scanHold(s.db.QueryRow(ctx, `SELECT `+holdColumns+` FROM holds h WHERE h.id = $1`, id), &hold)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return hold, ErrNotFound
+	}
+	return hold, err
+}
+
+// PlaceHold queues the borrower for a book whose copies are all lent or reserved
+func (s *Postgres) PlaceHold(ctx context.Context, bookID int, borrower models.User) (models.Hold, error) {
+	var hold models.Hold
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return hold, err
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Lock the book and make sure there is nothing to borrow right away
+	free, _, err := unreservedCopies(ctx, tx, bookID)
+	if err != nil {
+		return hold, err
+	}
+	if free > 0 {
+		return hold, ErrBookAvailable
+	}
+
+	// 2. Join the end of the queue
+	err = scanHold(tx.QueryRow(ctx, `INSERT INTO holds AS h (book_id, user_id, borrower_name) VALUES ($1, $2, $3)
+		RETURNING `+holdColumns, bookID, borrower.ID, borrower.Username), &hold)
+	if violatesConstraint(err, "holds_open_user_key") {
+		return hold, ErrDuplicateHold
+	}
+	if err != nil {
+		return hold, err
+	}
+
+	return hold, tx.Commit(ctx)
+}
+
+// ListHolds returns holds joined with their book, oldest first
+func (s *Postgres) ListHolds(ctx context.Context, filter HoldFilter) ([]models.HoldDetail, error) {
+	holds := make([]models.HoldDetail, 0)
+	err := s.eachHold(ctx, filter, func(hold models.HoldDetail) error {
+		holds = append(holds, hold)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
+
+// ExportHolds calls fn with every hold joined with its book, oldest first,
+// as the rows are read
+func (s *Postgres) ExportHolds(ctx context.Context, fn func(models.HoldDetail) error) error {
+	return s.eachHold(ctx, HoldFilter{}, fn)
+}
+
+// eachHold calls fn with each hold matching the filter, oldest first, as the
+// rows are read
+func (s *Postgres) eachHold(ctx context.Context, filter HoldFilter, fn func(models.HoldDetail) error) error {
+	// Queue positions are numbered over every waiting hold before filtering
+	query := `SELECT ` + holdColumns + `, b.title, q.position
+	          FROM holds h
+	          JOIN books b ON h.book_id = b.id
+	          LEFT JOIN (
+	              SELECT id, ROW_NUMBER() OVER (PARTITION BY book_id ORDER BY created_at, id)::int AS position
+	              FROM holds WHERE status = 'waiting'
+	          ) q ON q.id = h.id
+	          WHERE 1=1`
+	args := []interface{}{}
+	argCount := 1
+
+	if filter.BookID != 0 {
+		query += ` AND h.book_id = $` + strconv.Itoa(argCount)
+		args = append(args, filter.BookID)
+		argCount++
+	}
+	if filter.UserID != 0 {
+		query += ` AND h.user_id = $` + strconv.Itoa(argCount)
+		args = append(args, filter.UserID)
+		argCount++
+	}
+	switch filter.Status {
+	case "":
+	case "open":
+		query += ` AND h.status IN ('waiting', 'ready')`
+	default:
+		query += ` AND h.status = $` + strconv.Itoa(argCount)
+		args = append(args, filter.Status)
+		argCount++
+	}
+
+	query += ` ORDER BY h.created_at, h.id`
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hold models.HoldDetail
+		if err := scanHold(rows, &hold.Hold, &hold.BookTitle, &hold.Position); err != nil {
+			return err
+		}
+		if err := fn(hold); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CancelHold closes an open hold and passes a reserved copy on to the queue
+func (s *Postgres) CancelHold(ctx context.Context, id int) (models.Hold, error) {
+	var hold models.Hold
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return hold, err
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Lock the hold and check it is still open
+	var previous string
+	err = tx.QueryRow(ctx, `SELECT status FROM holds WHERE id = $1 FOR UPDATE`, id).Scan(&previous)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return hold, ErrNotFound
+		}
+		return hold, err
+	}
+	if previous != models.HoldStatusWaiting && previous != models.HoldStatusReady {
+		return hold, ErrHoldClosed
+	}
+
+	// 2. Cancel it
+	err = scanHold(tx.QueryRow(ctx, `UPDATE holds AS h SET status = 'cancelled' WHERE h.id = $1 RETURNING `+holdColumns,
+		id), &hold)
+	if err != nil {
+		return hold, err
+	}
+
+	// 3. A copy set aside for this hold goes to the next borrower in the queue
+	if previous == models.HoldStatusReady {
+		if err := promoteHolds(ctx, tx, hold.BookID); err != nil {
+			return hold, err
+		}
+	}
+
+	return hold, tx.Commit(ctx)
+}
+
+// ExpireHolds expires ready holds not picked up by their last pickup day and
+// passes the copies on to the next borrowers in each queue
+func (s *Postgres) ExpireHolds(ctx context.Context, asOf time.Time) (int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `UPDATE holds SET status = 'expired'
+		WHERE status = 'ready' AND expires_at < $1
+		RETURNING book_id`, asOf)
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	books := make(map[int]bool)
+	for rows.Next() {
+		var bookID int
+		if err := rows.Scan(&bookID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		books[bookID] = true
+		expired++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for bookID := range books {
+		if err := promoteHolds(ctx, tx, bookID); err != nil {
+			return 0, err
+		}
+	}
+
+	return expired, tx.Commit(ctx)
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"digital-library/backend/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const itemColumns = `i.id, i.book_id, i.barcode, i.status, i.condition, i.location, i.acquired_date, i.retired_at,
+	i.created_at, i.updated_at`
+
+func scanItem(row pgx.Row, item *models.BookItem) error {
+	return row.Scan(
+		&item.ID, &item.BookID, &item.Barcode, &item.Status, &item.Condition, &item.Location,
+		&item.AcquiredDate, &item.RetiredAt, &item.CreatedAt, &item.UpdatedAt,
+	)
+}
+
+// releaseItem puts the copy of a closed loan back on the shelf, or marks it
+// lost. Loans closed before copies were tracked have no copy to release.
+func releaseItem(ctx context.Context, db querier, itemID *int, condition string) error {
+	if itemID == nil {
+		return nil
+	}
+	status := models.ItemStatusAvailable
+	if condition == models.ConditionLost {
+		status = models.ItemStatusLost
+	}
+	_, err := db.Exec(ctx, `UPDATE book_items
+		SET status = $1, condition = CASE WHEN $2 = 'damaged' THEN 'damaged' ELSE condition END
+		WHERE id = $3`, status, condition, *itemID)
+	return err
+}
+
+// ListItems returns every copy of a book, oldest first
+func (s *Postgres) ListItems(ctx context.Context, bookID int) ([]models.BookItem, error) {
+	var exists bool
+	if err := s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM books WHERE id = $1)`, bookID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := s.db.Query(ctx, `SELECT `+itemColumns+` FROM book_items i WHERE i.book_id = $1
+		ORDER BY i.acquired_date, i.id`, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]models.BookItem, 0)
+	for rows.Next() {
+		var item models.BookItem
+		if err := scanItem(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// ExportItems calls fn with every copy in ID order as the rows are read
+func (s *Postgres) ExportItems(ctx context.Context, fn func(models.BookItem) error) error {
+	rows, err := s.db.Query(ctx, `SELECT `+itemColumns+` FROM book_items i ORDER BY i.id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.BookItem
+		if err := scanItem(rows, &item); err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// AddItem adds an available copy to a book and passes it on to the hold queue
+func (s *Postgres) AddItem(ctx context.Context, item *models.BookItem) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Lock the book
+	if _, _, err := unreservedCopies(ctx, tx, item.BookID); err != nil {
+		return err
+	}
+
+	// 2. Insert the copy, letting the database number it without a barcode
+	columns := `book_id, condition, location, acquired_date`
+	values := `$1, $2, $3, $4`
+	args := []interface{}{item.BookID, item.Condition, item.Location, item.AcquiredDate}
+	if item.Barcode != "" {
+		args = append(args, item.Barcode)
+		columns += `, barcode`
+		values += `, $` + strconv.Itoa(len(args))
+	}
+	err = scanItem(tx.QueryRow(ctx, `INSERT INTO book_items AS i (`+columns+`) VALUES (`+values+`)
+		RETURNING `+itemColumns, args...), item)
+	if violatesConstraint(err, "book_items_barcode_key") {
+		return ErrDuplicateBarcode
+	}
+	if err != nil {
+		return err
+	}
+
+	// 3. The new copy goes to waiting holds first
+	if err := promoteHolds(ctx, tx, item.BookID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// RetireItem withdraws an available or lost copy from the collection
+func (s *Postgres) RetireItem(ctx context.Context, bookID, itemID int) (models.BookItem, error) {
+	var item models.BookItem
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return item, err
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Lock the book and the copy
+	free, _, err := unreservedCopies(ctx, tx, bookID)
+	if err != nil {
+		return item, err
+	}
+	err = scanItem(tx.QueryRow(ctx, `SELECT `+itemColumns+` FROM book_items i
+		WHERE i.id = $1 AND i.book_id = $2 FOR UPDATE`, itemID, bookID), &item)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return item, ErrItemNotFound
+		}
+		return item, err
+	}
+
+	// 2. Copies still in use stay in the collection
+	switch item.Status {
+	case models.ItemStatusOnLoan:
+		return item, ErrItemOnLoan
+	case models.ItemStatusRetired:
+		return item, ErrItemRetired
+	case models.ItemStatusAvailable:
+		if free <= 0 {
+			return item, ErrItemReserved
+		}
+	}
+
+	// 3. Retire it
+	err = scanItem(tx.QueryRow(ctx, `UPDATE book_items AS i SET status = 'retired', retired_at = NOW()
+		WHERE i.id = $1 RETURNING `+itemColumns, itemID), &item)
+	if err != nil {
+		return item, err
+	}
+
+	return item, tx.Commit(ctx)
+}
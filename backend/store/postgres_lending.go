@@ -0,0 +1,461 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"digital-library/backend/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const lendingColumns = `lr.id, lr.book_id, lr.item_id, lr.user_id, lr.borrower_name, lr.borrow_date, lr.due_date, lr.return_date,
+	lr.loan_policy_id, lr.overdue_at, lr.return_condition, lr.renewal_count, lr.created_at, lr.updated_at`
+
+func scanLendingRecord(row pgx.Row, record *models.LendingRecord, extra ...interface{}) error {
+	dest := []interface{}{
+		&record.ID, &record.BookID, &record.ItemID, &record.UserID, &record.Borrower, &record.BorrowDate, &record.DueDate, &record.ReturnDate,
+		&record.LoanPolicyID, &record.OverdueAt, &record.ReturnCondition, &record.RenewalCount,
+		&record.CreatedAt, &record.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// GetLendingRecord returns a single lending record by ID
+func (s *Postgres) GetLendingRecord(ctx context.Context, id int) (models.LendingRecord, error) {
+	query := `SELECT ` + lendingColumns + ` FROM lending_records lr WHERE lr.id = $1`
+
+	var record models.LendingRecord
+	err := scanLendingRecord(s.db.QueryRow(ctx, query, id), &record)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return record, ErrNotFound
+	}
+	return record, err
+}
+
+// LendBook puts a copy of the book on loan and creates a lending record in one
+// transaction. The due date comes from the loan policy matching the book
+// category and the borrower's role. Copies reserved for ready holds are only
+// lent to their holders, and the borrower's own hold is marked fulfilled.
+func (s *Postgres) LendBook(ctx context.Context, bookID int, barcode string, borrower models.User, borrowDate time.Time) (models.LendingRecord, error) {
+	record := models.LendingRecord{BookID: bookID, UserID: &borrower.ID, Borrower: borrower.Username}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return record, err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback(ctx)
+
+	// 1. Check book quantity and lock the row for update
+	var currentQuantity int
+	var category string
+	err = tx.QueryRow(ctx, `SELECT quantity, COALESCE(category, '') FROM books WHERE id = $1 FOR UPDATE`, bookID).
+		Scan(&currentQuantity, &category)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return record, ErrNotFound
+		}
+		return record, err
+	}
+	if currentQuantity <= 0 {
+		return record, ErrOutOfStock
+	}
+
+	// 2. Copies set aside for ready holds may only go to their holders
+	var holdID int
+	var holdStatus string
+	err = tx.QueryRow(ctx, `SELECT id, status FROM holds
+		WHERE book_id = $1 AND user_id = $2 AND status IN ('waiting', 'ready')
+		FOR UPDATE`, bookID, borrower.ID).Scan(&holdID, &holdStatus)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return record, err
+	}
+	if holdStatus != models.HoldStatusReady {
+		var reserved int
+		err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM holds WHERE book_id = $1 AND status = 'ready'`, bookID).Scan(&reserved)
+		if err != nil {
+			return record, err
+		}
+		if currentQuantity <= reserved {
+			return record, ErrOnHold
+		}
+	}
+
+	// 3. Pick the copy and put it on loan, which also lowers the book quantity
+	itemID, err := availableItem(ctx, tx, bookID, barcode)
+	if err != nil {
+		return record, err
+	}
+	_, err = tx.Exec(ctx, `UPDATE book_items SET status = 'on_loan' WHERE id = $1`, itemID)
+	if err != nil {
+		return record, err
+	}
+
+	// 4. Resolve the loan policy
+	policy, err := resolveLoanPolicy(ctx, tx, category, borrower.Role)
+	if err != nil {
+		return record, err
+	}
+	var policyID *int
+	if policy.ID != 0 {
+		policyID = &policy.ID
+	}
+
+	// 5. Create lending record
+	insertQuery := `INSERT INTO lending_records AS lr (book_id, item_id, user_id, borrower_name, borrow_date, due_date, loan_policy_id) 
+	                VALUES ($1, $2, $3, $4, $5, $6, $7) 
+	                RETURNING ` + lendingColumns
+	err = scanLendingRecord(tx.QueryRow(ctx, insertQuery,
+		bookID, itemID, borrower.ID, borrower.Username, borrowDate, dueDate(borrowDate, policy), policyID), &record)
+	if err != nil {
+		return record, err
+	}
+
+	// 6. The borrower's hold on the book is fulfilled by this loan
+	if holdID != 0 {
+		_, err = tx.Exec(ctx, `UPDATE holds SET status = 'fulfilled' WHERE id = $1`, holdID)
+		if err != nil {
+			return record, err
+		}
+	}
+
+	return record, tx.Commit(ctx)
+}
+
+// availableItem locks the available copy of a book with the given barcode,
+// or the longest-held available copy when barcode is empty
+func availableItem(ctx context.Context, db querier, bookID int, barcode string) (int, error) {
+	if barcode == "" {
+		var id int
+		err := db.QueryRow(ctx, `SELECT id FROM book_items
+			WHERE book_id = $1 AND status = 'available'
+			ORDER BY acquired_date, id
+			LIMIT 1
+			FOR UPDATE`, bookID).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrOutOfStock
+		}
+		return id, err
+	}
+
+	var id, itemBookID int
+	var status string
+	err := db.QueryRow(ctx, `SELECT id, book_id, status FROM book_items WHERE barcode = $1 FOR UPDATE`, barcode).
+		Scan(&id, &itemBookID, &status)
+	if errors.Is(err, pgx.ErrNoRows) || err == nil && itemBookID != bookID {
+		return 0, ErrItemNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if status != models.ItemStatusAvailable {
+		return 0, ErrItemUnavailable
+	}
+	return id, nil
+}
+
+// ReturnBook closes a loan, gives the copy back to the book unless it was
+// lost and charges any fines due under the loan's policy, all in one transaction
+func (s *Postgres) ReturnBook(ctx context.Context, id int, returnDate time.Time, condition string) ([]models.FineEntry, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Close the lending record
+	var record models.LendingRecord
+	updateQuery := `UPDATE lending_records AS lr
+	                SET return_date = $1, return_condition = $2, updated_at = NOW() 
+	                WHERE id = $3 AND return_date IS NULL -- Only update if not already returned
+	                RETURNING ` + lendingColumns
+	err = scanLendingRecord(tx.QueryRow(ctx, updateQuery, returnDate, condition, id), &record)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Either the record doesn't exist or it was already returned
+			var returned bool
+			checkQuery := `SELECT EXISTS(SELECT 1 FROM lending_records WHERE id = $1 AND return_date IS NOT NULL)`
+			if errCheck := tx.QueryRow(ctx, checkQuery, id).Scan(&returned); errCheck == nil && returned {
+				return nil, ErrAlreadyReturned
+			}
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	// 2. Put the copy back on the shelf unless it is gone, and set it aside
+	// for the next hold in the queue
+	if err := releaseItem(ctx, tx, record.ItemID, condition); err != nil {
+		return nil, err
+	}
+	if condition != models.ConditionLost {
+		if err := promoteHolds(ctx, tx, record.BookID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. Charge fines under the policy the loan was made with
+	policy, err := loanPolicyByID(ctx, tx, record.LoanPolicyID)
+	if err != nil {
+		return nil, err
+	}
+	charges := fineCharges(record, policy, returnDate, condition)
+	for i := range charges {
+		if err := insertFineEntry(ctx, tx, &charges[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return charges, tx.Commit(ctx)
+}
+
+// RenewLoan extends the due date of an active loan under its loan policy
+func (s *Postgres) RenewLoan(ctx context.Context, id int, asOf time.Time) (models.LendingRecord, error) {
+	var record models.LendingRecord
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return record, err
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Lock the record and check it can be renewed
+	err = scanLendingRecord(tx.QueryRow(ctx, `SELECT `+lendingColumns+` FROM lending_records lr WHERE lr.id = $1 FOR UPDATE`, id), &record)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return record, ErrNotFound
+		}
+		return record, err
+	}
+	if record.ReturnDate != nil {
+		return record, ErrAlreadyReturned
+	}
+	if record.DueDate.Before(asOf) {
+		return record, ErrLoanOverdue
+	}
+	policy, err := loanPolicyByID(ctx, tx, record.LoanPolicyID)
+	if err != nil {
+		return record, err
+	}
+	if record.RenewalCount >= policy.MaxRenewals {
+		return record, ErrRenewalLimit
+	}
+
+	// 2. Other borrowers waiting for the book take precedence
+	var held bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM holds
+		WHERE book_id = $1 AND status IN ('waiting', 'ready') AND user_id IS DISTINCT FROM $2)`,
+		record.BookID, record.UserID).Scan(&held)
+	if err != nil {
+		return record, err
+	}
+	if held {
+		return record, ErrPendingHolds
+	}
+
+	// 3. Extend the due date
+	err = scanLendingRecord(tx.QueryRow(ctx, `UPDATE lending_records AS lr
+		SET due_date = $1, renewal_count = renewal_count + 1, updated_at = NOW()
+		WHERE lr.id = $2
+		RETURNING `+lendingColumns, renewedDueDate(record, asOf, policy), id), &record)
+	if err != nil {
+		return record, err
+	}
+
+	return record, tx.Commit(ctx)
+}
+
+// ListLendingRecords returns a page of lending records joined with their book,
+// newest loan first unless another sort is requested
+func (s *Postgres) ListLendingRecords(ctx context.Context, filter LendingFilter, page PageRequest) (models.LendingRecordPage, error) {
+	result := models.LendingRecordPage{Data: make([]models.LendingRecordDetail, 0)}
+	q, err := lendingListing.resolve(page)
+	if err != nil {
+		return result, err
+	}
+	sel := selectLendingRecords(filter)
+
+	// Count every match, then fetch the page after the cursor
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*)`+sel.from+sel.where, sel.args...).Scan(&result.Total); err != nil {
+		return result, err
+	}
+	err = s.eachLendingRecord(ctx, sel, q, func(record models.LendingRecordDetail) error {
+		result.Data = append(result.Data, record)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if q.limit > 0 && len(result.Data) > q.limit {
+		result.Data = result.Data[:q.limit]
+		last := result.Data[q.limit-1]
+		result.NextCursor = q.nextCursor(lendingSortValue(last, q.sort), last.ID)
+	}
+	return result, nil
+}
+
+// ExportLendingRecords calls fn with every lending record ListLendingRecords
+// would list for the filter, in the order of the requested sort, as the rows
+// are read
+func (s *Postgres) ExportLendingRecords(ctx context.Context, filter LendingFilter, page PageRequest, fn func(models.LendingRecordDetail) error) error {
+	page.Limit, page.Cursor, page.Offset = 0, "", 0
+	q, err := lendingListing.resolve(page)
+	if err != nil {
+		return err
+	}
+	return s.eachLendingRecord(ctx, selectLendingRecords(filter), q, fn)
+}
+
+// lendingSelect is the query selecting the lending records that match a
+// filter
+type lendingSelect struct {
+	from, where string
+	args        []interface{}
+	argCount    int
+}
+
+// selectLendingRecords builds the query selecting the lending records that
+// match the filter
+func selectLendingRecords(filter LendingFilter) lendingSelect {
+	sel := lendingSelect{
+		from: ` FROM lending_records lr
+	          JOIN books b ON lr.book_id = b.id
+	          LEFT JOIN book_items i ON lr.item_id = i.id
+	          WHERE 1=1`,
+		args:     []interface{}{},
+		argCount: 1,
+	}
+
+	// Add search condition (matches borrower name or book title)
+	if filter.Search != "" {
+		sel.where += ` AND (LOWER(lr.borrower_name) LIKE LOWER($` + strconv.Itoa(sel.argCount) + `) OR LOWER(b.title) LIKE LOWER($` + strconv.Itoa(sel.argCount) + `))`
+		sel.args = append(sel.args, "%"+filter.Search+"%")
+		sel.argCount++
+	}
+
+	// Add borrower account filter
+	if filter.UserID != 0 {
+		sel.where += ` AND lr.user_id = $` + strconv.Itoa(sel.argCount)
+		sel.args = append(sel.args, filter.UserID)
+		sel.argCount++
+	}
+
+	// Add status filter
+	switch filter.Status {
+	case models.LendingStatusActive:
+		sel.where += ` AND lr.return_date IS NULL`
+	case models.LendingStatusOverdue:
+		sel.where += ` AND lr.return_date IS NULL AND lr.due_date < $` + strconv.Itoa(sel.argCount)
+		sel.args = append(sel.args, today())
+		sel.argCount++
+	case models.LendingStatusReturned:
+		sel.where += ` AND lr.return_date IS NOT NULL AND lr.return_condition IS DISTINCT FROM 'lost'`
+	case models.LendingStatusLost:
+		sel.where += ` AND lr.return_condition = 'lost'`
+	}
+
+	// Add due date range filters
+	if filter.DueBefore != nil {
+		sel.where += ` AND lr.due_date <= $` + strconv.Itoa(sel.argCount)
+		sel.args = append(sel.args, *filter.DueBefore)
+		sel.argCount++
+	}
+	if filter.DueAfter != nil {
+		sel.where += ` AND lr.due_date >= $` + strconv.Itoa(sel.argCount)
+		sel.args = append(sel.args, *filter.DueAfter)
+		sel.argCount++
+	}
+
+	// Add book title filter
+	if filter.BookTitle != "" {
+		sel.where += ` AND LOWER(b.title) = LOWER($` + strconv.Itoa(sel.argCount) + `)`
+		sel.args = append(sel.args, filter.BookTitle)
+		sel.argCount++
+	}
+	return sel
+}
+
+// eachLendingRecord calls fn with each selected lending record after the
+// cursor, in order, as the rows are read
+func (s *Postgres) eachLendingRecord(ctx context.Context, sel lendingSelect, q pageQuery, fn func(models.LendingRecordDetail) error) error {
+	after, order, pageArgs := q.sql(lendingListing, sel.argCount)
+	rows, err := s.db.Query(ctx, `SELECT `+lendingColumns+`, 
+	            b.title AS book_title, b.author AS book_author, i.barcode`+sel.from+sel.where+after+order,
+		append(sel.args, pageArgs...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	now := today()
+	for rows.Next() {
+		var record models.LendingRecordDetail
+		err := scanLendingRecord(rows, &record.LendingRecord, &record.BookTitle, &record.BookAuthor, &record.ItemBarcode)
+		if err != nil {
+			return err
+		}
+		record.Status = record.LendingStatus(now)
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// DeleteLendingRecord removes a lending record, putting the copy back on the
+// shelf if the book had not been returned yet
+func (s *Postgres) DeleteLendingRecord(ctx context.Context, id int) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// 1. Delete the record and find out whether the book was still out
+	var bookID int
+	var itemID *int
+	var returnDate *time.Time
+	err = tx.QueryRow(ctx, `DELETE FROM lending_records WHERE id = $1 RETURNING book_id, item_id, return_date`, id).
+		Scan(&bookID, &itemID, &returnDate)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	// 2. If the book was *not* returned, the copy is back on the shelf
+	if returnDate == nil {
+		if err := releaseItem(ctx, tx, itemID, models.ConditionGood); err != nil {
+			return err
+		}
+		if err := promoteHolds(ctx, tx, bookID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// HasActiveLoan reports whether a user has an unreturned loan of a book
+func (s *Postgres) HasActiveLoan(ctx context.Context, userID, bookID int) (bool, error) {
+	var active bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM lending_records
+		WHERE user_id = $1 AND book_id = $2 AND return_date IS NULL)`, userID, bookID).Scan(&active)
+	return active, err
+}
+
+// MarkOverdueLoans flags unreturned loans due before asOf that have not been flagged yet
+func (s *Postgres) MarkOverdueLoans(ctx context.Context, asOf time.Time) (int, error) {
+	tag, err := s.db.Exec(ctx, `UPDATE lending_records
+		SET overdue_at = NOW()
+		WHERE return_date IS NULL AND overdue_at IS NULL AND due_date < $1`, asOf)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
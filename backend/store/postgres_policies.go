@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"digital-library/backend/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const loanPolicyColumns = `id, category, role, loan_days, max_renewals, grace_days,
+	daily_fine_cents, lost_fee_cents, damaged_fee_cents, hold_pickup_days, created_at, updated_at`
+
+func scanLoanPolicy(row pgx.Row, policy *models.LoanPolicy) error {
+	return row.Scan(
+		&policy.ID, &policy.Category, &policy.Role, &policy.LoanDays,
+		&policy.MaxRenewals, &policy.GraceDays,
+		&policy.DailyFineCents, &policy.LostFeeCents, &policy.DamagedFeeCents, &policy.HoldPickupDays,
+		&policy.CreatedAt, &policy.UpdatedAt,
+	)
+}
+
+// queryLoanPolicies runs a query returning loan policy rows
+func queryLoanPolicies(ctx context.Context, db querier, query string, args ...interface{}) ([]models.LoanPolicy, error) {
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := make([]models.LoanPolicy, 0)
+	for rows.Next() {
+		var policy models.LoanPolicy
+		if err := scanLoanPolicy(rows, &policy); err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// loanPolicyOrder lists catch-all policies first
+const loanPolicyOrder = ` ORDER BY category NULLS FIRST, role NULLS FIRST, id`
+
+// ListLoanPolicies returns every loan policy, catch-all policies first
+func (s *Postgres) ListLoanPolicies(ctx context.Context) ([]models.LoanPolicy, error) {
+	return queryLoanPolicies(ctx, s.db, `SELECT `+loanPolicyColumns+` FROM loan_policies`+loanPolicyOrder)
+}
+
+// ExportLoanPolicies calls fn with every loan policy, catch-all policies
+// first, as the rows are read
+func (s *Postgres) ExportLoanPolicies(ctx context.Context, fn func(models.LoanPolicy) error) error {
+	rows, err := s.db.Query(ctx, `SELECT `+loanPolicyColumns+` FROM loan_policies`+loanPolicyOrder)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var policy models.LoanPolicy
+		if err := scanLoanPolicy(rows, &policy); err != nil {
+			return err
+		}
+		if err := fn(policy); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CreateLoanPolicy inserts the policy and fills in its generated fields
+func (s *Postgres) CreateLoanPolicy(ctx context.Context, policy *models.LoanPolicy) error {
+	query := `INSERT INTO loan_policies (category, role, loan_days, max_renewals, grace_days,
+	              daily_fine_cents, lost_fee_cents, damaged_fee_cents, hold_pickup_days)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	          RETURNING id, created_at, updated_at`
+
+	err := s.db.QueryRow(ctx, query,
+		policy.Category, policy.Role, policy.LoanDays, policy.MaxRenewals, policy.GraceDays,
+		policy.DailyFineCents, policy.LostFeeCents, policy.DamagedFeeCents, policy.HoldPickupDays).
+		Scan(&policy.ID, &policy.CreatedAt, &policy.UpdatedAt)
+	if violatesConstraint(err, "loan_policies_scope_key") {
+		return ErrDuplicatePolicy
+	}
+	return err
+}
+
+// UpdateLoanPolicy replaces a policy and returns the stored row
+func (s *Postgres) UpdateLoanPolicy(ctx context.Context, id int, policy models.LoanPolicy) (models.LoanPolicy, error) {
+	query := `UPDATE loan_policies
+	          SET category = $1, role = $2, loan_days = $3, max_renewals = $4, grace_days = $5,
+	              daily_fine_cents = $6, lost_fee_cents = $7, damaged_fee_cents = $8, hold_pickup_days = $9
+	          WHERE id = $10
+	          RETURNING ` + loanPolicyColumns
+
+	var updated models.LoanPolicy
+	err := scanLoanPolicy(s.db.QueryRow(ctx, query,
+		policy.Category, policy.Role, policy.LoanDays, policy.MaxRenewals, policy.GraceDays,
+		policy.DailyFineCents, policy.LostFeeCents, policy.DamagedFeeCents, policy.HoldPickupDays, id), &updated)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return updated, ErrNotFound
+	case violatesConstraint(err, "loan_policies_scope_key"):
+		return updated, ErrDuplicatePolicy
+	}
+	return updated, err
+}
+
+// DeleteLoanPolicy removes a policy; loans made under it keep their due dates
+func (s *Postgres) DeleteLoanPolicy(ctx context.Context, id int) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM loan_policies WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ResolveLoanPolicy returns the most specific policy for a category and role
+func (s *Postgres) ResolveLoanPolicy(ctx context.Context, category, role string) (models.LoanPolicy, error) {
+	return resolveLoanPolicy(ctx, s.db, category, role)
+}
+
+func resolveLoanPolicy(ctx context.Context, db querier, category, role string) (models.LoanPolicy, error) {
+	policies, err := queryLoanPolicies(ctx, db, `SELECT `+loanPolicyColumns+` FROM loan_policies
+		WHERE (category IS NULL OR LOWER(category) = LOWER($1))
+		  AND (role IS NULL OR role = $2)`, category, role)
+	if err != nil {
+		return models.LoanPolicy{}, err
+	}
+	return mostSpecificPolicy(policies, category, role), nil
+}
+
+// loanPolicyByID returns the policy a loan was made under, falling back to
+// models.DefaultLoanPolicy when the loan has none or it was deleted
+func loanPolicyByID(ctx context.Context, db querier, id *int) (models.LoanPolicy, error) {
+	policy := models.DefaultLoanPolicy
+	if id == nil {
+		return policy, nil
+	}
+	err := scanLoanPolicy(db.QueryRow(ctx, `SELECT `+loanPolicyColumns+` FROM loan_policies WHERE id = $1`, *id), &policy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.DefaultLoanPolicy, nil
+	}
+	return policy, err
+}
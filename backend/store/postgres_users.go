@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"digital-library/backend/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const userColumns = `id, username, email, role, card_number, created_at, updated_at`
+
+func scanUser(row pgx.Row, user *models.User, extra ...interface{}) error {
+	dest := []interface{}{
+		&user.ID, &user.Username, &user.Email, &user.Role, &user.CardNumber, &user.CreatedAt, &user.UpdatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// CreateUser inserts a new user with an already hashed password. The patron
+// card number is assigned by the database.
+func (s *Postgres) CreateUser(ctx context.Context, username, passwordHash, email, role string) (models.User, error) {
+	query := `INSERT INTO users (username, password_hash, email, role) 
+	          VALUES ($1, $2, $3, $4) 
+	          RETURNING ` + userColumns
+
+	var user models.User
+	err := scanUser(s.db.QueryRow(ctx, query, username, passwordHash, email, role), &user)
+	switch {
+	case violatesConstraint(err, "users_username_key"):
+		return user, ErrDuplicateUsername
+	case violatesConstraint(err, "users_email_key"):
+		return user, ErrDuplicateEmail
+	}
+	return user, err
+}
+
+// GetUserByLogin looks a user up by username or email
+func (s *Postgres) GetUserByLogin(ctx context.Context, login string) (models.User, string, error) {
+	query := `SELECT ` + userColumns + `, password_hash 
+	          FROM users WHERE username = $1 OR email = $1`
+
+	var user models.User
+	var passwordHash string
+	err := scanUser(s.db.QueryRow(ctx, query, login), &user, &passwordHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return user, "", ErrNotFound
+	}
+	return user, passwordHash, err
+}
+
+// GetUser returns a single user by ID
+func (s *Postgres) GetUser(ctx context.Context, id int) (models.User, error) {
+	return s.getUser(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id)
+}
+
+// GetUserByCardNumber returns the user holding a patron card
+func (s *Postgres) GetUserByCardNumber(ctx context.Context, cardNumber string) (models.User, error) {
+	return s.getUser(ctx, `SELECT `+userColumns+` FROM users WHERE card_number = $1`, cardNumber)
+}
+
+func (s *Postgres) getUser(ctx context.Context, query string, arg interface{}) (models.User, error) {
+	var user models.User
+	err := scanUser(s.db.QueryRow(ctx, query, arg), &user)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return user, ErrNotFound
+	}
+	return user, err
+}
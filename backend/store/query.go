@@ -0,0 +1,164 @@
+package store
+
+import (
+	"strconv"
+	"strings"
+
+	"digital-library/backend/models"
+	"digital-library/backend/query"
+)
+
+// bookQueryColumns are the text columns of books each query field compares
+var bookQueryColumns = map[string]string{
+	query.FieldTitle:    "title",
+	query.FieldAuthor:   "author",
+	query.FieldCategory: "COALESCE(category, '')",
+	query.FieldISBN:     "isbn",
+}
+
+// bookQueryFields are the fields a term without a field searches
+var bookQueryFields = []string{query.FieldTitle, query.FieldAuthor, query.FieldCategory, query.FieldISBN}
+
+// predicate builds a parameterized SQL predicate from a query. Values only
+// ever reach the SQL as arguments; columns come from bookQueryColumns.
+type predicate struct {
+	args     []interface{}
+	argCount int
+}
+
+// bookQuerySQL returns the SQL predicate over books for a query and its
+// arguments, numbered from argCount
+func bookQuerySQL(node query.Node, argCount int) (string, []interface{}) {
+	p := &predicate{argCount: argCount}
+	return p.sql(node), p.args
+}
+
+func (p *predicate) sql(node query.Node) string {
+	switch n := node.(type) {
+	case *query.And:
+		return `(` + p.sql(n.Left) + ` AND ` + p.sql(n.Right) + `)`
+	case *query.Or:
+		return `(` + p.sql(n.Left) + ` OR ` + p.sql(n.Right) + `)`
+	case *query.Not:
+		return `NOT ` + p.sql(n.Node)
+	case *query.Term:
+		switch n.Field {
+		case query.FieldAvailable:
+			if n.Value == "true" {
+				return `quantity > 0`
+			}
+			return `quantity = 0`
+		case query.FieldAny:
+			conditions := make([]string, len(bookQueryFields))
+			for i, field := range bookQueryFields {
+				conditions[i] = p.compare(bookQueryColumns[field], n)
+			}
+			return `(` + strings.Join(conditions, ` OR `) + `)`
+		}
+		return p.compare(bookQueryColumns[n.Field], n)
+	}
+	return `TRUE`
+}
+
+// compare returns the condition comparing a column with the value of a term
+func (p *predicate) compare(column string, term *query.Term) string {
+	var condition string
+	switch term.Match {
+	case query.MatchEquals:
+		condition = `LOWER(` + column + `) = LOWER($` + strconv.Itoa(p.argCount) + `)`
+		p.args = append(p.args, term.Value)
+	case query.MatchPattern:
+		condition = `LOWER(` + column + `) LIKE LOWER($` + strconv.Itoa(p.argCount) + `) ESCAPE '\'`
+		p.args = append(p.args, likePattern(term.Value))
+	default:
+		condition = `LOWER(` + column + `) LIKE LOWER($` + strconv.Itoa(p.argCount) + `) ESCAPE '\'`
+		p.args = append(p.args, "%"+escapeLike(term.Value)+"%")
+	}
+	p.argCount++
+	return condition
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// likePattern turns a query pattern, where * stands for any text, into a
+// LIKE pattern
+func likePattern(pattern string) string {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = escapeLike(part)
+	}
+	return strings.Join(parts, "%")
+}
+
+// matchBookQuery evaluates a query against a book the way bookQuerySQL's
+// predicate would
+func matchBookQuery(node query.Node, book models.Book) bool {
+	switch n := node.(type) {
+	case *query.And:
+		return matchBookQuery(n.Left, book) && matchBookQuery(n.Right, book)
+	case *query.Or:
+		return matchBookQuery(n.Left, book) || matchBookQuery(n.Right, book)
+	case *query.Not:
+		return !matchBookQuery(n.Node, book)
+	case *query.Term:
+		switch n.Field {
+		case query.FieldAvailable:
+			return (book.Quantity > 0) == (n.Value == "true")
+		case query.FieldAny:
+			for _, field := range bookQueryFields {
+				if matchTerm(bookField(book, field), n) {
+					return true
+				}
+			}
+			return false
+		}
+		return matchTerm(bookField(book, n.Field), n)
+	}
+	return true
+}
+
+// bookField returns the text of a query field of a book
+func bookField(book models.Book, field string) string {
+	switch field {
+	case query.FieldTitle:
+		return book.Title
+	case query.FieldAuthor:
+		return book.Author
+	case query.FieldCategory:
+		return book.Category
+	}
+	return book.ISBN
+}
+
+// matchTerm compares a field value with the value of a term, ignoring case
+func matchTerm(value string, term *query.Term) bool {
+	value, want := strings.ToLower(value), strings.ToLower(term.Value)
+	switch term.Match {
+	case query.MatchEquals:
+		return value == want
+	case query.MatchPattern:
+		return matchPattern(value, strings.Split(want, "*"))
+	}
+	return strings.Contains(value, want)
+}
+
+// matchPattern reports whether s consists of the parts in order, with any
+// text between them
+func matchPattern(s string, parts []string) bool {
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return last == 0 && s == "" || last > 0 && strings.HasSuffix(s, parts[last])
+}
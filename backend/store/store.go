@@ -0,0 +1,270 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"digital-library/backend/models"
+	"digital-library/backend/query"
+)
+
+// Errors returned by every store implementation so handlers can map them to
+// HTTP responses without knowing which backend is in use.
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrDuplicateISBN     = errors.New("duplicate isbn")
+	ErrDuplicateUsername = errors.New("duplicate username")
+	ErrDuplicateEmail    = errors.New("duplicate email")
+	ErrOutOfStock        = errors.New("book out of stock")
+	ErrAlreadyReturned   = errors.New("book already returned")
+	ErrDuplicatePolicy   = errors.New("a loan policy already exists for this category and role")
+	ErrOnHold            = errors.New("available copies are held for other borrowers")
+	ErrBookAvailable     = errors.New("book has copies available")
+	ErrDuplicateHold     = errors.New("borrower already holds this book")
+	ErrHoldClosed        = errors.New("hold is no longer open")
+	ErrRenewalLimit      = errors.New("renewal limit reached")
+	ErrPendingHolds      = errors.New("book has pending holds")
+	ErrLoanOverdue       = errors.New("loan is overdue")
+	ErrItemNotFound      = errors.New("copy not found")
+	ErrItemUnavailable   = errors.New("copy is not available")
+	ErrItemOnLoan        = errors.New("copy is on loan")
+	ErrItemReserved      = errors.New("copy is set aside for a hold")
+	ErrItemRetired       = errors.New("copy is already retired")
+	ErrDuplicateBarcode  = errors.New("duplicate barcode")
+	ErrFileNotFound      = errors.New("file not found")
+	ErrInvalidSort       = errors.New("invalid sort")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+)
+
+// BookFilter holds the optional filters accepted when listing books
+type BookFilter struct {
+	Search    string // Full-text query over title, author, category and ISBN, in web search syntax
+	Category  string
+	Author    string
+	Available *bool      // nil means no availability filter
+	Query     query.Node // Parsed catalog query; nil means no query
+	IDs       []int      // Selected books; nil means every book
+
+	// Facets lists the facets from BookFacets to count over every matching
+	// book. It does not narrow the listing.
+	Facets []string
+}
+
+// ChangeFilter selects the book changes a metadata harvester asks for
+type ChangeFilter struct {
+	From     *time.Time // Changed at or after this time
+	Before   *time.Time // Changed before this time
+	Category string     // Empty means every category
+
+	// Position to continue after, from the last change of the previous
+	// page; a zero AfterID starts from the first change
+	AfterDatestamp time.Time
+	AfterID        int
+}
+
+// LendingFilter holds the optional filters accepted when listing lending records
+type LendingFilter struct {
+	Search    string // Matches borrower name or book title
+	UserID    int    // Zero means every borrower
+	Status    string // "active" (not returned), "overdue", "returned" or "lost"
+	BookTitle string
+	DueBefore *time.Time // Due on or before this date
+	DueAfter  *time.Time // Due on or after this date
+}
+
+// HoldFilter holds the optional filters accepted when listing holds
+type HoldFilter struct {
+	BookID int    // Zero means every book
+	UserID int    // Zero means every borrower
+	Status string // A hold status, or "open" for waiting and ready holds
+}
+
+// AnalyticsScope limits analytics queries to the lending history of a single
+// user account unless All is set
+type AnalyticsScope struct {
+	All    bool
+	UserID int
+}
+
+// BookStore manages the book catalog
+type BookStore interface {
+	// ListBooks returns a page of the books matching the filter. Invalid
+	// sorts and cursors give ErrInvalidSort and ErrInvalidCursor.
+	ListBooks(ctx context.Context, filter BookFilter, page PageRequest) (models.BookPage, error)
+	// ExportBooks calls fn with every book ListBooks would list for the
+	// filter, across all pages in the order of the page's sort, reading the
+	// books as fn takes them. It stops at the first error fn returns.
+	ExportBooks(ctx context.Context, filter BookFilter, page PageRequest, fn func(models.Book) error) error
+	GetBook(ctx context.Context, id int) (models.Book, error)
+	GetBookByISBN(ctx context.Context, isbn string) (models.Book, error)
+	CreateBook(ctx context.Context, book *models.Book) error
+	UpdateBook(ctx context.Context, id int, book models.Book) (models.Book, error)
+	DeleteBook(ctx context.Context, id int) error
+	SuggestBooks(ctx context.Context, query string, limit int) ([]models.BookSuggestion, error)
+	// ListFacetValues returns up to limit values of the category or author
+	// facet that sort after the given value, in order, with the number of
+	// books with each. Books without a category are not counted.
+	ListFacetValues(ctx context.Context, facet, after string, limit int) ([]models.FacetBucket, error)
+	// ListBookChanges returns up to limit of the latest changes to books
+	// matching the filter, deleted books included, in order of their
+	// datestamp and then book ID
+	ListBookChanges(ctx context.Context, filter ChangeFilter, limit int) ([]models.BookChange, error)
+	// GetBookChange returns the latest change to a book, which may be its
+	// deletion. Books that never existed give ErrNotFound.
+	GetBookChange(ctx context.Context, id int) (models.BookChange, error)
+	// EarliestBookChange returns the datestamp of the oldest change
+	// ListBookChanges can return, or the current time when there is none
+	EarliestBookChange(ctx context.Context) (time.Time, error)
+	// ImportBooks upserts a batch of books by ISBN in one transaction and
+	// returns the outcome of each in order. New books get Quantity copies;
+	// existing books keep their copies and only take the title, author and
+	// non-empty category of the import. With a dryRun nothing is saved;
+	// dryRun tracks what would have been for the later batches of the import.
+	ImportBooks(ctx context.Context, books []models.Book, dryRun *ImportDryRun) ([]models.ImportRow, error)
+}
+
+// LendingStore manages lending records and the status of the copies lent
+type LendingStore interface {
+	GetLendingRecord(ctx context.Context, id int) (models.LendingRecord, error)
+	// LendBook lends the copy with the given barcode, or any available copy
+	// of the book when barcode is empty
+	LendBook(ctx context.Context, bookID int, barcode string, borrower models.User, borrowDate time.Time) (models.LendingRecord, error)
+	// ReturnBook closes a loan with the copy in the given condition (see
+	// models.Condition*) and returns the fines charged for it. Lost copies are
+	// not given back to the book.
+	ReturnBook(ctx context.Context, id int, returnDate time.Time, condition string) ([]models.FineEntry, error)
+	// RenewLoan extends the due date of an active loan by the loan period of
+	// its policy, counted from the later of the current due date and asOf.
+	// Overdue loans, loans at the policy's renewal limit and books other
+	// borrowers hold cannot be renewed.
+	RenewLoan(ctx context.Context, id int, asOf time.Time) (models.LendingRecord, error)
+	// ListLendingRecords returns a page of the lending records matching the
+	// filter, newest loan first by default
+	ListLendingRecords(ctx context.Context, filter LendingFilter, page PageRequest) (models.LendingRecordPage, error)
+	// ExportLendingRecords calls fn with every lending record
+	// ListLendingRecords would list for the filter, like ExportBooks
+	ExportLendingRecords(ctx context.Context, filter LendingFilter, page PageRequest, fn func(models.LendingRecordDetail) error) error
+	DeleteLendingRecord(ctx context.Context, id int) error
+	// HasActiveLoan reports whether a user has borrowed a book and not
+	// returned it yet
+	HasActiveLoan(ctx context.Context, userID, bookID int) (bool, error)
+	// MarkOverdueLoans flags unreturned loans due before asOf that have not
+	// been flagged yet and returns how many were flagged
+	MarkOverdueLoans(ctx context.Context, asOf time.Time) (int, error)
+}
+
+// ItemStore manages the physical copies of books. A book's quantity is the
+// number of its available copies.
+type ItemStore interface {
+	// ListItems returns every copy of a book, retired ones included
+	ListItems(ctx context.Context, bookID int) ([]models.BookItem, error)
+	// ExportItems calls fn with every copy of every book in ID order, like
+	// ExportBooks
+	ExportItems(ctx context.Context, fn func(models.BookItem) error) error
+	// AddItem adds an available copy to a book and fills in its generated
+	// fields; an empty barcode is generated
+	AddItem(ctx context.Context, item *models.BookItem) error
+	// RetireItem withdraws an available or lost copy from the collection.
+	// Copies on loan or set aside for a ready hold cannot be retired.
+	RetireItem(ctx context.Context, bookID, itemID int) (models.BookItem, error)
+}
+
+// FileStore records the digital files of books. The content of the files is
+// kept in a blob store under their storage keys.
+type FileStore interface {
+	// ListBookFiles returns the files of a book, oldest first
+	ListBookFiles(ctx context.Context, bookID int) ([]models.BookFile, error)
+	// ListFilesOfBooks returns the files of several books, oldest first
+	ListFilesOfBooks(ctx context.Context, bookIDs []int) ([]models.BookFile, error)
+	// GetBookFile returns a file of a book. Missing books give ErrNotFound
+	// and missing files ErrFileNotFound.
+	GetBookFile(ctx context.Context, bookID, fileID int) (models.BookFile, error)
+	// CreateBookFile records a file stored under its storage key and fills
+	// in its generated fields
+	CreateBookFile(ctx context.Context, file *models.BookFile) error
+	// DeleteBookFile removes the record of a file and returns it, so its
+	// content can be deleted
+	DeleteBookFile(ctx context.Context, bookID, fileID int) (models.BookFile, error)
+}
+
+// LoanPolicyStore manages the loan policies applied when books are lent
+type LoanPolicyStore interface {
+	ListLoanPolicies(ctx context.Context) ([]models.LoanPolicy, error)
+	// ExportLoanPolicies calls fn with every loan policy in the order of
+	// ListLoanPolicies, like ExportBooks
+	ExportLoanPolicies(ctx context.Context, fn func(models.LoanPolicy) error) error
+	CreateLoanPolicy(ctx context.Context, policy *models.LoanPolicy) error
+	UpdateLoanPolicy(ctx context.Context, id int, policy models.LoanPolicy) (models.LoanPolicy, error)
+	DeleteLoanPolicy(ctx context.Context, id int) error
+	// ResolveLoanPolicy returns the most specific policy for a book category
+	// and borrower role, falling back to models.DefaultLoanPolicy
+	ResolveLoanPolicy(ctx context.Context, category, role string) (models.LoanPolicy, error)
+}
+
+// HoldStore manages the hold queues. Ready holds reserve one of the book's
+// available copies, which LendBook only lends to the holder.
+type HoldStore interface {
+	GetHold(ctx context.Context, id int) (models.Hold, error)
+	// PlaceHold queues the borrower for a book with no unreserved copies
+	PlaceHold(ctx context.Context, bookID int, borrower models.User) (models.Hold, error)
+	// ListHolds returns holds oldest first
+	ListHolds(ctx context.Context, filter HoldFilter) ([]models.HoldDetail, error)
+	// ExportHolds calls fn with every hold oldest first, like ExportBooks
+	ExportHolds(ctx context.Context, fn func(models.HoldDetail) error) error
+	// CancelHold closes an open hold, passing a reserved copy on to the next
+	// borrower in the queue
+	CancelHold(ctx context.Context, id int) (models.Hold, error)
+	// ExpireHolds expires ready holds whose pickup window ended before asOf
+	// and returns how many expired
+	ExpireHolds(ctx context.Context, asOf time.Time) (int, error)
+}
+
+// FineStore manages the fines ledger of user accounts
+type FineStore interface {
+	// ListFineEntries returns the ledger of one user, or of everyone when
+	// userID is zero, oldest entry first
+	ListFineEntries(ctx context.Context, userID int) ([]models.FineEntry, error)
+	// ExportFineEntries calls fn with every entry of the ledger oldest
+	// first, like ExportBooks
+	ExportFineEntries(ctx context.Context, fn func(models.FineEntry) error) error
+	FineBalance(ctx context.Context, userID int) (models.FineBalance, error)
+	AddFineEntry(ctx context.Context, entry *models.FineEntry) error
+}
+
+// UserStore manages user accounts
+type UserStore interface {
+	CreateUser(ctx context.Context, username, passwordHash, email, role string) (models.User, error)
+	// GetUserByLogin looks a user up by username or email and returns the
+	// stored password hash alongside the user.
+	GetUserByLogin(ctx context.Context, login string) (models.User, string, error)
+	GetUser(ctx context.Context, id int) (models.User, error)
+	GetUserByCardNumber(ctx context.Context, cardNumber string) (models.User, error)
+}
+
+// AnalyticsStore computes the dashboard statistics
+type AnalyticsStore interface {
+	MostBorrowedBooks(ctx context.Context, scope AnalyticsScope, limit int) ([]models.BorrowCount, error)
+	MonthlyLendingTrends(ctx context.Context, scope AnalyticsScope) ([]models.MonthlyTrend, error)
+	CategoryDistribution(ctx context.Context, scope AnalyticsScope) ([]models.CategoryDistribution, error)
+	// OverdueLoans lists unreturned loans due before asOf, most overdue first
+	OverdueLoans(ctx context.Context, scope AnalyticsScope, asOf time.Time) ([]models.OverdueLoan, error)
+}
+
+// Store is implemented by backends that provide every store
+type Store interface {
+	BookStore
+	ItemStore
+	FileStore
+	LendingStore
+	LoanPolicyStore
+	HoldStore
+	FineStore
+	UserStore
+	AnalyticsStore
+}
+
+// today returns the current UTC date, the day used for borrow, return and due dates
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
@@ -3,14 +3,21 @@
 // FORCE APPLY MARKER
 import { useState, useEffect, useCallback, Suspense } from 'react';
 import * as api from '@/lib/api';
-import { Book } from '@/lib/types';
+import { Book, FacetBucket } from '@/lib/types';
 import { useAuth } from '@/context/AuthContext'; // To ensure user is authenticated
 import BookFormModal from '@/components/BookFormModal'; // Import the modal
 import SearchFilter from '@/components/SearchFilter';
+import Highlight from '@/components/Highlight';
 import { useSearchParams } from 'next/navigation';
 
 function BooksContent() {
   const [books, setBooks] = useState<Book[]>([]);
+  const [total, setTotal] = useState(0);
+  const [similar, setSimilar] = useState(false); // Showing close spellings of the search
+  const [facets, setFacets] = useState<Record<string, FacetBucket[]>>({});
+  const [nextCursor, setNextCursor] = useState<string | undefined>();
+  const [listParams, setListParams] = useState<Record<string, string>>({}); // Filters of the loaded pages
+  const [loadingMore, setLoadingMore] = useState(false);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
   const { isAuthenticated, isLoading: authLoading, user } = useAuth(); // Get auth state and user info
@@ -27,8 +34,14 @@ function BooksContent() {
   
     try {
       setLoading(true);
-      const data = await api.getBooks(params);
-      setBooks(data);
+      // Facets count every matching book, so only the first page asks for them
+      const page = await api.getBooks({ ...params, facets: 'category,author,available' });
+      setBooks(page.data);
+      setTotal(page.total);
+      setSimilar(page.similar ?? false);
+      setFacets(page.facets ?? {});
+      setNextCursor(page.next_cursor);
+      setListParams(params);
       setError(null);
     } catch (err) {
       setError(err instanceof Error ? err.message : 'An error occurred');
@@ -36,6 +49,22 @@ function BooksContent() {
       setLoading(false);
     }
   }, [isAuthenticated]);
+
+  // Append the next page of the current listing
+  const loadMore = async () => {
+    if (!nextCursor) return;
+    try {
+      setLoadingMore(true);
+      const page = await api.getBooks({ ...listParams, cursor: nextCursor });
+      setBooks(prev => [...prev, ...page.data]);
+      setTotal(page.total);
+      setNextCursor(page.next_cursor);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'An error occurred');
+    } finally {
+      setLoadingMore(false);
+    }
+  };
   
   // Handle search from URL parameters
   useEffect(() => {
@@ -65,7 +94,7 @@ function BooksContent() {
 
   const handleSave = () => {
     handleCloseModal(); // Close modal after save
-    fetchBooks(); // Refresh book list
+    fetchBooks(listParams); // Refresh book list
   };
   // ---------------------
 
@@ -74,7 +103,7 @@ function BooksContent() {
     if (window.confirm('Are you sure you want to delete this book? This action cannot be undone.')) {
         try {
           await api.deleteBook(id);
-          fetchBooks(); 
+          fetchBooks(listParams); 
         } catch (err: unknown) { // Use unknown type
           console.error("Failed to delete book:", err);
           // Extract error message safely
@@ -87,14 +116,25 @@ function BooksContent() {
     }
   };
 
+  // Filter options come from the facet counts of the current results
+  const facetOptions = (name: string, label: (value: string) => string = value => value) =>
+    (facets[name] ?? []).map(bucket => ({ value: bucket.value, label: `${label(bucket.value)} (${bucket.count})` }));
+
   const bookFilters = [
+    {
+      name: 'category',
+      label: 'Category',
+      options: facetOptions('category'),
+    },
+    {
+      name: 'author',
+      label: 'Author',
+      options: facetOptions('author'),
+    },
     {
       name: 'available',
       label: 'Availability',
-      options: [
-        { value: 'true', label: 'Available' },
-        { value: 'false', label: 'Borrowed' },
-      ],
+      options: facetOptions('available', value => (value === 'true' ? 'Available' : 'Borrowed')),
     },
   ];
 
@@ -120,21 +160,35 @@ function BooksContent() {
       
       <SearchFilter
         filters={bookFilters}
-        placeholder="Search by title or author..."
+        placeholder='Search title, author, category or ISBN (e.g. "le guin" -earthsea)'
         className="mb-8"
       />
 
+      {similar && books.length > 0 && (
+        <p className="mb-4 text-sm text-gray-600">
+          No exact matches for &quot;{listParams.search}&quot;. Showing books with similar titles or authors.
+        </p>
+      )}
+
       <div className="flex justify-between items-center mb-6">
         <h1 className="text-3xl font-bold">Book Management</h1>
-        {/* Only show Add Book button for admin users */}
-        {isAdmin && (
-          <button 
-            onClick={() => handleOpenModal(null)}
-            className="bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded"
+        <div className="flex gap-2">
+          <button
+            onClick={() => api.exportBooks(listParams).catch((err) => setError(err.message || 'Export failed'))}
+            className="bg-gray-200 hover:bg-gray-300 text-gray-800 font-bold py-2 px-4 rounded"
           >
-            Add New Book
+            Export CSV
           </button>
-        )}
+          {/* Only show Add Book button for admin users */}
+          {isAdmin && (
+            <button 
+              onClick={() => handleOpenModal(null)}
+              className="bg-blue-500 hover:bg-blue-700 text-white font-bold py-2 px-4 rounded"
+            >
+              Add New Book
+            </button>
+          )}
+        </div>
       </div>
       
       {/* Books Table */}
@@ -164,10 +218,16 @@ function BooksContent() {
               ) : (
                 books.map((book) => (
                   <tr key={book.id}>
-                    <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900">{book.title}</td>
-                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500">{book.author}</td>
+                    <td className="px-6 py-4 whitespace-nowrap text-sm font-medium text-gray-900">
+                      {book.highlight ? <Highlight text={book.highlight.title} /> : book.title}
+                    </td>
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500">
+                      {book.highlight ? <Highlight text={book.highlight.author} /> : book.author}
+                    </td>
                     <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500">{book.isbn}</td>
-                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500">{book.category || 'N/A'}</td>
+                    <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500">
+                      {book.highlight?.category ? <Highlight text={book.highlight.category} /> : book.category || 'N/A'}
+                    </td>
                     <td className="px-6 py-4 whitespace-nowrap text-sm text-gray-500 text-center">{book.quantity}</td>
                     {/* Only show action buttons for admin users */}
                     {isAdmin && (
@@ -194,6 +254,19 @@ function BooksContent() {
         </div>
       </div>
 
+      <div className="flex justify-between items-center mt-4 text-sm text-gray-500">
+        <span>Showing {books.length} of {total} books</span>
+        {nextCursor && (
+          <button
+            onClick={loadMore}
+            disabled={loadingMore}
+            className="bg-gray-200 hover:bg-gray-300 text-gray-800 font-bold py-2 px-4 rounded disabled:opacity-50"
+          >
+            {loadingMore ? 'Loading...' : 'Load more'}
+          </button>
+        )}
+      </div>
+
       {/* Only render the modal for admin users */}
       {isAdmin && (
         <BookFormModal 
@@ -45,9 +45,9 @@ export default function DashboardPage() {
       setLoading(true);
       
       Promise.all([
-        api.getMostBorrowed(user.username, user.role),
-        api.getMonthlyTrends(user.username, user.role),
-        api.getCategoryDistribution(user.username, user.role),
+        api.getMostBorrowed(),
+        api.getMonthlyTrends(),
+        api.getCategoryDistribution(),
       ]).then(([borrowedData, trendsData, categoryData]) => {
         setMostBorrowed(borrowedData);
         setMonthlyTrends(trendsData);
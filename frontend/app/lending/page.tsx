@@ -11,6 +11,10 @@ import { useSearchParams } from "next/navigation";
 
 function LendingContent() {
   const [records, setRecords] = useState<LendingRecordDetail[]>([]);
+  const [total, setTotal] = useState(0);
+  const [nextCursor, setNextCursor] = useState<string | undefined>();
+  const [listParams, setListParams] = useState<Record<string, string>>({}); // Filters of the loaded pages
+  const [loadingMore, setLoadingMore] = useState(false);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
   const { isAuthenticated, isLoading: authLoading, user } = useAuth();
@@ -25,15 +29,12 @@ function LendingContent() {
 
       try {
         setLoading(true);
-        const data = await api.getLendingRecords(params);
-        // If user is not admin, filter to show only their records
-        if (user?.role !== "admin") {
-          setRecords(
-            data.filter((record) => record.borrower === user?.username)
-          );
-        } else {
-          setRecords(data);
-        }
+        // Regular users only get their own loans from the backend
+        const page = await api.getLendingRecords(params);
+        setRecords(page.data);
+        setTotal(page.total);
+        setNextCursor(page.next_cursor);
+        setListParams(params);
         setError(null);
       } catch (err) {
         setError(err instanceof Error ? err.message : "An error occurred");
@@ -44,6 +45,22 @@ function LendingContent() {
     [isAuthenticated, user]
   );
 
+  // Append the next page of the current listing
+  const loadMore = async () => {
+    if (!nextCursor) return;
+    try {
+      setLoadingMore(true);
+      const page = await api.getLendingRecords({ ...listParams, cursor: nextCursor });
+      setRecords((prev) => [...prev, ...page.data]);
+      setTotal(page.total);
+      setNextCursor(page.next_cursor);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "An error occurred");
+    } finally {
+      setLoadingMore(false);
+    }
+  };
+
   // Handle search from URL parameters
   useEffect(() => {
     if (!authLoading && isAuthenticated) {
@@ -61,7 +78,7 @@ function LendingContent() {
     if (window.confirm("Mark this book as returned?")) {
       try {
         await api.returnBook(recordId);
-        fetchLendingRecords(); // Refresh list
+        fetchLendingRecords(listParams); // Refresh list
       } catch (err: unknown) {
         console.error("Failed to return book:", err);
         let message = "Failed to return book";
@@ -73,6 +90,20 @@ function LendingContent() {
     }
   };
 
+  const handleRenew = async (recordId: number) => {
+    try {
+      await api.renewBook(recordId);
+      fetchLendingRecords(listParams); // Refresh list
+    } catch (err: unknown) {
+      console.error("Failed to renew loan:", err);
+      let message = "Failed to renew loan";
+      if (typeof err === "object" && err !== null && "message" in err) {
+        message = String((err as { message: unknown }).message);
+      }
+      setError(message);
+    }
+  };
+
   const handleDelete = async (recordId: number) => {
     if (user?.role !== "admin") return; // Only admins can delete
 
@@ -83,7 +114,7 @@ function LendingContent() {
     ) {
       try {
         await api.deleteLendingRecord(recordId);
-        fetchLendingRecords(); // Refresh list
+        fetchLendingRecords(listParams); // Refresh list
       } catch (err: unknown) {
         console.error("Failed to delete lending record:", err);
         let message = "Failed to delete record";
@@ -108,7 +139,7 @@ function LendingContent() {
   // Callback for successful save from lend modal
   const handleLendSave = () => {
     handleCloseLendModal();
-    fetchLendingRecords(); // Refresh the list
+    fetchLendingRecords(listParams); // Refresh the list
   };
 
   const lendingFilters = [
@@ -146,12 +177,20 @@ function LendingContent() {
     <div className="container mx-auto px-4 py-8">
       <div className="flex justify-between items-center mb-6">
         <h1 className="text-3xl font-bold">Lending Management</h1>
-        <button
-          onClick={handleOpenLendModal}
-          className="bg-green-500 hover:bg-green-700 text-white font-bold py-2 px-4 rounded"
-        >
-          {user?.role === 'admin' ? 'Lend New Book' : 'Borrow New Book'}
-        </button>
+        <div className="flex gap-2">
+          <button
+            onClick={() => api.exportLendingRecords(listParams).catch((err) => setError(err.message || "Export failed"))}
+            className="bg-gray-200 hover:bg-gray-300 text-gray-800 font-bold py-2 px-4 rounded"
+          >
+            Export CSV
+          </button>
+          <button
+            onClick={handleOpenLendModal}
+            className="bg-green-500 hover:bg-green-700 text-white font-bold py-2 px-4 rounded"
+          >
+            {user?.role === 'admin' ? 'Lend New Book' : 'Borrow New Book'}
+          </button>
+        </div>
       </div>
 
       <SearchFilter
@@ -198,6 +237,14 @@ function LendingContent() {
                           Return
                         </button>
                       )}
+                      {!record.return_date && (
+                        <button
+                          onClick={() => handleRenew(record.id)}
+                          className="text-indigo-600 hover:text-indigo-900"
+                        >
+                          Renew
+                        </button>
+                      )}
                       {user?.role === "admin" && (
                         <button
                           onClick={() => handleDelete(record.id)}
@@ -215,6 +262,21 @@ function LendingContent() {
         </div>
       </div>
 
+      <div className="flex justify-between items-center mt-4 text-sm text-gray-500">
+        <span>
+          Showing {records.length} of {total} records
+        </span>
+        {nextCursor && (
+          <button
+            onClick={loadMore}
+            disabled={loadingMore}
+            className="bg-gray-200 hover:bg-gray-300 text-gray-800 font-bold py-2 px-4 rounded disabled:opacity-50"
+          >
+            {loadingMore ? "Loading..." : "Load more"}
+          </button>
+        )}
+      </div>
+
       {/* Render the Lend Book Modal */}
       <LendBookModal
         isOpen={isLendModalOpen}
@@ -20,6 +20,8 @@ const initialFormData: BookFormData = {
   isbn: '',
   quantity: 0,
   category: '',
+  publisher: '',
+  year: null,
 };
 
 export default function BookFormModal({ isOpen, onClose, bookToEdit, onSave }: BookFormModalProps) {
@@ -37,6 +39,8 @@ export default function BookFormModal({ isOpen, onClose, bookToEdit, onSave }: B
           isbn: bookToEdit.isbn,
           quantity: bookToEdit.quantity,
           category: bookToEdit.category || '',
+          publisher: bookToEdit.publisher || '',
+          year: bookToEdit.year ?? null,
         });
       } else {
         setFormData(initialFormData); // Reset for new book
@@ -49,7 +53,9 @@ export default function BookFormModal({ isOpen, onClose, bookToEdit, onSave }: B
     const { name, value } = e.target;
     setFormData(prev => ({
       ...prev,
-      [name]: name === 'quantity' ? parseInt(value, 10) || 0 : value, // Ensure quantity is number
+      [name]: name === 'quantity' ? parseInt(value, 10) || 0 // Ensure quantity is number
+        : name === 'year' ? (value === '' ? null : parseInt(value, 10)) // Empty year is unknown
+        : value,
     }));
   };
 
@@ -102,16 +108,25 @@ export default function BookFormModal({ isOpen, onClose, bookToEdit, onSave }: B
           </div>
           <div className="mb-4">
             <label htmlFor="isbn" className="block text-sm font-medium text-gray-700">ISBN</label>
-            <input type="text" name="isbn" id="isbn" value={formData.isbn} onChange={handleChange} required className="mt-1 block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm focus:outline-none focus:ring-indigo-500 focus:border-indigo-500" />
+            <input type="text" name="isbn" id="isbn" value={formData.isbn} onChange={handleChange} required placeholder="ISBN-10 or ISBN-13, hyphens optional" className="mt-1 block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm focus:outline-none focus:ring-indigo-500 focus:border-indigo-500" />
           </div>
           <div className="mb-4">
-            <label htmlFor="quantity" className="block text-sm font-medium text-gray-700">Quantity</label>
-            <input type="number" name="quantity" id="quantity" value={formData.quantity} onChange={handleChange} required min="0" className="mt-1 block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm focus:outline-none focus:ring-indigo-500 focus:border-indigo-500" />
+            <label htmlFor="quantity" className="block text-sm font-medium text-gray-700">{bookToEdit ? 'Available Copies' : 'Copies'}</label>
+            {/* Existing books get copies added or retired one at a time, so the count is read-only here */}
+            <input type="number" name="quantity" id="quantity" value={formData.quantity} onChange={handleChange} required min="0" disabled={!!bookToEdit} className="mt-1 block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm focus:outline-none focus:ring-indigo-500 focus:border-indigo-500 disabled:bg-gray-100" />
           </div>
           <div className="mb-4">
             <label htmlFor="category" className="block text-sm font-medium text-gray-700">Category</label>
             <input type="text" name="category" id="category" value={formData.category} onChange={handleChange} className="mt-1 block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm focus:outline-none focus:ring-indigo-500 focus:border-indigo-500" />
           </div>
+          <div className="mb-4">
+            <label htmlFor="publisher" className="block text-sm font-medium text-gray-700">Publisher</label>
+            <input type="text" name="publisher" id="publisher" value={formData.publisher} onChange={handleChange} className="mt-1 block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm focus:outline-none focus:ring-indigo-500 focus:border-indigo-500" />
+          </div>
+          <div className="mb-4">
+            <label htmlFor="year" className="block text-sm font-medium text-gray-700">Year</label>
+            <input type="number" name="year" id="year" value={formData.year ?? ''} onChange={handleChange} min="1" max="9999" className="mt-1 block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm focus:outline-none focus:ring-indigo-500 focus:border-indigo-500" />
+          </div>
 
           {error && (
             <div className="mb-4 p-2 text-sm text-red-700 bg-red-100 rounded-md border border-red-300">
@@ -0,0 +1,14 @@
+// Renders a search highlight from the backend, where matched terms are wrapped
+// in <mark></mark>. The text is split on the markers rather than parsed as
+// HTML, so book fields can never inject markup.
+export default function Highlight({ text }: { text: string }) {
+  const parts = text.split(/<mark>|<\/mark>/);
+  return (
+    <>
+      {parts.map((part, i) =>
+        // Parts alternate between plain text and matches
+        i % 2 === 1 ? <mark key={i} className="bg-yellow-200">{part}</mark> : part
+      )}
+    </>
+  );
+}
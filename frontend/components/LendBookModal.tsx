@@ -14,7 +14,7 @@ interface LendBookModalProps {
 export default function LendBookModal({ isOpen, onClose, onSave }: LendBookModalProps) {
   const [books, setBooks] = useState<Book[]>([]);
   const [selectedBookId, setSelectedBookId] = useState<string>(''); // Store book ID as string
-  const [borrower, setBorrower] = useState('');
+  const [cardNumber, setCardNumber] = useState('');
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState<string | null>(null);
   const [booksLoading, setBooksLoading] = useState(false);
@@ -25,13 +25,11 @@ export default function LendBookModal({ isOpen, onClose, onSave }: LendBookModal
     if (isOpen) {
       setBooksLoading(true);
       setError(null);
-      setBorrower(''); // Reset fields
+      setCardNumber(''); // Reset fields
       setSelectedBookId('');
-      api.getBooks()
-        .then(data => {
-          // Filter books with quantity > 0 ? Maybe backend should handle this better
-          // Or display quantity and let user know if unavailable
-          setBooks(data.filter(b => b.quantity > 0)); // Only show books in stock
+      api.getBooks({ available: 'true', limit: '200' }) // Only show books in stock
+        .then(page => {
+          setBooks(page.data);
         })
         .catch(err => {
           console.error("Failed to fetch books for lending modal:", err);
@@ -43,26 +41,31 @@ export default function LendBookModal({ isOpen, onClose, onSave }: LendBookModal
     }
   }, [isOpen]);
 
-  // Set borrower name based on user role when modal opens
+  // Regular users borrow for themselves, so show their own card number
   useEffect(() => {
     if (isOpen && user) {
       if (user.role === 'user') {
-        setBorrower(user.username);
+        setCardNumber(user.card_number);
       }
     }
   }, [isOpen, user]);
 
   const handleSubmit = async (e: FormEvent) => {
     e.preventDefault();
-    if (!selectedBookId || !borrower) {
-      setError("Please select a book and enter borrower name.");
+    if (!selectedBookId || !cardNumber) {
+      setError("Please select a book and enter the borrower's card number.");
       return;
     }
     setLoading(true);
     setError(null);
 
     try {
-      await api.lendBook({ book_id: parseInt(selectedBookId, 10), borrower });
+      const bookId = parseInt(selectedBookId, 10);
+      if (user?.role === 'user') {
+        await api.lendBook({ book_id: bookId, user_id: user.id });
+      } else {
+        await api.lendBook({ book_id: bookId, card_number: cardNumber.trim() });
+      }
       onSave(); // Refresh lending list
       onClose(); // Close modal
     } catch (err: unknown) {
@@ -120,13 +123,14 @@ export default function LendBookModal({ isOpen, onClose, onSave }: LendBookModal
           </div>
 
           <div className="mb-4">
-            <label htmlFor="borrower" className="block text-sm font-medium text-gray-700">Borrower Name</label>
+            <label htmlFor="card_number" className="block text-sm font-medium text-gray-700">Borrower Card Number</label>
             <input 
               type="text" 
-              name="borrower" 
-              id="borrower" 
-              value={borrower} 
-              onChange={(e) => setBorrower(e.target.value)} 
+              name="card_number" 
+              id="card_number" 
+              value={cardNumber} 
+              onChange={(e) => setCardNumber(e.target.value)} 
+              placeholder="e.g. P00000002"
               required 
               className="mt-1 block w-full px-3 py-2 border border-gray-300 rounded-md shadow-sm focus:outline-none focus:ring-indigo-500 focus:border-indigo-500 disabled:bg-gray-100"
               disabled={loading || (user?.role === 'user')}
@@ -1,4 +1,4 @@
-import { Book, LendingRecordDetail, BorrowCount, MonthlyTrend, CategoryDistribution } from '@/lib/types';
+import { Book, BookItem, LendingRecordDetail, BorrowCount, MonthlyTrend, CategoryDistribution, FineEntry, Page, BookPage, BookSuggestion, ImportReport, Citation, CitationStyle } from '@/lib/types';
 
 // Base URL for the backend API
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 
@@ -70,6 +70,29 @@ const apiRequest = async <T = unknown>(endpoint: string, options: RequestInit =
   return data as T; // Cast to expected type T only on success
 };
 
+// Downloads a file from an export endpoint, sending the JWT the browser
+// would not add to a plain link
+const downloadExport = async (endpoint: string, params: Record<string, string>, filename: string): Promise<void> => {
+  const token = getToken();
+  const queryString = new URLSearchParams(params).toString();
+  const response = await fetch(`${API_BASE_URL}${endpoint}${queryString ? `?${queryString}` : ''}`, {
+    headers: token ? { Authorization: `Bearer ${token}` } : {},
+    credentials: 'include',
+    mode: 'cors',
+  });
+  if (!response.ok) {
+    const data = await response.json().catch(() => null);
+    throw { status: response.status, message: (data as { error?: string })?.error || response.statusText };
+  }
+
+  const url = URL.createObjectURL(await response.blob());
+  const link = document.createElement('a');
+  link.href = url;
+  link.download = filename;
+  link.click();
+  URL.revokeObjectURL(url);
+};
+
 // --- Auth API --- 
 
 interface LoginResponse {
@@ -79,6 +102,7 @@ interface LoginResponse {
     username: string;
     email: string;
     role: 'admin' | 'user';
+    card_number: string;
     created_at: string;
     updated_at: string;
   };
@@ -118,15 +142,58 @@ export const register = async (userData: { username: string; password: string; e
 // (Mirroring backend/models/Book but omitting server-generated fields)
 type BookInput = Omit<Book, 'id' | 'created_at' | 'updated_at'>;
 
-export const getBooks = async (params?: Record<string, string>): Promise<Book[]> => {
+// Accepts the listing filters plus limit, cursor, sort and order
+export const getBooks = async (params?: Record<string, string>): Promise<BookPage> => {
   const queryString = params ? new URLSearchParams(params).toString() : '';
-  return apiRequest<Book[]>(`/books${queryString ? `?${queryString}` : ''}`); 
+  return apiRequest<BookPage>(`/books${queryString ? `?${queryString}` : ''}`); 
+};
+
+// Downloads every book matching the listing filters
+type BookExportFormat = 'csv' | 'jsonl' | 'marc21' | 'marcxml' | 'bibtex' | 'ris' | 'csljson';
+
+const bookExportExtensions: Record<BookExportFormat, string> = {
+  csv: 'csv',
+  jsonl: 'jsonl',
+  marc21: 'mrc',
+  marcxml: 'xml',
+  bibtex: 'bib',
+  ris: 'ris',
+  csljson: 'json',
+};
+
+export const exportBooks = async (params: Record<string, string>, format: BookExportFormat = 'csv'): Promise<void> => {
+  return downloadExport('/books/export', { ...params, format }, `books.${bookExportExtensions[format]}`);
+};
+
+// Downloads selected books for a reference manager
+export const exportBookReferences = async (ids: number[], format: 'bibtex' | 'ris' | 'csljson'): Promise<void> => {
+  return exportBooks({ ids: ids.join(',') }, format);
+};
+
+export const citeBook = async (id: string | number, style: CitationStyle = 'apa'): Promise<Citation> => {
+  return apiRequest<Citation>(`/books/${id}/cite?style=${style}`);
+};
+
+// Downloads the MARC record of one book
+export const exportBookMarc = async (id: string | number, format: 'marcxml' | 'marc21' = 'marcxml'): Promise<void> => {
+  return downloadExport(`/books/${id}/marc`, { format }, `book-${id}.${bookExportExtensions[format]}`);
+};
+
+// Autocomplete for a partly typed or misspelled title or author
+export const suggestBooks = async (q: string, limit = 10): Promise<BookSuggestion[]> => {
+  const queryString = new URLSearchParams({ q, limit: String(limit) }).toString();
+  return apiRequest<BookSuggestion[]>(`/books/suggest?${queryString}`);
 };
 
 export const getBook = async (id: string | number): Promise<Book> => {
   return apiRequest<Book>(`/books/${id}`);
 };
 
+// Accepts an ISBN-10 or ISBN-13, with or without hyphens
+export const getBookByIsbn = async (isbn: string): Promise<Book> => {
+  return apiRequest<Book>(`/books/isbn/${encodeURIComponent(isbn)}`);
+};
+
 export const createBook = async (bookData: BookInput): Promise<Book> => {
   return apiRequest<Book>('/books', {
     method: 'POST',
@@ -134,6 +201,42 @@ export const createBook = async (bookData: BookInput): Promise<Book> => {
   });
 };
 
+export interface ImportBooksOptions {
+  dryRun?: boolean;
+  columns?: Record<string, string>; // Book field -> CSV header
+  delimiter?: string;
+}
+
+// Uploads a CSV file of books; rows are matched to existing books by ISBN
+export const importBooks = async (file: Blob, options: ImportBooksOptions = {}): Promise<ImportReport> => {
+  const params = new URLSearchParams();
+  if (options.dryRun) params.set('dry_run', 'true');
+  if (options.columns) {
+    params.set('columns', Object.entries(options.columns).map(([field, header]) => `${field}:${header}`).join(','));
+  }
+  if (options.delimiter) params.set('delimiter', options.delimiter);
+  const query = params.toString();
+  return apiRequest<ImportReport>(`/books/import${query ? `?${query}` : ''}`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'text/csv' },
+    body: file,
+  });
+};
+
+// Uploads a binary MARC 21 or MARCXML file of books; the format is detected
+// from the file unless given
+export const importMarc = async (file: Blob, options: { dryRun?: boolean; format?: 'marc21' | 'marcxml' } = {}): Promise<ImportReport> => {
+  const params = new URLSearchParams();
+  if (options.dryRun) params.set('dry_run', 'true');
+  if (options.format) params.set('format', options.format);
+  const query = params.toString();
+  return apiRequest<ImportReport>(`/books/import/marc${query ? `?${query}` : ''}`, {
+    method: 'POST',
+    headers: { 'Content-Type': options.format === 'marcxml' ? 'application/marcxml+xml' : 'application/marc' },
+    body: file,
+  });
+};
+
 export const updateBook = async (id: string | number, bookData: Partial<BookInput>): Promise<Book> => {
   // Use Partial<BookInput> to allow updating only some fields
   return apiRequest<Book>(`/books/${id}`, {
@@ -147,6 +250,32 @@ interface DeleteResponse {
   id: number;
 }
 
+// --- Book copies API ---
+
+interface BookItemInput {
+  barcode?: string; // Generated when omitted
+  condition?: BookItem['condition'];
+  location?: string;
+  acquired_date?: string; // YYYY-MM-DD
+}
+
+export const getBookItems = async (bookId: string | number): Promise<BookItem[]> => {
+  return apiRequest<BookItem[]>(`/books/${bookId}/items`);
+};
+
+export const addBookItem = async (bookId: string | number, item: BookItemInput = {}): Promise<BookItem> => {
+  return apiRequest<BookItem>(`/books/${bookId}/items`, {
+    method: 'POST',
+    body: JSON.stringify(item),
+  });
+};
+
+export const retireBookItem = async (bookId: string | number, itemId: string | number): Promise<BookItem> => {
+  return apiRequest<BookItem>(`/books/${bookId}/items/retire/${itemId}`, {
+    method: 'POST',
+  });
+};
+
 export const deleteBook = async (id: string | number): Promise<DeleteResponse> => {
   return apiRequest<DeleteResponse>(`/books/${id}`, {
     method: 'DELETE',
@@ -155,14 +284,24 @@ export const deleteBook = async (id: string | number): Promise<DeleteResponse> =
 
 // --- Lending API (Placeholders) --- 
 
-export const getLendingRecords = async (params?: Record<string, string>): Promise<LendingRecordDetail[]> => {
+// Accepts the listing filters plus limit, cursor, sort and order
+export const getLendingRecords = async (params?: Record<string, string>): Promise<Page<LendingRecordDetail>> => {
   const queryString = params ? new URLSearchParams(params).toString() : '';
-  return apiRequest<LendingRecordDetail[]>(`/lending${queryString ? `?${queryString}` : ''}`);
+  return apiRequest<Page<LendingRecordDetail>>(`/lending${queryString ? `?${queryString}` : ''}`);
 };
 
+// Downloads every lending record matching the listing filters
+export const exportLendingRecords = async (params: Record<string, string>, format: 'csv' | 'jsonl' = 'csv'): Promise<void> => {
+  return downloadExport('/lending/export', { ...params, format }, `lending.${format}`);
+};
+
+// Regular users borrow for themselves and can omit the borrower; admins name
+// the borrower by account ID or patron card number
 interface LendBookPayload {
   book_id: number;
-  borrower: string;
+  barcode?: string; // Specific copy; any available copy when omitted
+  user_id?: number;
+  card_number?: string;
 }
 
 // The backend returns the created LendingRecord on successful lend
@@ -175,6 +314,7 @@ export const lendBook = async (payload: LendBookPayload): Promise<LendingRecordD
 
 interface ReturnResponse {
   message: string;
+  fines: FineEntry[]; // Charges for late or damaged returns
 }
 
 export const returnBook = async (lendingRecordId: string | number): Promise<ReturnResponse> => {
@@ -183,6 +323,13 @@ export const returnBook = async (lendingRecordId: string | number): Promise<Retu
   });
 };
 
+// Renewing returns the lending record with its extended due date
+export const renewBook = async (lendingRecordId: string | number): Promise<LendingRecordDetail> => {
+  return apiRequest<LendingRecordDetail>(`/lending/renew/${lendingRecordId}`, {
+    method: 'POST',
+  });
+};
+
 interface DeleteLendingResponse {
   message: string;
 }
@@ -194,27 +341,19 @@ export const deleteLendingRecord = async (lendingRecordId: string | number): Pro
 };
 
 // --- Analytics API --- 
+// The data scope comes from the JWT; admins may pass a user id to see that user's view
 
-export const getMostBorrowed = async (username?: string, role?: string): Promise<BorrowCount[]> => {
-  const queryParams = new URLSearchParams();
-  if (username) queryParams.append('username', username);
-  if (role) queryParams.append('role', role);
-  const queryString = queryParams.toString();
-  return apiRequest<BorrowCount[]>(`/analytics/most-borrowed${queryString ? `?${queryString}` : ''}`);
+export const getMostBorrowed = async (userId?: number): Promise<BorrowCount[]> => {
+  const queryString = userId ? `?user_id=${userId}` : '';
+  return apiRequest<BorrowCount[]>(`/analytics/most-borrowed${queryString}`);
 };
 
-export const getMonthlyTrends = async (username?: string, role?: string): Promise<MonthlyTrend[]> => {
-  const queryParams = new URLSearchParams();
-  if (username) queryParams.append('username', username);
-  if (role) queryParams.append('role', role);
-  const queryString = queryParams.toString();
-  return apiRequest<MonthlyTrend[]>(`/analytics/monthly-trends${queryString ? `?${queryString}` : ''}`);
+export const getMonthlyTrends = async (userId?: number): Promise<MonthlyTrend[]> => {
+  const queryString = userId ? `?user_id=${userId}` : '';
+  return apiRequest<MonthlyTrend[]>(`/analytics/monthly-trends${queryString}`);
 };
 
-export const getCategoryDistribution = async (username?: string, role?: string): Promise<CategoryDistribution[]> => {
-  const queryParams = new URLSearchParams();
-  if (username) queryParams.append('username', username);
-  if (role) queryParams.append('role', role);
-  const queryString = queryParams.toString();
-  return apiRequest<CategoryDistribution[]>(`/analytics/category-distribution${queryString ? `?${queryString}` : ''}`);
+export const getCategoryDistribution = async (userId?: number): Promise<CategoryDistribution[]> => {
+  const queryString = userId ? `?user_id=${userId}` : '';
+  return apiRequest<CategoryDistribution[]>(`/analytics/category-distribution${queryString}`);
 }; 
\ No newline at end of file
@@ -4,10 +4,95 @@ export interface Book {
   title: string;
   author: string;
   isbn: string;
-  quantity: number;
+  quantity: number; // Available copies
   category: string;
+  publisher: string;
+  year?: number | null; // Year of publication, when known
   created_at: string; // Use string for dates, can parse if needed
   updated_at: string;
+  rank?: number; // Relevance, on search results only
+  highlight?: BookHighlight; // On search results only
+}
+
+// Matches backend/models/models.go -> BookHighlight, fields with matched
+// terms wrapped in <mark></mark>
+export interface BookHighlight {
+  title: string;
+  author: string;
+  category: string;
+}
+
+// Matches backend/models/models.go -> BookItem, a physical copy of a book
+export interface BookItem {
+  id: number;
+  book_id: number;
+  barcode: string;
+  status: 'available' | 'on_loan' | 'lost' | 'retired';
+  condition: 'good' | 'damaged';
+  location: string;
+  acquired_date: string;
+  retired_at?: string | null;
+  created_at: string;
+  updated_at: string;
+}
+
+// Matches backend/models/models.go -> BookPage and LendingRecordPage: one page
+// of a listing; pass next_cursor back as ?cursor= for the following page
+export interface Page<T> {
+  data: T[];
+  next_cursor?: string; // Missing on the last page
+  total: number; // Rows matching the filters across all pages
+}
+
+// Matches backend/models/models.go -> BookPage; similar is set when nothing
+// matched the search and data holds the closest spellings instead
+export interface BookPage extends Page<Book> {
+  similar?: boolean;
+  facets?: Record<string, FacetBucket[]>; // When requested with ?facets=
+}
+
+// Matches backend/models/models.go -> FacetBucket
+export interface FacetBucket {
+  value: string; // Usable as the filter of the same name
+  count: number;
+}
+
+// Matches backend/models/models.go -> BookSuggestion
+export interface BookSuggestion {
+  book_id: number;
+  title: string;
+  author: string;
+  score: number; // Trigram similarity, 0 to 1
+}
+
+// Matches backend/models/models.go -> ImportRow
+export interface ImportRow {
+  row: number; // Line of the record in the CSV file; the header is row 1
+  isbn?: string;
+  status: 'created' | 'updated' | 'skipped' | 'error';
+  book_id?: number;
+  reason?: string;
+}
+
+// Matches backend/models/models.go -> Citation
+export interface Citation {
+  book_id: number;
+  style: CitationStyle;
+  text: string;
+  html: string; // HTML-escaped, with the title in <i></i>
+}
+
+export type CitationStyle = 'apa' | 'mla' | 'chicago';
+
+// Matches backend/models/models.go -> ImportReport
+export interface ImportReport {
+  dry_run: boolean;
+  created: number;
+  updated: number;
+  skipped: number;
+  errors: number;
+  rows: ImportRow[];
+  unmapped?: Record<string, number>; // MARC imports: fields and subfields the mapping did not read
 }
 
 // User type definition
@@ -16,24 +101,46 @@ export interface User {
   username: string;
   email: string;
   role: 'admin' | 'user';
+  card_number: string; // Patron card number
   created_at: string;
   updated_at: string;
 }
 
-// Matches backend/handlers/lending_handler.go -> LendingRecordDetail
+// Matches backend/models/models.go -> LendingRecordDetail
 export interface LendingRecordDetail {
   id: number;
   book_id: number;
-  borrower: string;
+  item_id?: number | null; // Copy lent
+  user_id?: number | null; // Borrower's account; missing for loans that predate accounts
+  borrower: string; // Borrower's name at the time of the loan
   borrow_date: string; 
+  due_date: string;
   return_date?: string | null; // Optional/nullable
+  loan_policy_id?: number | null;
+  return_condition?: 'good' | 'damaged' | 'lost' | null;
+  renewal_count: number;
   created_at: string;
   updated_at: string;
   book_title: string; // Joined data
   book_author: string; // Joined data
+  item_barcode?: string | null; // Joined data
+  status: 'active' | 'overdue' | 'returned' | 'lost';
+}
+
+// Matches backend/models/models.go -> FineEntry (amounts in cents)
+export interface FineEntry {
+  id: number;
+  user_id?: number | null;
+  borrower: string;
+  lending_record_id?: number | null;
+  entry_type: 'charge' | 'payment' | 'waiver';
+  reason?: 'overdue' | 'lost' | 'damaged' | null;
+  amount_cents: number;
+  note: string;
+  created_at: string;
 }
 
-// Matches backend/handlers/analytics_handler.go -> BorrowCount
+// Matches backend/models/models.go -> BorrowCount
 export interface BorrowCount {
   book_id: number;
   book_title: string;